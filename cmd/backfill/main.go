@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+
+	"github.com/asecurityteam/logevent"
+
+	"github.com/asecurityteam/asset-inventory-api/pkg/backfill"
+	"github.com/asecurityteam/asset-inventory-api/pkg/domain"
+	v1 "github.com/asecurityteam/asset-inventory-api/pkg/handlers/v1"
+	"github.com/asecurityteam/asset-inventory-api/pkg/storage"
+	"github.com/asecurityteam/settings"
+)
+
+type config struct {
+	PostgresConfig *storage.PostgresConfig
+}
+
+func (*config) Name() string {
+	return "AIAPI"
+}
+
+type component struct {
+	PostgresConfig *storage.PostgresConfigComponent
+}
+
+func (c *component) Settings() *config {
+	return &config{
+		PostgresConfig: c.PostgresConfig.Settings(),
+	}
+}
+
+func (c *component) New(ctx context.Context, conf *config) (domain.CloudAssetStorer, error) {
+	return c.PostgresConfig.New(ctx, conf.PostgresConfig, storage.Primary)
+}
+
+// backfill stores a file of v1 change payloads, one per line, using the same environment settings as the service
+func main() {
+	fs := flag.NewFlagSet("backfill", flag.ContinueOnError)
+	file := fs.String("file", "", "file of change payloads, one JSON document per line")
+	workers := fs.Int("workers", 4, "number of resources stored concurrently")
+	checkpoint := fs.String("checkpoint", "", "file recording progress to resume from, defaults to the input file with a .checkpoint suffix")
+	dryRun := fs.Bool("dry-run", false, "validate the records without storing them")
+	if err := fs.Parse(os.Args[1:]); err != nil {
+		if err == flag.ErrHelp {
+			return
+		}
+		os.Exit(2)
+	}
+	if *file == "" {
+		fmt.Fprintln(os.Stderr, "-file is required")
+		os.Exit(2)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	interrupts := make(chan os.Signal, 1)
+	signal.Notify(interrupts, os.Interrupt)
+	go func() {
+		<-interrupts
+		cancel() // stop at the next record, the checkpoint is written on the way out
+	}()
+
+	logger := logevent.New(logevent.Config{Level: "INFO", Output: os.Stderr})
+	logFn := func(context.Context) domain.Logger { return logger }
+
+	var storer domain.CloudAssetStorer = backfill.DryRunStorer{}
+	if *dryRun {
+		*checkpoint = ""
+	} else {
+		if *checkpoint == "" {
+			*checkpoint = *file + ".checkpoint"
+		}
+		source, err := settings.NewEnvSource(os.Environ())
+		if err != nil {
+			panic(err.Error())
+		}
+		if err = settings.NewComponent(ctx, source, &component{PostgresConfig: storage.NewPostgresComponent()}, &storer); err != nil {
+			panic(err.Error())
+		}
+	}
+
+	input, err := os.Open(*file)
+	if err != nil {
+		panic(err.Error())
+	}
+	defer input.Close()
+
+	loader := backfill.Loader{
+		LogFn: logFn,
+		Inserter: &v1.CloudInsertHandler{
+			LogFn:            logFn,
+			StatFn:           domain.StatFromContext,
+			CloudAssetStorer: storer,
+		},
+		Workers:    *workers,
+		Checkpoint: *checkpoint,
+	}
+	summary, err := loader.Load(ctx, input)
+	fmt.Printf("applied %d, skipped %d, failed %d\n", summary.Applied, summary.Skipped, summary.Failed)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/asecurityteam/logevent"
+
+	"github.com/asecurityteam/asset-inventory-api/pkg/awsconfig"
+	"github.com/asecurityteam/asset-inventory-api/pkg/domain"
+	"github.com/asecurityteam/asset-inventory-api/pkg/storage"
+	"github.com/asecurityteam/settings"
+)
+
+type config struct {
+	PostgresConfig *storage.PostgresConfig
+}
+
+func (*config) Name() string {
+	return "AIAPI"
+}
+
+type component struct {
+	PostgresConfig *storage.PostgresConfigComponent
+}
+
+func (c *component) Settings() *config {
+	return &config{
+		PostgresConfig: c.PostgresConfig.Settings(),
+	}
+}
+
+func (c *component) New(ctx context.Context, conf *config) (*storage.DB, error) {
+	return c.PostgresConfig.New(ctx, conf.PostgresConfig, storage.Primary)
+}
+
+// import-snapshot records the state described by an AWS Config snapshot file, using the same environment settings as
+// the service
+func main() {
+	fs := flag.NewFlagSet("import-snapshot", flag.ContinueOnError)
+	file := fs.String("file", "", "AWS Config snapshot file")
+	at := fs.String("time", "", "time of the snapshot in RFC3339 format, defaults to the latest capture time of its items")
+	if err := fs.Parse(os.Args[1:]); err != nil {
+		if err == flag.ErrHelp {
+			return
+		}
+		os.Exit(2)
+	}
+	if *file == "" {
+		fmt.Fprintln(os.Stderr, "-file is required")
+		os.Exit(2)
+	}
+
+	input, err := os.Open(*file)
+	if err != nil {
+		panic(err.Error())
+	}
+	defer input.Close()
+	snapshot, err := awsconfig.ReadSnapshot(input)
+	if err != nil {
+		panic(err.Error())
+	}
+	var when time.Time
+	if *at != "" {
+		when, err = time.Parse(time.RFC3339Nano, *at)
+	} else {
+		when, err = snapshot.Time()
+	}
+	if err != nil {
+		panic(err.Error())
+	}
+
+	ctx := context.Background()
+	source, err := settings.NewEnvSource(os.Environ())
+	if err != nil {
+		panic(err.Error())
+	}
+	db := new(*storage.DB)
+	if err = settings.NewComponent(ctx, source, &component{PostgresConfig: storage.NewPostgresComponent()}, db); err != nil {
+		panic(err.Error())
+	}
+
+	logger := logevent.New(logevent.Config{Level: "INFO", Output: os.Stderr})
+	importer := awsconfig.Importer{
+		LogFn:   func(context.Context) domain.Logger { return logger },
+		Fetcher: *db,
+		Storer:  *db,
+	}
+	summary, err := importer.Import(ctx, snapshot, when)
+	fmt.Printf("snapshot %s at %s: recorded %d, terminated %d, unsupported %d, failed %d\n",
+		snapshot.ConfigSnapshotID, when.Format(time.RFC3339), summary.Recorded, summary.Terminated, summary.Unsupported, summary.Failed)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/asecurityteam/asset-inventory-api/pkg/domain"
+	"github.com/asecurityteam/asset-inventory-api/pkg/storage"
+	"github.com/asecurityteam/settings"
+)
+
+type config struct {
+	PostgresConfig *storage.PostgresConfig
+}
+
+func (*config) Name() string {
+	return "AIAPI"
+}
+
+type component struct {
+	PostgresConfig *storage.PostgresConfigComponent
+	Scope          domain.ReplayScope
+}
+
+func (c *component) Settings() *config {
+	return &config{
+		PostgresConfig: c.PostgresConfig.Settings(),
+	}
+}
+
+func (c *component) New(ctx context.Context, conf *config) (func(context.Context) (domain.ReplayResult, error), error) {
+	primaryStorage, err := c.PostgresConfig.New(ctx, conf.PostgresConfig, storage.Primary)
+	if err != nil {
+		return nil, err
+	}
+	return func(ctx context.Context) (domain.ReplayResult, error) {
+		return primaryStorage.Replay(ctx, c.Scope)
+	}, nil
+}
+
+// replay rebuilds resources from the change journal, using the same environment settings as the service
+func main() {
+	ctx := context.Background()
+	fs := flag.NewFlagSet("replay", flag.ContinueOnError)
+	resourceID := fs.String("resource", "", "resource ID to replay")
+	accountID := fs.String("account", "", "account ID to replay")
+	region := fs.String("region", "", "region to replay")
+	all := fs.Bool("all", false, "replay every resource in the journal")
+	if err := fs.Parse(os.Args[1:]); err != nil {
+		if err == flag.ErrHelp {
+			return
+		}
+		os.Exit(2)
+	}
+	if *resourceID == "" && *accountID == "" && *region == "" && !*all {
+		fmt.Fprintln(os.Stderr, "one of -resource, -account, -region or -all is required")
+		os.Exit(2)
+	}
+
+	source, err := settings.NewEnvSource(os.Environ())
+	if err != nil {
+		panic(err.Error())
+	}
+	cmp := &component{
+		PostgresConfig: storage.NewPostgresComponent(),
+		Scope:          domain.ReplayScope{ResourceID: *resourceID, AccountID: *accountID, Region: *region},
+	}
+	runner := new(func(context.Context) (domain.ReplayResult, error))
+	if err = settings.NewComponent(ctx, source, cmp, runner); err != nil {
+		panic(err.Error())
+	}
+	result, err := (*runner)(ctx)
+	if err != nil {
+		panic(err.Error())
+	}
+	fmt.Printf("replayed %d changes of %d resources\n", result.Events, result.Resources)
+}
@@ -0,0 +1,3 @@
+-- Removing GiST indexes on IP assignments
+DROP INDEX CONCURRENTLY IF EXISTS aws_private_ip_assignment_idx_private_ip_gist;
+DROP INDEX CONCURRENTLY IF EXISTS aws_public_ip_assignment_idx_public_ip_gist;
@@ -0,0 +1,7 @@
+-- Adding GiST indexes on IP assignments to support subnet containment lookups
+BEGIN;
+
+CREATE INDEX IF NOT EXISTS aws_private_ip_assignment_idx_private_ip_gist ON aws_private_ip_assignment USING gist (private_ip inet_ops);
+CREATE INDEX IF NOT EXISTS aws_public_ip_assignment_idx_public_ip_gist ON aws_public_ip_assignment USING gist (public_ip inet_ops);
+
+COMMIT;
@@ -0,0 +1,12 @@
+-- Removing VPC and subnet from private IP assignments along with the scoped lookups
+BEGIN;
+
+DROP FUNCTION IF EXISTS get_resource_by_private_ip(INET, TIMESTAMP, VARCHAR, VARCHAR);
+DROP FUNCTION IF EXISTS get_resource_by_public_ip(INET, TIMESTAMP, VARCHAR);
+
+DROP INDEX IF EXISTS idx_private_ip_vpc_id;
+
+ALTER TABLE aws_private_ip_assignment DROP COLUMN IF EXISTS subnet_id;
+ALTER TABLE aws_private_ip_assignment DROP COLUMN IF EXISTS vpc_id;
+
+COMMIT;
@@ -0,0 +1,150 @@
+-- Adding VPC and subnet to private IP assignments, and lookups narrowed to an account and VPC
+-- as RFC1918 ranges are reused across accounts and VPCs
+BEGIN;
+
+ALTER TABLE aws_private_ip_assignment ADD COLUMN IF NOT EXISTS vpc_id VARCHAR;
+ALTER TABLE aws_private_ip_assignment ADD COLUMN IF NOT EXISTS subnet_id VARCHAR;
+
+CREATE INDEX IF NOT EXISTS idx_private_ip_vpc_id ON aws_private_ip_assignment (private_ip, vpc_id);
+
+-- acct and vpc are optional, NULL matches any account or VPC
+CREATE OR REPLACE FUNCTION get_resource_by_private_ip(pip INET, ts TIMESTAMP, acct VARCHAR, vpc VARCHAR)
+    RETURNS TABLE
+            (
+                private_ip    INET,
+                arn_id        VARCHAR,
+                meta          JSONB,
+                region        VARCHAR,
+                resource_type VARCHAR,
+                account       VARCHAR,
+                id            INTEGER,
+                t_account     VARCHAR,
+                t_login       VARCHAR,
+                t_email       VARCHAR,
+                t_name        VARCHAR,
+                t_valid       BOOL,
+                p_login       VARCHAR,
+                p_email       VARCHAR,
+                p_name        VARCHAR,
+                p_valid       BOOL
+            )
+AS
+$$
+BEGIN
+    RETURN QUERY WITH wres AS (SELECT ia.private_ip,
+                                      res.arn_id,
+                                      res.meta,
+                                      ar.region,
+                                      rt.resource_type,
+                                      aa.account,
+                                      aa.id
+                               FROM aws_private_ip_assignment ia
+                                        LEFT JOIN aws_resource res ON ia.aws_resource_id = res.id
+                                        LEFT JOIN aws_region ar ON res.aws_region_id = ar.id
+                                        LEFT JOIN aws_resource_type rt ON res.aws_resource_type_id = rt.id
+                                        LEFT JOIN aws_account aa ON res.aws_account_id = aa.id
+                               WHERE ia.private_ip = pip
+                                 AND ia.not_before < ts
+                                 AND (ia.not_after IS NULL OR ia.not_after > ts)
+                                 AND (acct IS NULL OR aa.account = acct)
+                                 AND (vpc IS NULL OR ia.vpc_id = vpc))
+                 SELECT wres.private_ip,
+                        wres.arn_id,
+                        wres.meta,
+                        wres.region,
+                        wres.resource_type,
+                        wres.account,
+                        wres.id,
+                        b.t_account,
+                        b.t_login,
+                        b.t_email,
+                        b.t_name,
+                        b.t_valid,
+                        b.p_login,
+                        b.p_email,
+                        b.p_name,
+                        b.p_valid
+                 FROM wres
+                          LEFT JOIN
+                      (
+                          SELECT distinct iwres.id, f.*
+                          FROM wres iwres,
+                               LATERAL get_owner_and_champions_by_account_id(iwres.id) f
+                      ) b
+                      ON wres.account = b.t_account;
+END;
+$$
+    LANGUAGE 'plpgsql';
+
+-- acct is optional, NULL matches any account
+CREATE OR REPLACE FUNCTION get_resource_by_public_ip(pip INET, ts TIMESTAMP, acct VARCHAR)
+    RETURNS TABLE
+            (
+                public_ip     INET,
+                aws_hostname  VARCHAR,
+                arn_id        VARCHAR,
+                meta          JSONB,
+                region        VARCHAR,
+                resource_type VARCHAR,
+                account       VARCHAR,
+                id            INTEGER,
+                t_account     VARCHAR,
+                t_login       VARCHAR,
+                t_email       VARCHAR,
+                t_name        VARCHAR,
+                t_valid       BOOL,
+                p_login       VARCHAR,
+                p_email       VARCHAR,
+                p_name        VARCHAR,
+                p_valid       BOOL
+            )
+AS
+$$
+BEGIN
+    RETURN QUERY WITH wres AS (SELECT ia.public_ip,
+                                      ia.aws_hostname,
+                                      res.arn_id,
+                                      res.meta,
+                                      ar.region,
+                                      rt.resource_type,
+                                      aa.account,
+                                      aa.id
+                               FROM aws_public_ip_assignment ia
+                                        LEFT JOIN aws_resource res ON ia.aws_resource_id = res.id
+                                        LEFT JOIN aws_region ar ON res.aws_region_id = ar.id
+                                        LEFT JOIN aws_resource_type rt ON res.aws_resource_type_id = rt.id
+                                        LEFT JOIN aws_account aa ON res.aws_account_id = aa.id
+                               WHERE ia.public_ip = pip
+                                 AND ia.not_before < ts
+                                 AND (ia.not_after IS NULL OR ia.not_after > ts)
+                                 AND (acct IS NULL OR aa.account = acct))
+                 SELECT wres.public_ip,
+                        wres.aws_hostname,
+                        wres.arn_id,
+                        wres.meta,
+                        wres.region,
+                        wres.resource_type,
+                        wres.account,
+                        wres.id,
+                        b.t_account,
+                        b.t_login,
+                        b.t_email,
+                        b.t_name,
+                        b.t_valid,
+                        b.p_login,
+                        b.p_email,
+                        b.p_name,
+                        b.p_valid
+                 FROM wres
+                          LEFT JOIN
+                      (
+                          SELECT distinct iwres.id, f.*
+                          FROM wres iwres,
+                               LATERAL get_owner_and_champions_by_account_id(iwres.id) f
+                      ) b
+                      ON wres.account = b.t_account;
+END;
+$$
+    LANGUAGE 'plpgsql';
+
+COMMIT;
@@ -0,0 +1,6 @@
+-- Removing the index for the search by tag
+BEGIN;
+
+DROP INDEX IF EXISTS idx_aws_resource_tags_meta;
+
+COMMIT;
@@ -0,0 +1,7 @@
+-- Indexing tags of resources so assets can be searched by tag, the default operator class supports both
+-- containment (exact values) and key existence
+BEGIN;
+
+CREATE INDEX IF NOT EXISTS idx_aws_resource_tags_meta ON aws_resource_tags USING gin (meta);
+
+COMMIT;
@@ -0,0 +1,6 @@
+-- Removing the index for the hostname search, the extension is left in place as other objects may rely on it
+BEGIN;
+
+DROP INDEX IF EXISTS idx_aws_hostname_trgm;
+
+COMMIT;
@@ -0,0 +1,8 @@
+-- Indexing hostnames by trigrams so they can be searched by prefix, suffix or substring
+BEGIN;
+
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE INDEX IF NOT EXISTS idx_aws_hostname_trgm ON aws_public_ip_assignment USING gin (aws_hostname gin_trgm_ops);
+
+COMMIT;
@@ -0,0 +1,7 @@
+-- Removing indexes on both ends of aws_resource_relationship
+BEGIN;
+
+DROP INDEX IF EXISTS idx_aws_resource_relationship_related_arn_id;
+DROP INDEX IF EXISTS idx_aws_resource_relationship_arn_id;
+
+COMMIT;
@@ -0,0 +1,7 @@
+-- Adding indexes on both ends of aws_resource_relationship so relationships can be walked either way
+BEGIN;
+
+CREATE INDEX IF NOT EXISTS idx_aws_resource_relationship_arn_id ON aws_resource_relationship (arn_id);
+CREATE INDEX IF NOT EXISTS idx_aws_resource_relationship_related_arn_id ON aws_resource_relationship (related_arn_id);
+
+COMMIT;
@@ -0,0 +1,7 @@
+-- Removing the existence interval of resources
+BEGIN;
+
+ALTER TABLE aws_resource DROP COLUMN IF EXISTS not_after;
+ALTER TABLE aws_resource DROP COLUMN IF EXISTS not_before;
+
+COMMIT;
@@ -0,0 +1,7 @@
+-- Adding the existence interval of resources, set by resource level created and terminated events
+BEGIN;
+
+ALTER TABLE aws_resource ADD COLUMN IF NOT EXISTS not_before TIMESTAMP;
+ALTER TABLE aws_resource ADD COLUMN IF NOT EXISTS not_after TIMESTAMP;
+
+COMMIT;
@@ -0,0 +1,6 @@
+-- Removing the change journal
+BEGIN;
+
+DROP TABLE IF EXISTS aws_change_journal;
+
+COMMIT;
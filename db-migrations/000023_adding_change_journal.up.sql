@@ -0,0 +1,17 @@
+-- Adding an append-only journal of every change stored, so the assignments of resources can be rebuilt from it
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS aws_change_journal
+(
+    id          bigserial primary key,
+    received_at timestamp not null default now(),
+    change_time timestamp not null,
+    arn_id      varchar   not null, /* resource ID as in aws_resource, not the full ARN */
+    account     varchar   not null,
+    changes     JSONB     not null
+);
+
+CREATE INDEX IF NOT EXISTS idx_aws_change_journal_arn_id ON aws_change_journal (arn_id, change_time, id);
+CREATE INDEX IF NOT EXISTS idx_aws_change_journal_account ON aws_change_journal (account, arn_id);
+
+COMMIT;
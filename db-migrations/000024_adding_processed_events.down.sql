@@ -0,0 +1,6 @@
+-- Removing the processed event IDs
+BEGIN;
+
+DROP TABLE IF EXISTS aws_processed_event;
+
+COMMIT;
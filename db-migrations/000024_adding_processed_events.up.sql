@@ -0,0 +1,12 @@
+-- Adding the event IDs of processed changes, remembered for a configurable window to ignore redeliveries
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS aws_processed_event
+(
+    event_id     VARCHAR   NOT NULL PRIMARY KEY,
+    processed_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_aws_processed_event_processed_at ON aws_processed_event (processed_at);
+
+COMMIT;
@@ -0,0 +1,10 @@
+-- Removing the network interfaces of addresses, public IP addresses without a hostname get an empty one
+BEGIN;
+
+ALTER TABLE aws_public_ip_assignment DROP COLUMN IF EXISTS network_interface_id;
+ALTER TABLE aws_private_ip_assignment DROP COLUMN IF EXISTS network_interface_id;
+
+UPDATE aws_public_ip_assignment SET aws_hostname = '' WHERE aws_hostname IS NULL;
+ALTER TABLE aws_public_ip_assignment ALTER COLUMN aws_hostname SET NOT NULL;
+
+COMMIT;
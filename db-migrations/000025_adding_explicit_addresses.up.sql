@@ -0,0 +1,10 @@
+-- Adding public IP addresses without a hostname, and the network interface holding an address, as given by changes
+-- pairing each address with its own hostname and network interface
+BEGIN;
+
+ALTER TABLE aws_public_ip_assignment ALTER COLUMN aws_hostname DROP NOT NULL;
+
+ALTER TABLE aws_private_ip_assignment ADD COLUMN IF NOT EXISTS network_interface_id VARCHAR;
+ALTER TABLE aws_public_ip_assignment ADD COLUMN IF NOT EXISTS network_interface_id VARCHAR;
+
+COMMIT;
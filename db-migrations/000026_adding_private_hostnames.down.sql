@@ -0,0 +1,8 @@
+-- Removing hostnames of private IP addresses
+BEGIN;
+
+DROP FUNCTION IF EXISTS get_private_hostnames_by_arn_id(VARCHAR, TIMESTAMP);
+DROP FUNCTION IF EXISTS get_resource_by_private_hostname(VARCHAR, TIMESTAMP);
+DROP TABLE IF EXISTS aws_private_hostname_assignment;
+
+COMMIT;
@@ -0,0 +1,117 @@
+-- Adding hostnames of private IP addresses, such as private DNS names and internal Route53 names. A private address
+-- may be known by several names at once, so they are assigned separately from the address.
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS aws_private_hostname_assignment
+(
+    id              bigserial primary key,
+    not_before      timestamp not null,
+    not_after       timestamp,
+    private_ip      inet      not null,
+    aws_hostname    varchar   not null,
+    aws_resource_id bigint    not null,
+    foreign key (aws_resource_id) references aws_resource (id)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS aws_private_hostname_assignment_idx_no_after
+    ON aws_private_hostname_assignment (not_before, private_ip, aws_hostname, aws_resource_id) WHERE not_after IS NULL;
+CREATE INDEX IF NOT EXISTS idx_private_hostname ON aws_private_hostname_assignment (aws_hostname);
+CREATE INDEX IF NOT EXISTS idx_private_hostname_resource_id ON aws_private_hostname_assignment (aws_resource_id);
+
+CREATE OR REPLACE FUNCTION get_resource_by_private_hostname(name VARCHAR, ts TIMESTAMP)
+    RETURNS TABLE
+            (
+                private_ip    INET,
+                arn_id        VARCHAR,
+                meta          JSONB,
+                region        VARCHAR,
+                resource_type VARCHAR,
+                account       VARCHAR,
+                id            INTEGER,
+                t_account     VARCHAR,
+                t_login       VARCHAR,
+                t_email       VARCHAR,
+                t_name        VARCHAR,
+                t_valid       BOOL,
+                p_login       VARCHAR,
+                p_email       VARCHAR,
+                p_name        VARCHAR,
+                p_valid       BOOL
+            )
+AS
+$$
+BEGIN
+    RETURN QUERY WITH wres AS (SELECT ha.private_ip,
+                                      res.arn_id,
+                                      get_tags_at(res.id, ts) AS meta,
+                                      ar.region,
+                                      rt.resource_type,
+                                      aa.account,
+                                      aa.id
+                               FROM aws_private_hostname_assignment ha
+                                        LEFT JOIN aws_resource res ON ha.aws_resource_id = res.id
+                                        LEFT JOIN aws_region ar ON res.aws_region_id = ar.id
+                                        LEFT JOIN aws_resource_type rt ON res.aws_resource_type_id = rt.id
+                                        LEFT JOIN aws_account aa ON res.aws_account_id = aa.id
+                               WHERE ha.aws_hostname = name
+                                 AND ha.not_before < ts
+                                 AND (ha.not_after IS NULL OR ha.not_after > ts))
+                 SELECT wres.private_ip,
+                        wres.arn_id,
+                        wres.meta,
+                        wres.region,
+                        wres.resource_type,
+                        wres.account,
+                        wres.id,
+                        b.t_account,
+                        b.t_login,
+                        b.t_email,
+                        b.t_name,
+                        b.t_valid,
+                        b.p_login,
+                        b.p_email,
+                        b.p_name,
+                        b.p_valid
+                 FROM wres
+                          LEFT JOIN
+                      (
+                          SELECT distinct iwres.id, f.*
+                          FROM wres iwres,
+                               LATERAL get_owner_and_champions_by_account_id(iwres.id) f
+                      ) b
+                      ON wres.account = b.t_account;
+END;
+$$
+    LANGUAGE 'plpgsql';
+
+-- the private hostnames reported along with the addresses of get_resource_by_arn_id, which come from the resource
+-- relating to the given one when there is such a resource
+CREATE OR REPLACE FUNCTION get_private_hostnames_by_arn_id(aid VARCHAR, ts TIMESTAMP)
+    RETURNS TABLE
+            (
+                aws_hostname VARCHAR
+            )
+AS
+$$
+DECLARE
+    var_parent_arn_id varchar;
+BEGIN
+    SELECT arn_id INTO var_parent_arn_id FROM aws_resource_relationship
+    WHERE related_arn_id = aid;
+
+    IF NOT FOUND THEN
+        var_parent_arn_id := aid;
+    END IF;
+
+    RETURN QUERY SELECT DISTINCT ha.aws_hostname
+                 FROM aws_private_hostname_assignment ha
+                          JOIN aws_resource res ON ha.aws_resource_id = res.id
+                 WHERE res.arn_id = var_parent_arn_id
+                   AND ha.not_before < ts
+                   AND (ha.not_after IS NULL OR ha.not_after > ts)
+                 ORDER BY ha.aws_hostname;
+END;
+$$
+    LANGUAGE 'plpgsql';
+
+COMMIT;
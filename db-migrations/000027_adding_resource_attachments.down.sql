@@ -0,0 +1,6 @@
+-- Removing attachments of network interfaces and elastic IPs
+BEGIN;
+
+DROP TABLE IF EXISTS aws_resource_attachment;
+
+COMMIT;
@@ -0,0 +1,19 @@
+-- Adding attachments of network interfaces and elastic IPs, so that an address held by one of them can be followed
+-- to the instance it was attached to at the time. Like relationships, attachments are recorded by resource ID.
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS aws_resource_attachment
+(
+    id                 bigserial primary key,
+    not_before         timestamp not null,
+    not_after          timestamp,
+    arn_id             varchar   not null,
+    attached_to_arn_id varchar   not null
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS aws_resource_attachment_idx_no_after
+    ON aws_resource_attachment (not_before, arn_id, attached_to_arn_id) WHERE not_after IS NULL;
+CREATE INDEX IF NOT EXISTS idx_aws_resource_attachment_arn_id ON aws_resource_attachment (arn_id, not_before);
+CREATE INDEX IF NOT EXISTS idx_aws_resource_attachment_attached_to ON aws_resource_attachment (attached_to_arn_id);
+
+COMMIT;
@@ -0,0 +1,19 @@
+-- Falling back to the tags seen at creation whenever no tags are in effect
+BEGIN;
+
+CREATE OR REPLACE FUNCTION get_tags_at(rid BIGINT, ts TIMESTAMP)
+    RETURNS JSONB
+AS
+$$
+SELECT coalesce((SELECT t.meta
+                 FROM aws_resource_tags t
+                 WHERE t.aws_resource_id = rid
+                   AND t.not_before < ts
+                   AND (t.not_after IS NULL OR t.not_after > ts)
+                 ORDER BY t.not_before DESC
+                 LIMIT 1),
+                (SELECT res.meta FROM aws_resource res WHERE res.id = rid));
+$$
+    LANGUAGE 'sql' STABLE;
+
+COMMIT;
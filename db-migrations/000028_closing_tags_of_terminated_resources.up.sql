@@ -0,0 +1,23 @@
+-- The tags of a terminated resource are closed at its termination, so the tags seen at creation are only a fallback
+-- for resources stored before their tags were tracked, not for the time after their last tags were closed
+BEGIN;
+
+CREATE OR REPLACE FUNCTION get_tags_at(rid BIGINT, ts TIMESTAMP)
+    RETURNS JSONB
+AS
+$$
+SELECT CASE
+           WHEN EXISTS(SELECT 1 FROM aws_resource_tags t WHERE t.aws_resource_id = rid)
+               THEN (SELECT t.meta
+                     FROM aws_resource_tags t
+                     WHERE t.aws_resource_id = rid
+                       AND t.not_before < ts
+                       AND (t.not_after IS NULL OR t.not_after > ts)
+                     ORDER BY t.not_before DESC
+                     LIMIT 1)
+           ELSE (SELECT res.meta FROM aws_resource res WHERE res.id = rid)
+           END;
+$$
+    LANGUAGE 'sql' STABLE;
+
+COMMIT;
@@ -0,0 +1,7 @@
+-- Removing the region from the change journal
+BEGIN;
+
+ALTER TABLE aws_change_journal
+    DROP COLUMN IF EXISTS region;
+
+COMMIT;
@@ -0,0 +1,11 @@
+-- Adding the region to the change journal, as resources are only unique by resource ID, account and region
+BEGIN;
+
+ALTER TABLE aws_change_journal
+    ADD COLUMN IF NOT EXISTS region varchar not null default '';
+
+UPDATE aws_change_journal
+SET region = coalesce(changes ->> 'Region', '')
+WHERE region = '';
+
+COMMIT;
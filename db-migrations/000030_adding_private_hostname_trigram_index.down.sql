@@ -0,0 +1,6 @@
+-- Removing the index for the private hostname search, the extension is left in place as other objects rely on it
+BEGIN;
+
+DROP INDEX IF EXISTS idx_aws_private_hostname_trgm;
+
+COMMIT;
@@ -0,0 +1,8 @@
+-- Indexing the hostnames of private IP addresses by trigrams so they can be searched like public ones
+BEGIN;
+
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE INDEX IF NOT EXISTS idx_aws_private_hostname_trgm ON aws_private_hostname_assignment USING gin (aws_hostname gin_trgm_ops);
+
+COMMIT;
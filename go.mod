@@ -5,7 +5,7 @@ go 1.12
 require (
 	github.com/DATA-DOG/go-sqlmock v1.3.3
 	github.com/andreyvit/diff v0.0.0-20170406064948-c7f18ee00883 // indirect
-	github.com/asecurityteam/logevent v0.0.0-20190225122144-b32737d8d51c // indirect
+	github.com/asecurityteam/logevent v0.0.0-20190225122144-b32737d8d51c
 	github.com/asecurityteam/runhttp v0.0.0-20190308211650-60620809c493
 	github.com/asecurityteam/serverfull v0.1.0
 	github.com/asecurityteam/settings v0.1.0
@@ -0,0 +1,94 @@
+// +build integration
+
+package tests
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	openapi "github.com/asecurityteam/asset-inventory-api/client"
+)
+
+func TestLookupByIPFollowsAttachments(t *testing.T) {
+	if schemaVersion != maxSchemaVersion {
+		t.Skip("attachments are stored with the latest schema only")
+	}
+	ctx := context.Background()
+	api := assetInventoryAPI.DefaultApi
+	instanceID := "i-0123456789abcdef2"
+	eniID := "eni-0123456789abcdef2"
+	eipID := "eipalloc-0123456789abcdef2"
+	at := time.Date(2018, 01, 12, 22, 51, 48, 324359102, time.UTC)
+
+	eni := openapi.CloudAssetChangesV2{
+		ChangeTime:   at,
+		ResourceType: "AWS::EC2::NetworkInterface",
+		AccountId:    accountID,
+		Region:       "us-west-1",
+		Arn:          fmt.Sprintf("arn:aws:ec2:us-west-1:%s:network-interface/%s", accountID, eniID),
+		Changes: []openapi.CloudAssetChangeV2{{
+			Addresses:        []openapi.CloudAssetAddress{{IpAddress: "10.9.2.1", NetworkInterfaceId: eniID}},
+			RelatedResources: []string{},
+			AttachedTo:       []string{instanceID},
+			ChangeType:       "ADDED",
+		}},
+	}
+	eip := openapi.CloudAssetChangesV2{
+		ChangeTime:   at,
+		ResourceType: "AWS::EC2::EIP",
+		AccountId:    accountID,
+		Region:       "us-west-1",
+		Arn:          fmt.Sprintf("arn:aws:ec2:us-west-1:%s:elastic-ip/%s", accountID, eipID),
+		Changes: []openapi.CloudAssetChangeV2{{
+			Addresses:        []openapi.CloudAssetAddress{{IpAddress: "8.8.10.10", Public: true}},
+			RelatedResources: []string{},
+			AttachedTo:       []string{eniID},
+			ChangeType:       "ADDED",
+		}},
+	}
+	// the network interface is detached from its instance an hour later, the elastic IP stays with the interface
+	detached := eni
+	detached.ChangeTime = at.Add(time.Hour)
+	detached.Changes = []openapi.CloudAssetChangeV2{{
+		RelatedResources: []string{},
+		AttachedTo:       []string{instanceID},
+		ChangeType:       "DELETED",
+	}}
+	for _, chg := range []openapi.CloudAssetChangesV2{eni, eip, detached} {
+		_, err := api.V2CloudChangePost(ctx, chg)
+		require.NoError(t, err)
+	}
+
+	testCases := map[string]struct {
+		ip         string
+		ts         time.Time
+		arn        string
+		attachedTo []string
+	}{
+		"NetworkInterface":         {"10.9.2.1", at.Add(time.Second), eni.Arn, []string{instanceID}},
+		"ElasticIP":                {"8.8.10.10", at.Add(time.Second), eip.Arn, []string{eniID, instanceID}},
+		"DetachedNetworkInterface": {"10.9.2.1", detached.ChangeTime.Add(time.Second), eni.Arn, nil},
+		"DetachedElasticIP":        {"8.8.10.10", detached.ChangeTime.Add(time.Second), eip.Arn, []string{eniID}},
+	}
+	for name, tc := range testCases {
+		t.Run(addSchemaVersion(name),
+			func(t *testing.T) {
+				assets, httpRes, err := api.V1CloudIpIpAddressGet(ctx, tc.ip, tc.ts)
+				require.NoError(t, err)
+				assert.Equal(t, http.StatusOK, httpRes.StatusCode)
+				require.Len(t, assets.Assets, 1)
+				assert.Contains(t, tc.arn, assets.Assets[0].Arn)
+				if len(tc.attachedTo) == 0 {
+					assert.Empty(t, assets.Assets[0].AttachedTo)
+				} else {
+					assert.Equal(t, tc.attachedTo, assets.Assets[0].AttachedTo) // nearest first
+				}
+			})
+	}
+}
@@ -0,0 +1,108 @@
+// +build integration
+
+package tests
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	openapi "github.com/asecurityteam/asset-inventory-api/client"
+)
+
+func natSampleChanges(resourceType string, arn string, at time.Time, change openapi.CloudAssetChangeV2) openapi.CloudAssetChangesV2 {
+	change.RelatedResources = append([]string{}, change.RelatedResources...)
+	change.ChangeType = "ADDED"
+	return openapi.CloudAssetChangesV2{
+		ChangeTime:   at,
+		ResourceType: resourceType,
+		AccountId:    accountID,
+		Region:       "us-west-1",
+		Arn:          arn,
+		Changes:      []openapi.CloudAssetChangeV2{change},
+	}
+}
+
+func TestLookupBehindNATGateway(t *testing.T) {
+	if schemaVersion != maxSchemaVersion {
+		t.Skip("subnets and NAT gateways are stored with the latest schema only")
+	}
+	ctx := context.Background()
+	api := assetInventoryAPI.DefaultApi
+	vpcID := "vpc-0123456789abcdef3"
+	servedSubnetID := "subnet-0123456789abcdef3"
+	at := time.Date(2018, 01, 12, 22, 51, 48, 324359102, time.UTC)
+	instanceARN := func(id string) string {
+		return fmt.Sprintf("arn:aws:ec2:us-west-1:%s:instance/%s", accountID, id)
+	}
+
+	// the NAT gateway sits in a subnet of its own, and serves the subnet it is related to
+	nat := natSampleChanges("AWS::EC2::NatGateway",
+		fmt.Sprintf("arn:aws:ec2:us-west-1:%s:natgateway/%s", accountID, "nat-0123456789abcdef3"), at,
+		openapi.CloudAssetChangeV2{
+			Addresses: []openapi.CloudAssetAddress{
+				{IpAddress: "8.8.11.11", Public: true},
+				{IpAddress: "10.9.3.5"},
+			},
+			RelatedResources: []string{servedSubnetID},
+			VpcId:            vpcID,
+			SubnetId:         "subnet-0123456789abcdef4",
+		})
+	served := natSampleChanges("AWS::EC2::Instance", instanceARN("i-0123456789abcdef3"), at,
+		openapi.CloudAssetChangeV2{
+			Addresses: []openapi.CloudAssetAddress{{IpAddress: "10.9.3.10"}},
+			VpcId:     vpcID,
+			SubnetId:  servedSubnetID,
+		})
+	// an address recorded without a subnet falls back to its VPC, the VPC of the NAT gateway
+	sameVPC := natSampleChanges("AWS::EC2::Instance", instanceARN("i-0123456789abcdef4"), at,
+		openapi.CloudAssetChangeV2{
+			Addresses: []openapi.CloudAssetAddress{{IpAddress: "10.9.3.11"}},
+			VpcId:     vpcID,
+		})
+	otherVPC := natSampleChanges("AWS::EC2::Instance", instanceARN("i-0123456789abcdef5"), at,
+		openapi.CloudAssetChangeV2{
+			Addresses: []openapi.CloudAssetAddress{{IpAddress: "10.9.3.12"}},
+			VpcId:     "vpc-0123456789abcdef5",
+			SubnetId:  "subnet-0123456789abcdef5",
+		})
+	for _, chg := range []openapi.CloudAssetChangesV2{nat, served, sameVPC, otherVPC} {
+		_, err := api.V2CloudChangePost(ctx, chg)
+		require.NoError(t, err)
+	}
+
+	t.Run(addSchemaVersion("Valid"), func(t *testing.T) {
+		assets, httpRes, err := api.V1CloudNatGet(ctx, "8.8.11.11", at.Add(time.Second), nil)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, httpRes.StatusCode)
+		arns := make([]string, 0, len(assets.Assets))
+		for _, asset := range assets.Assets {
+			arns = append(arns, asset.Arn)
+		}
+		assert.ElementsMatch(t, []string{"i-0123456789abcdef3", "i-0123456789abcdef4"}, arns)
+	})
+
+	testCases := map[string]struct {
+		ip       string
+		ts       time.Time
+		httpCode int
+	}{
+		"TSBefore":  {"8.8.11.11", at.Add(-1 * time.Second), http.StatusNotFound},
+		"NotNAT":    {"10.9.3.10", at.Add(time.Second), http.StatusNotFound},
+		"IPInvalid": {"not an address", at.Add(time.Second), http.StatusBadRequest},
+	}
+	for name, tc := range testCases {
+		t.Run(addSchemaVersion(name),
+			func(t *testing.T) {
+				_, httpRes, err := api.V1CloudNatGet(ctx, tc.ip, tc.ts, nil)
+				assert.Error(t, err) // openapi bindings treat 404 as error
+				require.NotNil(t, httpRes)
+				assert.Equal(t, tc.httpCode, httpRes.StatusCode)
+			})
+	}
+}
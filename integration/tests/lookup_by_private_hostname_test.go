@@ -0,0 +1,79 @@
+// +build integration
+
+package tests
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	openapi "github.com/asecurityteam/asset-inventory-api/client"
+)
+
+func TestLookupByPrivateHostname(t *testing.T) {
+	if schemaVersion != maxSchemaVersion {
+		t.Skip("private hostnames are stored with the latest schema only")
+	}
+	ctx := context.Background()
+	api := assetInventoryAPI.DefaultApi
+	privateHostname := "ip-10-9-1-1.us-west-1.compute.internal"
+	sharedHostname := "shared.internal.example.com"
+
+	chgAssign := SampleAssetChanges()
+	chgAssign.Arn = fmt.Sprintf("arn:aws:ec2:%s:%s:instance/%s", chgAssign.Region, chgAssign.AccountId, "i-0123456789abcdef1")
+	chgAssign.Changes = []openapi.CloudAssetChange{{
+		PrivateIpAddresses: []string{"10.9.1.1"},
+		PublicIpAddresses:  []string{"8.8.9.9"},
+		Hostnames:          []string{sharedHostname},
+		PrivateHostnames:   map[string]string{"10.9.1.1": privateHostname},
+		RelatedResources:   []string{},
+		ChangeType:         "ADDED",
+	}}
+	chgRemove := chgAssign
+	chgRemove.ChangeTime = chgAssign.ChangeTime.Add(24 * time.Hour)
+	chgRemove.Changes = []openapi.CloudAssetChange{chgAssign.Changes[0]}
+	chgRemove.Changes[0].ChangeType = "DELETED"
+	// the same hostname names the private address too, the asset is expected once
+	chgShared := chgAssign
+	chgShared.ChangeTime = chgAssign.ChangeTime.Add(time.Second)
+	chgShared.Changes = []openapi.CloudAssetChange{{
+		PrivateIpAddresses: []string{"10.9.1.1"},
+		PrivateHostnames:   map[string]string{"10.9.1.1": sharedHostname},
+		RelatedResources:   []string{},
+		ChangeType:         "ADDED",
+	}}
+	for _, chg := range []openapi.CloudAssetChanges{chgAssign, chgShared, chgRemove} {
+		_, err := api.V1CloudChangePost(ctx, chg)
+		require.NoError(t, err)
+	}
+
+	tsDuring := chgShared.ChangeTime.Add(1 * time.Second)
+	testCases := map[string]struct {
+		hostname string
+		ts       time.Time
+		httpCode int
+	}{
+		"Valid":         {privateHostname, tsDuring, http.StatusOK},
+		"PublicPrivate": {sharedHostname, tsDuring, http.StatusOK},
+		"TSBefore":      {privateHostname, chgAssign.ChangeTime.Add(-1 * time.Second), http.StatusNotFound},
+		"TSAfter":       {privateHostname, chgRemove.ChangeTime.Add(1 * time.Second), http.StatusNotFound},
+	}
+	for name, tc := range testCases {
+		t.Run(addSchemaVersion(name),
+			func(t *testing.T) {
+				assets, httpRes, _ := api.V1CloudHostnameHostnameGet(ctx, tc.hostname, tc.ts)
+				require.NotNil(t, httpRes)
+				assert.Equal(t, tc.httpCode, httpRes.StatusCode)
+				if tc.httpCode == http.StatusOK {
+					require.Len(t, assets.Assets, 1)
+					assert.True(t, ChangesInResponse(chgAssign, assets.Assets))
+					assert.Contains(t, assets.Assets[0].PrivateIpAddresses, "10.9.1.1")
+				}
+			})
+	}
+}
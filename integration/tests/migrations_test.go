@@ -0,0 +1,45 @@
+// +build integration
+
+package tests
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// the last schema version before the inet gist indexes, the first of the migrations rewriting lookups and adding tables
+const preGistSchemaVersion int32 = 15
+
+func TestMigrationsRoundTrip(t *testing.T) {
+	if schemaVersion != maxSchemaVersion {
+		t.Skip("the round trip migrates the schema itself, it runs once with the latest schema")
+	}
+	ctx := context.Background()
+	chgAssign, _, api := Setup(t, ctx)
+	ip := chgAssign.Changes[0].PrivateIpAddresses[0]
+	tsDuring := chgAssign.ChangeTime.Add(1 * time.Second)
+
+	defer func() {
+		require.NoError(t, setSchemaVersion(maxSchemaVersion))
+	}()
+	// step one migration at a time, so a failing down or up migration is reported by its version
+	for v := maxSchemaVersion - 1; v >= preGistSchemaVersion; v-- {
+		require.NoError(t, setSchemaVersion(v), "migrating down to %d", v)
+		assert.Equal(t, v, getSchemaVersion(ctx, api))
+	}
+	for v := preGistSchemaVersion + 1; v <= maxSchemaVersion; v++ {
+		require.NoError(t, setSchemaVersion(v), "migrating up to %d", v)
+		assert.Equal(t, v, getSchemaVersion(ctx, api))
+	}
+
+	// the assignments stored before the round trip are still found
+	assets, httpRes, err := api.V1CloudIpIpAddressGet(ctx, ip, tsDuring)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, httpRes.StatusCode)
+	assert.True(t, ChangesInResponse(chgAssign, assets.Assets))
+}
@@ -0,0 +1,140 @@
+// +build integration
+
+package tests
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	openapi "github.com/asecurityteam/asset-inventory-api/client"
+)
+
+// the last schema version without the change journal
+const preJournalSchemaVersion int32 = 22
+
+func TestReplayKeepsPreJournalHistory(t *testing.T) {
+	if schemaVersion != maxSchemaVersion {
+		t.Skip("the replay migrates the schema itself, it runs once with the latest schema")
+	}
+	ctx := context.Background()
+	api := assetInventoryAPI.DefaultApi
+	resourceID := "i-0123456789abcdef9"
+
+	// the first address is assigned before the journal existed, so replaying can not rebuild it
+	preJournal := SampleAssetChanges()
+	preJournal.Arn = fmt.Sprintf("arn:aws:ec2:%s:%s:instance/%s", preJournal.Region, preJournal.AccountId, resourceID)
+	preJournal.Changes = []openapi.CloudAssetChange{{
+		PrivateIpAddresses: []string{"10.9.0.1"},
+		RelatedResources:   []string{},
+		ChangeType:         "ADDED",
+	}}
+	journaled := preJournal
+	journaled.ChangeTime = preJournal.ChangeTime.Add(time.Hour)
+	journaled.Changes = []openapi.CloudAssetChange{{
+		PrivateIpAddresses: []string{"10.9.0.2"},
+		RelatedResources:   []string{},
+		ChangeType:         "ADDED",
+	}}
+	released := preJournal
+	released.ChangeTime = preJournal.ChangeTime.Add(2 * time.Hour)
+	released.Changes = []openapi.CloudAssetChange{{
+		PrivateIpAddresses: []string{"10.9.0.1"},
+		RelatedResources:   []string{},
+		ChangeType:         "DELETED",
+	}}
+
+	defer func() {
+		require.NoError(t, setSchemaVersion(maxSchemaVersion))
+	}()
+	require.NoError(t, setSchemaVersion(preJournalSchemaVersion))
+	_, err := api.V1CloudChangePost(ctx, preJournal)
+	require.NoError(t, err)
+	require.NoError(t, setSchemaVersion(maxSchemaVersion))
+	for _, chg := range []openapi.CloudAssetChanges{journaled, released} {
+		_, err = api.V1CloudChangePost(ctx, chg)
+		require.NoError(t, err)
+	}
+
+	result, _, err := api.OpsPgsqlV1ReplayPost(ctx, openapi.ReplayScope{ResourceId: resourceID})
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), result.Resources)
+	assert.Equal(t, int32(2), result.Events)
+
+	testCases := map[string]struct {
+		ip       string
+		ts       time.Time
+		httpCode int
+	}{
+		"PreJournalAssignment": {"10.9.0.1", preJournal.ChangeTime.Add(time.Second), http.StatusOK},
+		"PreJournalRelease":    {"10.9.0.1", released.ChangeTime.Add(time.Second), http.StatusNotFound},
+		"JournaledAssignment":  {"10.9.0.2", journaled.ChangeTime.Add(time.Second), http.StatusOK},
+		"BeforeJournal":        {"10.9.0.2", preJournal.ChangeTime.Add(time.Second), http.StatusNotFound},
+	}
+	for name, tc := range testCases {
+		t.Run(addSchemaVersion(name),
+			func(t *testing.T) {
+				assets, httpRes, _ := api.V1CloudIpIpAddressGet(ctx, tc.ip, tc.ts)
+				require.NotNil(t, httpRes)
+				assert.Equal(t, tc.httpCode, httpRes.StatusCode)
+				if tc.httpCode == http.StatusOK {
+					assert.True(t, ChangesInResponse(preJournal, assets.Assets))
+				}
+			})
+	}
+}
+
+func TestReplayAccountRegion(t *testing.T) {
+	if schemaVersion != maxSchemaVersion {
+		t.Skip("the journal region is stored with the latest schema only")
+	}
+	ctx := context.Background()
+	api := assetInventoryAPI.DefaultApi
+
+	// a region of its own, so the replay covers the changes of this test only
+	assigned := SampleAssetChanges()
+	assigned.Region = "ap-southeast-2"
+	assigned.Arn = fmt.Sprintf("arn:aws:ec2:%s:%s:instance/%s", assigned.Region, assigned.AccountId, "i-0123456789abcdef8")
+	assigned.Changes = []openapi.CloudAssetChange{{
+		PrivateIpAddresses: []string{"10.9.4.1"},
+		RelatedResources:   []string{},
+		ChangeType:         "ADDED",
+	}}
+	released := assigned
+	released.ChangeTime = assigned.ChangeTime.Add(time.Hour)
+	released.Changes = []openapi.CloudAssetChange{assigned.Changes[0]}
+	released.Changes[0].ChangeType = "DELETED"
+	for _, chg := range []openapi.CloudAssetChanges{assigned, released} {
+		_, err := api.V1CloudChangePost(ctx, chg)
+		require.NoError(t, err)
+	}
+
+	result, _, err := api.OpsPgsqlV1ReplayPost(ctx, openapi.ReplayScope{AccountId: accountID, Region: assigned.Region})
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), result.Resources)
+	assert.Equal(t, int32(2), result.Events)
+
+	testCases := map[string]struct {
+		ts       time.Time
+		httpCode int
+	}{
+		"Assigned": {assigned.ChangeTime.Add(time.Second), http.StatusOK},
+		"Released": {released.ChangeTime.Add(time.Second), http.StatusNotFound},
+	}
+	for name, tc := range testCases {
+		t.Run(addSchemaVersion(name),
+			func(t *testing.T) {
+				assets, httpRes, _ := api.V1CloudIpIpAddressGet(ctx, "10.9.4.1", tc.ts)
+				require.NotNil(t, httpRes)
+				assert.Equal(t, tc.httpCode, httpRes.StatusCode)
+				if tc.httpCode == http.StatusOK {
+					assert.True(t, ChangesInResponse(assigned, assets.Assets))
+				}
+			})
+	}
+}
@@ -10,13 +10,15 @@ import (
 
 	"github.com/asecurityteam/asset-inventory-api/pkg/domain"
 	v1 "github.com/asecurityteam/asset-inventory-api/pkg/handlers/v1"
+	v2 "github.com/asecurityteam/asset-inventory-api/pkg/handlers/v2"
 	"github.com/asecurityteam/asset-inventory-api/pkg/storage"
 	"github.com/asecurityteam/serverfull"
 	"github.com/asecurityteam/settings"
 )
 
 type config struct {
-	PostgresConfig *storage.PostgresConfig
+	PostgresConfig  *storage.PostgresConfig
+	PageTokenConfig *v1.PageTokenConfig
 }
 
 func (*config) Name() string {
@@ -24,18 +26,21 @@ func (*config) Name() string {
 }
 
 type component struct {
-	PostgresConfig *storage.PostgresConfigComponent
+	PostgresConfig  *storage.PostgresConfigComponent
+	PageTokenConfig *v1.PageTokenConfigComponent
 }
 
 func newComponent() *component {
 	return &component{
-		PostgresConfig: storage.NewPostgresComponent(),
+		PostgresConfig:  storage.NewPostgresComponent(),
+		PageTokenConfig: v1.NewPageTokenComponent(),
 	}
 }
 
 func (c *component) Settings() *config {
 	return &config{
-		PostgresConfig: c.PostgresConfig.Settings(),
+		PostgresConfig:  c.PostgresConfig.Settings(),
+		PageTokenConfig: c.PageTokenConfig.Settings(),
 	}
 }
 
@@ -44,6 +49,10 @@ func (c *component) New(ctx context.Context, conf *config) (func(context.Context
 	if err != nil {
 		return nil, err
 	}
+	pageTokens, err := c.PageTokenConfig.New(ctx, conf.PageTokenConfig)
+	if err != nil {
+		return nil, err
+	}
 	replicaStorage, err := c.PostgresConfig.New(ctx, conf.PostgresConfig, storage.Replica)
 	if err != nil || replicaStorage == nil { //if the replica is not properly configured - fall back to primary
 		replicaStorage = primaryStorage
@@ -70,11 +79,42 @@ func (c *component) New(ctx context.Context, conf *config) (func(context.Context
 		StatFn:           domain.StatFromContext,
 		CloudAssetStorer: primaryStorage,
 	}
+	insertV2 := &v2.CloudInsertHandler{
+		LogFn:            domain.LoggerFromContext,
+		StatFn:           domain.StatFromContext,
+		CloudAssetStorer: primaryStorage,
+	}
+	insertKubernetes := &v1.KubernetesInsertHandler{
+		LogFn:               domain.LoggerFromContext,
+		StatFn:              domain.StatFromContext,
+		CloudAssetStorer:    primaryStorage,
+		PrivateIPClassifier: primaryStorage,
+	}
+	insertWorkload := &v1.WorkloadInsertHandler{
+		LogFn:            domain.LoggerFromContext,
+		StatFn:           domain.StatFromContext,
+		CloudAssetStorer: primaryStorage,
+	}
+	insertBatch := &v1.CloudInsertBatchHandler{
+		LogFn:  domain.LoggerFromContext,
+		StatFn: domain.StatFromContext,
+		Storer: primaryStorage,
+	}
+	insertConfigurationItem := &v1.ConfigurationItemInsertHandler{
+		LogFn:  domain.LoggerFromContext,
+		StatFn: domain.StatFromContext,
+		Storer: primaryStorage,
+	}
 	fetchByIP := &v1.CloudFetchByIPHandler{
 		LogFn:   domain.LoggerFromContext,
 		StatFn:  domain.StatFromContext,
 		Fetcher: replicaStorage,
 	}
+	fetchByIPBatch := &v1.CloudFetchByIPBatchHandler{
+		LogFn:   domain.LoggerFromContext,
+		StatFn:  domain.StatFromContext,
+		Fetcher: replicaStorage,
+	}
 	fetchByHostname := &v1.CloudFetchByHostnameHandler{
 		LogFn:   domain.LoggerFromContext,
 		StatFn:  domain.StatFromContext,
@@ -85,16 +125,76 @@ func (c *component) New(ctx context.Context, conf *config) (func(context.Context
 		StatFn:  domain.StatFromContext,
 		Fetcher: replicaStorage,
 	}
-	fetchAllAssetsByTime := &v1.CloudFetchAllAssetsByTimeHandler{
+	fetchByHostnamePattern := &v1.CloudFetchByHostnamePatternHandler{
+		LogFn:      domain.LoggerFromContext,
+		StatFn:     domain.StatFromContext,
+		Fetcher:    replicaStorage,
+		PageTokens: pageTokens,
+	}
+	fetchByHostnamePatternPage := &v1.CloudFetchByHostnamePatternPageHandler{
+		LogFn:      domain.LoggerFromContext,
+		StatFn:     domain.StatFromContext,
+		Fetcher:    replicaStorage,
+		PageTokens: pageTokens,
+	}
+	fetchByCIDR := &v1.CloudFetchByCIDRHandler{
+		LogFn:      domain.LoggerFromContext,
+		StatFn:     domain.StatFromContext,
+		Fetcher:    replicaStorage,
+		PageTokens: pageTokens,
+	}
+	fetchByCIDRPage := &v1.CloudFetchByCIDRPageHandler{
+		LogFn:      domain.LoggerFromContext,
+		StatFn:     domain.StatFromContext,
+		Fetcher:    replicaStorage,
+		PageTokens: pageTokens,
+	}
+	fetchBehindNATGateway := &v1.CloudFetchBehindNATGatewayHandler{
+		LogFn:      domain.LoggerFromContext,
+		StatFn:     domain.StatFromContext,
+		Fetcher:    replicaStorage,
+		PageTokens: pageTokens,
+	}
+	fetchBehindNATGatewayPage := &v1.CloudFetchBehindNATGatewayPageHandler{
+		LogFn:      domain.LoggerFromContext,
+		StatFn:     domain.StatFromContext,
+		Fetcher:    replicaStorage,
+		PageTokens: pageTokens,
+	}
+	fetchByTags := &v1.CloudFetchByTagsHandler{
+		LogFn:      domain.LoggerFromContext,
+		StatFn:     domain.StatFromContext,
+		Fetcher:    replicaStorage,
+		PageTokens: pageTokens,
+	}
+	fetchByTagsPage := &v1.CloudFetchByTagsPageHandler{
+		LogFn:      domain.LoggerFromContext,
+		StatFn:     domain.StatFromContext,
+		Fetcher:    replicaStorage,
+		PageTokens: pageTokens,
+	}
+	fetchHistory := &v1.CloudFetchHistoryHandler{
 		LogFn:   domain.LoggerFromContext,
 		StatFn:  domain.StatFromContext,
 		Fetcher: replicaStorage,
 	}
-	fetchAllAssetsByTimePage := &v1.CloudFetchAllAssetsByTimePageHandler{
+	fetchGraph := &v1.CloudFetchGraphHandler{
 		LogFn:   domain.LoggerFromContext,
 		StatFn:  domain.StatFromContext,
 		Fetcher: replicaStorage,
 	}
+	fetchAllAssetsByTime := &v1.CloudFetchAllAssetsByTimeHandler{
+		LogFn:      domain.LoggerFromContext,
+		StatFn:     domain.StatFromContext,
+		Fetcher:    replicaStorage,
+		PageTokens: pageTokens,
+	}
+	fetchAllAssetsByTimePage := &v1.CloudFetchAllAssetsByTimePageHandler{
+		LogFn:      domain.LoggerFromContext,
+		StatFn:     domain.StatFromContext,
+		Fetcher:    replicaStorage,
+		PageTokens: pageTokens,
+	}
 	getSchemaVersion := &v1.GetSchemaVersionHandler{
 		LogFn:  domain.LoggerFromContext,
 		Getter: schemaManager,
@@ -111,6 +211,10 @@ func (c *component) New(ctx context.Context, conf *config) (func(context.Context
 		LogFn:               domain.LoggerFromContext,
 		SchemaVersionForcer: schemaManager,
 	}
+	replay := &v1.ReplayHandler{
+		LogFn:    domain.LoggerFromContext,
+		Replayer: primaryStorage,
+	}
 	insertAccountOwner := &v1.AccountOwnerInsertHandler{
 		LogFn:              domain.LoggerFromContext,
 		StatFn:             domain.StatFromContext,
@@ -118,18 +222,35 @@ func (c *component) New(ctx context.Context, conf *config) (func(context.Context
 	}
 
 	handlers := map[string]serverfull.Function{
-		"insert":                     serverfull.NewFunction(insert.Handle),
-		"fetchByIP":                  serverfull.NewFunction(fetchByIP.Handle),
-		"fetchByHostname":            serverfull.NewFunction(fetchByHostname.Handle),
-		"fetchByArnID":               serverfull.NewFunction(fetchByResourceID.Handle),
-		"fetchByResourceID":          serverfull.NewFunction(fetchByResourceID.Handle),
-		"fetchAllAssetsByTime":       serverfull.NewFunction(fetchAllAssetsByTime.Handle),
-		"fetchMoreAssetsByPageToken": serverfull.NewFunction(fetchAllAssetsByTimePage.Handle),
-		"getSchemaVersion":           serverfull.NewFunction(getSchemaVersion.Handle),
-		"schemaVersionStepUp":        serverfull.NewFunction(schemaVersionStepUp.Handle),
-		"schemaVersionStepDown":      serverfull.NewFunction(schemaVersionStepDown.Handle),
-		"forceSchemaVersion":         serverfull.NewFunction(forceSchemaVersion.Handle),
-		"insertAccountOwner":         serverfull.NewFunction(insertAccountOwner.Handle),
+		"insert":                             serverfull.NewFunction(insert.Handle),
+		"insertV2":                           serverfull.NewFunction(insertV2.Handle),
+		"insertKubernetes":                   serverfull.NewFunction(insertKubernetes.Handle),
+		"insertWorkload":                     serverfull.NewFunction(insertWorkload.Handle),
+		"insertBatch":                        serverfull.NewFunction(insertBatch.Handle),
+		"insertConfigurationItem":            serverfull.NewFunction(insertConfigurationItem.Handle),
+		"fetchByIP":                          serverfull.NewFunction(fetchByIP.Handle),
+		"fetchByIPBatch":                     serverfull.NewFunction(fetchByIPBatch.Handle),
+		"fetchByHostname":                    serverfull.NewFunction(fetchByHostname.Handle),
+		"fetchByHostnamePattern":             serverfull.NewFunction(fetchByHostnamePattern.Handle),
+		"fetchMoreByHostnamePageToken":       serverfull.NewFunction(fetchByHostnamePatternPage.Handle),
+		"fetchByArnID":                       serverfull.NewFunction(fetchByResourceID.Handle),
+		"fetchByResourceID":                  serverfull.NewFunction(fetchByResourceID.Handle),
+		"fetchByCIDR":                        serverfull.NewFunction(fetchByCIDR.Handle),
+		"fetchMoreByCIDRPageToken":           serverfull.NewFunction(fetchByCIDRPage.Handle),
+		"fetchBehindNATGateway":              serverfull.NewFunction(fetchBehindNATGateway.Handle),
+		"fetchMoreBehindNATGatewayPageToken": serverfull.NewFunction(fetchBehindNATGatewayPage.Handle),
+		"fetchByTags":                        serverfull.NewFunction(fetchByTags.Handle),
+		"fetchMoreByTagsPageToken":           serverfull.NewFunction(fetchByTagsPage.Handle),
+		"fetchHistoryByResourceID":           serverfull.NewFunction(fetchHistory.Handle),
+		"fetchGraphByResourceID":             serverfull.NewFunction(fetchGraph.Handle),
+		"fetchAllAssetsByTime":               serverfull.NewFunction(fetchAllAssetsByTime.Handle),
+		"fetchMoreAssetsByPageToken":         serverfull.NewFunction(fetchAllAssetsByTimePage.Handle),
+		"getSchemaVersion":                   serverfull.NewFunction(getSchemaVersion.Handle),
+		"schemaVersionStepUp":                serverfull.NewFunction(schemaVersionStepUp.Handle),
+		"schemaVersionStepDown":              serverfull.NewFunction(schemaVersionStepDown.Handle),
+		"forceSchemaVersion":                 serverfull.NewFunction(forceSchemaVersion.Handle),
+		"replay":                             serverfull.NewFunction(replay.Handle),
+		"insertAccountOwner":                 serverfull.NewFunction(insertAccountOwner.Handle),
 	}
 
 	fetcher := &serverfull.StaticFetcher{Functions: handlers}
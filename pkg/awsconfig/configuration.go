@@ -0,0 +1,142 @@
+package awsconfig
+
+import (
+	"sort"
+
+	"github.com/asecurityteam/asset-inventory-api/pkg/domain"
+)
+
+// The parts of resource configurations holding network state, as recorded by AWS Config
+
+type association struct {
+	PublicIP      string `json:"publicIp"`
+	PublicDNSName string `json:"publicDnsName"`
+}
+
+type privateIPAddress struct {
+	PrivateIPAddress string       `json:"privateIpAddress"`
+	PrivateDNSName   string       `json:"privateDnsName"`
+	Association      *association `json:"association"`
+}
+
+type networkInterfaceConfiguration struct {
+	VPCID              string             `json:"vpcId"`
+	SubnetID           string             `json:"subnetId"`
+	Description        string             `json:"description"`
+	PrivateIPAddresses []privateIPAddress `json:"privateIpAddresses"`
+	Attachment         struct {
+		InstanceID string `json:"instanceId"`
+	} `json:"attachment"`
+}
+
+type instanceConfiguration struct {
+	NetworkInterfaces []networkInterfaceConfiguration `json:"networkInterfaces"`
+}
+
+type elbConfiguration struct {
+	Instances []struct {
+		InstanceID string `json:"instanceId"`
+	} `json:"instances"`
+}
+
+type natGatewayConfiguration struct {
+	VPCID               string `json:"vpcId"`
+	SubnetID            string `json:"subnetId"`
+	NatGatewayAddresses []struct {
+		PrivateIP string `json:"privateIp"`
+		PublicIP  string `json:"publicIp"`
+	} `json:"natGatewayAddresses"`
+}
+
+type eipConfiguration struct {
+	PublicIP           string `json:"publicIp"`
+	InstanceID         string `json:"instanceId"`
+	NetworkInterfaceID string `json:"networkInterfaceId"`
+}
+
+type routeTableConfiguration struct {
+	VPCID  string `json:"vpcId"`
+	Routes []struct {
+		NatGatewayID string `json:"natGatewayId"`
+	} `json:"routes"`
+	Associations []struct {
+		SubnetID string `json:"subnetId"`
+		Main     bool   `json:"main"`
+	} `json:"associations"`
+}
+
+// addTo adds the addresses of the NAT gateway to the state. The elastic IPs they are allocated from are recorded by
+// their own items, attached to the network interface of the NAT gateway. The subnet of the NAT gateway is where it
+// lives, not one it serves, so it is not related.
+func (nat natGatewayConfiguration) addTo(state *domain.CloudAssetState) {
+	for _, address := range nat.NatGatewayAddresses {
+		if address.PrivateIP != "" {
+			state.PrivateIPAddresses = append(state.PrivateIPAddresses, domain.PrivateIPAssignment{
+				IPAddress: address.PrivateIP,
+				VPCID:     nat.VPCID,
+				SubnetID:  nat.SubnetID,
+			})
+		}
+		if address.PublicIP != "" {
+			state.PublicIPAddresses = append(state.PublicIPAddresses, domain.PublicIPAssignment{IPAddress: address.PublicIP})
+		}
+	}
+}
+
+// addTo relates the route table to the NAT gateways it routes through and to the subnets it serves. The main route
+// table serves the subnets of its VPC without an association of their own, so it is related to the VPC.
+func (rtb routeTableConfiguration) addTo(state *domain.CloudAssetState) {
+	related := make(map[string]struct{})
+	for _, route := range rtb.Routes {
+		if route.NatGatewayID != "" {
+			related[route.NatGatewayID] = struct{}{}
+		}
+	}
+	for _, association := range rtb.Associations {
+		if association.SubnetID != "" {
+			related[association.SubnetID] = struct{}{}
+		}
+		if association.Main && rtb.VPCID != "" {
+			related[rtb.VPCID] = struct{}{}
+		}
+	}
+	for resource := range related {
+		state.RelatedResources = append(state.RelatedResources, resource)
+	}
+	sort.Strings(state.RelatedResources)
+}
+
+// addTo adds the address of the elastic IP to the state, along with what it is associated with. An elastic IP of a
+// VPC is associated with a network interface, which is attached to the instance in turn, so the instance is only used
+// for elastic IPs without a network interface.
+func (eip eipConfiguration) addTo(state *domain.CloudAssetState) {
+	if eip.PublicIP != "" {
+		state.PublicIPAddresses = append(state.PublicIPAddresses, domain.PublicIPAssignment{IPAddress: eip.PublicIP})
+	}
+	switch {
+	case eip.NetworkInterfaceID != "":
+		state.AttachedTo = append(state.AttachedTo, eip.NetworkInterfaceID)
+	case eip.InstanceID != "":
+		state.AttachedTo = append(state.AttachedTo, eip.InstanceID)
+	}
+}
+
+// addTo adds the addresses of the network interface to the state, each with its own hostname
+func (eni networkInterfaceConfiguration) addTo(state *domain.CloudAssetState) {
+	for _, address := range eni.PrivateIPAddresses {
+		if address.PrivateIPAddress != "" {
+			state.PrivateIPAddresses = append(state.PrivateIPAddresses, domain.PrivateIPAssignment{
+				IPAddress: address.PrivateIPAddress,
+				VPCID:     eni.VPCID,
+				SubnetID:  eni.SubnetID,
+				Hostname:  address.PrivateDNSName,
+			})
+		}
+		if address.Association != nil && address.Association.PublicIP != "" {
+			state.PublicIPAddresses = append(state.PublicIPAddresses, domain.PublicIPAssignment{
+				IPAddress: address.Association.PublicIP,
+				Hostname:  address.Association.PublicDNSName,
+			})
+		}
+	}
+}
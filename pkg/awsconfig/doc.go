@@ -0,0 +1,3 @@
+// Package awsconfig derives cloud asset changes from AWS Config configuration items, by comparing the state they
+// describe with the state previously recorded.
+package awsconfig
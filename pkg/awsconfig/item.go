@@ -0,0 +1,268 @@
+package awsconfig
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/asecurityteam/asset-inventory-api/pkg/domain"
+)
+
+// Resource types with a known configuration
+const (
+	TypeInstance         = "AWS::EC2::Instance"
+	TypeNetworkInterface = "AWS::EC2::NetworkInterface"
+	TypeELB              = "AWS::ElasticLoadBalancing::LoadBalancer"
+	TypeALB              = "AWS::ElasticLoadBalancingV2::LoadBalancer"
+	TypeNATGateway       = "AWS::EC2::NatGateway"
+	TypeRouteTable       = "AWS::EC2::RouteTable"
+	TypeEIP              = "AWS::EC2::EIP"
+)
+
+// Statuses of configuration items with a resource level change
+const (
+	statusDiscovered         = "ResourceDiscovered"
+	statusDeleted            = "ResourceDeleted"
+	statusDeletedNotRecorded = "ResourceDeletedNotRecorded"
+)
+
+const (
+	added   = "ADDED"
+	deleted = "DELETED"
+	// load balancers describe their network interfaces after their own name
+	networkInterfaceELBPrefix = "ELB "
+)
+
+// ConfigurationItem is an AWS Config configuration item, as found in notifications and snapshots
+type ConfigurationItem struct {
+	CaptureTime   string            `json:"configurationItemCaptureTime"`
+	Status        string            `json:"configurationItemStatus"`
+	StateID       json.Number       `json:"configurationStateId"`
+	AccountID     string            `json:"awsAccountId"`
+	Region        string            `json:"awsRegion"`
+	ARN           string            `json:"ARN"`
+	ResourceType  string            `json:"resourceType"`
+	ResourceID    string            `json:"resourceId"`
+	Tags          map[string]string `json:"tags"`
+	Configuration json.RawMessage   `json:"configuration"`
+}
+
+// UnsupportedResourceType is returned for configuration items of resources whose configuration is not known
+type UnsupportedResourceType struct {
+	ResourceType string
+}
+
+func (u UnsupportedResourceType) Error() string {
+	return fmt.Sprintf("unsupported resource type %s", u.ResourceType)
+}
+
+// Deleted tells whether the item records the deletion of the resource
+func (ci ConfigurationItem) Deleted() bool {
+	return ci.Status == statusDeleted || ci.Status == statusDeletedNotRecorded
+}
+
+// State extracts the IP addresses, hostnames, relationships and attachments held by the resource from its
+// configuration
+func (ci ConfigurationItem) State() (domain.CloudAssetState, error) {
+	state := domain.CloudAssetState{
+		PrivateIPAddresses: make([]domain.PrivateIPAssignment, 0),
+		PublicIPAddresses:  make([]domain.PublicIPAssignment, 0),
+		RelatedResources:   make([]string, 0),
+		AttachedTo:         make([]string, 0),
+	}
+	switch ci.ResourceType {
+	case TypeInstance, TypeNetworkInterface, TypeELB, TypeALB, TypeNATGateway, TypeRouteTable, TypeEIP:
+	default:
+		return domain.CloudAssetState{}, UnsupportedResourceType{ResourceType: ci.ResourceType}
+	}
+	if ci.Deleted() {
+		return state, nil
+	}
+	var err error
+	switch ci.ResourceType {
+	case TypeInstance:
+		var conf instanceConfiguration
+		if err = json.Unmarshal(ci.Configuration, &conf); err == nil {
+			for _, eni := range conf.NetworkInterfaces {
+				eni.addTo(&state)
+			}
+		}
+	case TypeNetworkInterface:
+		var conf networkInterfaceConfiguration
+		if err = json.Unmarshal(ci.Configuration, &conf); err == nil {
+			conf.addTo(&state)
+			if conf.Attachment.InstanceID != "" {
+				state.AttachedTo = append(state.AttachedTo, conf.Attachment.InstanceID)
+			}
+			// the name is the resource ID of the load balancer, as found in its ARN
+			if strings.HasPrefix(conf.Description, networkInterfaceELBPrefix) {
+				state.RelatedResources = append(state.RelatedResources, strings.TrimPrefix(conf.Description, networkInterfaceELBPrefix))
+			}
+		}
+	case TypeELB:
+		var conf elbConfiguration
+		if err = json.Unmarshal(ci.Configuration, &conf); err == nil {
+			for _, instance := range conf.Instances {
+				state.RelatedResources = append(state.RelatedResources, instance.InstanceID)
+			}
+		}
+	case TypeALB:
+		// the addresses of application load balancers are held by their network interfaces
+	case TypeNATGateway:
+		var conf natGatewayConfiguration
+		if err = json.Unmarshal(ci.Configuration, &conf); err == nil {
+			conf.addTo(&state)
+		}
+	case TypeRouteTable:
+		var conf routeTableConfiguration
+		if err = json.Unmarshal(ci.Configuration, &conf); err == nil {
+			conf.addTo(&state)
+		}
+	case TypeEIP:
+		var conf eipConfiguration
+		if err = json.Unmarshal(ci.Configuration, &conf); err == nil {
+			conf.addTo(&state)
+		}
+	}
+	if err != nil {
+		return domain.CloudAssetState{}, err
+	}
+	return state, nil
+}
+
+// Changes derives the changes turning the previous state of the resource into the one described by the item.
+// A deleted resource is terminated, which releases everything it still holds.
+func (ci ConfigurationItem) Changes(previous domain.CloudAssetState) (domain.CloudAssetChanges, error) {
+	changeTime, err := time.Parse(time.RFC3339Nano, ci.CaptureTime)
+	if err != nil {
+		return domain.CloudAssetChanges{}, err
+	}
+	state, err := ci.State()
+	if err != nil {
+		return domain.CloudAssetChanges{}, err
+	}
+	changes := domain.CloudAssetChanges{
+		ChangeTime:   changeTime,
+		ResourceType: ci.ResourceType,
+		AccountID:    ci.AccountID,
+		Region:       ci.Region,
+		ARN:          ci.ARN,
+		Changes:      make([]domain.NetworkChanges, 0),
+	}
+	if ci.StateID != "" {
+		changes.EventID = ci.ResourceID + "/" + ci.StateID.String()
+	}
+	switch {
+	case ci.Deleted():
+		changes.Lifecycle = domain.LifecycleTerminated
+		return changes, nil
+	case ci.Status == statusDiscovered:
+		changes.Lifecycle = domain.LifecycleCreated
+	}
+	// the item holds every tag of the resource, so missing tags were removed
+	changes.Tags = ci.Tags
+	if changes.Tags == nil {
+		changes.Tags = make(map[string]string)
+	}
+
+	current := make(map[string]domain.PrivateIPAssignment, len(state.PrivateIPAddresses))
+	for _, assignment := range state.PrivateIPAddresses {
+		current[assignment.IPAddress] = assignment
+	}
+	// an address whose hostname changed is released and assigned again with its new hostname
+	held := make(map[string]domain.PrivateIPAssignment, len(previous.PrivateIPAddresses))
+	for _, assignment := range previous.PrivateIPAddresses {
+		held[assignment.IPAddress] = assignment
+		if now, ok := current[assignment.IPAddress]; !ok || now.Hostname != assignment.Hostname {
+			changes.Changes = append(changes.Changes, privateIPChange(assignment, deleted))
+		}
+	}
+	for _, assignment := range state.PrivateIPAddresses {
+		if before, ok := held[assignment.IPAddress]; !ok || before.Hostname != assignment.Hostname {
+			changes.Changes = append(changes.Changes, privateIPChange(assignment, added))
+		}
+	}
+
+	for _, assignment := range difference(previous.PublicIPAddresses, state.PublicIPAddresses) {
+		changes.Changes = append(changes.Changes, publicIPChange(assignment, deleted))
+	}
+	for _, assignment := range difference(state.PublicIPAddresses, previous.PublicIPAddresses) {
+		changes.Changes = append(changes.Changes, publicIPChange(assignment, added))
+	}
+
+	if released := missingFrom(previous.RelatedResources, state.RelatedResources); len(released) > 0 {
+		changes.Changes = append(changes.Changes, domain.NetworkChanges{RelatedResources: released, ChangeType: deleted})
+	}
+	if assigned := missingFrom(state.RelatedResources, previous.RelatedResources); len(assigned) > 0 {
+		changes.Changes = append(changes.Changes, domain.NetworkChanges{RelatedResources: assigned, ChangeType: added})
+	}
+
+	if released := missingFrom(previous.AttachedTo, state.AttachedTo); len(released) > 0 {
+		changes.Changes = append(changes.Changes, domain.NetworkChanges{AttachedTo: released, ChangeType: deleted})
+	}
+	if assigned := missingFrom(state.AttachedTo, previous.AttachedTo); len(assigned) > 0 {
+		changes.Changes = append(changes.Changes, domain.NetworkChanges{AttachedTo: assigned, ChangeType: added})
+	}
+	return changes, nil
+}
+
+// privateIPChange keeps the address with its own hostname, if any
+func privateIPChange(assignment domain.PrivateIPAssignment, changeType string) domain.NetworkChanges {
+	if assignment.Hostname != "" {
+		return domain.NetworkChanges{
+			Addresses:  []domain.AddressChange{{IPAddress: assignment.IPAddress, Hostname: assignment.Hostname}},
+			VPCID:      assignment.VPCID,
+			SubnetID:   assignment.SubnetID,
+			ChangeType: changeType,
+		}
+	}
+	return domain.NetworkChanges{
+		PrivateIPAddresses: []string{assignment.IPAddress},
+		VPCID:              assignment.VPCID,
+		SubnetID:           assignment.SubnetID,
+		ChangeType:         changeType,
+	}
+}
+
+// publicIPChange keeps the address with its own hostname, if any
+func publicIPChange(assignment domain.PublicIPAssignment, changeType string) domain.NetworkChanges {
+	return domain.NetworkChanges{
+		Addresses: []domain.AddressChange{
+			{IPAddress: assignment.IPAddress, Hostname: assignment.Hostname, Public: true},
+		},
+		ChangeType: changeType,
+	}
+}
+
+// difference returns the public IP assignments of a that are not in b
+func difference(a, b []domain.PublicIPAssignment) []domain.PublicIPAssignment {
+	inB := make(map[domain.PublicIPAssignment]struct{}, len(b))
+	for _, assignment := range b {
+		inB[assignment] = struct{}{}
+	}
+	result := make([]domain.PublicIPAssignment, 0)
+	for _, assignment := range a {
+		if _, ok := inB[assignment]; !ok {
+			result = append(result, assignment)
+		}
+	}
+	return result
+}
+
+// missingFrom returns the sorted values of a that are not in b
+func missingFrom(a, b []string) []string {
+	inB := make(map[string]struct{}, len(b))
+	for _, value := range b {
+		inB[value] = struct{}{}
+	}
+	result := make([]string, 0)
+	for _, value := range a {
+		if _, ok := inB[value]; !ok {
+			result = append(result, value)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
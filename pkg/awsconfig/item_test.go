@@ -0,0 +1,261 @@
+package awsconfig
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/asecurityteam/asset-inventory-api/pkg/domain"
+)
+
+func loadItem(t *testing.T, name string) ConfigurationItem {
+	content, err := ioutil.ReadFile("testdata/" + name)
+	require.NoError(t, err)
+	var ci ConfigurationItem
+	require.NoError(t, json.Unmarshal(content, &ci))
+	return ci
+}
+
+func TestNetworkInterfaceState(t *testing.T) {
+	state, err := loadItem(t, "network_interface.json").State()
+	require.NoError(t, err)
+	assert.Equal(t, domain.CloudAssetState{
+		PrivateIPAddresses: []domain.PrivateIPAssignment{
+			{IPAddress: "10.0.0.1", VPCID: "vpc-1", SubnetID: "subnet-1"},
+			{IPAddress: "10.0.0.2", VPCID: "vpc-1", SubnetID: "subnet-1"},
+		},
+		PublicIPAddresses: []domain.PublicIPAssignment{
+			{IPAddress: "34.0.0.1", Hostname: "ec2-34-0-0-1.us-west-2.compute.amazonaws.com"},
+		},
+		RelatedResources: []string{"app/marketp-ALB/ffffffff66666666"},
+		AttachedTo:       []string{},
+	}, state)
+}
+
+func TestAttachedNetworkInterfaceState(t *testing.T) {
+	ci := ConfigurationItem{
+		ResourceType: TypeNetworkInterface,
+		Configuration: json.RawMessage(`{"vpcId": "vpc-1", "subnetId": "subnet-1", "attachment": {"instanceId": "i-1"},
+			"privateIpAddresses": [{"privateIpAddress": "10.0.0.1"}]}`),
+	}
+	state, err := ci.State()
+	require.NoError(t, err)
+	assert.Empty(t, state.RelatedResources)
+	assert.Equal(t, []string{"i-1"}, state.AttachedTo)
+}
+
+func TestInstanceState(t *testing.T) {
+	ci := ConfigurationItem{
+		ResourceType: TypeInstance,
+		Configuration: json.RawMessage(`{"networkInterfaces": [
+			{"vpcId": "vpc-1", "subnetId": "subnet-1", "privateIpAddresses": [{"privateIpAddress": "10.0.0.1", "privateDnsName": "ip-10-0-0-1.ec2.internal", "association": {"publicIp": "34.0.0.1"}}]},
+			{"vpcId": "vpc-1", "subnetId": "subnet-2", "privateIpAddresses": [{"privateIpAddress": "10.0.1.1"}]}
+		]}`),
+	}
+	state, err := ci.State()
+	require.NoError(t, err)
+	assert.Equal(t, []domain.PrivateIPAssignment{
+		{IPAddress: "10.0.0.1", VPCID: "vpc-1", SubnetID: "subnet-1", Hostname: "ip-10-0-0-1.ec2.internal"},
+		{IPAddress: "10.0.1.1", VPCID: "vpc-1", SubnetID: "subnet-2"},
+	}, state.PrivateIPAddresses)
+	assert.Equal(t, []domain.PublicIPAssignment{{IPAddress: "34.0.0.1"}}, state.PublicIPAddresses) // no DNS name
+}
+
+func TestELBState(t *testing.T) {
+	ci := ConfigurationItem{
+		ResourceType:  TypeELB,
+		Configuration: json.RawMessage(`{"dnsname": "elb.amazonaws.com", "instances": [{"instanceId": "i-1"}, {"instanceId": "i-2"}]}`),
+	}
+	state, err := ci.State()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"i-1", "i-2"}, state.RelatedResources)
+}
+
+func TestNATGatewayState(t *testing.T) {
+	ci := ConfigurationItem{
+		ResourceType: TypeNATGateway,
+		Configuration: json.RawMessage(`{"natGatewayId": "nat-1", "vpcId": "vpc-1", "subnetId": "subnet-public",
+			"natGatewayAddresses": [{"allocationId": "eipalloc-1", "networkInterfaceId": "eni-1", "privateIp": "10.0.0.5", "publicIp": "34.0.0.1"}]}`),
+	}
+	state, err := ci.State()
+	require.NoError(t, err)
+	assert.Equal(t, domain.CloudAssetState{
+		PrivateIPAddresses: []domain.PrivateIPAssignment{{IPAddress: "10.0.0.5", VPCID: "vpc-1", SubnetID: "subnet-public"}},
+		PublicIPAddresses:  []domain.PublicIPAssignment{{IPAddress: "34.0.0.1"}},
+		RelatedResources:   []string{},
+		AttachedTo:         []string{},
+	}, state)
+}
+
+func TestEIPState(t *testing.T) {
+	ci := ConfigurationItem{
+		ResourceType: TypeEIP,
+		Configuration: json.RawMessage(`{"allocationId": "eipalloc-1", "publicIp": "34.0.0.1", "domain": "vpc",
+			"instanceId": "i-1", "networkInterfaceId": "eni-1", "privateIpAddress": "10.0.0.1"}`),
+	}
+	state, err := ci.State()
+	require.NoError(t, err)
+	assert.Empty(t, state.PrivateIPAddresses)
+	assert.Equal(t, []domain.PublicIPAssignment{{IPAddress: "34.0.0.1"}}, state.PublicIPAddresses)
+	// the network interface is attached to the instance in turn
+	assert.Equal(t, []string{"eni-1"}, state.AttachedTo)
+
+	ci.Configuration = json.RawMessage(`{"allocationId": "eipalloc-1", "publicIp": "34.0.0.1", "instanceId": "i-1"}`)
+	state, err = ci.State()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"i-1"}, state.AttachedTo)
+}
+
+func TestRouteTableState(t *testing.T) {
+	ci := ConfigurationItem{
+		ResourceType: TypeRouteTable,
+		Configuration: json.RawMessage(`{"routeTableId": "rtb-1", "vpcId": "vpc-1",
+			"routes": [{"destinationCidrBlock": "10.0.0.0/16", "gatewayId": "local"}, {"destinationCidrBlock": "0.0.0.0/0", "natGatewayId": "nat-1"}],
+			"associations": [{"subnetId": "subnet-2", "main": false}, {"subnetId": "subnet-1", "main": false}, {"main": true}]}`),
+	}
+	state, err := ci.State()
+	require.NoError(t, err)
+	assert.Empty(t, state.PrivateIPAddresses)
+	assert.Empty(t, state.PublicIPAddresses)
+	assert.Equal(t, []string{"nat-1", "subnet-1", "subnet-2", "vpc-1"}, state.RelatedResources)
+}
+
+func TestStateUnsupportedResourceType(t *testing.T) {
+	_, err := ConfigurationItem{ResourceType: "AWS::S3::Bucket"}.State()
+	assert.Equal(t, UnsupportedResourceType{ResourceType: "AWS::S3::Bucket"}, err)
+}
+
+func TestStateInvalidConfiguration(t *testing.T) {
+	_, err := ConfigurationItem{ResourceType: TypeNetworkInterface, Configuration: json.RawMessage(`[]`)}.State()
+	assert.Error(t, err)
+}
+
+func TestChanges(t *testing.T) {
+	ci := loadItem(t, "network_interface.json")
+	previous := domain.CloudAssetState{
+		PrivateIPAddresses: []domain.PrivateIPAssignment{
+			{IPAddress: "10.0.0.1", VPCID: "vpc-1", SubnetID: "subnet-1"},
+			{IPAddress: "10.0.0.3", VPCID: "vpc-1", SubnetID: "subnet-1"},
+		},
+		PublicIPAddresses: []domain.PublicIPAssignment{
+			{IPAddress: "34.0.0.1", Hostname: "old.example.com"},
+		},
+		RelatedResources: []string{"i-1"},
+	}
+
+	changes, err := ci.Changes(previous)
+	require.NoError(t, err)
+	changeTime, _ := time.Parse(time.RFC3339, "2019-04-09T08:29:35Z")
+	assert.Equal(t, domain.CloudAssetChanges{
+		ChangeTime:   changeTime,
+		ResourceType: TypeNetworkInterface,
+		AccountID:    "123456789012",
+		Region:       "us-west-2",
+		ARN:          "arn:aws:ec2:us-west-2:123456789012:network-interface/eni-1",
+		Tags:         map[string]string{"team": "security"},
+		EventID:      "eni-1/1554798575000",
+		Changes: []domain.NetworkChanges{
+			{PrivateIPAddresses: []string{"10.0.0.3"}, VPCID: "vpc-1", SubnetID: "subnet-1", ChangeType: "DELETED"},
+			{PrivateIPAddresses: []string{"10.0.0.2"}, VPCID: "vpc-1", SubnetID: "subnet-1", ChangeType: "ADDED"},
+			{Addresses: []domain.AddressChange{{IPAddress: "34.0.0.1", Hostname: "old.example.com", Public: true}}, ChangeType: "DELETED"},
+			{Addresses: []domain.AddressChange{{IPAddress: "34.0.0.1", Hostname: "ec2-34-0-0-1.us-west-2.compute.amazonaws.com", Public: true}}, ChangeType: "ADDED"},
+			{RelatedResources: []string{"i-1"}, ChangeType: "DELETED"},
+			{RelatedResources: []string{"app/marketp-ALB/ffffffff66666666"}, ChangeType: "ADDED"},
+		},
+	}, changes)
+}
+
+func TestChangesPrivateHostname(t *testing.T) {
+	ci := ConfigurationItem{
+		CaptureTime:  "2019-04-09T08:29:35Z",
+		ResourceType: TypeNetworkInterface,
+		Configuration: json.RawMessage(`{"vpcId": "vpc-1", "subnetId": "subnet-1", "privateIpAddresses": [
+			{"privateIpAddress": "10.0.0.1", "privateDnsName": "ip-10-0-0-1.ec2.internal"},
+			{"privateIpAddress": "10.0.0.2", "privateDnsName": "ip-10-0-0-2.ec2.internal"}
+		]}`),
+	}
+	// the first address was stored before its hostname was known
+	previous := domain.CloudAssetState{
+		PrivateIPAddresses: []domain.PrivateIPAssignment{
+			{IPAddress: "10.0.0.1", VPCID: "vpc-1", SubnetID: "subnet-1"},
+			{IPAddress: "10.0.0.2", VPCID: "vpc-1", SubnetID: "subnet-1", Hostname: "ip-10-0-0-2.ec2.internal"},
+		},
+	}
+
+	changes, err := ci.Changes(previous)
+	require.NoError(t, err)
+	assert.Equal(t, []domain.NetworkChanges{
+		{PrivateIPAddresses: []string{"10.0.0.1"}, VPCID: "vpc-1", SubnetID: "subnet-1", ChangeType: "DELETED"},
+		{
+			Addresses:  []domain.AddressChange{{IPAddress: "10.0.0.1", Hostname: "ip-10-0-0-1.ec2.internal"}},
+			VPCID:      "vpc-1",
+			SubnetID:   "subnet-1",
+			ChangeType: "ADDED",
+		},
+	}, changes.Changes)
+}
+
+func TestChangesAttachment(t *testing.T) {
+	ci := ConfigurationItem{
+		CaptureTime:   "2019-04-09T08:29:35Z",
+		ResourceType:  TypeEIP,
+		Configuration: json.RawMessage(`{"allocationId": "eipalloc-1", "publicIp": "34.0.0.1", "networkInterfaceId": "eni-2"}`),
+	}
+	previous := domain.CloudAssetState{
+		PublicIPAddresses: []domain.PublicIPAssignment{{IPAddress: "34.0.0.1"}},
+		AttachedTo:        []string{"eni-1"},
+	}
+
+	changes, err := ci.Changes(previous)
+	require.NoError(t, err)
+	assert.Equal(t, []domain.NetworkChanges{
+		{AttachedTo: []string{"eni-1"}, ChangeType: "DELETED"},
+		{AttachedTo: []string{"eni-2"}, ChangeType: "ADDED"},
+	}, changes.Changes)
+}
+
+func TestChangesUnchanged(t *testing.T) {
+	ci := loadItem(t, "network_interface.json")
+	state, err := ci.State()
+	require.NoError(t, err)
+
+	changes, err := ci.Changes(state)
+	require.NoError(t, err)
+	assert.Empty(t, changes.Changes)
+	assert.Empty(t, changes.Lifecycle)
+}
+
+func TestChangesDiscovered(t *testing.T) {
+	ci := loadItem(t, "network_interface.json")
+	ci.Status = "ResourceDiscovered"
+	ci.Tags = nil
+
+	changes, err := ci.Changes(domain.CloudAssetState{})
+	require.NoError(t, err)
+	assert.Equal(t, domain.LifecycleCreated, changes.Lifecycle)
+	assert.Equal(t, map[string]string{}, changes.Tags)
+	assert.Len(t, changes.Changes, 4)
+}
+
+func TestChangesDeleted(t *testing.T) {
+	ci := loadItem(t, "network_interface.json")
+	ci.Status = "ResourceDeleted"
+	ci.Configuration = nil
+
+	changes, err := ci.Changes(domain.CloudAssetState{RelatedResources: []string{"i-1"}})
+	require.NoError(t, err)
+	assert.Equal(t, domain.LifecycleTerminated, changes.Lifecycle)
+	assert.Empty(t, changes.Changes)
+	assert.Nil(t, changes.Tags)
+}
+
+func TestChangesInvalidCaptureTime(t *testing.T) {
+	ci := loadItem(t, "network_interface.json")
+	ci.CaptureTime = "yesterday"
+	_, err := ci.Changes(domain.CloudAssetState{})
+	assert.Error(t, err)
+}
@@ -0,0 +1,174 @@
+package awsconfig
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/asecurityteam/asset-inventory-api/pkg/domain"
+	"github.com/asecurityteam/asset-inventory-api/pkg/logs"
+)
+
+// Snapshot is an AWS Config configuration snapshot, holding an item for every resource recorded in an account and region
+type Snapshot struct {
+	ConfigSnapshotID   string              `json:"configSnapshotId"`
+	ConfigurationItems []ConfigurationItem `json:"configurationItems"`
+}
+
+// ReadSnapshot decodes a snapshot file
+func ReadSnapshot(r io.Reader) (Snapshot, error) {
+	var snapshot Snapshot
+	err := json.NewDecoder(r).Decode(&snapshot)
+	return snapshot, err
+}
+
+// Time is the latest capture time of the items of the snapshot, the time of the snapshot is not recorded otherwise
+func (s Snapshot) Time() (time.Time, error) {
+	var latest time.Time
+	for _, ci := range s.ConfigurationItems {
+		captureTime, err := time.Parse(time.RFC3339Nano, ci.CaptureTime)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if captureTime.After(latest) {
+			latest = captureTime
+		}
+	}
+	return latest, nil
+}
+
+// ImportSummary counts the resources of an import. Recorded resources were found in the snapshot, terminated ones
+// were missing from it, and unsupported ones are of a type whose configuration is not known.
+type ImportSummary struct {
+	Recorded    int
+	Terminated  int
+	Unsupported int
+	Failed      int
+}
+
+// Importer records the state described by a snapshot
+type Importer struct {
+	LogFn   domain.LogFn
+	Fetcher interface {
+		domain.CloudAssetStateFetcher
+		domain.CloudAssetResourceIDsFetcher
+	}
+	Storer domain.CloudAssetStorer
+}
+
+type accountRegionType struct {
+	accountID    string
+	region       string
+	resourceType string
+}
+
+// Import records the state of every resource of the snapshot at the time, as if its item was captured then, and
+// terminates the resources of the same accounts, regions and types that were missing from the snapshot. Failures to
+// record a resource are logged and counted, so that a single bad item does not hold the others back.
+func (i *Importer) Import(ctx context.Context, snapshot Snapshot, when time.Time) (ImportSummary, error) {
+	logger := i.LogFn(ctx)
+	summary := ImportSummary{}
+	captureTime := when.Format(time.RFC3339Nano)
+	seen := make(map[accountRegionType]map[string]struct{})
+	for _, ci := range snapshot.ConfigurationItems {
+		if _, err := ci.State(); err != nil {
+			if _, ok := err.(UnsupportedResourceType); ok {
+				summary.Unsupported++
+				continue
+			}
+		}
+		key := accountRegionType{accountID: ci.AccountID, region: ci.Region, resourceType: ci.ResourceType}
+		if seen[key] == nil {
+			seen[key] = make(map[string]struct{})
+		}
+		if !ci.Deleted() {
+			// resources are stored by the resource ID found in their ARN, which may not be the one given by Config,
+			// e.g. the resource ID of an application load balancer is its full ARN
+			seen[key][domain.ResourceIDFromARN(ci.ARN)] = struct{}{}
+		}
+		ci.CaptureTime = captureTime
+		if err := i.record(ctx, ci, when); err != nil {
+			logger.Error(logs.SnapshotImportFailure{ResourceID: ci.ResourceID, Reason: err.Error()})
+			summary.Failed++
+			continue
+		}
+		summary.Recorded++
+	}
+
+	keys := make([]accountRegionType, 0, len(seen))
+	for key := range seen {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(a, b int) bool {
+		return keys[a].accountID+keys[a].region+keys[a].resourceType < keys[b].accountID+keys[b].region+keys[b].resourceType
+	})
+	for _, key := range keys {
+		resIDs, err := i.Fetcher.FetchResourceIDs(ctx, when, key.accountID, key.region, key.resourceType)
+		if err != nil {
+			return summary, err
+		}
+		for _, resID := range resIDs {
+			if _, ok := seen[key][resID]; ok {
+				continue
+			}
+			if err = i.terminate(ctx, key, resID, when); err != nil {
+				logger.Error(logs.SnapshotImportFailure{ResourceID: resID, Reason: err.Error()})
+				summary.Failed++
+				continue
+			}
+			summary.Terminated++
+		}
+	}
+	return summary, nil
+}
+
+func (i *Importer) record(ctx context.Context, ci ConfigurationItem, when time.Time) error {
+	previous, err := i.Fetcher.FetchState(ctx, when, ci.ARN)
+	if err != nil {
+		return err
+	}
+	changes, err := ci.Changes(previous)
+	if err != nil {
+		return err
+	}
+	if err = i.Storer.Store(ctx, changes); err != nil {
+		if _, ok := err.(domain.DuplicateEvent); !ok {
+			return err
+		}
+	}
+	return nil
+}
+
+func (i *Importer) terminate(ctx context.Context, key accountRegionType, resID string, when time.Time) error {
+	return i.Storer.Store(ctx, domain.CloudAssetChanges{
+		ChangeTime:   when,
+		ResourceType: key.resourceType,
+		AccountID:    key.accountID,
+		Region:       key.region,
+		ARN:          arnOf(key.resourceType, key.region, key.accountID, resID),
+		Lifecycle:    domain.LifecycleTerminated,
+		Changes:      make([]domain.NetworkChanges, 0),
+	})
+}
+
+// arnOf rebuilds the ARN of a resource from the resource ID stored for it, in the aws partition
+func arnOf(resourceType string, region string, accountID string, resID string) string {
+	var service, resource string
+	switch resourceType {
+	case TypeInstance:
+		service, resource = "ec2", "instance/"
+	case TypeNetworkInterface:
+		service, resource = "ec2", "network-interface/"
+	case TypeELB, TypeALB:
+		service, resource = "elasticloadbalancing", "loadbalancer/"
+	case TypeNATGateway:
+		service, resource = "ec2", "natgateway/"
+	case TypeRouteTable:
+		service, resource = "ec2", "route-table/"
+	case TypeEIP:
+		service, resource = "ec2", "elastic-ip/"
+	}
+	return "arn:aws:" + service + ":" + region + ":" + accountID + ":" + resource + resID
+}
@@ -0,0 +1,142 @@
+package awsconfig
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/asecurityteam/asset-inventory-api/pkg/domain"
+)
+
+type nopLogger struct{}
+
+func (*nopLogger) Debug(event interface{})                 {}
+func (*nopLogger) Info(event interface{})                  {}
+func (*nopLogger) Warn(event interface{})                  {}
+func (*nopLogger) Error(event interface{})                 {}
+func (*nopLogger) SetField(name string, value interface{}) {}
+func (logger *nopLogger) Copy() domain.Logger {
+	return logger
+}
+
+func testLogFn(context.Context) domain.Logger { return &nopLogger{} }
+
+// fakeInventory holds the state of a few resources and records what is stored
+type fakeInventory struct {
+	states  map[string]domain.CloudAssetState
+	resIDs  map[string][]string
+	stored  []domain.CloudAssetChanges
+	failing string
+}
+
+func (f *fakeInventory) FetchState(_ context.Context, _ time.Time, arn string) (domain.CloudAssetState, error) {
+	return f.states[arn], nil
+}
+
+func (f *fakeInventory) FetchResourceIDs(_ context.Context, _ time.Time, accountID string, region string, resourceType string) ([]string, error) {
+	return f.resIDs[accountID+"/"+region+"/"+resourceType], nil
+}
+
+func (f *fakeInventory) Store(_ context.Context, changes domain.CloudAssetChanges) error {
+	if changes.ARN == f.failing {
+		return errors.New("no bueno")
+	}
+	f.stored = append(f.stored, changes)
+	return nil
+}
+
+func loadSnapshot(t *testing.T) Snapshot {
+	f, err := os.Open("testdata/snapshot.json")
+	require.NoError(t, err)
+	defer f.Close()
+	snapshot, err := ReadSnapshot(f)
+	require.NoError(t, err)
+	return snapshot
+}
+
+func TestSnapshotTime(t *testing.T) {
+	when, err := loadSnapshot(t).Time()
+	require.NoError(t, err)
+	expected, _ := time.Parse(time.RFC3339, "2019-04-09T08:29:35Z")
+	assert.Equal(t, expected, when)
+}
+
+func TestImport(t *testing.T) {
+	snapshot := loadSnapshot(t)
+	when, _ := snapshot.Time()
+	inventory := &fakeInventory{
+		states: map[string]domain.CloudAssetState{
+			"arn:aws:ec2:region:aid:network-interface/eni-1": {
+				PrivateIPAddresses: []domain.PrivateIPAssignment{{IPAddress: "10.0.0.1"}},
+			},
+		},
+		resIDs: map[string][]string{
+			"aid/region/AWS::EC2::NetworkInterface":                {"eni-0", "eni-1"},
+			"aid/region/AWS::ElasticLoadBalancingV2::LoadBalancer": {"app/my-alb/1"},
+		},
+	}
+	importer := Importer{LogFn: testLogFn, Fetcher: inventory, Storer: inventory}
+
+	summary, err := importer.Import(context.Background(), snapshot, when)
+	require.NoError(t, err)
+	assert.Equal(t, ImportSummary{Recorded: 3, Terminated: 1, Unsupported: 1}, summary)
+	require.Len(t, inventory.stored, 4)
+	// the unchanged resource is recorded with its tags only
+	assert.Empty(t, inventory.stored[0].Changes)
+	assert.Equal(t, when, inventory.stored[0].ChangeTime)
+	// the new resource gains its address at the time of the snapshot
+	assert.Equal(t, []domain.NetworkChanges{
+		{PrivateIPAddresses: []string{"10.0.0.2"}, VPCID: "vpc-1", SubnetID: "subnet-1", ChangeType: "ADDED"},
+	}, inventory.stored[1].Changes)
+	assert.Equal(t, when, inventory.stored[1].ChangeTime)
+	// the load balancer is found by the resource ID in its ARN rather than the one given by Config, and kept
+	assert.Equal(t, "arn:aws:elasticloadbalancing:region:aid:loadbalancer/app/my-alb/1", inventory.stored[2].ARN)
+	assert.Empty(t, inventory.stored[2].Lifecycle)
+	// the missing resource is terminated
+	assert.Equal(t, domain.CloudAssetChanges{
+		ChangeTime:   when,
+		ResourceType: TypeNetworkInterface,
+		AccountID:    "aid",
+		Region:       "region",
+		ARN:          "arn:aws:ec2:region:aid:network-interface/eni-0",
+		Lifecycle:    domain.LifecycleTerminated,
+		Changes:      []domain.NetworkChanges{},
+	}, inventory.stored[3])
+}
+
+func TestImportFailure(t *testing.T) {
+	snapshot := loadSnapshot(t)
+	when, _ := snapshot.Time()
+	inventory := &fakeInventory{failing: "arn:aws:ec2:region:aid:network-interface/eni-1"}
+	importer := Importer{LogFn: testLogFn, Fetcher: inventory, Storer: inventory}
+
+	summary, err := importer.Import(context.Background(), snapshot, when)
+	require.NoError(t, err)
+	assert.Equal(t, ImportSummary{Recorded: 2, Unsupported: 1, Failed: 1}, summary)
+}
+
+func TestARNOf(t *testing.T) {
+	tc := []struct {
+		resourceType string
+		resID        string
+		expected     string
+	}{
+		{TypeInstance, "i-1", "arn:aws:ec2:region:aid:instance/i-1"},
+		{TypeNetworkInterface, "eni-1", "arn:aws:ec2:region:aid:network-interface/eni-1"},
+		{TypeELB, "my-elb", "arn:aws:elasticloadbalancing:region:aid:loadbalancer/my-elb"},
+		{TypeALB, "app/my-alb/1", "arn:aws:elasticloadbalancing:region:aid:loadbalancer/app/my-alb/1"},
+		{TypeNATGateway, "nat-1", "arn:aws:ec2:region:aid:natgateway/nat-1"},
+		{TypeRouteTable, "rtb-1", "arn:aws:ec2:region:aid:route-table/rtb-1"},
+		{TypeEIP, "eipalloc-1", "arn:aws:ec2:region:aid:elastic-ip/eipalloc-1"},
+	}
+	for _, tt := range tc {
+		t.Run(tt.resourceType, func(t *testing.T) {
+			assert.Equal(t, tt.expected, arnOf(tt.resourceType, "region", "aid", tt.resID))
+		})
+	}
+}
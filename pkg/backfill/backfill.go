@@ -0,0 +1,296 @@
+package backfill
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/asecurityteam/asset-inventory-api/pkg/domain"
+	v1 "github.com/asecurityteam/asset-inventory-api/pkg/handlers/v1"
+	"github.com/asecurityteam/asset-inventory-api/pkg/logs"
+)
+
+// maxLineSize caps the size of a record, configuration heavy resources make for long lines
+const maxLineSize = 16 * 1024 * 1024
+
+// checkpointInterval is how many completed records are waited for between writes of the checkpoint
+const checkpointInterval = 100
+
+// Inserter stores a change payload the way the change endpoint does
+type Inserter interface {
+	Handle(ctx context.Context, input v1.CloudAssetChanges) (v1.CloudInsertResult, error)
+}
+
+// Summary counts the records of a run. Skipped records were deduplicated, or already completed by a previous run.
+// Failed records are invalid, along with the record which failed to be stored and stopped the run.
+type Summary struct {
+	Applied int
+	Skipped int
+	Failed  int
+}
+
+// Loader streams records into an Inserter. Records of the same resource are stored one after the other in the order
+// of the file, records of different resources are spread over the workers. A record relating its resource to others
+// depends on the records of those resources, so it is stored alone, once every record before it was, as if there was a
+// single worker.
+type Loader struct {
+	LogFn    domain.LogFn
+	Inserter Inserter
+	Workers  int
+	// Checkpoint is the path of a file holding the line up to which every record was completed, so that an interrupted
+	// run can resume from there. Checkpointing is disabled when empty.
+	Checkpoint string
+}
+
+type record struct {
+	line    int
+	payload v1.CloudAssetChanges
+}
+
+type outcome int
+
+const (
+	applied outcome = iota
+	skipped
+	failed
+)
+
+// progress tracks completed records, in any order, to find the line up to which every record was completed
+type progress struct {
+	sync.Mutex
+	summary   Summary
+	completed map[int]struct{}
+	watermark int
+	pending   int
+}
+
+// fail counts a record which failed to be stored, without completing it so that the next run starts over from there
+func (p *progress) fail() {
+	p.Lock()
+	defer p.Unlock()
+	p.summary.Failed++
+}
+
+func (p *progress) complete(line int, o outcome) {
+	p.Lock()
+	defer p.Unlock()
+	switch o {
+	case applied:
+		p.summary.Applied++
+	case skipped:
+		p.summary.Skipped++
+	case failed:
+		p.summary.Failed++
+	}
+	p.completed[line] = struct{}{}
+	for {
+		if _, ok := p.completed[p.watermark+1]; !ok {
+			break
+		}
+		delete(p.completed, p.watermark+1)
+		p.watermark++
+	}
+	p.pending++
+}
+
+// Load reads every record from the reader and stores it, returning once all of them were handled. When the context
+// is cancelled, records not yet stored are left for the next run. Invalid records are reported and skipped, while a
+// record which fails to be stored stops the run, and is the first one stored again by the next run.
+func (l *Loader) Load(ctx context.Context, r io.Reader) (Summary, error) {
+	logger := l.LogFn(ctx)
+	parent := ctx
+	ctx, stop := context.WithCancel(parent)
+	defer stop()
+	var storeErr error
+	var storeErrOnce sync.Once
+	resumeAfter, err := l.readCheckpoint()
+	if err != nil {
+		return Summary{}, err
+	}
+	workers := l.Workers
+	if workers < 1 {
+		workers = 1
+	}
+	p := &progress{completed: make(map[int]struct{})}
+
+	var checkpointErr error
+	var checkpointMu sync.Mutex
+	maybeCheckpoint := func(force bool) {
+		checkpointMu.Lock()
+		defer checkpointMu.Unlock()
+		p.Lock()
+		if !force && p.pending < checkpointInterval {
+			p.Unlock()
+			return
+		}
+		p.pending = 0
+		watermark := p.watermark
+		p.Unlock()
+		if e := l.writeCheckpoint(watermark); e != nil && checkpointErr == nil {
+			checkpointErr = e
+		}
+	}
+
+	store := func(rec record) {
+		if ctx.Err() != nil {
+			return // left for the next run
+		}
+		res, e := l.Inserter.Handle(ctx, rec.payload)
+		switch {
+		case e != nil && ctx.Err() != nil:
+			return
+		case isInvalidInput(e):
+			logger.Error(logs.BackfillFailure{Line: rec.line, Reason: e.Error()})
+			p.complete(rec.line, failed)
+		case e != nil:
+			logger.Error(logs.BackfillFailure{Line: rec.line, Reason: e.Error()})
+			p.fail()
+			storeErrOnce.Do(func() {
+				storeErr = fmt.Errorf("failed to store line %d: %s", rec.line, e.Error())
+				stop()
+			})
+		case res.Deduplicated:
+			p.complete(rec.line, skipped)
+		default:
+			p.complete(rec.line, applied)
+		}
+		maybeCheckpoint(false)
+	}
+
+	queues := make([]chan record, workers)
+	var inflight sync.WaitGroup // records handed to the workers and not stored yet
+	var wg sync.WaitGroup
+	for i := range queues {
+		queues[i] = make(chan record, 16)
+		wg.Add(1)
+		go func(queue chan record) {
+			defer wg.Done()
+			for rec := range queue {
+				store(rec)
+				inflight.Done()
+			}
+		}(queues[i])
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+	line := 0
+	for scanner.Scan() && ctx.Err() == nil {
+		line++
+		if line <= resumeAfter {
+			p.complete(line, skipped)
+			continue
+		}
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			p.complete(line, skipped)
+			continue
+		}
+		var payload v1.CloudAssetChanges
+		if e := json.Unmarshal([]byte(text), &payload); e != nil {
+			logger.Error(logs.BackfillFailure{Line: line, Reason: e.Error()})
+			p.complete(line, failed)
+			continue
+		}
+		linking := hasLinks(payload)
+		if linking {
+			inflight.Wait()
+		}
+		inflight.Add(1)
+		queues[workerFor(payload.ARN, workers)] <- record{line: line, payload: payload}
+		if linking {
+			inflight.Wait()
+		}
+	}
+	for _, queue := range queues {
+		close(queue)
+	}
+	wg.Wait()
+	maybeCheckpoint(true)
+
+	if storeErr != nil {
+		return p.summary, storeErr
+	}
+	if err = scanner.Err(); err != nil {
+		return p.summary, err
+	}
+	if err = parent.Err(); err != nil {
+		return p.summary, err
+	}
+	return p.summary, checkpointErr
+}
+
+// isInvalidInput tells whether the record itself was rejected, as storing it again would fail the same way
+func isInvalidInput(err error) bool {
+	_, ok := err.(v1.InvalidInput)
+	return ok
+}
+
+// hasLinks tells whether the record relates its resource to others
+func hasLinks(payload v1.CloudAssetChanges) bool {
+	for _, val := range payload.Changes {
+		if len(val.RelatedResources) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// workerFor picks the worker of a resource, so that its records are not stored concurrently nor out of order
+func workerFor(arn string, workers int) int {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(arn))
+	return int(h.Sum32() % uint32(workers))
+}
+
+func (l *Loader) readCheckpoint() (int, error) {
+	if l.Checkpoint == "" {
+		return 0, nil
+	}
+	content, err := ioutil.ReadFile(l.Checkpoint)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(strings.TrimSpace(string(content)))
+}
+
+// writeCheckpoint replaces the checkpoint file in a single step, so that an interrupted write leaves the previous one
+func (l *Loader) writeCheckpoint(line int) error {
+	if l.Checkpoint == "" {
+		return nil
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(l.Checkpoint), filepath.Base(l.Checkpoint))
+	if err != nil {
+		return err
+	}
+	if _, err = tmp.WriteString(strconv.Itoa(line) + "\n"); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), l.Checkpoint)
+}
+
+// DryRunStorer accepts changes without storing them, so that a run only validates the records
+type DryRunStorer struct{}
+
+// Store does nothing
+func (DryRunStorer) Store(context.Context, domain.CloudAssetChanges) error {
+	return nil
+}
@@ -0,0 +1,169 @@
+package backfill
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/asecurityteam/asset-inventory-api/pkg/domain"
+	v1 "github.com/asecurityteam/asset-inventory-api/pkg/handlers/v1"
+)
+
+type nopLogger struct{}
+
+func (*nopLogger) Debug(event interface{})                 {}
+func (*nopLogger) Info(event interface{})                  {}
+func (*nopLogger) Warn(event interface{})                  {}
+func (*nopLogger) Error(event interface{})                 {}
+func (*nopLogger) SetField(name string, value interface{}) {}
+func (logger *nopLogger) Copy() domain.Logger {
+	return logger
+}
+
+func testLogFn(context.Context) domain.Logger { return &nopLogger{} }
+
+// recordingInserter remembers the change times stored for each resource, deduplicating, rejecting and failing some of
+// them
+type recordingInserter struct {
+	sync.Mutex
+	stored map[string][]string
+	order  []string
+}
+
+func (r *recordingInserter) Handle(_ context.Context, input v1.CloudAssetChanges) (v1.CloudInsertResult, error) {
+	switch input.EventID {
+	case "duplicate":
+		return v1.CloudInsertResult{Deduplicated: true}, nil
+	case "invalid":
+		return v1.CloudInsertResult{}, v1.InvalidInput{Field: "changeTime", Cause: errors.New("no bueno")}
+	case "failing":
+		return v1.CloudInsertResult{}, errors.New("no bueno")
+	}
+	r.Lock()
+	defer r.Unlock()
+	r.stored[input.ARN] = append(r.stored[input.ARN], input.ChangeTime)
+	r.order = append(r.order, input.ARN)
+	return v1.CloudInsertResult{}, nil
+}
+
+const records = `{"arn":"a","changeTime":"1"}
+{"arn":"b","changeTime":"1"}
+{"arn":"a","changeTime":"2","eventId":"duplicate"}
+
+not json
+{"arn":"b","changeTime":"2","eventId":"invalid"}
+{"arn":"a","changeTime":"3"}
+{"arn":"b","changeTime":"3"}
+`
+
+func TestLoad(t *testing.T) {
+	inserter := &recordingInserter{stored: make(map[string][]string)}
+	loader := Loader{LogFn: testLogFn, Inserter: inserter, Workers: 3}
+
+	summary, err := loader.Load(context.Background(), strings.NewReader(records))
+	require.NoError(t, err)
+	assert.Equal(t, Summary{Applied: 4, Skipped: 2, Failed: 2}, summary)
+	assert.Equal(t, []string{"1", "3"}, inserter.stored["a"])
+	assert.Equal(t, []string{"1", "3"}, inserter.stored["b"])
+}
+
+func TestLoadLinkingRecordsAlone(t *testing.T) {
+	input := `{"arn":"a","changeTime":"1"}
+{"arn":"b","changeTime":"1"}
+{"arn":"c","changeTime":"1"}
+{"arn":"link","changeTime":"2","changes":[{"relatedResources":["a","b"],"changeType":"ADDED"}]}
+{"arn":"a","changeTime":"3"}
+{"arn":"b","changeTime":"3"}
+`
+	inserter := &recordingInserter{stored: make(map[string][]string)}
+	loader := Loader{LogFn: testLogFn, Inserter: inserter, Workers: 3}
+
+	summary, err := loader.Load(context.Background(), strings.NewReader(input))
+	require.NoError(t, err)
+	assert.Equal(t, Summary{Applied: 6}, summary)
+	// the linking record comes after every record before it, and before every record after it
+	require.Len(t, inserter.order, 6)
+	assert.ElementsMatch(t, []string{"a", "b", "c"}, inserter.order[:3])
+	assert.Equal(t, "link", inserter.order[3])
+	assert.ElementsMatch(t, []string{"a", "b"}, inserter.order[4:])
+}
+
+func TestLoadResumesFromCheckpoint(t *testing.T) {
+	dir, err := ioutil.TempDir("", "backfill")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	checkpoint := filepath.Join(dir, "checkpoint")
+	require.NoError(t, ioutil.WriteFile(checkpoint, []byte("6\n"), 0600))
+
+	inserter := &recordingInserter{stored: make(map[string][]string)}
+	loader := Loader{LogFn: testLogFn, Inserter: inserter, Workers: 2, Checkpoint: checkpoint}
+
+	summary, err := loader.Load(context.Background(), strings.NewReader(records))
+	require.NoError(t, err)
+	assert.Equal(t, Summary{Applied: 2, Skipped: 6}, summary)
+	assert.Equal(t, []string{"3"}, inserter.stored["a"])
+	assert.Equal(t, []string{"3"}, inserter.stored["b"])
+
+	content, err := ioutil.ReadFile(checkpoint)
+	require.NoError(t, err)
+	assert.Equal(t, "8\n", string(content))
+}
+
+func TestLoadStopsOnStorageError(t *testing.T) {
+	dir, err := ioutil.TempDir("", "backfill")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	checkpoint := filepath.Join(dir, "checkpoint")
+
+	input := `{"arn":"a","changeTime":"1"}
+{"arn":"b","changeTime":"1","eventId":"failing"}
+{"arn":"a","changeTime":"2"}
+`
+	inserter := &recordingInserter{stored: make(map[string][]string)}
+	loader := Loader{LogFn: testLogFn, Inserter: inserter, Workers: 1, Checkpoint: checkpoint}
+
+	summary, err := loader.Load(context.Background(), strings.NewReader(input))
+	assert.EqualError(t, err, "failed to store line 2: no bueno")
+	assert.Equal(t, 1, summary.Failed)
+	assert.Equal(t, []string{"1"}, inserter.stored["a"])
+
+	// the failing record is stored again by the next run
+	content, err := ioutil.ReadFile(checkpoint)
+	require.NoError(t, err)
+	assert.Equal(t, "1\n", string(content))
+}
+
+func TestLoadInvalidCheckpoint(t *testing.T) {
+	dir, err := ioutil.TempDir("", "backfill")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	checkpoint := filepath.Join(dir, "checkpoint")
+	require.NoError(t, ioutil.WriteFile(checkpoint, []byte("garbage"), 0600))
+
+	loader := Loader{LogFn: testLogFn, Inserter: &recordingInserter{}, Checkpoint: checkpoint}
+	_, err = loader.Load(context.Background(), strings.NewReader(records))
+	assert.Error(t, err)
+}
+
+func TestLoadDryRun(t *testing.T) {
+	inserter := &v1.CloudInsertHandler{
+		LogFn:            testLogFn,
+		CloudAssetStorer: DryRunStorer{},
+	}
+	loader := Loader{LogFn: testLogFn, Inserter: inserter}
+
+	input := `{"arn":"a","changeTime":"2019-04-09T08:29:35Z","changes":[{"privateIpAddresses":["10.0.0.1"],"changeType":"ADDED"}]}
+{"arn":"a","changeTime":"yesterday"}
+`
+	summary, err := loader.Load(context.Background(), strings.NewReader(input))
+	require.NoError(t, err)
+	assert.Equal(t, Summary{Applied: 1, Failed: 1}, summary)
+}
@@ -0,0 +1,3 @@
+// Package backfill loads files of cloud asset changes, one JSON encoded v1 payload per line, as if each line was
+// sent to the change endpoint.
+package backfill
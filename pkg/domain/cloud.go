@@ -1,6 +1,8 @@
 package domain
 
 import (
+	"fmt"
+	"strings"
 	"time"
 )
 
@@ -13,17 +15,98 @@ type CloudAssetChanges struct {
 	Region       string
 	ARN          string
 	Tags         map[string]string
+	Lifecycle    string
+	EventID      string // optional, identifies redeliveries of the same event
 }
 
-// NetworkChanges represent changes to an asset's IP addresses or associated host names
+// DuplicateEvent is returned when changes carry an event ID that was already processed, the changes are not applied
+type DuplicateEvent struct {
+	EventID string
+}
+
+func (d DuplicateEvent) Error() string {
+	return fmt.Sprintf("event %s was already processed", d.EventID)
+}
+
+// StaleChange is returned when changes derived from the state of a resource are older than the latest changes stored
+// for it, the state they were derived from no longer holds and the changes are not applied
+type StaleChange struct {
+	ARN        string
+	ChangeTime time.Time
+	Latest     time.Time
+}
+
+func (s StaleChange) Error() string {
+	return fmt.Sprintf("changes of %s at %s are older than the latest ones stored at %s", s.ARN,
+		s.ChangeTime.Format(time.RFC3339Nano), s.Latest.Format(time.RFC3339Nano))
+}
+
+// Resource level changes carried by CloudAssetChanges, the lifecycle is left empty for changes to the network only.
+// A terminated resource no longer holds any IP address, hostname or relationship from the change time on.
+const (
+	LifecycleCreated    = "CREATED"
+	LifecycleTerminated = "TERMINATED"
+)
+
+// Kubernetes workloads have no ARN of their own. They are given one under the EKS service, whose resource ID is the
+// kind, cluster, namespace and name of the workload, e.g. pod/cluster/namespace/name.
+const (
+	ResourceTypeKubernetesPod     = "Kubernetes::Pod"
+	ResourceTypeKubernetesService = "Kubernetes::Service"
+	KubernetesKindPod             = "pod"
+	KubernetesKindService         = "service"
+)
+
+// KubernetesARN returns the ARN identifying a kubernetes workload of the given kind
+func KubernetesARN(region string, accountID string, kind string, cluster string, namespace string, name string) string {
+	return fmt.Sprintf("arn:aws:eks:%s:%s:%s/%s/%s/%s", region, accountID, kind, cluster, namespace, name)
+}
+
+// ResourceIDFromARN extracts the unique resource-type/resource-id from a full ARN, which resources are stored by.
+// It is always the part after account-id:
+// https://docs.aws.amazon.com/general/latest/gr/aws-arns-and-namespaces.html
+// kubernetes workloads keep their kind, cluster and namespace, as names are only unique within those
+// lambda functions are known by their name, without any version or alias qualifier
+func ResourceIDFromARN(ARN string) string {
+	parts := strings.SplitN(ARN, ":", 6)
+	resourceID := parts[len(parts)-1]
+	if strings.HasPrefix(resourceID, "loadbalancer/app") {
+		return resourceID[13:]
+	}
+	if len(parts) == 6 && parts[2] == "eks" &&
+		(strings.HasPrefix(resourceID, KubernetesKindPod+"/") || strings.HasPrefix(resourceID, KubernetesKindService+"/")) {
+		return resourceID
+	}
+	if len(parts) == 6 && parts[2] == "lambda" && strings.HasPrefix(resourceID, "function:") {
+		return strings.SplitN(resourceID, ":", 3)[1]
+	}
+	parts = strings.SplitAfterN(resourceID, "/", -1)
+	return parts[len(parts)-1]
+}
+
+// NetworkChanges represent changes to an asset's IP addresses or associated host names.
+// Every public IP address is paired with every hostname, unlike Addresses which are stored as they are given.
 type NetworkChanges struct {
 	PrivateIPAddresses []string
 	PublicIPAddresses  []string
 	Hostnames          []string
+	Addresses          []AddressChange
 	RelatedResources   []string
+	AttachedTo         []string // resources a network interface or elastic IP is attached to, by resource ID
+	VPCID              string   // VPC of the private IP addresses, if known
+	SubnetID           string   // subnet of the private IP addresses, if known
 	ChangeType         string
 }
 
+// AddressChange is an IP address of an asset, with the hostname it is known by and the network interface holding it.
+// The hostname and network interface are optional. Private addresses are in the VPC and subnet of their change.
+type AddressChange struct {
+	IPAddress          string
+	Hostname           string
+	NetworkInterfaceID string
+	Public             bool
+}
+
 // CloudAssetDetails represent an asset, associated metadata, account owner and champions.
 type CloudAssetDetails struct {
 	PrivateIPAddresses []string
@@ -35,6 +118,7 @@ type CloudAssetDetails struct {
 	ARN                string
 	Tags               map[string]string
 	AccountOwner       AccountOwner // AccountOwner has account owner and champion(s)
+	AttachedTo         []string     // resources the asset is attached to through one another, nearest first
 }
 
 // AccountOwner represents an AWS account with its owner and account champions
@@ -51,3 +135,126 @@ type Person struct {
 	Email *string
 	Valid *bool
 }
+
+// Kinds of entries found in the history of a cloud asset
+const (
+	HistoryPrivateIP       = "privateIp"
+	HistoryPublicIP        = "publicIp"
+	HistoryHostname        = "hostname"
+	HistoryRelatedResource = "relatedResource"
+	HistoryAttachedTo      = "attachedTo"
+)
+
+// CloudAssetHistoryEntry represents an interval during which an asset held an IP address, a hostname or a relationship
+// with another resource. A nil NotBefore means the start of the interval is not known, a nil NotAfter that the
+// interval is still open.
+type CloudAssetHistoryEntry struct {
+	Kind      string
+	Value     string
+	NotBefore *time.Time
+	NotAfter  *time.Time
+}
+
+// IPScope narrows an IP address lookup to an account and VPC, as private address ranges are reused across them.
+// Empty fields match any account or VPC. The VPC only applies to private addresses.
+type IPScope struct {
+	AccountID string
+	VPCID     string
+}
+
+// IPLookup is an IP address to resolve to the cloud assets holding it at a point in time
+type IPLookup struct {
+	IPAddress string
+	When      time.Time
+	Scope     IPScope
+}
+
+// Ways a TagPredicate matches the value of a tag
+const (
+	TagMatchExact  = "exact"
+	TagMatchPrefix = "prefix"
+	TagMatchExists = "exists"
+)
+
+// TagPredicate matches assets by one of their tags. The value is ignored when only checking that the tag exists.
+type TagPredicate struct {
+	Key   string
+	Value string
+	Match string
+}
+
+// Ways a hostname search pattern matches a hostname
+const (
+	HostnameMatchPrefix   = "prefix"
+	HostnameMatchSuffix   = "suffix"
+	HostnameMatchContains = "contains"
+)
+
+// Directions in which relationships between resources are followed. Relationships point from the resource that
+// reported them to the related resource.
+const (
+	GraphDirectionOutgoing = "outgoing"
+	GraphDirectionIncoming = "incoming"
+	GraphDirectionBoth     = "both"
+)
+
+// ResourceGraphNode is a resource reached while walking relationships, at the number of hops from the starting resource.
+// The type is empty for resources that are only known as the target of a relationship.
+type ResourceGraphNode struct {
+	ResourceID   string
+	ResourceType string
+	Depth        uint
+}
+
+// ResourceGraphEdge is a relationship between two resources, valid during the interval. A nil NotBefore means the
+// start of the interval is not known, a nil NotAfter that the relationship is still in place.
+type ResourceGraphEdge struct {
+	From      string
+	To        string
+	NotBefore *time.Time
+	NotAfter  *time.Time
+}
+
+// ResourceGraph is the set of resources and relationships reachable from a resource at a point in time.
+// The starting resource is always the first node.
+type ResourceGraph struct {
+	Nodes []ResourceGraphNode
+	Edges []ResourceGraphEdge
+}
+
+// CloudAssetState represents the IP addresses, hostnames, relationships and attachments a resource holds at a point
+// in time
+type CloudAssetState struct {
+	PrivateIPAddresses []PrivateIPAssignment
+	PublicIPAddresses  []PublicIPAssignment
+	RelatedResources   []string
+	AttachedTo         []string
+}
+
+// PrivateIPAssignment represents a private IP address held by a resource, with the network it belongs to and the
+// private hostname resolving to it when known
+type PrivateIPAssignment struct {
+	IPAddress string
+	VPCID     string
+	SubnetID  string
+	Hostname  string
+}
+
+// PublicIPAssignment represents a public IP address held by a resource along with the hostname resolving to it, if any
+type PublicIPAssignment struct {
+	IPAddress string
+	Hostname  string
+}
+
+// ReplayScope selects the resources rebuilt from the change journal. Empty fields match any resource, account or region.
+type ReplayScope struct {
+	ResourceID string
+	AccountID  string
+	Region     string
+}
+
+// ReplayResult counts the resources rebuilt from the change journal and the changes applied to them
+type ReplayResult struct {
+	Resources int
+	Events    int
+}
@@ -10,9 +10,28 @@ type CloudAssetStorer interface {
 	Store(context.Context, CloudAssetChanges) error
 }
 
-// CloudAssetByIPFetcher fetches details for a cloud asset with a given IP address at a point in time
+// CloudAssetBatchStorer stores several cloud asset changes in the order of their change time, all of them or none
+// when atomic, as many as possible otherwise. The item errors line up with the changes, a DuplicateEvent marks changes
+// that were already processed.
+type CloudAssetBatchStorer interface {
+	StoreBatch(ctx context.Context, changes []CloudAssetChanges, atomic bool) ([]error, error)
+}
+
+// PrivateIPClassifier tells whether an IP address is looked up as private, so that addresses given without saying are
+// stored the same way
+type PrivateIPClassifier interface {
+	IsPrivateIP(ipAddress string) bool
+}
+
+// CloudAssetByIPFetcher fetches details for a cloud asset with a given IP address at a point in time, within the scope
 type CloudAssetByIPFetcher interface {
-	FetchByIP(ctx context.Context, when time.Time, ipAddress string) ([]CloudAssetDetails, error)
+	FetchByIP(ctx context.Context, when time.Time, ipAddress string, scope IPScope) ([]CloudAssetDetails, error)
+}
+
+// CloudAssetByIPBatchFetcher fetches details for the cloud assets holding each of a set of IP addresses at a point in time.
+// The result holds the assets for each lookup at the same index, and is empty for lookups without a match.
+type CloudAssetByIPBatchFetcher interface {
+	FetchByIPs(ctx context.Context, lookups []IPLookup) ([][]CloudAssetDetails, error)
 }
 
 // CloudAssetByHostnameFetcher fetches details for a cloud asset with a given hostname at a point in time
@@ -20,14 +39,79 @@ type CloudAssetByHostnameFetcher interface {
 	FetchByHostname(ctx context.Context, when time.Time, hostname string) ([]CloudAssetDetails, error)
 }
 
+// CloudAssetByHostnamePatternFetcher fetches details for the cloud assets with a hostname that starts with, ends with or
+// contains the pattern at a point in time, ignoring case, one page at a time. Pages are keyed the same way as for
+// CloudAllAssetsByTimeFetcher.
+type CloudAssetByHostnamePatternFetcher interface {
+	FetchByHostnamePattern(ctx context.Context, when time.Time, pattern string, match string, count uint, after int64) ([]CloudAssetDetails, int64, error)
+}
+
+// CloudAssetByCIDRFetcher fetches details for the cloud assets holding an IP address within a network at a point in time,
+// one page at a time. Pages are keyed the same way as for CloudAllAssetsByTimeFetcher.
+type CloudAssetByCIDRFetcher interface {
+	FetchByCIDR(ctx context.Context, when time.Time, cidr string, count uint, after int64) ([]CloudAssetDetails, int64, error)
+}
+
+// CloudAssetBehindNATGatewayFetcher fetches details for the cloud assets holding a private IP address in a subnet
+// served by the NAT gateway with a public IP address at a point in time, one page at a time. Those are the candidate
+// sources of traffic leaving through the NAT gateway. Pages are keyed the same way as for CloudAllAssetsByTimeFetcher.
+type CloudAssetBehindNATGatewayFetcher interface {
+	FetchBehindNATGateway(ctx context.Context, when time.Time, ipAddress string, count uint, after int64) ([]CloudAssetDetails, int64, error)
+}
+
+// CloudAssetByTagsFetcher fetches details for the cloud assets whose tags match all of the predicates at a point in
+// time, one page at a time. Pages are keyed the same way as for CloudAllAssetsByTimeFetcher.
+type CloudAssetByTagsFetcher interface {
+	FetchByTags(ctx context.Context, when time.Time, predicates []TagPredicate, count uint, after int64) ([]CloudAssetDetails, int64, error)
+}
+
+// CloudAssetStateFetcher fetches the IP addresses, hostnames, relationships and attachments held by a resource at a
+// point in time
+type CloudAssetStateFetcher interface {
+	FetchState(ctx context.Context, when time.Time, arn string) (CloudAssetState, error)
+}
+
+// CloudAssetStateStorer stores the changes derived from the state a resource holds at the change time. The state is
+// read and the changes stored in a single transaction locking the resource, so that no other change of the resource
+// is stored in between. Changes older than the latest ones stored for the resource are rejected with StaleChange.
+type CloudAssetStateStorer interface {
+	StoreFromState(ctx context.Context, when time.Time, arn string, derive func(CloudAssetState) (CloudAssetChanges, error)) error
+}
+
+// CloudAssetResourceIDsFetcher fetches the IDs of the resources of a type existing in an account and region at a point
+// in time
+type CloudAssetResourceIDsFetcher interface {
+	FetchResourceIDs(ctx context.Context, when time.Time, accountID string, region string, resourceType string) ([]string, error)
+}
+
+// CloudAssetGraphFetcher fetches the resources related to a resource at a point in time, following relationships in the
+// direction up to depth hops away
+type CloudAssetGraphFetcher interface {
+	FetchGraph(ctx context.Context, when time.Time, resID string, direction string, depth uint) (ResourceGraph, error)
+}
+
 // CloudAssetByResourceIDFetcher fetches details for a cloud asset with a given resource ID at a point in time
 type CloudAssetByResourceIDFetcher interface {
 	FetchByResourceID(ctx context.Context, when time.Time, resid string) ([]CloudAssetDetails, error)
 }
 
-// CloudAllAssetsByTimeFetcher fetches details for all cloud assets based on limit and optional offset with a given point in time
+// CloudAssetHistoryFetcher fetches every interval of IP addresses, hostnames and related resources a cloud asset with
+// a given resource ID ever held, ordered by time
+type CloudAssetHistoryFetcher interface {
+	FetchHistoryByResourceID(ctx context.Context, resid string) ([]CloudAssetHistoryEntry, error)
+}
+
+// CloudAllAssetsByTimeFetcher fetches details for all cloud assets of a type at a given point in time, one page at a time.
+// Pages are keyed on the position of the last asset of the previous page (0 for the first page), and the position of the
+// last asset returned is passed back to fetch the following page.
 type CloudAllAssetsByTimeFetcher interface {
-	FetchAll(ctx context.Context, when time.Time, count uint, offset uint, assetType string) ([]CloudAssetDetails, error)
+	FetchAll(ctx context.Context, when time.Time, count uint, after int64, assetType string) ([]CloudAssetDetails, int64, error)
+}
+
+// CloudAssetChangesReplayer rebuilds the IP address, hostname, relationship and tag intervals of the resources in scope
+// by applying the changes recorded in the change journal again, in the order of their change time
+type CloudAssetChangesReplayer interface {
+	Replay(ctx context.Context, scope ReplayScope) (ReplayResult, error)
 }
 
 // EventExportHandler handles exporting a single event during export
@@ -2,10 +2,10 @@ package v1
 
 import (
 	"context"
-	"encoding/base32"
-	"encoding/json"
 	"errors"
 	"fmt"
+	"net"
+	"strings"
 	"time"
 
 	"github.com/asecurityteam/asset-inventory-api/pkg/domain"
@@ -16,6 +16,11 @@ const (
 	awsEC2 = "AWS::EC2::Instance"
 	awsELB = "AWS::ElasticLoadBalancing::LoadBalancer"
 	awsALB = "AWS::ElasticLoadBalancingV2::LoadBalancer"
+	awsENI = "AWS::EC2::NetworkInterface"
+	awsEIP = "AWS::EC2::EIP"
+	awsECS = "AWS::ECS::Task"
+	awsFn  = "AWS::Lambda::Function"
+	awsNAT = "AWS::EC2::NatGateway"
 )
 
 // CloudAssets represents a list of assets
@@ -29,7 +34,8 @@ type PagedCloudAssets struct {
 	NextPageToken string `json:"nextPageToken"`
 }
 
-// CloudAssetDetails represent an asset and associated attributes
+// CloudAssetDetails represent an asset and associated attributes. AttachedTo lists the resources the asset is
+// attached to, nearest first, and is only reported by lookups by IP address.
 type CloudAssetDetails struct {
 	PrivateIPAddresses []string            `json:"privateIpAddresses"`
 	PublicIPAddresses  []string            `json:"publicIpAddresses"`
@@ -40,12 +46,16 @@ type CloudAssetDetails struct {
 	ARN                string              `json:"arn"`
 	Tags               map[string]string   `json:"tags"`
 	AccountOwner       domain.AccountOwner `json:"accountOwner"`
+	AttachedTo         []string            `json:"attachedTo"`
 }
 
-// CloudAssetFetchByIPParameters represents the incoming payload for fetching cloud assets by IP address
+// CloudAssetFetchByIPParameters represents the incoming payload for fetching cloud assets by IP address.
+// The account and VPC are optional, and narrow the lookup for address ranges reused across accounts or VPCs.
 type CloudAssetFetchByIPParameters struct {
 	IPAddress string `json:"ipAddress"`
 	Timestamp string `json:"time"`
+	AccountID string `json:"accountId"`
+	VPCID     string `json:"vpcId"`
 }
 
 // CloudAssetFetchByHostnameParameters represents the incoming payload for fetching cloud assets by hostname
@@ -58,37 +68,11 @@ type CloudAssetFetchByHostnameParameters struct {
 type CloudAssetFetchAllByTimestampParameters struct {
 	Timestamp string `json:"time"`
 	Count     uint   `json:"count"`
-	Offset    uint   `json:"offset"`
 	Type      string `json:"type"`
 }
 
-func (p *CloudAssetFetchAllByTimestampParameters) toNextPageToken() (string, error) {
-	nextPageParameters := CloudAssetFetchAllByTimestampParameters{
-		Timestamp: p.Timestamp,
-		Count:     p.Count,
-		Offset:    p.Offset + p.Count,
-		Type:      p.Type,
-	}
-	js, err := json.Marshal(nextPageParameters)
-	if err != nil {
-		return "", err
-	}
-	token := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(js)
-	return token, nil
-}
-
-func fetchAllByTimeStampParametersForToken(token string) (*CloudAssetFetchAllByTimestampParameters, error) {
-	js, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(token)
-	if err != nil {
-		return nil, err
-	}
-	ret := CloudAssetFetchAllByTimestampParameters{}
-	err = json.Unmarshal(js, &ret)
-	if err != nil {
-		return nil, err
-	}
-	return &ret, nil
-}
+// bulkPageKind identifies page tokens issued by the bulk asset listing
+const bulkPageKind = "bulk"
 
 // CloudAssetFetchAllByTimeStampPageParameters represents the request for subsequent pages of bulk fetching cloud assets
 type CloudAssetFetchAllByTimeStampPageParameters struct {
@@ -118,7 +102,18 @@ func (h *CloudFetchByIPHandler) Handle(ctx context.Context, input CloudAssetFetc
 		return CloudAssets{}, InvalidInput{Field: "ipAddress", Cause: e}
 	}
 
-	assets, e := h.Fetcher.FetchByIP(ctx, ts, input.IPAddress)
+	if net.ParseIP(input.IPAddress) == nil {
+		e = fmt.Errorf("invalid IPv4 or IPv6 address %q", input.IPAddress)
+		logger.Info(logs.InvalidInput{Reason: e.Error()})
+		return CloudAssets{}, InvalidInput{Field: "ipAddress", Cause: e}
+	}
+
+	if e = validateVPCID(input.VPCID); e != nil {
+		logger.Info(logs.InvalidInput{Reason: e.Error()})
+		return CloudAssets{}, InvalidInput{Field: "vpcId", Cause: e}
+	}
+
+	assets, e := h.Fetcher.FetchByIP(ctx, ts, input.IPAddress, domain.IPScope{AccountID: input.AccountID, VPCID: input.VPCID})
 	if e != nil {
 		logger.Error(logs.StorageError{Reason: e.Error()})
 		return CloudAssets{}, e
@@ -165,9 +160,17 @@ func (h *CloudFetchByHostnameHandler) Handle(ctx context.Context, input CloudAss
 	return extractOutput(assets), nil
 }
 
+// validateVPCID checks that an optional VPC ID looks like one
+func validateVPCID(input string) error {
+	if input != "" && !strings.HasPrefix(input, "vpc-") {
+		return fmt.Errorf("invalid VPC ID %s", input)
+	}
+	return nil
+}
+
 func validateAssetType(input string) (string, error) {
 	switch input {
-	case awsEC2, awsELB, awsALB:
+	case awsEC2, awsELB, awsALB, awsENI, awsEIP, awsECS, awsFn, awsNAT, domain.ResourceTypeKubernetesPod, domain.ResourceTypeKubernetesService:
 		return input, nil
 	default:
 		return "", fmt.Errorf("unknown asset type %s", input)
@@ -176,9 +179,10 @@ func validateAssetType(input string) (string, error) {
 
 // CloudFetchAllAssetsByTimeHandler defines a lambda handler for bulk fetching cloud assets known at specific point in time
 type CloudFetchAllAssetsByTimeHandler struct {
-	LogFn   domain.LogFn
-	StatFn  domain.StatFn
-	Fetcher domain.CloudAllAssetsByTimeFetcher
+	LogFn      domain.LogFn
+	StatFn     domain.StatFn
+	Fetcher    domain.CloudAllAssetsByTimeFetcher
+	PageTokens *PageTokenSigner
 }
 
 // Handle handles fetching cloud assets with pagination
@@ -203,21 +207,12 @@ func (h *CloudFetchAllAssetsByTimeHandler) Handle(ctx context.Context, input Clo
 		return PagedCloudAssets{}, InvalidInput{Field: "type", Cause: e}
 	}
 
-	var offset uint // do not offset as this is the first page
-	assets, e := h.Fetcher.FetchAll(ctx, ts, input.Count, offset, assetType)
-	if e != nil {
-		logger.Error(logs.StorageError{Reason: e.Error()})
-		return PagedCloudAssets{}, e
-	}
-	if len(assets) == 0 {
-		return PagedCloudAssets{}, NotFound{ID: "any"}
-	}
-
-	nextPageToken, e := input.toNextPageToken()
-	if e != nil {
-		logger.Error(logs.StorageError{Reason: e.Error()})
-	}
-	return PagedCloudAssets{extractOutput(assets), nextPageToken}, nil
+	return fetchAllAssetsPage(ctx, logger, h.Fetcher, h.PageTokens, pageCursor{
+		Kind:      bulkPageKind,
+		Timestamp: input.Timestamp,
+		Count:     input.Count,
+		Filter:    assetType,
+	}, ts)
 }
 
 // CloudAssetFetchByResourceIDParameters represents the incoming payload for fetching cloud assets by resource ID
@@ -263,46 +258,61 @@ func (h *CloudFetchByResourceIDHandler) Handle(ctx context.Context, input CloudA
 
 // CloudFetchAllAssetsByTimePageHandler defines a lambda handler for bulk fetching subsequent pages of cloud assets known at specific point in time
 type CloudFetchAllAssetsByTimePageHandler struct {
-	LogFn   domain.LogFn
-	StatFn  domain.StatFn
-	Fetcher domain.CloudAllAssetsByTimeFetcher
+	LogFn      domain.LogFn
+	StatFn     domain.StatFn
+	Fetcher    domain.CloudAllAssetsByTimeFetcher
+	PageTokens *PageTokenSigner
 }
 
 // Handle handles subsequent page fetching of cloud assets
 func (h *CloudFetchAllAssetsByTimePageHandler) Handle(ctx context.Context, input CloudAssetFetchAllByTimeStampPageParameters) (PagedCloudAssets, error) {
 	logger := h.LogFn(ctx)
-	params, e := fetchAllByTimeStampParametersForToken(input.PageToken)
+	//generic error to report to caller to avoid exposing the internal token structure NB, the specific error is still logged
+	tokenError := errors.New("malformed pageToken")
+
+	cursor, e := verifyPageToken(logger, h.PageTokens, input.PageToken, bulkPageKind)
 	if e != nil {
-		logger.Info(logs.InvalidInput{Reason: e.Error()})
-		return PagedCloudAssets{}, InvalidInput{Field: "pageToken", Cause: e}
+		return PagedCloudAssets{}, e
 	}
-	ts, e := time.Parse(time.RFC3339Nano, params.Timestamp)
+	ts, e := time.Parse(time.RFC3339Nano, cursor.Timestamp)
 	if e != nil {
 		logger.Info(logs.InvalidInput{Reason: e.Error()})
-		return PagedCloudAssets{}, InvalidInput{Field: "pageToken", Cause: e}
+		return PagedCloudAssets{}, InvalidInput{Field: "pageToken", Cause: tokenError}
 	}
 
-	//generic error to report to caller to avoid exposing the internal token structure NB, the specific error is still logged
-	tokenError := errors.New("malformed pageToken")
-	if params.Count == 0 {
+	if cursor.Count == 0 {
 		e = errors.New("missing or malformed required parameter count")
 		logger.Info(logs.InvalidInput{Reason: e.Error()})
 		return PagedCloudAssets{}, InvalidInput{Field: "pageToken", Cause: tokenError}
 	}
 
-	if params.Offset == 0 {
-		e = errors.New("missing or malformed required parameter offset")
+	if cursor.After == 0 {
+		e = errors.New("missing or malformed required parameter after")
 		logger.Info(logs.InvalidInput{Reason: e.Error()})
 		return PagedCloudAssets{}, InvalidInput{Field: "pageToken", Cause: tokenError}
 	}
 
-	assetType, e := validateAssetType(params.Type)
-	if e != nil {
+	if _, e = validateAssetType(cursor.Filter); e != nil {
 		logger.Info(logs.InvalidInput{Reason: e.Error()})
 		return PagedCloudAssets{}, InvalidInput{Field: "pageToken", Cause: tokenError}
 	}
 
-	assets, e := h.Fetcher.FetchAll(ctx, ts, params.Count, params.Offset, assetType)
+	return fetchAllAssetsPage(ctx, logger, h.Fetcher, h.PageTokens, cursor, ts)
+}
+
+// fetchAllAssetsPage fetches the page of the bulk listing at the cursor and issues the token for the following page
+func fetchAllAssetsPage(ctx context.Context, logger domain.Logger, fetcher domain.CloudAllAssetsByTimeFetcher,
+	signer *PageTokenSigner, cursor pageCursor, ts time.Time) (PagedCloudAssets, error) {
+	return fetchAssetsPage(logger, signer, cursor, func(after int64) ([]domain.CloudAssetDetails, int64, error) {
+		return fetcher.FetchAll(ctx, ts, cursor.Count, after, cursor.Filter)
+	})
+}
+
+// fetchAssetsPage fetches the page of a paged listing at the cursor and issues the token for the following page.
+// No token is issued once a page comes back short, as that page is the last one.
+func fetchAssetsPage(logger domain.Logger, signer *PageTokenSigner, cursor pageCursor,
+	fetch func(after int64) ([]domain.CloudAssetDetails, int64, error)) (PagedCloudAssets, error) {
+	assets, last, e := fetch(cursor.After)
 	if e != nil {
 		logger.Error(logs.StorageError{Reason: e.Error()})
 		return PagedCloudAssets{}, e
@@ -311,7 +321,14 @@ func (h *CloudFetchAllAssetsByTimePageHandler) Handle(ctx context.Context, input
 		return PagedCloudAssets{}, NotFound{ID: "any"}
 	}
 
-	nextPageToken, _ := params.toNextPageToken()
+	var nextPageToken string
+	if uint(len(assets)) == cursor.Count {
+		cursor.After = last
+		if nextPageToken, e = signer.sign(cursor); e != nil {
+			logger.Error(logs.StorageError{Reason: e.Error()})
+			return PagedCloudAssets{}, e
+		}
+	}
 	return PagedCloudAssets{extractOutput(assets), nextPageToken}, nil
 }
 
@@ -332,6 +349,10 @@ func extractOutput(assets []domain.CloudAssetDetails) CloudAssets {
 		if len(publicIPAddresses) == 0 {
 			publicIPAddresses = make([]string, 0)
 		}
+		attachedTo := asset.AttachedTo
+		if len(attachedTo) == 0 {
+			attachedTo = make([]string, 0)
+		}
 		tags := asset.Tags
 		if len(tags) == 0 {
 			tags = make(map[string]string)
@@ -359,6 +380,7 @@ func extractOutput(assets []domain.CloudAssetDetails) CloudAssets {
 			ARN:                asset.ARN,
 			Tags:               tags,
 			AccountOwner:       owner,
+			AttachedTo:         attachedTo,
 		}
 	}
 	return cloudAssets
@@ -0,0 +1,135 @@
+package v1
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/asecurityteam/asset-inventory-api/pkg/domain"
+	"github.com/asecurityteam/asset-inventory-api/pkg/logs"
+)
+
+const (
+	// cidrPageKind identifies page tokens issued by the CIDR asset listing
+	cidrPageKind = "cidr"
+	// maxCIDRCount caps the page size of the CIDR listing, as large networks can hold a large part of the inventory
+	maxCIDRCount = 500
+	// minIPv4PrefixLength and minIPv6PrefixLength are the shortest prefixes listed, so that a single request can not
+	// scan every address assignment
+	minIPv4PrefixLength = 8
+	minIPv6PrefixLength = 32
+)
+
+// CloudAssetFetchByCIDRParameters represents the incoming payload for fetching cloud assets within a network
+type CloudAssetFetchByCIDRParameters struct {
+	CIDR      string `json:"cidr"`
+	Timestamp string `json:"time"`
+	Count     uint   `json:"count"`
+}
+
+// CloudAssetFetchByCIDRPageParameters represents the request for subsequent pages of cloud assets within a network
+type CloudAssetFetchByCIDRPageParameters struct {
+	PageToken string `json:"pageToken"`
+}
+
+// CloudFetchByCIDRHandler defines a lambda handler for fetching cloud assets holding an IP address within a network
+type CloudFetchByCIDRHandler struct {
+	LogFn      domain.LogFn
+	StatFn     domain.StatFn
+	Fetcher    domain.CloudAssetByCIDRFetcher
+	PageTokens *PageTokenSigner
+}
+
+// Handle handles fetching the first page of cloud assets within a network
+func (h *CloudFetchByCIDRHandler) Handle(ctx context.Context, input CloudAssetFetchByCIDRParameters) (PagedCloudAssets, error) {
+	logger := h.LogFn(ctx)
+
+	ts, e := time.Parse(time.RFC3339Nano, input.Timestamp)
+	if e != nil {
+		logger.Info(logs.InvalidInput{Reason: e.Error()})
+		return PagedCloudAssets{}, InvalidInput{Field: "time", Cause: e}
+	}
+
+	if input.Count == 0 || input.Count > maxCIDRCount {
+		e = fmt.Errorf("count must be between 1 and %d", maxCIDRCount)
+		logger.Info(logs.InvalidInput{Reason: e.Error()})
+		return PagedCloudAssets{}, InvalidInput{Field: "count", Cause: e}
+	}
+
+	network, e := parseCIDR(input.CIDR)
+	if e != nil {
+		logger.Info(logs.InvalidInput{Reason: e.Error()})
+		return PagedCloudAssets{}, InvalidInput{Field: "cidr", Cause: e}
+	}
+
+	return fetchCIDRPage(ctx, logger, h.Fetcher, h.PageTokens, pageCursor{
+		Kind:      cidrPageKind,
+		Timestamp: input.Timestamp,
+		Count:     input.Count,
+		Filter:    network.String(), // host bits are dropped, 10.20.1.1/16 lists 10.20.0.0/16
+	}, ts)
+}
+
+// CloudFetchByCIDRPageHandler defines a lambda handler for fetching subsequent pages of cloud assets within a network
+type CloudFetchByCIDRPageHandler struct {
+	LogFn      domain.LogFn
+	StatFn     domain.StatFn
+	Fetcher    domain.CloudAssetByCIDRFetcher
+	PageTokens *PageTokenSigner
+}
+
+// Handle handles fetching subsequent pages of cloud assets within a network
+func (h *CloudFetchByCIDRPageHandler) Handle(ctx context.Context, input CloudAssetFetchByCIDRPageParameters) (PagedCloudAssets, error) {
+	logger := h.LogFn(ctx)
+	//generic error to report to caller to avoid exposing the internal token structure NB, the specific error is still logged
+	tokenError := errors.New("malformed pageToken")
+
+	cursor, e := verifyPageToken(logger, h.PageTokens, input.PageToken, cidrPageKind)
+	if e != nil {
+		return PagedCloudAssets{}, e
+	}
+	ts, e := time.Parse(time.RFC3339Nano, cursor.Timestamp)
+	if e != nil {
+		logger.Info(logs.InvalidInput{Reason: e.Error()})
+		return PagedCloudAssets{}, InvalidInput{Field: "pageToken", Cause: tokenError}
+	}
+
+	if cursor.Count == 0 || cursor.Count > maxCIDRCount || cursor.After == 0 {
+		e = errors.New("missing or malformed required parameter count or after")
+		logger.Info(logs.InvalidInput{Reason: e.Error()})
+		return PagedCloudAssets{}, InvalidInput{Field: "pageToken", Cause: tokenError}
+	}
+
+	if _, e = parseCIDR(cursor.Filter); e != nil {
+		logger.Info(logs.InvalidInput{Reason: e.Error()})
+		return PagedCloudAssets{}, InvalidInput{Field: "pageToken", Cause: tokenError}
+	}
+
+	return fetchCIDRPage(ctx, logger, h.Fetcher, h.PageTokens, cursor, ts)
+}
+
+// fetchCIDRPage fetches the page of the CIDR listing at the cursor and issues the token for the following page
+func fetchCIDRPage(ctx context.Context, logger domain.Logger, fetcher domain.CloudAssetByCIDRFetcher,
+	signer *PageTokenSigner, cursor pageCursor, ts time.Time) (PagedCloudAssets, error) {
+	return fetchAssetsPage(logger, signer, cursor, func(after int64) ([]domain.CloudAssetDetails, int64, error) {
+		return fetcher.FetchByCIDR(ctx, ts, cursor.Filter, cursor.Count, after)
+	})
+}
+
+// parseCIDR parses a network in CIDR notation, rejecting networks too large to be listed
+func parseCIDR(cidr string) (*net.IPNet, error) {
+	_, network, e := net.ParseCIDR(cidr)
+	if e != nil {
+		return nil, e
+	}
+	minPrefixLength := minIPv6PrefixLength
+	if network.IP.To4() != nil {
+		minPrefixLength = minIPv4PrefixLength
+	}
+	if ones, _ := network.Mask.Size(); ones < minPrefixLength {
+		return nil, fmt.Errorf("network %s is larger than /%d", network, minPrefixLength)
+	}
+	return network, nil
+}
@@ -0,0 +1,156 @@
+package v1
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/asecurityteam/asset-inventory-api/pkg/domain"
+)
+
+func newFetchByCIDRHandler(fetcher domain.CloudAssetByCIDRFetcher) *CloudFetchByCIDRHandler {
+	return &CloudFetchByCIDRHandler{
+		LogFn:      testLogFn,
+		StatFn:     testStatFn,
+		Fetcher:    fetcher,
+		PageTokens: testPageTokenSigner(),
+	}
+}
+
+func newFetchByCIDRPageHandler(fetcher domain.CloudAssetByCIDRFetcher) *CloudFetchByCIDRPageHandler {
+	return &CloudFetchByCIDRPageHandler{
+		LogFn:      testLogFn,
+		StatFn:     testStatFn,
+		Fetcher:    fetcher,
+		PageTokens: testPageTokenSigner(),
+	}
+}
+
+func validFetchByCIDRInput() CloudAssetFetchByCIDRParameters {
+	return CloudAssetFetchByCIDRParameters{
+		CIDR:      "10.20.0.0/16",
+		Timestamp: time.Now().Format(time.RFC3339Nano),
+		Count:     1,
+	}
+}
+
+func TestFetchByCIDRInvalidInput(t *testing.T) {
+	now := time.Now().Format(time.RFC3339Nano)
+	tc := []struct {
+		name  string
+		input CloudAssetFetchByCIDRParameters
+	}{
+		{"invalid timestamp", CloudAssetFetchByCIDRParameters{Timestamp: "foo", CIDR: "10.0.0.0/8", Count: 1}},
+		{"no count", CloudAssetFetchByCIDRParameters{Timestamp: now, CIDR: "10.0.0.0/8"}},
+		{"no cidr", CloudAssetFetchByCIDRParameters{Timestamp: now, Count: 1}},
+		{"no prefix", CloudAssetFetchByCIDRParameters{Timestamp: now, CIDR: "10.0.0.1", Count: 1}},
+		{"invalid prefix", CloudAssetFetchByCIDRParameters{Timestamp: now, CIDR: "10.0.0.0/33", Count: 1}},
+		{"count too large", CloudAssetFetchByCIDRParameters{Timestamp: now, CIDR: "10.0.0.0/8", Count: maxCIDRCount + 1}},
+		{"ipv4 network too large", CloudAssetFetchByCIDRParameters{Timestamp: now, CIDR: "0.0.0.0/0", Count: 1}},
+		{"ipv4 prefix shorter than /8", CloudAssetFetchByCIDRParameters{Timestamp: now, CIDR: "10.0.0.0/7", Count: 1}},
+		{"ipv6 network too large", CloudAssetFetchByCIDRParameters{Timestamp: now, CIDR: "::/0", Count: 1}},
+		{"ipv6 prefix shorter than /32", CloudAssetFetchByCIDRParameters{Timestamp: now, CIDR: "2001:db8::/31", Count: 1}},
+	}
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			_, e := newFetchByCIDRHandler(nil).Handle(context.Background(), tt.input)
+			require.NotNil(t, e)
+			assert.IsType(t, InvalidInput{}, e)
+		})
+	}
+}
+
+func TestFetchByCIDRStorageError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	fetcher := NewMockCloudAssetByCIDRFetcher(ctrl)
+	input := validFetchByCIDRInput()
+	ts, _ := time.Parse(time.RFC3339Nano, input.Timestamp)
+	fetcher.EXPECT().FetchByCIDR(gomock.Any(), ts, input.CIDR, input.Count, int64(0)).Return(nil, int64(0), errors.New(""))
+
+	_, e := newFetchByCIDRHandler(fetcher).Handle(context.Background(), input)
+	require.NotNil(t, e)
+}
+
+func TestFetchByCIDRNotFound(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	fetcher := NewMockCloudAssetByCIDRFetcher(ctrl)
+	input := validFetchByCIDRInput()
+	ts, _ := time.Parse(time.RFC3339Nano, input.Timestamp)
+	fetcher.EXPECT().FetchByCIDR(gomock.Any(), ts, input.CIDR, input.Count, int64(0)).Return([]domain.CloudAssetDetails{}, int64(0), nil)
+
+	_, e := newFetchByCIDRHandler(fetcher).Handle(context.Background(), input)
+	require.NotNil(t, e)
+	assert.IsType(t, NotFound{}, e)
+}
+
+func TestFetchByCIDRNormalizesNetwork(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	fetcher := NewMockCloudAssetByCIDRFetcher(ctrl)
+	input := validFetchByCIDRInput()
+	input.CIDR = "10.20.30.40/16"
+	input.Count = 2
+	ts, _ := time.Parse(time.RFC3339Nano, input.Timestamp)
+	fetcher.EXPECT().FetchByCIDR(gomock.Any(), ts, "10.20.0.0/16", input.Count, int64(0)).Return([]domain.CloudAssetDetails{{ARN: "arn"}}, int64(7), nil)
+
+	res, e := newFetchByCIDRHandler(fetcher).Handle(context.Background(), input)
+	require.Nil(t, e)
+	assert.Len(t, res.Assets, 1)
+	assert.Empty(t, res.NextPageToken)
+}
+
+func TestFetchByCIDRPaging(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	fetcher := NewMockCloudAssetByCIDRFetcher(ctrl)
+	input := validFetchByCIDRInput()
+	ts, _ := time.Parse(time.RFC3339Nano, input.Timestamp)
+	gomock.InOrder(
+		fetcher.EXPECT().FetchByCIDR(gomock.Any(), ts, input.CIDR, input.Count, int64(0)).Return([]domain.CloudAssetDetails{{ARN: "arn1"}}, int64(7), nil),
+		fetcher.EXPECT().FetchByCIDR(gomock.Any(), ts, input.CIDR, input.Count, int64(7)).Return([]domain.CloudAssetDetails{{ARN: "arn2"}}, int64(9), nil),
+	)
+
+	first, e := newFetchByCIDRHandler(fetcher).Handle(context.Background(), input)
+	require.Nil(t, e)
+	require.NotEmpty(t, first.NextPageToken)
+
+	second, e := newFetchByCIDRPageHandler(fetcher).Handle(context.Background(), CloudAssetFetchByCIDRPageParameters{PageToken: first.NextPageToken})
+	require.Nil(t, e)
+	assert.Equal(t, "arn2", second.Assets[0].ARN)
+}
+
+func TestFetchByCIDRPageInvalidToken(t *testing.T) {
+	signer := testPageTokenSigner()
+	bulkToken, _ := signer.sign(pageCursor{Kind: bulkPageKind, Timestamp: time.Now().Format(time.RFC3339Nano), Count: 1, After: 1, Filter: awsEC2})
+	badCIDR, _ := signer.sign(pageCursor{Kind: cidrPageKind, Timestamp: time.Now().Format(time.RFC3339Nano), Count: 1, After: 1, Filter: "nope"})
+	noAfter, _ := signer.sign(pageCursor{Kind: cidrPageKind, Timestamp: time.Now().Format(time.RFC3339Nano), Count: 1, Filter: "10.0.0.0/8"})
+	badTime, _ := signer.sign(pageCursor{Kind: cidrPageKind, Timestamp: "foo", Count: 1, After: 1, Filter: "10.0.0.0/8"})
+	tooMany, _ := signer.sign(pageCursor{Kind: cidrPageKind, Timestamp: time.Now().Format(time.RFC3339Nano), Count: maxCIDRCount + 1, After: 1, Filter: "10.0.0.0/8"})
+	tooLarge, _ := signer.sign(pageCursor{Kind: cidrPageKind, Timestamp: time.Now().Format(time.RFC3339Nano), Count: 1, After: 1, Filter: "0.0.0.0/0"})
+	for name, token := range map[string]string{
+		"empty":      "",
+		"bulk token": bulkToken,
+		"bad cidr":   badCIDR,
+		"no after":   noAfter,
+		"bad time":   badTime,
+		"too many":   tooMany,
+		"too large":  tooLarge,
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, e := newFetchByCIDRPageHandler(nil).Handle(context.Background(), CloudAssetFetchByCIDRPageParameters{PageToken: token})
+			require.NotNil(t, e)
+			assert.IsType(t, InvalidInput{}, e)
+		})
+	}
+}
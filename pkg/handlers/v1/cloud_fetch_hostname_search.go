@@ -0,0 +1,146 @@
+package v1
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/asecurityteam/asset-inventory-api/pkg/domain"
+	"github.com/asecurityteam/asset-inventory-api/pkg/logs"
+)
+
+const (
+	// hostnameSearchPageKind identifies page tokens issued by the hostname search
+	hostnameSearchPageKind = "hostname"
+	// maxHostnameSearchCount caps the page size of the hostname search, as short patterns can match a large part of the inventory
+	maxHostnameSearchCount = 500
+	// minHostnamePatternLength is the shortest pattern the trigram index can narrow down
+	minHostnamePatternLength = 3
+)
+
+// CloudAssetFetchByHostnamePatternParameters represents the incoming payload for searching cloud assets by partial hostname
+type CloudAssetFetchByHostnamePatternParameters struct {
+	Pattern   string `json:"pattern"`
+	Match     string `json:"match"`
+	Timestamp string `json:"time"`
+	Count     uint   `json:"count"`
+}
+
+// CloudAssetFetchByHostnamePatternPageParameters represents the request for subsequent pages of a hostname search
+type CloudAssetFetchByHostnamePatternPageParameters struct {
+	PageToken string `json:"pageToken"`
+}
+
+// CloudFetchByHostnamePatternHandler defines a lambda handler for searching cloud assets with a hostname that starts
+// with, ends with or contains a pattern
+type CloudFetchByHostnamePatternHandler struct {
+	LogFn      domain.LogFn
+	StatFn     domain.StatFn
+	Fetcher    domain.CloudAssetByHostnamePatternFetcher
+	PageTokens *PageTokenSigner
+}
+
+// Handle handles fetching the first page of cloud assets with a hostname matching the pattern
+func (h *CloudFetchByHostnamePatternHandler) Handle(ctx context.Context, input CloudAssetFetchByHostnamePatternParameters) (PagedCloudAssets, error) {
+	logger := h.LogFn(ctx)
+
+	ts, e := time.Parse(time.RFC3339Nano, input.Timestamp)
+	if e != nil {
+		logger.Info(logs.InvalidInput{Reason: e.Error()})
+		return PagedCloudAssets{}, InvalidInput{Field: "time", Cause: e}
+	}
+
+	if input.Count == 0 || input.Count > maxHostnameSearchCount {
+		e = fmt.Errorf("count must be between 1 and %d", maxHostnameSearchCount)
+		logger.Info(logs.InvalidInput{Reason: e.Error()})
+		return PagedCloudAssets{}, InvalidInput{Field: "count", Cause: e}
+	}
+
+	match := strings.ToLower(input.Match)
+	if e = validateHostnameMatch(match); e != nil {
+		logger.Info(logs.InvalidInput{Reason: e.Error()})
+		return PagedCloudAssets{}, InvalidInput{Field: "match", Cause: e}
+	}
+
+	if e = validateHostnamePattern(input.Pattern); e != nil {
+		logger.Info(logs.InvalidInput{Reason: e.Error()})
+		return PagedCloudAssets{}, InvalidInput{Field: "pattern", Cause: e}
+	}
+
+	return fetchHostnamePatternPage(ctx, logger, h.Fetcher, h.PageTokens, pageCursor{
+		Kind:      hostnameSearchPageKind,
+		Timestamp: input.Timestamp,
+		Count:     input.Count,
+		Filter:    match + ":" + input.Pattern,
+	}, ts)
+}
+
+// CloudFetchByHostnamePatternPageHandler defines a lambda handler for fetching subsequent pages of a hostname search
+type CloudFetchByHostnamePatternPageHandler struct {
+	LogFn      domain.LogFn
+	StatFn     domain.StatFn
+	Fetcher    domain.CloudAssetByHostnamePatternFetcher
+	PageTokens *PageTokenSigner
+}
+
+// Handle handles fetching subsequent pages of cloud assets with a hostname matching the pattern
+func (h *CloudFetchByHostnamePatternPageHandler) Handle(ctx context.Context, input CloudAssetFetchByHostnamePatternPageParameters) (PagedCloudAssets, error) {
+	logger := h.LogFn(ctx)
+	//generic error to report to caller to avoid exposing the internal token structure NB, the specific error is still logged
+	tokenError := errors.New("malformed pageToken")
+
+	cursor, e := verifyPageToken(logger, h.PageTokens, input.PageToken, hostnameSearchPageKind)
+	if e != nil {
+		return PagedCloudAssets{}, e
+	}
+	ts, e := time.Parse(time.RFC3339Nano, cursor.Timestamp)
+	if e != nil {
+		logger.Info(logs.InvalidInput{Reason: e.Error()})
+		return PagedCloudAssets{}, InvalidInput{Field: "pageToken", Cause: tokenError}
+	}
+
+	if cursor.Count == 0 || cursor.Count > maxHostnameSearchCount || cursor.After == 0 {
+		e = errors.New("missing or malformed required parameter count or after")
+		logger.Info(logs.InvalidInput{Reason: e.Error()})
+		return PagedCloudAssets{}, InvalidInput{Field: "pageToken", Cause: tokenError}
+	}
+
+	parts := strings.SplitN(cursor.Filter, ":", 2)
+	if len(parts) != 2 {
+		e = errors.New("missing hostname match")
+	} else if e = validateHostnameMatch(parts[0]); e == nil {
+		e = validateHostnamePattern(parts[1])
+	}
+	if e != nil {
+		logger.Info(logs.InvalidInput{Reason: e.Error()})
+		return PagedCloudAssets{}, InvalidInput{Field: "pageToken", Cause: tokenError}
+	}
+
+	return fetchHostnamePatternPage(ctx, logger, h.Fetcher, h.PageTokens, cursor, ts)
+}
+
+func validateHostnameMatch(match string) error {
+	switch match {
+	case domain.HostnameMatchPrefix, domain.HostnameMatchSuffix, domain.HostnameMatchContains:
+		return nil
+	}
+	return fmt.Errorf("unknown hostname match %s", match)
+}
+
+func validateHostnamePattern(pattern string) error {
+	if len(pattern) < minHostnamePatternLength {
+		return fmt.Errorf("pattern must be at least %d characters long", minHostnamePatternLength)
+	}
+	return nil
+}
+
+// fetchHostnamePatternPage fetches the page of the hostname search at the cursor and issues the token for the following page
+func fetchHostnamePatternPage(ctx context.Context, logger domain.Logger, fetcher domain.CloudAssetByHostnamePatternFetcher,
+	signer *PageTokenSigner, cursor pageCursor, ts time.Time) (PagedCloudAssets, error) {
+	parts := strings.SplitN(cursor.Filter, ":", 2)
+	return fetchAssetsPage(logger, signer, cursor, func(after int64) ([]domain.CloudAssetDetails, int64, error) {
+		return fetcher.FetchByHostnamePattern(ctx, ts, parts[1], parts[0], cursor.Count, after)
+	})
+}
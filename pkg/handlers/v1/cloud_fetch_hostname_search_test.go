@@ -0,0 +1,141 @@
+package v1
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/asecurityteam/asset-inventory-api/pkg/domain"
+)
+
+func newFetchByHostnamePatternHandler(fetcher domain.CloudAssetByHostnamePatternFetcher) *CloudFetchByHostnamePatternHandler {
+	return &CloudFetchByHostnamePatternHandler{
+		LogFn:      testLogFn,
+		StatFn:     testStatFn,
+		Fetcher:    fetcher,
+		PageTokens: testPageTokenSigner(),
+	}
+}
+
+func newFetchByHostnamePatternPageHandler(fetcher domain.CloudAssetByHostnamePatternFetcher) *CloudFetchByHostnamePatternPageHandler {
+	return &CloudFetchByHostnamePatternPageHandler{
+		LogFn:      testLogFn,
+		StatFn:     testStatFn,
+		Fetcher:    fetcher,
+		PageTokens: testPageTokenSigner(),
+	}
+}
+
+func validFetchByHostnamePatternInput() CloudAssetFetchByHostnamePatternParameters {
+	return CloudAssetFetchByHostnamePatternParameters{
+		Pattern:   ".example.com",
+		Match:     "Suffix",
+		Timestamp: time.Now().Format(time.RFC3339Nano),
+		Count:     1,
+	}
+}
+
+func TestFetchByHostnamePatternInvalidInput(t *testing.T) {
+	now := time.Now().Format(time.RFC3339Nano)
+	tc := []struct {
+		name  string
+		input CloudAssetFetchByHostnamePatternParameters
+		field string
+	}{
+		{"invalid timestamp", CloudAssetFetchByHostnamePatternParameters{Timestamp: "foo", Pattern: "api", Match: "prefix", Count: 1}, "time"},
+		{"no count", CloudAssetFetchByHostnamePatternParameters{Timestamp: now, Pattern: "api", Match: "prefix"}, "count"},
+		{"count over cap", CloudAssetFetchByHostnamePatternParameters{Timestamp: now, Pattern: "api", Match: "prefix", Count: maxHostnameSearchCount + 1}, "count"},
+		{"unknown match", CloudAssetFetchByHostnamePatternParameters{Timestamp: now, Pattern: "api", Match: "regex", Count: 1}, "match"},
+		{"short pattern", CloudAssetFetchByHostnamePatternParameters{Timestamp: now, Pattern: "ap", Match: "prefix", Count: 1}, "pattern"},
+	}
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			_, e := newFetchByHostnamePatternHandler(nil).Handle(context.Background(), tt.input)
+			require.NotNil(t, e)
+			require.IsType(t, InvalidInput{}, e)
+			assert.Equal(t, tt.field, e.(InvalidInput).Field)
+		})
+	}
+}
+
+func TestFetchByHostnamePatternStorageError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	fetcher := NewMockCloudAssetByHostnamePatternFetcher(ctrl)
+	input := validFetchByHostnamePatternInput()
+	ts, _ := time.Parse(time.RFC3339Nano, input.Timestamp)
+	fetcher.EXPECT().FetchByHostnamePattern(gomock.Any(), ts, input.Pattern, domain.HostnameMatchSuffix, input.Count, int64(0)).Return(nil, int64(0), errors.New(""))
+
+	_, e := newFetchByHostnamePatternHandler(fetcher).Handle(context.Background(), input)
+	require.NotNil(t, e)
+}
+
+func TestFetchByHostnamePatternNotFound(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	fetcher := NewMockCloudAssetByHostnamePatternFetcher(ctrl)
+	input := validFetchByHostnamePatternInput()
+	ts, _ := time.Parse(time.RFC3339Nano, input.Timestamp)
+	fetcher.EXPECT().FetchByHostnamePattern(gomock.Any(), ts, input.Pattern, domain.HostnameMatchSuffix, input.Count, int64(0)).Return([]domain.CloudAssetDetails{}, int64(0), nil)
+
+	_, e := newFetchByHostnamePatternHandler(fetcher).Handle(context.Background(), input)
+	require.NotNil(t, e)
+	assert.IsType(t, NotFound{}, e)
+}
+
+func TestFetchByHostnamePatternPaging(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	fetcher := NewMockCloudAssetByHostnamePatternFetcher(ctrl)
+	input := validFetchByHostnamePatternInput()
+	input.Pattern = "web:8080" // the separator of the token filter may appear in the pattern
+	ts, _ := time.Parse(time.RFC3339Nano, input.Timestamp)
+	gomock.InOrder(
+		fetcher.EXPECT().FetchByHostnamePattern(gomock.Any(), ts, input.Pattern, domain.HostnameMatchSuffix, input.Count, int64(0)).Return([]domain.CloudAssetDetails{{ARN: "arn1"}}, int64(7), nil),
+		fetcher.EXPECT().FetchByHostnamePattern(gomock.Any(), ts, input.Pattern, domain.HostnameMatchSuffix, input.Count, int64(7)).Return([]domain.CloudAssetDetails{{ARN: "arn2"}}, int64(9), nil),
+	)
+
+	first, e := newFetchByHostnamePatternHandler(fetcher).Handle(context.Background(), input)
+	require.Nil(t, e)
+	require.NotEmpty(t, first.NextPageToken)
+
+	second, e := newFetchByHostnamePatternPageHandler(fetcher).Handle(context.Background(), CloudAssetFetchByHostnamePatternPageParameters{PageToken: first.NextPageToken})
+	require.Nil(t, e)
+	assert.Equal(t, "arn2", second.Assets[0].ARN)
+}
+
+func TestFetchByHostnamePatternPageInvalidToken(t *testing.T) {
+	signer := testPageTokenSigner()
+	now := time.Now().Format(time.RFC3339Nano)
+	cidrToken, _ := signer.sign(pageCursor{Kind: cidrPageKind, Timestamp: now, Count: 1, After: 1, Filter: "10.0.0.0/8"})
+	noMatch, _ := signer.sign(pageCursor{Kind: hostnameSearchPageKind, Timestamp: now, Count: 1, After: 1, Filter: "example.com"})
+	badMatch, _ := signer.sign(pageCursor{Kind: hostnameSearchPageKind, Timestamp: now, Count: 1, After: 1, Filter: "regex:example.com"})
+	shortPattern, _ := signer.sign(pageCursor{Kind: hostnameSearchPageKind, Timestamp: now, Count: 1, After: 1, Filter: "prefix:ap"})
+	overCap, _ := signer.sign(pageCursor{Kind: hostnameSearchPageKind, Timestamp: now, Count: maxHostnameSearchCount + 1, After: 1, Filter: "prefix:api"})
+	noAfter, _ := signer.sign(pageCursor{Kind: hostnameSearchPageKind, Timestamp: now, Count: 1, Filter: "prefix:api"})
+	badTime, _ := signer.sign(pageCursor{Kind: hostnameSearchPageKind, Timestamp: "foo", Count: 1, After: 1, Filter: "prefix:api"})
+	for name, token := range map[string]string{
+		"empty":         "",
+		"cidr token":    cidrToken,
+		"no match":      noMatch,
+		"bad match":     badMatch,
+		"short pattern": shortPattern,
+		"over cap":      overCap,
+		"no after":      noAfter,
+		"bad time":      badTime,
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, e := newFetchByHostnamePatternPageHandler(nil).Handle(context.Background(), CloudAssetFetchByHostnamePatternPageParameters{PageToken: token})
+			require.NotNil(t, e)
+			assert.IsType(t, InvalidInput{}, e)
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package v1
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/asecurityteam/asset-inventory-api/pkg/domain"
+	"github.com/asecurityteam/asset-inventory-api/pkg/logs"
+)
+
+// maxIPBatchSize is the largest number of lookups accepted in a single batch
+const maxIPBatchSize = 5000
+
+// CloudAssetFetchByIPBatchParameters represents the incoming payload for fetching cloud assets for a batch of IP addresses
+type CloudAssetFetchByIPBatchParameters struct {
+	Lookups []CloudAssetFetchByIPParameters `json:"lookups"`
+}
+
+// CloudAssetsByIPBatch represents the assets found for each lookup of a batch, in the order of the request
+type CloudAssetsByIPBatch struct {
+	Results []CloudAssetsByIPResult `json:"results"`
+}
+
+// CloudAssetsByIPResult represents the assets found for a single lookup of a batch.
+// A lookup without a match has an empty list of assets.
+type CloudAssetsByIPResult struct {
+	IPAddress string              `json:"ipAddress"`
+	Timestamp string              `json:"time"`
+	Assets    []CloudAssetDetails `json:"assets"`
+}
+
+// CloudFetchByIPBatchHandler defines a lambda handler for fetching cloud assets for a batch of IP addresses,
+// each at its own point in time
+type CloudFetchByIPBatchHandler struct {
+	LogFn   domain.LogFn
+	StatFn  domain.StatFn
+	Fetcher domain.CloudAssetByIPBatchFetcher
+}
+
+// Handle handles fetching cloud assets for a batch of IP addresses. Lookups without a match do not fail the batch.
+func (h *CloudFetchByIPBatchHandler) Handle(ctx context.Context, input CloudAssetFetchByIPBatchParameters) (CloudAssetsByIPBatch, error) {
+	logger := h.LogFn(ctx)
+
+	if len(input.Lookups) == 0 || len(input.Lookups) > maxIPBatchSize {
+		e := fmt.Errorf("lookups must hold between 1 and %d entries", maxIPBatchSize)
+		logger.Info(logs.InvalidInput{Reason: e.Error()})
+		return CloudAssetsByIPBatch{}, InvalidInput{Field: "lookups", Cause: e}
+	}
+
+	lookups := make([]domain.IPLookup, len(input.Lookups))
+	for i, lookup := range input.Lookups {
+		ts, e := time.Parse(time.RFC3339Nano, lookup.Timestamp)
+		if e != nil {
+			logger.Info(logs.InvalidInput{Reason: e.Error()})
+			return CloudAssetsByIPBatch{}, InvalidInput{Field: fmt.Sprintf("lookups[%d].time", i), Cause: e}
+		}
+		if net.ParseIP(lookup.IPAddress) == nil {
+			e = fmt.Errorf("invalid IP address %q", lookup.IPAddress)
+			logger.Info(logs.InvalidInput{Reason: e.Error()})
+			return CloudAssetsByIPBatch{}, InvalidInput{Field: fmt.Sprintf("lookups[%d].ipAddress", i), Cause: e}
+		}
+		if e = validateVPCID(lookup.VPCID); e != nil {
+			logger.Info(logs.InvalidInput{Reason: e.Error()})
+			return CloudAssetsByIPBatch{}, InvalidInput{Field: fmt.Sprintf("lookups[%d].vpcId", i), Cause: e}
+		}
+		lookups[i] = domain.IPLookup{
+			IPAddress: lookup.IPAddress,
+			When:      ts,
+			Scope:     domain.IPScope{AccountID: lookup.AccountID, VPCID: lookup.VPCID},
+		}
+	}
+
+	found, e := h.Fetcher.FetchByIPs(ctx, lookups)
+	if e != nil {
+		logger.Error(logs.StorageError{Reason: e.Error()})
+		return CloudAssetsByIPBatch{}, e
+	}
+
+	results := make([]CloudAssetsByIPResult, len(input.Lookups))
+	for i, lookup := range input.Lookups {
+		var assets []domain.CloudAssetDetails
+		if i < len(found) {
+			assets = found[i]
+		}
+		results[i] = CloudAssetsByIPResult{
+			IPAddress: lookup.IPAddress,
+			Timestamp: lookup.Timestamp,
+			Assets:    extractOutput(assets).Assets,
+		}
+	}
+	return CloudAssetsByIPBatch{Results: results}, nil
+}
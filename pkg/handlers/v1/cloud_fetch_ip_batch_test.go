@@ -0,0 +1,103 @@
+package v1
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/asecurityteam/asset-inventory-api/pkg/domain"
+)
+
+func newFetchByIPBatchHandler(fetcher domain.CloudAssetByIPBatchFetcher) *CloudFetchByIPBatchHandler {
+	return &CloudFetchByIPBatchHandler{
+		LogFn:   testLogFn,
+		StatFn:  testStatFn,
+		Fetcher: fetcher,
+	}
+}
+
+func TestFetchByIPBatchInvalidInput(t *testing.T) {
+	now := time.Now().Format(time.RFC3339Nano)
+	tooMany := make([]CloudAssetFetchByIPParameters, maxIPBatchSize+1)
+	for i := range tooMany {
+		tooMany[i] = CloudAssetFetchByIPParameters{IPAddress: "10.0.0.1", Timestamp: now}
+	}
+	tc := []struct {
+		name  string
+		input CloudAssetFetchByIPBatchParameters
+		field string
+	}{
+		{"empty", CloudAssetFetchByIPBatchParameters{}, "lookups"},
+		{"too many", CloudAssetFetchByIPBatchParameters{Lookups: tooMany}, "lookups"},
+		{"invalid timestamp", CloudAssetFetchByIPBatchParameters{Lookups: []CloudAssetFetchByIPParameters{
+			{IPAddress: "10.0.0.1", Timestamp: now},
+			{IPAddress: "10.0.0.1", Timestamp: "foo"},
+		}}, "lookups[1].time"},
+		{"invalid ip", CloudAssetFetchByIPBatchParameters{Lookups: []CloudAssetFetchByIPParameters{
+			{IPAddress: "nope", Timestamp: now},
+		}}, "lookups[0].ipAddress"},
+		{"invalid vpc", CloudAssetFetchByIPBatchParameters{Lookups: []CloudAssetFetchByIPParameters{
+			{IPAddress: "10.0.0.1", Timestamp: now, VPCID: "nope"},
+		}}, "lookups[0].vpcId"},
+	}
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			_, e := newFetchByIPBatchHandler(nil).Handle(context.Background(), tt.input)
+			require.NotNil(t, e)
+			require.IsType(t, InvalidInput{}, e)
+			assert.Equal(t, tt.field, e.(InvalidInput).Field)
+		})
+	}
+}
+
+func TestFetchByIPBatchStorageError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	fetcher := NewMockCloudAssetByIPBatchFetcher(ctrl)
+	fetcher.EXPECT().FetchByIPs(gomock.Any(), gomock.Any()).Return(nil, errors.New(""))
+
+	_, e := newFetchByIPBatchHandler(fetcher).Handle(context.Background(), CloudAssetFetchByIPBatchParameters{
+		Lookups: []CloudAssetFetchByIPParameters{{IPAddress: "10.0.0.1", Timestamp: time.Now().Format(time.RFC3339Nano)}},
+	})
+	require.NotNil(t, e)
+}
+
+func TestFetchByIPBatch(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	first := time.Now().Add(-time.Hour)
+	second := time.Now()
+	input := CloudAssetFetchByIPBatchParameters{Lookups: []CloudAssetFetchByIPParameters{
+		{IPAddress: "10.0.0.1", Timestamp: first.Format(time.RFC3339Nano), AccountID: "123456789012", VPCID: "vpc-1234"},
+		{IPAddress: "9.8.7.6", Timestamp: second.Format(time.RFC3339Nano)},
+	}}
+	firstTS, _ := time.Parse(time.RFC3339Nano, input.Lookups[0].Timestamp)
+	secondTS, _ := time.Parse(time.RFC3339Nano, input.Lookups[1].Timestamp)
+
+	fetcher := NewMockCloudAssetByIPBatchFetcher(ctrl)
+	fetcher.EXPECT().FetchByIPs(gomock.Any(), []domain.IPLookup{
+		{IPAddress: "10.0.0.1", When: firstTS, Scope: domain.IPScope{AccountID: "123456789012", VPCID: "vpc-1234"}},
+		{IPAddress: "9.8.7.6", When: secondTS},
+	}).Return([][]domain.CloudAssetDetails{
+		{{ARN: "arn", PrivateIPAddresses: []string{"10.0.0.1"}}},
+		{},
+	}, nil)
+
+	res, e := newFetchByIPBatchHandler(fetcher).Handle(context.Background(), input)
+	require.Nil(t, e)
+	require.Len(t, res.Results, 2)
+	assert.Equal(t, "10.0.0.1", res.Results[0].IPAddress)
+	assert.Equal(t, input.Lookups[0].Timestamp, res.Results[0].Timestamp)
+	require.Len(t, res.Results[0].Assets, 1)
+	assert.Equal(t, "arn", res.Results[0].Assets[0].ARN)
+	assert.Equal(t, "9.8.7.6", res.Results[1].IPAddress)
+	assert.NotNil(t, res.Results[1].Assets)
+	assert.Empty(t, res.Results[1].Assets)
+}
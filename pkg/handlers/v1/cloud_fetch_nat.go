@@ -0,0 +1,117 @@
+package v1
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/asecurityteam/asset-inventory-api/pkg/domain"
+	"github.com/asecurityteam/asset-inventory-api/pkg/logs"
+)
+
+// natPageKind identifies page tokens issued by the listing of assets behind a NAT gateway
+const natPageKind = "nat"
+
+// CloudAssetFetchBehindNATGatewayParameters represents the incoming payload for fetching the cloud assets behind the
+// NAT gateway with a public IP address
+type CloudAssetFetchBehindNATGatewayParameters struct {
+	IPAddress string `json:"ipAddress"`
+	Timestamp string `json:"time"`
+	Count     uint   `json:"count"`
+}
+
+// CloudAssetFetchBehindNATGatewayPageParameters represents the request for subsequent pages of cloud assets behind a
+// NAT gateway
+type CloudAssetFetchBehindNATGatewayPageParameters struct {
+	PageToken string `json:"pageToken"`
+}
+
+// CloudFetchBehindNATGatewayHandler defines a lambda handler for fetching the cloud assets in the subnets served by the
+// NAT gateway with a public IP address, the candidate sources of traffic seen from that address
+type CloudFetchBehindNATGatewayHandler struct {
+	LogFn      domain.LogFn
+	StatFn     domain.StatFn
+	Fetcher    domain.CloudAssetBehindNATGatewayFetcher
+	PageTokens *PageTokenSigner
+}
+
+// Handle handles fetching the first page of cloud assets behind a NAT gateway
+func (h *CloudFetchBehindNATGatewayHandler) Handle(ctx context.Context, input CloudAssetFetchBehindNATGatewayParameters) (PagedCloudAssets, error) {
+	logger := h.LogFn(ctx)
+
+	ts, e := time.Parse(time.RFC3339Nano, input.Timestamp)
+	if e != nil {
+		logger.Info(logs.InvalidInput{Reason: e.Error()})
+		return PagedCloudAssets{}, InvalidInput{Field: "time", Cause: e}
+	}
+
+	if input.Count == 0 {
+		e = errors.New("missing or malformed required parameter count")
+		logger.Info(logs.InvalidInput{Reason: e.Error()})
+		return PagedCloudAssets{}, InvalidInput{Field: "count", Cause: e}
+	}
+
+	ip := net.ParseIP(input.IPAddress)
+	if ip == nil {
+		e = fmt.Errorf("invalid IPv4 or IPv6 address %q", input.IPAddress)
+		logger.Info(logs.InvalidInput{Reason: e.Error()})
+		return PagedCloudAssets{}, InvalidInput{Field: "ipAddress", Cause: e}
+	}
+
+	return fetchBehindNATGatewayPage(ctx, logger, h.Fetcher, h.PageTokens, pageCursor{
+		Kind:      natPageKind,
+		Timestamp: input.Timestamp,
+		Count:     input.Count,
+		Filter:    ip.String(),
+	}, ts)
+}
+
+// CloudFetchBehindNATGatewayPageHandler defines a lambda handler for fetching subsequent pages of cloud assets behind a
+// NAT gateway
+type CloudFetchBehindNATGatewayPageHandler struct {
+	LogFn      domain.LogFn
+	StatFn     domain.StatFn
+	Fetcher    domain.CloudAssetBehindNATGatewayFetcher
+	PageTokens *PageTokenSigner
+}
+
+// Handle handles fetching subsequent pages of cloud assets behind a NAT gateway
+func (h *CloudFetchBehindNATGatewayPageHandler) Handle(ctx context.Context, input CloudAssetFetchBehindNATGatewayPageParameters) (PagedCloudAssets, error) {
+	logger := h.LogFn(ctx)
+	//generic error to report to caller to avoid exposing the internal token structure NB, the specific error is still logged
+	tokenError := errors.New("malformed pageToken")
+
+	cursor, e := verifyPageToken(logger, h.PageTokens, input.PageToken, natPageKind)
+	if e != nil {
+		return PagedCloudAssets{}, e
+	}
+	ts, e := time.Parse(time.RFC3339Nano, cursor.Timestamp)
+	if e != nil {
+		logger.Info(logs.InvalidInput{Reason: e.Error()})
+		return PagedCloudAssets{}, InvalidInput{Field: "pageToken", Cause: tokenError}
+	}
+
+	if cursor.Count == 0 || cursor.After == 0 {
+		e = errors.New("missing or malformed required parameter count or after")
+		logger.Info(logs.InvalidInput{Reason: e.Error()})
+		return PagedCloudAssets{}, InvalidInput{Field: "pageToken", Cause: tokenError}
+	}
+
+	if net.ParseIP(cursor.Filter) == nil {
+		e = fmt.Errorf("invalid IPv4 or IPv6 address %q", cursor.Filter)
+		logger.Info(logs.InvalidInput{Reason: e.Error()})
+		return PagedCloudAssets{}, InvalidInput{Field: "pageToken", Cause: tokenError}
+	}
+
+	return fetchBehindNATGatewayPage(ctx, logger, h.Fetcher, h.PageTokens, cursor, ts)
+}
+
+// fetchBehindNATGatewayPage fetches the page of the listing at the cursor and issues the token for the following page
+func fetchBehindNATGatewayPage(ctx context.Context, logger domain.Logger, fetcher domain.CloudAssetBehindNATGatewayFetcher,
+	signer *PageTokenSigner, cursor pageCursor, ts time.Time) (PagedCloudAssets, error) {
+	return fetchAssetsPage(logger, signer, cursor, func(after int64) ([]domain.CloudAssetDetails, int64, error) {
+		return fetcher.FetchBehindNATGateway(ctx, ts, cursor.Filter, cursor.Count, after)
+	})
+}
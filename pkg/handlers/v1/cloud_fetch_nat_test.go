@@ -0,0 +1,151 @@
+package v1
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/asecurityteam/asset-inventory-api/pkg/domain"
+)
+
+func newFetchBehindNATGatewayHandler(fetcher domain.CloudAssetBehindNATGatewayFetcher) *CloudFetchBehindNATGatewayHandler {
+	return &CloudFetchBehindNATGatewayHandler{
+		LogFn:      testLogFn,
+		StatFn:     testStatFn,
+		Fetcher:    fetcher,
+		PageTokens: testPageTokenSigner(),
+	}
+}
+
+func newFetchBehindNATGatewayPageHandler(fetcher domain.CloudAssetBehindNATGatewayFetcher) *CloudFetchBehindNATGatewayPageHandler {
+	return &CloudFetchBehindNATGatewayPageHandler{
+		LogFn:      testLogFn,
+		StatFn:     testStatFn,
+		Fetcher:    fetcher,
+		PageTokens: testPageTokenSigner(),
+	}
+}
+
+func validFetchBehindNATGatewayInput() CloudAssetFetchBehindNATGatewayParameters {
+	return CloudAssetFetchBehindNATGatewayParameters{
+		IPAddress: "34.0.0.1",
+		Timestamp: time.Now().Format(time.RFC3339Nano),
+		Count:     1,
+	}
+}
+
+func TestFetchBehindNATGatewayInvalidInput(t *testing.T) {
+	now := time.Now().Format(time.RFC3339Nano)
+	tc := []struct {
+		name  string
+		input CloudAssetFetchBehindNATGatewayParameters
+	}{
+		{"invalid timestamp", CloudAssetFetchBehindNATGatewayParameters{Timestamp: "foo", IPAddress: "34.0.0.1", Count: 1}},
+		{"no count", CloudAssetFetchBehindNATGatewayParameters{Timestamp: now, IPAddress: "34.0.0.1"}},
+		{"no address", CloudAssetFetchBehindNATGatewayParameters{Timestamp: now, Count: 1}},
+		{"invalid address", CloudAssetFetchBehindNATGatewayParameters{Timestamp: now, IPAddress: "34.0.0.256", Count: 1}},
+	}
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			_, e := newFetchBehindNATGatewayHandler(nil).Handle(context.Background(), tt.input)
+			require.NotNil(t, e)
+			assert.IsType(t, InvalidInput{}, e)
+		})
+	}
+}
+
+func TestFetchBehindNATGatewayStorageError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	fetcher := NewMockCloudAssetBehindNATGatewayFetcher(ctrl)
+	input := validFetchBehindNATGatewayInput()
+	ts, _ := time.Parse(time.RFC3339Nano, input.Timestamp)
+	fetcher.EXPECT().FetchBehindNATGateway(gomock.Any(), ts, input.IPAddress, input.Count, int64(0)).Return(nil, int64(0), errors.New(""))
+
+	_, e := newFetchBehindNATGatewayHandler(fetcher).Handle(context.Background(), input)
+	require.NotNil(t, e)
+}
+
+func TestFetchBehindNATGatewayNotFound(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	fetcher := NewMockCloudAssetBehindNATGatewayFetcher(ctrl)
+	input := validFetchBehindNATGatewayInput()
+	ts, _ := time.Parse(time.RFC3339Nano, input.Timestamp)
+	fetcher.EXPECT().FetchBehindNATGateway(gomock.Any(), ts, input.IPAddress, input.Count, int64(0)).Return([]domain.CloudAssetDetails{}, int64(0), nil)
+
+	_, e := newFetchBehindNATGatewayHandler(fetcher).Handle(context.Background(), input)
+	require.NotNil(t, e)
+	assert.IsType(t, NotFound{}, e)
+}
+
+func TestFetchBehindNATGatewayPaging(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	fetcher := NewMockCloudAssetBehindNATGatewayFetcher(ctrl)
+	input := validFetchBehindNATGatewayInput()
+	ts, _ := time.Parse(time.RFC3339Nano, input.Timestamp)
+	gomock.InOrder(
+		fetcher.EXPECT().FetchBehindNATGateway(gomock.Any(), ts, input.IPAddress, input.Count, int64(0)).Return([]domain.CloudAssetDetails{{ARN: "i-1"}}, int64(7), nil),
+		fetcher.EXPECT().FetchBehindNATGateway(gomock.Any(), ts, input.IPAddress, input.Count, int64(7)).Return([]domain.CloudAssetDetails{{ARN: "i-2"}}, int64(9), nil),
+	)
+
+	first, e := newFetchBehindNATGatewayHandler(fetcher).Handle(context.Background(), input)
+	require.Nil(t, e)
+	require.NotEmpty(t, first.NextPageToken)
+	assert.Equal(t, "i-1", first.Assets[0].ARN)
+
+	second, e := newFetchBehindNATGatewayPageHandler(fetcher).Handle(context.Background(),
+		CloudAssetFetchBehindNATGatewayPageParameters{PageToken: first.NextPageToken})
+	require.Nil(t, e)
+	assert.Equal(t, "i-2", second.Assets[0].ARN)
+}
+
+func TestFetchBehindNATGatewayPageInvalidToken(t *testing.T) {
+	signer := testPageTokenSigner()
+	cidrToken, _ := signer.sign(pageCursor{Kind: cidrPageKind, Timestamp: time.Now().Format(time.RFC3339Nano), Count: 1, After: 1, Filter: "10.0.0.0/8"})
+	badIP, _ := signer.sign(pageCursor{Kind: natPageKind, Timestamp: time.Now().Format(time.RFC3339Nano), Count: 1, After: 1, Filter: "nope"})
+	noAfter, _ := signer.sign(pageCursor{Kind: natPageKind, Timestamp: time.Now().Format(time.RFC3339Nano), Count: 1, Filter: "34.0.0.1"})
+	badTime, _ := signer.sign(pageCursor{Kind: natPageKind, Timestamp: "foo", Count: 1, After: 1, Filter: "34.0.0.1"})
+	for name, token := range map[string]string{
+		"empty":      "",
+		"cidr token": cidrToken,
+		"bad ip":     badIP,
+		"no after":   noAfter,
+		"bad time":   badTime,
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, e := newFetchBehindNATGatewayPageHandler(nil).Handle(context.Background(), CloudAssetFetchBehindNATGatewayPageParameters{PageToken: token})
+			require.NotNil(t, e)
+			assert.IsType(t, InvalidInput{}, e)
+		})
+	}
+}
+
+func TestFetchBehindNATGatewayPageTokensDisabled(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	fetcher := NewMockCloudAssetBehindNATGatewayFetcher(ctrl)
+	fetcher.EXPECT().FetchBehindNATGateway(gomock.Any(), gomock.Any(), "34.0.0.1", uint(1), int64(0)).
+		Return([]domain.CloudAssetDetails{{ARN: "i-1"}}, int64(1), nil)
+	handler := newFetchBehindNATGatewayHandler(fetcher)
+	handler.PageTokens = &PageTokenSigner{TTL: time.Hour}
+
+	// the first page is fetched, but no token is issued for the following one
+	_, e := handler.Handle(context.Background(), validFetchBehindNATGatewayInput())
+	assert.Equal(t, errPageTokensDisabled, e)
+
+	pageHandler := newFetchBehindNATGatewayPageHandler(nil)
+	pageHandler.PageTokens = &PageTokenSigner{TTL: time.Hour}
+	_, e = pageHandler.Handle(context.Background(), CloudAssetFetchBehindNATGatewayPageParameters{PageToken: "token"})
+	assert.Equal(t, errPageTokensDisabled, e)
+}
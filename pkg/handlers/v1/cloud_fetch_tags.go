@@ -0,0 +1,157 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/asecurityteam/asset-inventory-api/pkg/domain"
+	"github.com/asecurityteam/asset-inventory-api/pkg/logs"
+)
+
+// tagsPageKind identifies page tokens issued by the tag search
+const tagsPageKind = "tags"
+
+// TagPredicate matches assets by one of their tags. Match is one of exact, prefix or exists and defaults to exact.
+type TagPredicate struct {
+	Key   string `json:"key"`
+	Value string `json:"value,omitempty"`
+	Match string `json:"match,omitempty"`
+}
+
+// CloudAssetFetchByTagsParameters represents the incoming payload for searching cloud assets by tags
+type CloudAssetFetchByTagsParameters struct {
+	Tags      []TagPredicate `json:"tags"`
+	Timestamp string         `json:"time"`
+	Count     uint           `json:"count"`
+}
+
+// CloudAssetFetchByTagsPageParameters represents the request for subsequent pages of a search by tags
+type CloudAssetFetchByTagsPageParameters struct {
+	PageToken string `json:"pageToken"`
+}
+
+// CloudFetchByTagsHandler defines a lambda handler for searching cloud assets whose tags match all of the predicates
+type CloudFetchByTagsHandler struct {
+	LogFn      domain.LogFn
+	StatFn     domain.StatFn
+	Fetcher    domain.CloudAssetByTagsFetcher
+	PageTokens *PageTokenSigner
+}
+
+// Handle handles fetching the first page of cloud assets matching the tags
+func (h *CloudFetchByTagsHandler) Handle(ctx context.Context, input CloudAssetFetchByTagsParameters) (PagedCloudAssets, error) {
+	logger := h.LogFn(ctx)
+
+	ts, e := time.Parse(time.RFC3339Nano, input.Timestamp)
+	if e != nil {
+		logger.Info(logs.InvalidInput{Reason: e.Error()})
+		return PagedCloudAssets{}, InvalidInput{Field: "time", Cause: e}
+	}
+
+	if input.Count == 0 {
+		e = errors.New("missing or malformed required parameter count")
+		logger.Info(logs.InvalidInput{Reason: e.Error()})
+		return PagedCloudAssets{}, InvalidInput{Field: "count", Cause: e}
+	}
+
+	predicates, field, e := normalizeTagPredicates(input.Tags)
+	if e != nil {
+		logger.Info(logs.InvalidInput{Reason: e.Error()})
+		return PagedCloudAssets{}, InvalidInput{Field: field, Cause: e}
+	}
+	filter, e := json.Marshal(predicates)
+	if e != nil {
+		logger.Error(logs.StorageError{Reason: e.Error()})
+		return PagedCloudAssets{}, e
+	}
+
+	return fetchTagsPage(ctx, logger, h.Fetcher, h.PageTokens, pageCursor{
+		Kind:      tagsPageKind,
+		Timestamp: input.Timestamp,
+		Count:     input.Count,
+		Filter:    string(filter),
+	}, ts, predicates)
+}
+
+// CloudFetchByTagsPageHandler defines a lambda handler for fetching subsequent pages of a search by tags
+type CloudFetchByTagsPageHandler struct {
+	LogFn      domain.LogFn
+	StatFn     domain.StatFn
+	Fetcher    domain.CloudAssetByTagsFetcher
+	PageTokens *PageTokenSigner
+}
+
+// Handle handles fetching subsequent pages of cloud assets matching the tags
+func (h *CloudFetchByTagsPageHandler) Handle(ctx context.Context, input CloudAssetFetchByTagsPageParameters) (PagedCloudAssets, error) {
+	logger := h.LogFn(ctx)
+	//generic error to report to caller to avoid exposing the internal token structure NB, the specific error is still logged
+	tokenError := errors.New("malformed pageToken")
+
+	cursor, e := verifyPageToken(logger, h.PageTokens, input.PageToken, tagsPageKind)
+	if e != nil {
+		return PagedCloudAssets{}, e
+	}
+	ts, e := time.Parse(time.RFC3339Nano, cursor.Timestamp)
+	if e != nil {
+		logger.Info(logs.InvalidInput{Reason: e.Error()})
+		return PagedCloudAssets{}, InvalidInput{Field: "pageToken", Cause: tokenError}
+	}
+
+	if cursor.Count == 0 || cursor.After == 0 {
+		e = errors.New("missing or malformed required parameter count or after")
+		logger.Info(logs.InvalidInput{Reason: e.Error()})
+		return PagedCloudAssets{}, InvalidInput{Field: "pageToken", Cause: tokenError}
+	}
+
+	var predicates []TagPredicate
+	if e = json.Unmarshal([]byte(cursor.Filter), &predicates); e == nil {
+		predicates, _, e = normalizeTagPredicates(predicates)
+	}
+	if e != nil {
+		logger.Info(logs.InvalidInput{Reason: e.Error()})
+		return PagedCloudAssets{}, InvalidInput{Field: "pageToken", Cause: tokenError}
+	}
+
+	return fetchTagsPage(ctx, logger, h.Fetcher, h.PageTokens, cursor, ts, predicates)
+}
+
+// normalizeTagPredicates validates the predicates and fills in the default match, returning the offending field on error
+func normalizeTagPredicates(predicates []TagPredicate) ([]TagPredicate, string, error) {
+	if len(predicates) == 0 {
+		return nil, "tags", errors.New("at least one tag predicate is required")
+	}
+	normalized := make([]TagPredicate, 0, len(predicates))
+	for i, predicate := range predicates {
+		if predicate.Key == "" {
+			return nil, fmt.Sprintf("tags[%d].key", i), errors.New("missing tag key")
+		}
+		match := strings.ToLower(predicate.Match)
+		switch match {
+		case "":
+			match = domain.TagMatchExact
+		case domain.TagMatchExact, domain.TagMatchPrefix:
+		case domain.TagMatchExists:
+			predicate.Value = ""
+		default:
+			return nil, fmt.Sprintf("tags[%d].match", i), fmt.Errorf("unknown tag match %s", predicate.Match)
+		}
+		normalized = append(normalized, TagPredicate{Key: predicate.Key, Value: predicate.Value, Match: match})
+	}
+	return normalized, "", nil
+}
+
+// fetchTagsPage fetches the page of the tag search at the cursor and issues the token for the following page
+func fetchTagsPage(ctx context.Context, logger domain.Logger, fetcher domain.CloudAssetByTagsFetcher,
+	signer *PageTokenSigner, cursor pageCursor, ts time.Time, predicates []TagPredicate) (PagedCloudAssets, error) {
+	domainPredicates := make([]domain.TagPredicate, 0, len(predicates))
+	for _, predicate := range predicates {
+		domainPredicates = append(domainPredicates, domain.TagPredicate(predicate))
+	}
+	return fetchAssetsPage(logger, signer, cursor, func(after int64) ([]domain.CloudAssetDetails, int64, error) {
+		return fetcher.FetchByTags(ctx, ts, domainPredicates, cursor.Count, after)
+	})
+}
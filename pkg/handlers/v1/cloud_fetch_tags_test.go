@@ -0,0 +1,147 @@
+package v1
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/asecurityteam/asset-inventory-api/pkg/domain"
+)
+
+func newFetchByTagsHandler(fetcher domain.CloudAssetByTagsFetcher) *CloudFetchByTagsHandler {
+	return &CloudFetchByTagsHandler{
+		LogFn:      testLogFn,
+		StatFn:     testStatFn,
+		Fetcher:    fetcher,
+		PageTokens: testPageTokenSigner(),
+	}
+}
+
+func newFetchByTagsPageHandler(fetcher domain.CloudAssetByTagsFetcher) *CloudFetchByTagsPageHandler {
+	return &CloudFetchByTagsPageHandler{
+		LogFn:      testLogFn,
+		StatFn:     testStatFn,
+		Fetcher:    fetcher,
+		PageTokens: testPageTokenSigner(),
+	}
+}
+
+func validFetchByTagsInput() CloudAssetFetchByTagsParameters {
+	return CloudAssetFetchByTagsParameters{
+		Tags: []TagPredicate{
+			{Key: "env", Value: "prod"},
+			{Key: "service_name", Value: "foo", Match: "PREFIX"},
+			{Key: "owner", Value: "ignored", Match: "exists"},
+		},
+		Timestamp: time.Now().Format(time.RFC3339Nano),
+		Count:     1,
+	}
+}
+
+var validFetchByTagsPredicates = []domain.TagPredicate{
+	{Key: "env", Value: "prod", Match: domain.TagMatchExact},
+	{Key: "service_name", Value: "foo", Match: domain.TagMatchPrefix},
+	{Key: "owner", Match: domain.TagMatchExists},
+}
+
+func TestFetchByTagsInvalidInput(t *testing.T) {
+	now := time.Now().Format(time.RFC3339Nano)
+	env := []TagPredicate{{Key: "env", Value: "prod"}}
+	tc := []struct {
+		name  string
+		input CloudAssetFetchByTagsParameters
+		field string
+	}{
+		{"invalid timestamp", CloudAssetFetchByTagsParameters{Timestamp: "foo", Tags: env, Count: 1}, "time"},
+		{"no count", CloudAssetFetchByTagsParameters{Timestamp: now, Tags: env}, "count"},
+		{"no tags", CloudAssetFetchByTagsParameters{Timestamp: now, Count: 1}, "tags"},
+		{"no key", CloudAssetFetchByTagsParameters{Timestamp: now, Tags: []TagPredicate{{Value: "prod"}}, Count: 1}, "tags[0].key"},
+		{"unknown match", CloudAssetFetchByTagsParameters{Timestamp: now, Tags: []TagPredicate{{Key: "env"}, {Key: "env", Match: "regex"}}, Count: 1}, "tags[1].match"},
+	}
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			_, e := newFetchByTagsHandler(nil).Handle(context.Background(), tt.input)
+			require.NotNil(t, e)
+			require.IsType(t, InvalidInput{}, e)
+			assert.Equal(t, tt.field, e.(InvalidInput).Field)
+		})
+	}
+}
+
+func TestFetchByTagsStorageError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	fetcher := NewMockCloudAssetByTagsFetcher(ctrl)
+	input := validFetchByTagsInput()
+	ts, _ := time.Parse(time.RFC3339Nano, input.Timestamp)
+	fetcher.EXPECT().FetchByTags(gomock.Any(), ts, validFetchByTagsPredicates, input.Count, int64(0)).Return(nil, int64(0), errors.New(""))
+
+	_, e := newFetchByTagsHandler(fetcher).Handle(context.Background(), input)
+	require.NotNil(t, e)
+}
+
+func TestFetchByTagsNotFound(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	fetcher := NewMockCloudAssetByTagsFetcher(ctrl)
+	input := validFetchByTagsInput()
+	ts, _ := time.Parse(time.RFC3339Nano, input.Timestamp)
+	fetcher.EXPECT().FetchByTags(gomock.Any(), ts, validFetchByTagsPredicates, input.Count, int64(0)).Return([]domain.CloudAssetDetails{}, int64(0), nil)
+
+	_, e := newFetchByTagsHandler(fetcher).Handle(context.Background(), input)
+	require.NotNil(t, e)
+	assert.IsType(t, NotFound{}, e)
+}
+
+func TestFetchByTagsPaging(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	fetcher := NewMockCloudAssetByTagsFetcher(ctrl)
+	input := validFetchByTagsInput()
+	ts, _ := time.Parse(time.RFC3339Nano, input.Timestamp)
+	gomock.InOrder(
+		fetcher.EXPECT().FetchByTags(gomock.Any(), ts, validFetchByTagsPredicates, input.Count, int64(0)).Return([]domain.CloudAssetDetails{{ARN: "arn1"}}, int64(7), nil),
+		fetcher.EXPECT().FetchByTags(gomock.Any(), ts, validFetchByTagsPredicates, input.Count, int64(7)).Return([]domain.CloudAssetDetails{{ARN: "arn2", Tags: map[string]string{"env": "prod"}}}, int64(9), nil),
+	)
+
+	first, e := newFetchByTagsHandler(fetcher).Handle(context.Background(), input)
+	require.Nil(t, e)
+	require.NotEmpty(t, first.NextPageToken)
+
+	second, e := newFetchByTagsPageHandler(fetcher).Handle(context.Background(), CloudAssetFetchByTagsPageParameters{PageToken: first.NextPageToken})
+	require.Nil(t, e)
+	assert.Equal(t, "arn2", second.Assets[0].ARN)
+	assert.Equal(t, map[string]string{"env": "prod"}, second.Assets[0].Tags)
+}
+
+func TestFetchByTagsPageInvalidToken(t *testing.T) {
+	signer := testPageTokenSigner()
+	now := time.Now().Format(time.RFC3339Nano)
+	cidrToken, _ := signer.sign(pageCursor{Kind: cidrPageKind, Timestamp: now, Count: 1, After: 1, Filter: "10.0.0.0/8"})
+	badFilter, _ := signer.sign(pageCursor{Kind: tagsPageKind, Timestamp: now, Count: 1, After: 1, Filter: "env=prod"})
+	noTags, _ := signer.sign(pageCursor{Kind: tagsPageKind, Timestamp: now, Count: 1, After: 1, Filter: "[]"})
+	noAfter, _ := signer.sign(pageCursor{Kind: tagsPageKind, Timestamp: now, Count: 1, Filter: `[{"key":"env"}]`})
+	badTime, _ := signer.sign(pageCursor{Kind: tagsPageKind, Timestamp: "foo", Count: 1, After: 1, Filter: `[{"key":"env"}]`})
+	for name, token := range map[string]string{
+		"empty":      "",
+		"cidr token": cidrToken,
+		"bad filter": badFilter,
+		"no tags":    noTags,
+		"no after":   noAfter,
+		"bad time":   badTime,
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, e := newFetchByTagsPageHandler(nil).Handle(context.Background(), CloudAssetFetchByTagsPageParameters{PageToken: token})
+			require.NotNil(t, e)
+			assert.IsType(t, InvalidInput{}, e)
+		})
+	}
+}
@@ -2,9 +2,7 @@ package v1
 
 import (
 	"context"
-	"encoding/base32"
 	"errors"
-	"reflect"
 	"testing"
 	"time"
 
@@ -42,17 +40,19 @@ func newFetchByResourceIDHandler(fetcher domain.CloudAssetByResourceIDFetcher) *
 
 func newCloudFetchAllAssetsByTimeHandler(fetcher domain.CloudAllAssetsByTimeFetcher) *CloudFetchAllAssetsByTimeHandler {
 	return &CloudFetchAllAssetsByTimeHandler{
-		LogFn:   testLogFn,
-		StatFn:  testStatFn,
-		Fetcher: fetcher,
+		LogFn:      testLogFn,
+		StatFn:     testStatFn,
+		Fetcher:    fetcher,
+		PageTokens: testPageTokenSigner(),
 	}
 }
 
 func newCloudFetchAllAssetsByTimePageHandler(fetcher domain.CloudAllAssetsByTimeFetcher) *CloudFetchAllAssetsByTimePageHandler {
 	return &CloudFetchAllAssetsByTimePageHandler{
-		LogFn:   testLogFn,
-		StatFn:  testStatFn,
-		Fetcher: fetcher,
+		LogFn:      testLogFn,
+		StatFn:     testStatFn,
+		Fetcher:    fetcher,
+		PageTokens: testPageTokenSigner(),
 	}
 }
 
@@ -79,15 +79,29 @@ func validFetchByResourceIDInput() CloudAssetFetchByResourceIDParameters {
 
 func validFetchAllByTimestampInput() CloudAssetFetchAllByTimestampParameters {
 	var count uint = 100
-	var offset uint
 	return CloudAssetFetchAllByTimestampParameters{
 		Timestamp: time.Now().Format(time.RFC3339Nano),
 		Count:     count,
-		Offset:    offset,
 		Type:      awsEC2,
 	}
 }
 
+// validBulkPageCursor returns the cursor for the second page of the listing for input
+func validBulkPageCursor(input CloudAssetFetchAllByTimestampParameters) pageCursor {
+	return pageCursor{
+		Kind:      bulkPageKind,
+		Timestamp: input.Timestamp,
+		Count:     input.Count,
+		After:     42,
+		Filter:    input.Type,
+	}
+}
+
+func validBulkPageToken(input CloudAssetFetchAllByTimestampParameters) string {
+	token, _ := testPageTokenSigner().sign(validBulkPageCursor(input))
+	return token
+}
+
 func TestCloudFetchAllAssetsByTimeInvalidDate(t *testing.T) {
 	input := validFetchAllByTimestampInput()
 	input.Timestamp = "not a valid date"
@@ -116,7 +130,7 @@ func TestCloudFetchAllAssetsByTimeStorageError(t *testing.T) {
 	fetcher := NewMockCloudAllAssetsByTimeFetcher(ctrl)
 	input := validFetchAllByTimestampInput()
 	ts, _ := time.Parse(time.RFC3339Nano, input.Timestamp)
-	fetcher.EXPECT().FetchAll(gomock.Any(), ts, input.Count, input.Offset, input.Type).Return([]domain.CloudAssetDetails{}, errors.New(""))
+	fetcher.EXPECT().FetchAll(gomock.Any(), ts, input.Count, int64(0), input.Type).Return(nil, int64(0), errors.New(""))
 
 	_, e := newCloudFetchAllAssetsByTimeHandler(fetcher).Handle(context.Background(), input)
 	require.NotNil(t, e)
@@ -128,41 +142,110 @@ func TestCloudFetchAllAssetsByTimeNoResults(t *testing.T) {
 	fetcher := NewMockCloudAllAssetsByTimeFetcher(ctrl)
 	input := validFetchAllByTimestampInput()
 	ts, _ := time.Parse(time.RFC3339Nano, input.Timestamp)
-	fetcher.EXPECT().FetchAll(gomock.Any(), ts, input.Count, input.Offset, input.Type).Return([]domain.CloudAssetDetails{}, nil)
+	fetcher.EXPECT().FetchAll(gomock.Any(), ts, input.Count, int64(0), input.Type).Return([]domain.CloudAssetDetails{}, int64(0), nil)
 
 	_, e := newCloudFetchAllAssetsByTimeHandler(fetcher).Handle(context.Background(), input)
 	require.NotNil(t, e)
 }
 
-func TestCloudFetchAllAssetsByTimePageInvalidDate(t *testing.T) {
+func TestCloudFetchAllAssetsByTimeFullPage(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	fetcher := NewMockCloudAllAssetsByTimeFetcher(ctrl)
 	input := validFetchAllByTimestampInput()
-	input.Timestamp = "not a valid date"
-	pageToken, _ := input.toNextPageToken()
-	_, err := newCloudFetchAllAssetsByTimePageHandler(nil).Handle(
-		context.Background(),
-		CloudAssetFetchAllByTimeStampPageParameters{PageToken: pageToken},
-	)
-	require.NotNil(t, err)
+	input.Count = 1
+	ts, _ := time.Parse(time.RFC3339Nano, input.Timestamp)
+	fetcher.EXPECT().FetchAll(gomock.Any(), ts, input.Count, int64(0), input.Type).Return([]domain.CloudAssetDetails{{ARN: "arn"}}, int64(42), nil)
+
+	res, e := newCloudFetchAllAssetsByTimeHandler(fetcher).Handle(context.Background(), input)
+	require.Nil(t, e)
+	require.Len(t, res.Assets, 1)
+	cursor, e := testPageTokenSigner().verify(res.NextPageToken, bulkPageKind)
+	require.Nil(t, e)
+	assert.Equal(t, int64(42), cursor.After)
+	assert.Equal(t, input.Count, cursor.Count)
+	assert.Equal(t, input.Type, cursor.Filter)
+	assert.Equal(t, input.Timestamp, cursor.Timestamp)
 }
 
-func TestCloudFetchAllAssetsByTimePageInvalidCount(t *testing.T) {
+func TestCloudFetchAllAssetsByTimeLastPage(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	fetcher := NewMockCloudAllAssetsByTimeFetcher(ctrl)
 	input := validFetchAllByTimestampInput()
-	input.Count = 0
-	pageToken, _ := input.toNextPageToken()
-	_, err := newCloudFetchAllAssetsByTimePageHandler(nil).Handle(
-		context.Background(),
-		CloudAssetFetchAllByTimeStampPageParameters{PageToken: pageToken})
-	require.NotNil(t, err)
+	ts, _ := time.Parse(time.RFC3339Nano, input.Timestamp)
+	fetcher.EXPECT().FetchAll(gomock.Any(), ts, input.Count, int64(0), input.Type).Return([]domain.CloudAssetDetails{{ARN: "arn"}}, int64(42), nil)
+
+	res, e := newCloudFetchAllAssetsByTimeHandler(fetcher).Handle(context.Background(), input)
+	require.Nil(t, e)
+	require.Len(t, res.Assets, 1)
+	assert.Empty(t, res.NextPageToken)
 }
 
-func TestCloudFetchAllAssetsByTimePageInvalidType(t *testing.T) {
+func TestCloudFetchAllAssetsByTimePageInvalidToken(t *testing.T) {
 	input := validFetchAllByTimestampInput()
-	input.Type = "Very wrong type"
-	pageToken, _ := input.toNextPageToken()
+	validToken := validBulkPageToken(input)
+	otherSigner := testPageTokenSigner()
+	otherSigner.Secret = []byte("some other secret")
+	forged, _ := otherSigner.sign(validBulkPageCursor(input))
+	invalidDate := validBulkPageCursor(input)
+	invalidDate.Timestamp = "not a valid date"
+	invalidCount := validBulkPageCursor(input)
+	invalidCount.Count = 0
+	invalidAfter := validBulkPageCursor(input)
+	invalidAfter.After = 0
+	invalidType := validBulkPageCursor(input)
+	invalidType.Filter = "Very wrong type"
+	otherKind := validBulkPageCursor(input)
+	otherKind.Kind = "other"
+
+	tc := []struct {
+		name  string
+		token string
+	}{
+		{"Empty", ""},
+		{"Garbage", "this is not a token $@&%#@&*^*&%(*"},
+		{"Tampered", validToken[1:]},
+		{"Forged", forged},
+	}
+	for name, cursor := range map[string]pageCursor{
+		"InvalidDate":  invalidDate,
+		"InvalidCount": invalidCount,
+		"InvalidAfter": invalidAfter,
+		"InvalidType":  invalidType,
+		"OtherKind":    otherKind,
+	} {
+		token, _ := testPageTokenSigner().sign(cursor)
+		tc = append(tc, struct {
+			name  string
+			token string
+		}{name, token})
+	}
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newCloudFetchAllAssetsByTimePageHandler(nil).Handle(
+				context.Background(),
+				CloudAssetFetchAllByTimeStampPageParameters{PageToken: tt.token},
+			)
+			require.NotNil(t, err)
+			assert.IsType(t, InvalidInput{}, err)
+		})
+	}
+}
+
+func TestCloudFetchAllAssetsByTimePageExpiredToken(t *testing.T) {
+	input := validFetchAllByTimestampInput()
+	signer := testPageTokenSigner()
+	signer.now = func() time.Time { return time.Now().Add(-48 * time.Hour) }
+	token, _ := signer.sign(validBulkPageCursor(input))
 	_, err := newCloudFetchAllAssetsByTimePageHandler(nil).Handle(
 		context.Background(),
-		CloudAssetFetchAllByTimeStampPageParameters{PageToken: pageToken})
+		CloudAssetFetchAllByTimeStampPageParameters{PageToken: token},
+	)
 	require.NotNil(t, err)
+	assert.IsType(t, InvalidInput{}, err)
 }
 
 func TestCloudFetchAllAssetsByTimePageStorageError(t *testing.T) {
@@ -171,27 +254,46 @@ func TestCloudFetchAllAssetsByTimePageStorageError(t *testing.T) {
 
 	fetcher := NewMockCloudAllAssetsByTimeFetcher(ctrl)
 	input := validFetchAllByTimestampInput()
-	pageToken, _ := input.toNextPageToken()
-	input.Offset += input.Count //emulate the paging
+	pageToken := validBulkPageToken(input)
 	ts, _ := time.Parse(time.RFC3339Nano, input.Timestamp)
-	fetcher.EXPECT().FetchAll(gomock.Any(), ts, input.Count, input.Offset, input.Type).Return([]domain.CloudAssetDetails{}, errors.New(""))
+	fetcher.EXPECT().FetchAll(gomock.Any(), ts, input.Count, int64(42), input.Type).Return(nil, int64(0), errors.New(""))
 
 	_, e := newCloudFetchAllAssetsByTimePageHandler(fetcher).Handle(context.Background(), CloudAssetFetchAllByTimeStampPageParameters{PageToken: pageToken})
 	require.NotNil(t, e)
 }
+
 func TestCloudFetchAllAssetsByTimePageNoResults(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
 	fetcher := NewMockCloudAllAssetsByTimeFetcher(ctrl)
 	input := validFetchAllByTimestampInput()
-	pageToken, _ := input.toNextPageToken()
-	input.Offset += input.Count //emulate the paging
+	pageToken := validBulkPageToken(input)
 	ts, _ := time.Parse(time.RFC3339Nano, input.Timestamp)
-	fetcher.EXPECT().FetchAll(gomock.Any(), ts, input.Count, input.Offset, input.Type).Return([]domain.CloudAssetDetails{}, nil)
+	fetcher.EXPECT().FetchAll(gomock.Any(), ts, input.Count, int64(42), input.Type).Return([]domain.CloudAssetDetails{}, int64(42), nil)
 
 	_, e := newCloudFetchAllAssetsByTimePageHandler(fetcher).Handle(context.Background(), CloudAssetFetchAllByTimeStampPageParameters{PageToken: pageToken})
 	require.NotNil(t, e)
+	assert.IsType(t, NotFound{}, e)
+}
+
+func TestCloudFetchAllAssetsByTimePageSuccess(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	fetcher := NewMockCloudAllAssetsByTimeFetcher(ctrl)
+	input := validFetchAllByTimestampInput()
+	input.Count = 1
+	pageToken := validBulkPageToken(input)
+	ts, _ := time.Parse(time.RFC3339Nano, input.Timestamp)
+	fetcher.EXPECT().FetchAll(gomock.Any(), ts, input.Count, int64(42), input.Type).Return([]domain.CloudAssetDetails{{ARN: "arn"}}, int64(43), nil)
+
+	res, e := newCloudFetchAllAssetsByTimePageHandler(fetcher).Handle(context.Background(), CloudAssetFetchAllByTimeStampPageParameters{PageToken: pageToken})
+	require.Nil(t, e)
+	require.Len(t, res.Assets, 1)
+	cursor, e := testPageTokenSigner().verify(res.NextPageToken, bulkPageKind)
+	require.Nil(t, e)
+	assert.Equal(t, int64(43), cursor.After)
 }
 
 func TestFetchByIPInvalidInput(t *testing.T) {
@@ -211,6 +313,14 @@ func TestFetchByIPInvalidInput(t *testing.T) {
 			name:  "no ipAddress",
 			input: CloudAssetFetchByIPParameters{Timestamp: time.Now().Format(time.RFC3339Nano)},
 		},
+		{
+			name:  "invalid ipAddress",
+			input: CloudAssetFetchByIPParameters{Timestamp: time.Now().Format(time.RFC3339Nano), IPAddress: "fd00::a::1"},
+		},
+		{
+			name:  "invalid vpcId",
+			input: CloudAssetFetchByIPParameters{Timestamp: time.Now().Format(time.RFC3339Nano), IPAddress: "10.0.0.1", VPCID: "subnet-1234"},
+		},
 	}
 
 	for _, tt := range tc {
@@ -232,7 +342,7 @@ func TestFetchByIPStorageError(t *testing.T) {
 	fetcher := NewMockCloudAssetByIPFetcher(ctrl)
 	input := validFetchByIPInput()
 	ts, _ := time.Parse(time.RFC3339Nano, input.Timestamp)
-	fetcher.EXPECT().FetchByIP(gomock.Any(), ts, input.IPAddress).Return([]domain.CloudAssetDetails{}, errors.New(""))
+	fetcher.EXPECT().FetchByIP(gomock.Any(), ts, input.IPAddress, domain.IPScope{}).Return([]domain.CloudAssetDetails{}, errors.New(""))
 
 	_, e := newFetchByIPHandler(fetcher).Handle(context.Background(), input)
 	assert.NotNil(t, e)
@@ -245,7 +355,7 @@ func TestFetchByIPNotFound(t *testing.T) {
 	fetcher := NewMockCloudAssetByIPFetcher(ctrl)
 	input := validFetchByIPInput()
 	ts, _ := time.Parse(time.RFC3339Nano, input.Timestamp)
-	fetcher.EXPECT().FetchByIP(gomock.Any(), ts, input.IPAddress).Return([]domain.CloudAssetDetails{}, nil)
+	fetcher.EXPECT().FetchByIP(gomock.Any(), ts, input.IPAddress, domain.IPScope{}).Return([]domain.CloudAssetDetails{}, nil)
 
 	_, e := newFetchByIPHandler(fetcher).Handle(context.Background(), input)
 	assert.NotNil(t, e)
@@ -265,7 +375,7 @@ func TestFetchByIPSuccess(t *testing.T) {
 			Hostnames:          []string{"foo"},
 		},
 	}
-	fetcher.EXPECT().FetchByIP(gomock.Any(), ts, input.IPAddress).Return(output, nil)
+	fetcher.EXPECT().FetchByIP(gomock.Any(), ts, input.IPAddress, domain.IPScope{}).Return(output, nil)
 
 	asset, e := newFetchByIPHandler(fetcher).Handle(context.Background(), input)
 	assert.Nil(t, e)
@@ -350,6 +460,23 @@ func TestFetchByHostnameSuccess(t *testing.T) {
 	assert.NotNil(t, asset)
 }
 
+func TestFetchByIPScoped(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	fetcher := NewMockCloudAssetByIPFetcher(ctrl)
+	input := validFetchByIPInput()
+	input.AccountID = "123456789012"
+	input.VPCID = "vpc-1234"
+	ts, _ := time.Parse(time.RFC3339Nano, input.Timestamp)
+	fetcher.EXPECT().FetchByIP(gomock.Any(), ts, input.IPAddress, domain.IPScope{AccountID: "123456789012", VPCID: "vpc-1234"}).
+		Return([]domain.CloudAssetDetails{{ARN: "arn"}}, nil)
+
+	res, e := newFetchByIPHandler(fetcher).Handle(context.Background(), input)
+	require.Nil(t, e)
+	assert.Len(t, res.Assets, 1)
+}
+
 func TestFetchByResourceIDInvalidInput(t *testing.T) {
 	tc := []struct {
 		name  string
@@ -478,6 +605,7 @@ func TestExtractOutput(t *testing.T) {
 						AccountID:          "accountId",
 						Region:             "Region",
 						ARN:                "arn",
+						AttachedTo:         make([]string, 0),
 						Tags:               make(map[string]string),
 						AccountOwner: domain.AccountOwner{
 							AccountID: toStringPointer("accountID"),
@@ -500,6 +628,7 @@ func TestExtractOutput(t *testing.T) {
 					Hostnames:          []string{"hostname"},
 					PublicIPAddresses:  []string{"1.1.1.1"},
 					PrivateIPAddresses: []string{"10.1.1.1"},
+					AttachedTo:         []string{"eni-1", "i-1"},
 					AccountOwner: domain.AccountOwner{
 						AccountID: toStringPointer("accountID"),
 						Owner: domain.Person{
@@ -559,6 +688,7 @@ func TestExtractOutput(t *testing.T) {
 						PrivateIPAddresses: []string{"10.1.1.1"},
 						PublicIPAddresses:  []string{"1.1.1.1"},
 						Hostnames:          []string{"hostname"},
+						AttachedTo:         []string{"eni-1", "i-1"},
 						Tags:               make(map[string]string),
 						AccountOwner: domain.AccountOwner{
 							AccountID: toStringPointer("accountID"),
@@ -588,6 +718,7 @@ func TestExtractOutput(t *testing.T) {
 						PrivateIPAddresses: []string{"10.2.2.2"},
 						PublicIPAddresses:  []string{"2.2.2.2"},
 						Hostnames:          []string{"hostname"},
+						AttachedTo:         make([]string, 0),
 						Tags:               make(map[string]string),
 						AccountOwner: domain.AccountOwner{
 							AccountID: toStringPointer("accountID2"),
@@ -636,6 +767,13 @@ func Test_validateAssetType(t *testing.T) {
 	}{
 		{"ValidEC2", awsEC2, awsEC2, false},
 		{"ValidALB", awsALB, awsALB, false},
+		{"ValidENI", awsENI, awsENI, false},
+		{"ValidEIP", awsEIP, awsEIP, false},
+		{"ValidECSTask", awsECS, awsECS, false},
+		{"ValidLambda", awsFn, awsFn, false},
+		{"ValidNATGateway", awsNAT, awsNAT, false},
+		{"ValidPod", domain.ResourceTypeKubernetesPod, domain.ResourceTypeKubernetesPod, false},
+		{"ValidService", domain.ResourceTypeKubernetesService, domain.ResourceTypeKubernetesService, false},
 		{"ValidELB", awsELB, awsELB, false},
 		{"Invalid", "not a valid asset type", "", true},
 		{"Empty", "", "", true},
@@ -654,35 +792,6 @@ func Test_validateAssetType(t *testing.T) {
 	}
 }
 
-func Test_fetchAllByTimeStampParametersForToken(t *testing.T) {
-	validParameters := validFetchAllByTimestampInput()
-	validToken, _ := validParameters.toNextPageToken()
-	validParameters.Offset += validParameters.Count //next page
-	brokenJS := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("this is not json"))
-	tests := []struct {
-		name    string
-		token   string
-		want    *CloudAssetFetchAllByTimestampParameters
-		wantErr bool
-	}{
-		{"not base32", "this is not base32 $@&%#@&*^*&%(*", nil, true},
-		{"not valid json", brokenJS, nil, true},
-		{"valid", validToken, &validParameters, false},
-	}
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			got, err := fetchAllByTimeStampParametersForToken(tt.token)
-			if (err != nil) != tt.wantErr {
-				t.Errorf("fetchAllByTimeStampParametersForToken() error = %v, wantErr %v", err, tt.wantErr)
-				return
-			}
-			if !reflect.DeepEqual(got, tt.want) {
-				t.Errorf("fetchAllByTimeStampParametersForToken() got = %v, want %v", got, tt.want)
-			}
-		})
-	}
-}
-
 // Helper function to convert strings to pointers (for nullability)
 func toStringPointer(s string) *string {
 	return &s
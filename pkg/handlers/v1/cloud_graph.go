@@ -0,0 +1,125 @@
+package v1
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/asecurityteam/asset-inventory-api/pkg/domain"
+	"github.com/asecurityteam/asset-inventory-api/pkg/logs"
+)
+
+// maxGraphDepth caps how many hops away from the starting resource relationships are followed
+const maxGraphDepth = 5
+
+// CloudAssetGraphParameters represents the incoming payload for fetching the resources related to a cloud asset.
+// Direction defaults to both and depth to a single hop.
+type CloudAssetGraphParameters struct {
+	ResourceID string `json:"resourceid"`
+	Timestamp  string `json:"time"`
+	Direction  string `json:"direction"`
+	Depth      uint   `json:"depth"`
+}
+
+// CloudAssetGraph represents the resources and relationships reachable from a cloud asset at a point in time
+type CloudAssetGraph struct {
+	ResourceID string                `json:"resourceId"`
+	Nodes      []CloudAssetGraphNode `json:"nodes"`
+	Edges      []CloudAssetGraphEdge `json:"edges"`
+}
+
+// CloudAssetGraphNode represents a resource in the graph, the type is omitted for resources only known as the target
+// of a relationship
+type CloudAssetGraphNode struct {
+	ResourceID   string `json:"resourceId"`
+	ResourceType string `json:"resourceType,omitempty"`
+	Depth        uint   `json:"depth"`
+}
+
+// CloudAssetGraphEdge represents a relationship from one resource to another, with its validity interval.
+// Bounds are omitted when the start of the interval is unknown or the relationship is still in place.
+type CloudAssetGraphEdge struct {
+	From      string     `json:"from"`
+	To        string     `json:"to"`
+	NotBefore *time.Time `json:"notBefore,omitempty"`
+	NotAfter  *time.Time `json:"notAfter,omitempty"`
+}
+
+// CloudFetchGraphHandler defines a lambda handler for fetching the resources related to a cloud asset with a given
+// resource ID
+type CloudFetchGraphHandler struct {
+	LogFn   domain.LogFn
+	StatFn  domain.StatFn
+	Fetcher domain.CloudAssetGraphFetcher
+}
+
+// Handle handles fetching the resources related to a cloud asset by resource ID
+func (h *CloudFetchGraphHandler) Handle(ctx context.Context, input CloudAssetGraphParameters) (CloudAssetGraph, error) {
+	logger := h.LogFn(ctx)
+
+	ts, e := time.Parse(time.RFC3339Nano, input.Timestamp)
+	if e != nil {
+		logger.Info(logs.InvalidInput{Reason: e.Error()})
+		return CloudAssetGraph{}, InvalidInput{Field: "time", Cause: e}
+	}
+
+	if input.ResourceID == "" {
+		e = fmt.Errorf("Resource ID cannot be empty")
+		logger.Info(logs.InvalidInput{Reason: e.Error()})
+		return CloudAssetGraph{}, InvalidInput{Field: "Resource ID", Cause: e}
+	}
+
+	direction := strings.ToLower(input.Direction)
+	switch direction {
+	case "":
+		direction = domain.GraphDirectionBoth
+	case domain.GraphDirectionOutgoing, domain.GraphDirectionIncoming, domain.GraphDirectionBoth:
+	default:
+		e = fmt.Errorf("unknown direction %s", input.Direction)
+		logger.Info(logs.InvalidInput{Reason: e.Error()})
+		return CloudAssetGraph{}, InvalidInput{Field: "direction", Cause: e}
+	}
+
+	depth := input.Depth
+	if depth == 0 {
+		depth = 1
+	}
+	if depth > maxGraphDepth {
+		e = fmt.Errorf("depth can not exceed %d", maxGraphDepth)
+		logger.Info(logs.InvalidInput{Reason: e.Error()})
+		return CloudAssetGraph{}, InvalidInput{Field: "depth", Cause: e}
+	}
+
+	graph, e := h.Fetcher.FetchGraph(ctx, ts, input.ResourceID, direction, depth)
+	if e != nil {
+		logger.Error(logs.StorageError{Reason: e.Error()})
+		return CloudAssetGraph{}, e
+	}
+	// a resource that was never stored and is not related to anything is unknown
+	if len(graph.Edges) == 0 && (len(graph.Nodes) == 0 || graph.Nodes[0].ResourceType == "") {
+		return CloudAssetGraph{}, NotFound{ID: input.ResourceID}
+	}
+
+	output := CloudAssetGraph{
+		ResourceID: input.ResourceID,
+		Nodes:      make([]CloudAssetGraphNode, len(graph.Nodes)),
+		Edges:      make([]CloudAssetGraphEdge, len(graph.Edges)),
+	}
+	for i, node := range graph.Nodes {
+		output.Nodes[i] = CloudAssetGraphNode{
+			ResourceID:   node.ResourceID,
+			ResourceType: node.ResourceType,
+			Depth:        node.Depth,
+		}
+	}
+	for i, edge := range graph.Edges {
+		output.Edges[i] = CloudAssetGraphEdge{
+			From:      edge.From,
+			To:        edge.To,
+			NotBefore: edge.NotBefore,
+			NotAfter:  edge.NotAfter,
+		}
+	}
+	return output, nil
+}
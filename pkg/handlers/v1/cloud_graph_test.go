@@ -0,0 +1,131 @@
+package v1
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/asecurityteam/asset-inventory-api/pkg/domain"
+)
+
+func newFetchGraphHandler(fetcher domain.CloudAssetGraphFetcher) *CloudFetchGraphHandler {
+	return &CloudFetchGraphHandler{
+		LogFn:   testLogFn,
+		StatFn:  testStatFn,
+		Fetcher: fetcher,
+	}
+}
+
+func TestFetchGraphInvalidInput(t *testing.T) {
+	now := time.Now().Format(time.RFC3339Nano)
+	tc := []struct {
+		name  string
+		input CloudAssetGraphParameters
+		field string
+	}{
+		{"invalid timestamp", CloudAssetGraphParameters{ResourceID: "i-1", Timestamp: "foo"}, "time"},
+		{"no resource ID", CloudAssetGraphParameters{Timestamp: now}, "Resource ID"},
+		{"unknown direction", CloudAssetGraphParameters{ResourceID: "i-1", Timestamp: now, Direction: "sideways"}, "direction"},
+		{"too deep", CloudAssetGraphParameters{ResourceID: "i-1", Timestamp: now, Depth: maxGraphDepth + 1}, "depth"},
+	}
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			_, e := newFetchGraphHandler(nil).Handle(context.Background(), tt.input)
+			require.NotNil(t, e)
+			require.IsType(t, InvalidInput{}, e)
+			assert.Equal(t, tt.field, e.(InvalidInput).Field)
+		})
+	}
+}
+
+func TestFetchGraphStorageError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	now := time.Now()
+	fetcher := NewMockCloudAssetGraphFetcher(ctrl)
+	fetcher.EXPECT().FetchGraph(gomock.Any(), gomock.Any(), "i-1", domain.GraphDirectionBoth, uint(1)).Return(domain.ResourceGraph{}, errors.New(""))
+
+	_, e := newFetchGraphHandler(fetcher).Handle(context.Background(), CloudAssetGraphParameters{ResourceID: "i-1", Timestamp: now.Format(time.RFC3339Nano)})
+	require.NotNil(t, e)
+}
+
+func TestFetchGraphNotFound(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	now := time.Now()
+	fetcher := NewMockCloudAssetGraphFetcher(ctrl)
+	fetcher.EXPECT().FetchGraph(gomock.Any(), gomock.Any(), "i-1", domain.GraphDirectionIncoming, uint(2)).Return(domain.ResourceGraph{
+		Nodes: []domain.ResourceGraphNode{{ResourceID: "i-1"}},
+		Edges: []domain.ResourceGraphEdge{},
+	}, nil)
+
+	_, e := newFetchGraphHandler(fetcher).Handle(context.Background(), CloudAssetGraphParameters{
+		ResourceID: "i-1",
+		Timestamp:  now.Format(time.RFC3339Nano),
+		Direction:  "Incoming",
+		Depth:      2,
+	})
+	require.NotNil(t, e)
+	assert.IsType(t, NotFound{}, e)
+}
+
+func TestFetchGraphIsolatedResource(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	now := time.Now()
+	fetcher := NewMockCloudAssetGraphFetcher(ctrl)
+	fetcher.EXPECT().FetchGraph(gomock.Any(), gomock.Any(), "i-1", domain.GraphDirectionBoth, uint(1)).Return(domain.ResourceGraph{
+		Nodes: []domain.ResourceGraphNode{{ResourceID: "i-1", ResourceType: "AWS::EC2::Instance"}},
+		Edges: []domain.ResourceGraphEdge{},
+	}, nil)
+
+	res, e := newFetchGraphHandler(fetcher).Handle(context.Background(), CloudAssetGraphParameters{ResourceID: "i-1", Timestamp: now.Format(time.RFC3339Nano)})
+	require.Nil(t, e)
+	assert.Len(t, res.Nodes, 1)
+	assert.Empty(t, res.Edges)
+}
+
+func TestFetchGraph(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	now := time.Now()
+	before := now.Add(-time.Hour)
+	ts, _ := time.Parse(time.RFC3339Nano, now.Format(time.RFC3339Nano))
+	fetcher := NewMockCloudAssetGraphFetcher(ctrl)
+	fetcher.EXPECT().FetchGraph(gomock.Any(), ts, "app/lb/1", domain.GraphDirectionOutgoing, uint(2)).Return(domain.ResourceGraph{
+		Nodes: []domain.ResourceGraphNode{
+			{ResourceID: "app/lb/1", ResourceType: "AWS::ElasticLoadBalancingV2::LoadBalancer"},
+			{ResourceID: "eni-1", Depth: 1},
+		},
+		Edges: []domain.ResourceGraphEdge{
+			{From: "app/lb/1", To: "eni-1", NotBefore: &before},
+		},
+	}, nil)
+
+	res, e := newFetchGraphHandler(fetcher).Handle(context.Background(), CloudAssetGraphParameters{
+		ResourceID: "app/lb/1",
+		Timestamp:  now.Format(time.RFC3339Nano),
+		Direction:  domain.GraphDirectionOutgoing,
+		Depth:      2,
+	})
+	require.Nil(t, e)
+	assert.Equal(t, CloudAssetGraph{
+		ResourceID: "app/lb/1",
+		Nodes: []CloudAssetGraphNode{
+			{ResourceID: "app/lb/1", ResourceType: "AWS::ElasticLoadBalancingV2::LoadBalancer"},
+			{ResourceID: "eni-1", Depth: 1},
+		},
+		Edges: []CloudAssetGraphEdge{
+			{From: "app/lb/1", To: "eni-1", NotBefore: &before},
+		},
+	}, res)
+}
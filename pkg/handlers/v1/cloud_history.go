@@ -0,0 +1,68 @@
+package v1
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/asecurityteam/asset-inventory-api/pkg/domain"
+	"github.com/asecurityteam/asset-inventory-api/pkg/logs"
+)
+
+// CloudAssetHistoryParameters represents the incoming payload for fetching the history of a cloud asset
+type CloudAssetHistoryParameters struct {
+	ResourceID string `json:"resourceid"`
+}
+
+// CloudAssetHistory represents the ordered timeline of IP addresses, hostnames and related resources held by an asset
+type CloudAssetHistory struct {
+	ResourceID string                   `json:"resourceId"`
+	Timeline   []CloudAssetHistoryEntry `json:"timeline"`
+}
+
+// CloudAssetHistoryEntry represents a single interval in the history of an asset.
+// Bounds are omitted when the start of the interval is unknown or the interval is still open.
+type CloudAssetHistoryEntry struct {
+	Type      string     `json:"type"`
+	Value     string     `json:"value"`
+	NotBefore *time.Time `json:"notBefore,omitempty"`
+	NotAfter  *time.Time `json:"notAfter,omitempty"`
+}
+
+// CloudFetchHistoryHandler defines a lambda handler for fetching the history of a cloud asset with a given resource ID
+type CloudFetchHistoryHandler struct {
+	LogFn   domain.LogFn
+	StatFn  domain.StatFn
+	Fetcher domain.CloudAssetHistoryFetcher
+}
+
+// Handle handles fetching the history of a cloud asset by resource ID
+func (h *CloudFetchHistoryHandler) Handle(ctx context.Context, input CloudAssetHistoryParameters) (CloudAssetHistory, error) {
+	logger := h.LogFn(ctx)
+
+	if input.ResourceID == "" {
+		e := fmt.Errorf("Resource ID cannot be empty")
+		logger.Info(logs.InvalidInput{Reason: e.Error()})
+		return CloudAssetHistory{}, InvalidInput{Field: "Resource ID", Cause: e}
+	}
+
+	history, e := h.Fetcher.FetchHistoryByResourceID(ctx, input.ResourceID)
+	if e != nil {
+		logger.Error(logs.StorageError{Reason: e.Error()})
+		return CloudAssetHistory{}, e
+	}
+	if len(history) == 0 {
+		return CloudAssetHistory{}, NotFound{ID: input.ResourceID}
+	}
+
+	timeline := make([]CloudAssetHistoryEntry, len(history))
+	for i, entry := range history {
+		timeline[i] = CloudAssetHistoryEntry{
+			Type:      entry.Kind,
+			Value:     entry.Value,
+			NotBefore: entry.NotBefore,
+			NotAfter:  entry.NotAfter,
+		}
+	}
+	return CloudAssetHistory{ResourceID: input.ResourceID, Timeline: timeline}, nil
+}
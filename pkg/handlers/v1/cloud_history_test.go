@@ -0,0 +1,74 @@
+package v1
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/asecurityteam/asset-inventory-api/pkg/domain"
+)
+
+func newFetchHistoryHandler(fetcher domain.CloudAssetHistoryFetcher) *CloudFetchHistoryHandler {
+	return &CloudFetchHistoryHandler{
+		LogFn:   testLogFn,
+		StatFn:  testStatFn,
+		Fetcher: fetcher,
+	}
+}
+
+func TestFetchHistoryInvalidInput(t *testing.T) {
+	_, e := newFetchHistoryHandler(nil).Handle(context.Background(), CloudAssetHistoryParameters{})
+	require.NotNil(t, e)
+	assert.IsType(t, InvalidInput{}, e)
+}
+
+func TestFetchHistoryStorageError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	fetcher := NewMockCloudAssetHistoryFetcher(ctrl)
+	fetcher.EXPECT().FetchHistoryByResourceID(gomock.Any(), "resid").Return(nil, errors.New(""))
+
+	_, e := newFetchHistoryHandler(fetcher).Handle(context.Background(), CloudAssetHistoryParameters{ResourceID: "resid"})
+	require.NotNil(t, e)
+}
+
+func TestFetchHistoryNotFound(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	fetcher := NewMockCloudAssetHistoryFetcher(ctrl)
+	fetcher.EXPECT().FetchHistoryByResourceID(gomock.Any(), "resid").Return([]domain.CloudAssetHistoryEntry{}, nil)
+
+	_, e := newFetchHistoryHandler(fetcher).Handle(context.Background(), CloudAssetHistoryParameters{ResourceID: "resid"})
+	require.NotNil(t, e)
+	assert.IsType(t, NotFound{}, e)
+}
+
+func TestFetchHistory(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	first := time.Now().Add(-time.Hour)
+	second := time.Now()
+	fetcher := NewMockCloudAssetHistoryFetcher(ctrl)
+	fetcher.EXPECT().FetchHistoryByResourceID(gomock.Any(), "resid").Return([]domain.CloudAssetHistoryEntry{
+		{Kind: domain.HistoryPrivateIP, Value: "10.0.0.1", NotAfter: &first},
+		{Kind: domain.HistoryRelatedResource, Value: "eni-1234", NotBefore: &first, NotAfter: &second},
+	}, nil)
+
+	history, e := newFetchHistoryHandler(fetcher).Handle(context.Background(), CloudAssetHistoryParameters{ResourceID: "resid"})
+	require.Nil(t, e)
+	assert.Equal(t, CloudAssetHistory{
+		ResourceID: "resid",
+		Timeline: []CloudAssetHistoryEntry{
+			{Type: domain.HistoryPrivateIP, Value: "10.0.0.1", NotAfter: &first},
+			{Type: domain.HistoryRelatedResource, Value: "eni-1234", NotBefore: &first, NotAfter: &second},
+		},
+	}, history)
+}
@@ -2,12 +2,21 @@ package v1
 
 import (
 	"context"
+	"fmt"
+	"net"
+	"strings"
 	"time"
 
 	"github.com/asecurityteam/asset-inventory-api/pkg/domain"
 	"github.com/asecurityteam/asset-inventory-api/pkg/logs"
 )
 
+// Change types of the addresses and relationships in the payloads of workloads
+const (
+	changeTypeAdded   = "ADDED"
+	changeTypeDeleted = "DELETED"
+)
+
 // CloudAssetChanges represents the incoming payload
 type CloudAssetChanges struct {
 	Changes      []NetworkChanges  `json:"changes"`
@@ -17,15 +26,27 @@ type CloudAssetChanges struct {
 	Region       string            `json:"region"`
 	ARN          string            `json:"arn"`
 	Tags         map[string]string `json:"tags"`
+	Lifecycle    string            `json:"lifecycle"`
+	EventID      string            `json:"eventId"`
+}
+
+// CloudInsertResult represents the outcome of storing cloud asset changes. Changes carrying the event ID of changes
+// already processed are deduplicated, they are accepted without taking effect.
+type CloudInsertResult struct {
+	Deduplicated bool `json:"deduplicated"`
 }
 
-// NetworkChanges detail the changes in ip addresses and host names for an asset
+// NetworkChanges detail the changes in ip addresses and host names for an asset. Hostnames are those of the public IP
+// addresses, while private hostnames are keyed by the private IP address they resolve to.
 type NetworkChanges struct {
-	PrivateIPAddresses []string `json:"privateIpAddresses"`
-	PublicIPAddresses  []string `json:"publicIpAddresses"`
-	Hostnames          []string `json:"hostnames"`
-	RelatedResources   []string `json:"relatedResources"`
-	ChangeType         string   `json:"changeType"`
+	PrivateIPAddresses []string          `json:"privateIpAddresses"`
+	PublicIPAddresses  []string          `json:"publicIpAddresses"`
+	Hostnames          []string          `json:"hostnames"`
+	PrivateHostnames   map[string]string `json:"privateHostnames"`
+	RelatedResources   []string          `json:"relatedResources"`
+	VPCID              string            `json:"vpcId"`
+	SubnetID           string            `json:"subnetId"`
+	ChangeType         string            `json:"changeType"`
 }
 
 // CloudInsertHandler defines a lambda handler for inserting new cloud asset or changes to existing cloud assets
@@ -36,13 +57,52 @@ type CloudInsertHandler struct {
 }
 
 // Handle handles the insert operation for cloud assets
-func (h *CloudInsertHandler) Handle(ctx context.Context, input CloudAssetChanges) error {
+func (h *CloudInsertHandler) Handle(ctx context.Context, input CloudAssetChanges) (CloudInsertResult, error) {
 	logger := h.LogFn(ctx)
 
-	changeTime, e := time.Parse(time.RFC3339Nano, input.ChangeTime)
+	assetChanges, e := toDomainCloudAssetChanges(input)
 	if e != nil {
 		logger.Info(logs.InvalidInput{Reason: e.Error()})
-		return InvalidInput{Field: "changeTime", Cause: e}
+		return CloudInsertResult{}, e
+	}
+	return StoreChanges(ctx, logger, h.CloudAssetStorer, assetChanges)
+}
+
+// ValidateChangeTimeAndLifecycle parses the change time of a payload and checks its lifecycle, which is returned in
+// upper case. It returns an InvalidInput error naming the offending field.
+func ValidateChangeTimeAndLifecycle(changeTime string, lifecycle string) (time.Time, string, error) {
+	ts, e := time.Parse(time.RFC3339Nano, changeTime)
+	if e != nil {
+		return time.Time{}, "", InvalidInput{Field: "changeTime", Cause: e}
+	}
+	switch strings.ToUpper(lifecycle) {
+	case "", domain.LifecycleCreated, domain.LifecycleTerminated:
+		return ts, strings.ToUpper(lifecycle), nil
+	default:
+		e = fmt.Errorf("unknown lifecycle %s", lifecycle)
+		return time.Time{}, "", InvalidInput{Field: "lifecycle", Cause: e}
+	}
+}
+
+// StoreChanges stores the changes of a payload, which are deduplicated rather than failed when carrying the event ID
+// of changes already processed
+func StoreChanges(ctx context.Context, logger domain.Logger, storer domain.CloudAssetStorer, assetChanges domain.CloudAssetChanges) (CloudInsertResult, error) {
+	if e := storer.Store(ctx, assetChanges); e != nil {
+		if _, ok := e.(domain.DuplicateEvent); ok {
+			logger.Info(logs.DuplicateEvent{EventID: assetChanges.EventID})
+			return CloudInsertResult{Deduplicated: true}, nil
+		}
+		logger.Error(logs.StorageError{Reason: e.Error()})
+		return CloudInsertResult{}, e
+	}
+	return CloudInsertResult{}, nil
+}
+
+// toDomainCloudAssetChanges validates the incoming payload, returning an InvalidInput error naming the offending field
+func toDomainCloudAssetChanges(input CloudAssetChanges) (domain.CloudAssetChanges, error) {
+	changeTime, lifecycle, e := ValidateChangeTimeAndLifecycle(input.ChangeTime, input.Lifecycle)
+	if e != nil {
+		return domain.CloudAssetChanges{}, e
 	}
 	assetChanges := domain.CloudAssetChanges{
 		ChangeTime:   changeTime,
@@ -51,20 +111,78 @@ func (h *CloudInsertHandler) Handle(ctx context.Context, input CloudAssetChanges
 		Region:       input.Region,
 		ARN:          input.ARN,
 		Tags:         input.Tags,
+		Lifecycle:    lifecycle,
+		EventID:      input.EventID,
 		Changes:      make([]domain.NetworkChanges, 0, len(input.Changes)),
 	}
-	for _, val := range input.Changes {
+	for i, val := range input.Changes {
+		if e = validateIPAddresses(val.PrivateIPAddresses); e != nil {
+			return domain.CloudAssetChanges{}, InvalidInput{Field: fmt.Sprintf("changes[%d].privateIpAddresses", i), Cause: e}
+		}
+		if e = validateIPAddresses(val.PublicIPAddresses); e != nil {
+			return domain.CloudAssetChanges{}, InvalidInput{Field: fmt.Sprintf("changes[%d].publicIpAddresses", i), Cause: e}
+		}
+		privateIPs, addresses, e := splitPrivateHostnames(val.PrivateIPAddresses, val.PrivateHostnames)
+		if e != nil {
+			return domain.CloudAssetChanges{}, InvalidInput{Field: fmt.Sprintf("changes[%d].privateHostnames", i), Cause: e}
+		}
 		assetChanges.Changes = append(assetChanges.Changes, domain.NetworkChanges{
-			PrivateIPAddresses: val.PrivateIPAddresses,
+			PrivateIPAddresses: privateIPs,
 			PublicIPAddresses:  val.PublicIPAddresses,
 			Hostnames:          val.Hostnames,
+			Addresses:          addresses,
 			RelatedResources:   val.RelatedResources,
+			VPCID:              val.VPCID,
+			SubnetID:           val.SubnetID,
 			ChangeType:         val.ChangeType,
 		})
 	}
-	if e := h.CloudAssetStorer.Store(ctx, assetChanges); e != nil {
-		logger.Error(logs.StorageError{Reason: e.Error()})
-		return e
+	return assetChanges, nil
+}
+
+// splitPrivateHostnames separates the private IP addresses with a hostname, which are recorded along with it, from
+// those without one. Every hostname must be keyed by one of the private IP addresses of the change.
+func splitPrivateHostnames(privateIPs []string, hostnames map[string]string) ([]string, []domain.AddressChange, error) {
+	if len(hostnames) == 0 {
+		return privateIPs, nil, nil
+	}
+	held := make(map[string]struct{}, len(privateIPs))
+	for _, ip := range privateIPs {
+		held[ip] = struct{}{}
+	}
+	for ip := range hostnames {
+		if _, ok := held[ip]; !ok {
+			return nil, nil, fmt.Errorf("hostname of %s, which is not a private IP address of the change", ip)
+		}
+	}
+	withoutHostname := make([]string, 0, len(privateIPs))
+	addresses := make([]domain.AddressChange, 0, len(hostnames))
+	for _, ip := range privateIPs {
+		if hostname := hostnames[ip]; hostname != "" {
+			addresses = append(addresses, domain.AddressChange{IPAddress: ip, Hostname: hostname})
+		} else {
+			withoutHostname = append(withoutHostname, ip)
+		}
+	}
+	return withoutHostname, addresses, nil
+}
+
+// validateChangeType checks that a payload of a workload either assigns or releases, regardless of case
+func validateChangeType(changeType string) (string, error) {
+	switch strings.ToUpper(changeType) {
+	case changeTypeAdded, changeTypeDeleted:
+		return strings.ToUpper(changeType), nil
+	default:
+		return "", fmt.Errorf("unknown change type %s", changeType)
+	}
+}
+
+// validateIPAddresses checks that every entry is an IPv4 or IPv6 address
+func validateIPAddresses(ipAddresses []string) error {
+	for _, ip := range ipAddresses {
+		if net.ParseIP(ip) == nil {
+			return fmt.Errorf("invalid IPv4 or IPv6 address %q", ip)
+		}
 	}
 	return nil
 }
@@ -0,0 +1,134 @@
+package v1
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/asecurityteam/asset-inventory-api/pkg/domain"
+	"github.com/asecurityteam/asset-inventory-api/pkg/logs"
+)
+
+// maxBatchSize caps how many changes are stored by a single batch request
+const maxBatchSize = 1000
+
+// Modes of storing a batch of changes
+const (
+	// BatchModeAtomic stores all of the changes or none of them
+	BatchModeAtomic = "atomic"
+	// BatchModeBestEffort stores every change that can be stored and reports the others
+	BatchModeBestEffort = "bestEffort"
+)
+
+// Outcomes of storing an item of a batch
+const (
+	BatchItemStored       = "stored"
+	BatchItemDeduplicated = "deduplicated"
+	BatchItemInvalid      = "invalid"
+	BatchItemFailed       = "failed"
+	// BatchItemNotApplied is the outcome of a valid change rolled back by the failure of another change in atomic mode
+	BatchItemNotApplied = "notApplied"
+)
+
+// CloudAssetChangesBatch represents the incoming payload for storing several changes at once. The mode defaults to
+// atomic.
+type CloudAssetChangesBatch struct {
+	Changes []CloudAssetChanges `json:"changes"`
+	Mode    string              `json:"mode"`
+}
+
+// CloudInsertBatchResult represents the outcome of every item of a batch, in the order they were sent in
+type CloudInsertBatchResult struct {
+	Results []CloudInsertBatchItemResult `json:"results"`
+}
+
+// CloudInsertBatchItemResult represents the outcome of storing an item of a batch, with the reason of a failure
+type CloudInsertBatchItemResult struct {
+	Index  int    `json:"index"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// CloudInsertBatchHandler defines a lambda handler for inserting several changes to cloud assets at once
+type CloudInsertBatchHandler struct {
+	LogFn  domain.LogFn
+	StatFn domain.StatFn
+	Storer domain.CloudAssetBatchStorer
+}
+
+// Handle handles the insert operation for a batch of cloud asset changes
+func (h *CloudInsertBatchHandler) Handle(ctx context.Context, input CloudAssetChangesBatch) (CloudInsertBatchResult, error) {
+	logger := h.LogFn(ctx)
+
+	mode := input.Mode
+	switch mode {
+	case "":
+		mode = BatchModeAtomic
+	case BatchModeAtomic, BatchModeBestEffort:
+	default:
+		e := fmt.Errorf("unknown mode %s", input.Mode)
+		logger.Info(logs.InvalidInput{Reason: e.Error()})
+		return CloudInsertBatchResult{}, InvalidInput{Field: "mode", Cause: e}
+	}
+	if len(input.Changes) > maxBatchSize {
+		e := fmt.Errorf("a batch can not hold more than %d changes", maxBatchSize)
+		logger.Info(logs.InvalidInput{Reason: e.Error()})
+		return CloudInsertBatchResult{}, InvalidInput{Field: "changes", Cause: e}
+	}
+
+	results := make([]CloudInsertBatchItemResult, len(input.Changes))
+	valid := make([]domain.CloudAssetChanges, 0, len(input.Changes))
+	validIndexes := make([]int, 0, len(input.Changes))
+	for i, item := range input.Changes {
+		results[i] = CloudInsertBatchItemResult{Index: i, Status: BatchItemStored}
+		assetChanges, e := toDomainCloudAssetChanges(item)
+		if e != nil {
+			logger.Info(logs.InvalidInput{Reason: e.Error()})
+			if mode == BatchModeAtomic {
+				invalid := e.(InvalidInput)
+				return CloudInsertBatchResult{}, InvalidInput{Field: fmt.Sprintf("changes[%d].%s", i, invalid.Field), Cause: invalid.Cause}
+			}
+			results[i].Status = BatchItemInvalid
+			results[i].Error = e.Error()
+			continue
+		}
+		valid = append(valid, assetChanges)
+		validIndexes = append(validIndexes, i)
+	}
+
+	itemErrors, e := h.Storer.StoreBatch(ctx, valid, mode == BatchModeAtomic)
+	if e != nil {
+		logger.Error(logs.StorageError{Reason: e.Error()})
+		if !hasItemFailure(itemErrors) {
+			return CloudInsertBatchResult{}, e
+		}
+		// the failing change is known, every other change was rolled back with it
+		for _, i := range validIndexes {
+			results[i].Status = BatchItemNotApplied
+		}
+	}
+	for j, itemError := range itemErrors {
+		if itemError == nil {
+			continue
+		}
+		i := validIndexes[j]
+		if _, ok := itemError.(domain.DuplicateEvent); ok {
+			logger.Info(logs.DuplicateEvent{EventID: valid[j].EventID})
+			results[i].Status = BatchItemDeduplicated
+			continue
+		}
+		logger.Error(logs.StorageError{Reason: itemError.Error()})
+		results[i].Status = BatchItemFailed
+		results[i].Error = itemError.Error()
+	}
+	return CloudInsertBatchResult{Results: results}, nil
+}
+
+// hasItemFailure tells whether a change of the batch failed to be stored, rather than the batch as a whole
+func hasItemFailure(itemErrors []error) bool {
+	for _, itemError := range itemErrors {
+		if _, ok := itemError.(domain.DuplicateEvent); itemError != nil && !ok {
+			return true
+		}
+	}
+	return false
+}
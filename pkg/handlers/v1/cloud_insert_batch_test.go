@@ -0,0 +1,110 @@
+package v1
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/asecurityteam/asset-inventory-api/pkg/domain"
+)
+
+func newInsertBatchHandler(storer domain.CloudAssetBatchStorer) *CloudInsertBatchHandler {
+	return &CloudInsertBatchHandler{
+		LogFn:  testLogFn,
+		StatFn: testStatFn,
+		Storer: storer,
+	}
+}
+
+func TestInsertBatchInvalidMode(t *testing.T) {
+	_, e := newInsertBatchHandler(nil).Handle(context.Background(), CloudAssetChangesBatch{Mode: "sometimes"})
+	require.IsType(t, InvalidInput{}, e)
+	assert.Equal(t, "mode", e.(InvalidInput).Field)
+}
+
+func TestInsertBatchTooLarge(t *testing.T) {
+	input := CloudAssetChangesBatch{Changes: make([]CloudAssetChanges, maxBatchSize+1)}
+	_, e := newInsertBatchHandler(nil).Handle(context.Background(), input)
+	require.IsType(t, InvalidInput{}, e)
+	assert.Equal(t, "changes", e.(InvalidInput).Field)
+}
+
+func TestInsertBatchAtomicInvalidItem(t *testing.T) {
+	invalid := validInsertInput()
+	invalid.ChangeTime = "not a timestamp"
+	input := CloudAssetChangesBatch{Changes: []CloudAssetChanges{validInsertInput(), invalid}}
+
+	_, e := newInsertBatchHandler(nil).Handle(context.Background(), input)
+	require.IsType(t, InvalidInput{}, e)
+	assert.Equal(t, "changes[1].changeTime", e.(InvalidInput).Field)
+}
+
+func TestInsertBatchAtomicStorageError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	storer := NewMockCloudAssetBatchStorer(ctrl)
+	storer.EXPECT().StoreBatch(gomock.Any(), gomock.Len(2), true).Return(make([]error, 2), errors.New(""))
+
+	input := CloudAssetChangesBatch{Changes: []CloudAssetChanges{validInsertInput(), validInsertInput()}}
+	_, e := newInsertBatchHandler(storer).Handle(context.Background(), input)
+	assert.Error(t, e)
+}
+
+func TestInsertBatchAtomicItemFailure(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	duplicate := validInsertInput()
+	duplicate.EventID = "event-1"
+	input := CloudAssetChangesBatch{Changes: []CloudAssetChanges{validInsertInput(), duplicate, validInsertInput()}}
+
+	storer := NewMockCloudAssetBatchStorer(ctrl)
+	storer.EXPECT().StoreBatch(gomock.Any(), gomock.Len(3), true).Return([]error{
+		nil,
+		domain.DuplicateEvent{EventID: "event-1"},
+		errors.New("no bueno"),
+	}, errors.New("failed to store changes 2: no bueno"))
+
+	res, e := newInsertBatchHandler(storer).Handle(context.Background(), input)
+	require.NoError(t, e)
+	assert.Equal(t, CloudInsertBatchResult{Results: []CloudInsertBatchItemResult{
+		{Index: 0, Status: BatchItemNotApplied},
+		{Index: 1, Status: BatchItemDeduplicated},
+		{Index: 2, Status: BatchItemFailed, Error: "no bueno"},
+	}}, res)
+}
+
+func TestInsertBatchBestEffort(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	invalid := validInsertInput()
+	invalid.Lifecycle = "resurrected"
+	duplicate := validInsertInput()
+	duplicate.EventID = "event-1"
+	input := CloudAssetChangesBatch{
+		Mode:    BatchModeBestEffort,
+		Changes: []CloudAssetChanges{validInsertInput(), invalid, duplicate, validInsertInput()},
+	}
+
+	storer := NewMockCloudAssetBatchStorer(ctrl)
+	storer.EXPECT().StoreBatch(gomock.Any(), gomock.Len(3), false).Return([]error{
+		nil,
+		domain.DuplicateEvent{EventID: "event-1"},
+		errors.New("no bueno"),
+	}, nil)
+
+	res, e := newInsertBatchHandler(storer).Handle(context.Background(), input)
+	require.NoError(t, e)
+	assert.Equal(t, CloudInsertBatchResult{Results: []CloudInsertBatchItemResult{
+		{Index: 0, Status: BatchItemStored},
+		{Index: 1, Status: BatchItemInvalid, Error: "the value for field lifecycle was invalid: unknown lifecycle resurrected"},
+		{Index: 2, Status: BatchItemDeduplicated},
+		{Index: 3, Status: BatchItemFailed, Error: "no bueno"},
+	}}, res)
+}
@@ -44,7 +44,7 @@ func TestInsertInvalidInput(t *testing.T) {
 	input := CloudAssetChanges{
 		ChangeTime: "not a timestamp",
 	}
-	e := newInsertHandler(nil).Handle(context.Background(), input)
+	_, e := newInsertHandler(nil).Handle(context.Background(), input)
 	assert.NotNil(t, e)
 
 	_, ok := e.(InvalidInput)
@@ -58,7 +58,7 @@ func TestInsertStorageError(t *testing.T) {
 	storage := NewMockCloudAssetStorer(ctrl)
 	storage.EXPECT().Store(gomock.Any(), gomock.Any()).Return(errors.New(""))
 
-	e := newInsertHandler(storage).Handle(context.Background(), validInsertInput())
+	_, e := newInsertHandler(storage).Handle(context.Background(), validInsertInput())
 	assert.NotNil(t, e)
 }
 
@@ -69,7 +69,7 @@ func TestInsertStorage(t *testing.T) {
 	storage := NewMockCloudAssetStorer(ctrl)
 	storage.EXPECT().Store(gomock.Any(), gomock.Any()).Return(nil)
 
-	e := newInsertHandler(storage).Handle(context.Background(), validInsertInput())
+	_, e := newInsertHandler(storage).Handle(context.Background(), validInsertInput())
 	assert.Nil(t, e)
 }
 
@@ -77,9 +77,180 @@ func TestInsertInvalidResourceType(t *testing.T) {
 	input := CloudAssetChanges{
 		ResourceType: "MS:Windows:2000",
 	}
-	e := newInsertHandler(nil).Handle(context.Background(), input)
+	_, e := newInsertHandler(nil).Handle(context.Background(), input)
 	assert.NotNil(t, e)
 
 	_, ok := e.(InvalidInput)
 	assert.True(t, ok)
 }
+
+func TestInsertInvalidIPAddress(t *testing.T) {
+	private := validInsertInput()
+	private.Changes[0].PrivateIPAddresses = []string{"10.0.0.1", "10.0.0"}
+	public := validInsertInput()
+	public.Changes[0].PublicIPAddresses = []string{"2600:1f18::1::2"}
+
+	for name, input := range map[string]CloudAssetChanges{"private": private, "public": public} {
+		t.Run(name, func(t *testing.T) {
+			_, e := newInsertHandler(nil).Handle(context.Background(), input)
+			assert.NotNil(t, e)
+			_, ok := e.(InvalidInput)
+			assert.True(t, ok)
+		})
+	}
+}
+
+func TestInsertMixedFamilies(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	input := validInsertInput()
+	input.Changes[0].PrivateIPAddresses = []string{"10.0.0.1", "fd00::a"}
+	input.Changes[0].PublicIPAddresses = []string{"2.2.2.2", "2600:1f18::1"}
+
+	storage := NewMockCloudAssetStorer(ctrl)
+	storage.EXPECT().Store(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, changes domain.CloudAssetChanges) error {
+		assert.Equal(t, input.Changes[0].PrivateIPAddresses, changes.Changes[0].PrivateIPAddresses)
+		assert.Equal(t, input.Changes[0].PublicIPAddresses, changes.Changes[0].PublicIPAddresses)
+		return nil
+	})
+
+	_, e := newInsertHandler(storage).Handle(context.Background(), input)
+	assert.Nil(t, e)
+}
+
+func TestInsertWithVPC(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	input := validInsertInput()
+	input.Changes[0].VPCID = "vpc-1234"
+	input.Changes[0].SubnetID = "subnet-5678"
+
+	storage := NewMockCloudAssetStorer(ctrl)
+	storage.EXPECT().Store(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, changes domain.CloudAssetChanges) error {
+		assert.Equal(t, "vpc-1234", changes.Changes[0].VPCID)
+		assert.Equal(t, "subnet-5678", changes.Changes[0].SubnetID)
+		return nil
+	})
+
+	_, e := newInsertHandler(storage).Handle(context.Background(), input)
+	assert.Nil(t, e)
+}
+
+func TestInsertWithPrivateHostnames(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	input := validInsertInput()
+	input.Changes[0].PrivateIPAddresses = []string{"10.0.0.1", "10.0.0.2"}
+	input.Changes[0].PrivateHostnames = map[string]string{"10.0.0.2": "ip-10-0-0-2.ec2.internal"}
+
+	storage := NewMockCloudAssetStorer(ctrl)
+	storage.EXPECT().Store(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, changes domain.CloudAssetChanges) error {
+		assert.Equal(t, []string{"10.0.0.1"}, changes.Changes[0].PrivateIPAddresses)
+		assert.Equal(t, []domain.AddressChange{{IPAddress: "10.0.0.2", Hostname: "ip-10-0-0-2.ec2.internal"}}, changes.Changes[0].Addresses)
+		return nil
+	})
+
+	_, e := newInsertHandler(storage).Handle(context.Background(), input)
+	assert.Nil(t, e)
+}
+
+func TestInsertPrivateHostnameWithoutAddress(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	input := validInsertInput()
+	input.Changes[0].PrivateHostnames = map[string]string{"10.9.9.9": "ip-10-9-9-9.ec2.internal"}
+
+	_, e := newInsertHandler(NewMockCloudAssetStorer(ctrl)).Handle(context.Background(), input)
+	assert.IsType(t, InvalidInput{}, e)
+	assert.Equal(t, "changes[0].privateHostnames", e.(InvalidInput).Field)
+}
+
+func TestInsertInvalidLifecycle(t *testing.T) {
+	input := validInsertInput()
+	input.Lifecycle = "PAUSED"
+
+	_, e := newInsertHandler(nil).Handle(context.Background(), input)
+	assert.IsType(t, InvalidInput{}, e)
+	assert.Equal(t, "lifecycle", e.(InvalidInput).Field)
+}
+
+func TestInsertTerminated(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	input := validInsertInput()
+	input.Changes = []NetworkChanges{}
+	input.Lifecycle = "terminated"
+
+	storage := NewMockCloudAssetStorer(ctrl)
+	storage.EXPECT().Store(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, changes domain.CloudAssetChanges) error {
+		assert.Equal(t, domain.LifecycleTerminated, changes.Lifecycle)
+		assert.Empty(t, changes.Changes)
+		return nil
+	})
+
+	_, e := newInsertHandler(storage).Handle(context.Background(), input)
+	assert.Nil(t, e)
+}
+
+func TestInsertDuplicateEvent(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	input := validInsertInput()
+	input.EventID = "event-1"
+
+	storage := NewMockCloudAssetStorer(ctrl)
+	storage.EXPECT().Store(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, changes domain.CloudAssetChanges) error {
+		assert.Equal(t, "event-1", changes.EventID)
+		return domain.DuplicateEvent{EventID: changes.EventID}
+	})
+
+	res, e := newInsertHandler(storage).Handle(context.Background(), input)
+	assert.Nil(t, e)
+	assert.True(t, res.Deduplicated)
+}
+
+func TestInsertNewEvent(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	input := validInsertInput()
+	input.EventID = "event-1"
+
+	storage := NewMockCloudAssetStorer(ctrl)
+	storage.EXPECT().Store(gomock.Any(), gomock.Any()).Return(nil)
+
+	res, e := newInsertHandler(storage).Handle(context.Background(), input)
+	assert.Nil(t, e)
+	assert.False(t, res.Deduplicated)
+}
+
+func TestValidateChangeTimeAndLifecycle(t *testing.T) {
+	at, _ := time.Parse(time.RFC3339Nano, "2019-04-09T08:29:35.123Z")
+	ts, lifecycle, e := ValidateChangeTimeAndLifecycle("2019-04-09T08:29:35.123Z", "terminated")
+	assert.NoError(t, e)
+	assert.Equal(t, at, ts)
+	assert.Equal(t, domain.LifecycleTerminated, lifecycle)
+
+	tc := []struct {
+		name       string
+		changeTime string
+		lifecycle  string
+		field      string
+	}{
+		{"invalid change time", "yesterday", "", "changeTime"},
+		{"unknown lifecycle", "2019-04-09T08:29:35Z", "resurrected", "lifecycle"},
+	}
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, e := ValidateChangeTimeAndLifecycle(tt.changeTime, tt.lifecycle)
+			assert.IsType(t, InvalidInput{}, e)
+			assert.Equal(t, tt.field, e.(InvalidInput).Field)
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package v1
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// PageTokenConfig contains the settings for signing page tokens of paged listings
+type PageTokenConfig struct {
+	Secret string        `description:"Shared secret used to sign page tokens. Must be identical across all instances. Paged listings only return their first page when not set."`
+	TTL    time.Duration `description:"How long a page token remains valid after it is issued."`
+}
+
+// Name is used by the settings library to replace the default naming convention.
+func (c *PageTokenConfig) Name() string {
+	return "PageToken"
+}
+
+// PageTokenConfigComponent satisfies the settings library Component API,
+// and may be used by the settings.NewComponent function.
+type PageTokenConfigComponent struct{}
+
+// NewPageTokenComponent generates a PageTokenConfigComponent
+func NewPageTokenComponent() *PageTokenConfigComponent {
+	return &PageTokenConfigComponent{}
+}
+
+// Settings populates a set of defaults if none are provided via config.
+func (*PageTokenConfigComponent) Settings() *PageTokenConfig {
+	return &PageTokenConfig{
+		TTL: 24 * time.Hour,
+	}
+}
+
+// New constructs a PageTokenSigner from a config. The secret is optional so that deployments predating page tokens
+// keep starting, paged listings fail to issue tokens until it is set.
+func (*PageTokenConfigComponent) New(_ context.Context, c *PageTokenConfig) (*PageTokenSigner, error) {
+	if c.TTL <= 0 {
+		return nil, errors.New("page token TTL must be positive")
+	}
+	return &PageTokenSigner{
+		Secret: []byte(c.Secret),
+		TTL:    c.TTL,
+	}, nil
+}
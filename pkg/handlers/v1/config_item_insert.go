@@ -0,0 +1,66 @@
+package v1
+
+import (
+	"context"
+	"time"
+
+	"github.com/asecurityteam/asset-inventory-api/pkg/awsconfig"
+	"github.com/asecurityteam/asset-inventory-api/pkg/domain"
+	"github.com/asecurityteam/asset-inventory-api/pkg/logs"
+)
+
+// ConfigurationItemInsertHandler defines a lambda handler for recording AWS Config configuration items. The changes
+// are derived by comparing the configuration with the state recorded for the resource at the capture time, and
+// stored along with reading it so that concurrent items of the same resource do not derive from the same state.
+type ConfigurationItemInsertHandler struct {
+	LogFn  domain.LogFn
+	StatFn domain.StatFn
+	Storer domain.CloudAssetStateStorer
+}
+
+// Handle handles the insert operation for configuration items
+func (h *ConfigurationItemInsertHandler) Handle(ctx context.Context, input awsconfig.ConfigurationItem) (CloudInsertResult, error) {
+	logger := h.LogFn(ctx)
+
+	captureTime, e := time.Parse(time.RFC3339Nano, input.CaptureTime)
+	if e != nil {
+		logger.Info(logs.InvalidInput{Reason: e.Error()})
+		return CloudInsertResult{}, InvalidInput{Field: "configurationItemCaptureTime", Cause: e}
+	}
+	// the configuration is checked before looking the previous state up
+	if _, e = input.State(); e != nil {
+		logger.Info(logs.InvalidInput{Reason: e.Error()})
+		field := "configuration"
+		if _, ok := e.(awsconfig.UnsupportedResourceType); ok {
+			field = "resourceType"
+		}
+		return CloudInsertResult{}, InvalidInput{Field: field, Cause: e}
+	}
+
+	var eventID string
+	e = h.Storer.StoreFromState(ctx, captureTime, input.ARN, func(previous domain.CloudAssetState) (domain.CloudAssetChanges, error) {
+		assetChanges, e := input.Changes(previous)
+		if e != nil {
+			return domain.CloudAssetChanges{}, InvalidInput{Field: "configuration", Cause: e}
+		}
+		eventID = assetChanges.EventID
+		return assetChanges, nil
+	})
+	if e == nil {
+		return CloudInsertResult{}, nil
+	}
+	if _, ok := e.(domain.DuplicateEvent); ok {
+		logger.Info(logs.DuplicateEvent{EventID: eventID})
+		return CloudInsertResult{Deduplicated: true}, nil
+	}
+	if invalid, ok := e.(InvalidInput); ok {
+		logger.Info(logs.InvalidInput{Reason: invalid.Cause.Error()})
+		return CloudInsertResult{}, invalid
+	}
+	if stale, ok := e.(domain.StaleChange); ok {
+		logger.Info(logs.InvalidInput{Reason: stale.Error()})
+		return CloudInsertResult{}, InvalidInput{Field: "configurationItemCaptureTime", Cause: stale}
+	}
+	logger.Error(logs.StorageError{Reason: e.Error()})
+	return CloudInsertResult{}, e
+}
@@ -0,0 +1,145 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/asecurityteam/asset-inventory-api/pkg/awsconfig"
+	"github.com/asecurityteam/asset-inventory-api/pkg/domain"
+)
+
+func newConfigurationItemInsertHandler(storer domain.CloudAssetStateStorer) *ConfigurationItemInsertHandler {
+	return &ConfigurationItemInsertHandler{
+		LogFn:  testLogFn,
+		StatFn: testStatFn,
+		Storer: storer,
+	}
+}
+
+// deriveFrom has the storer derive the changes from the state, and returns its error
+func deriveFrom(state domain.CloudAssetState, storeErr error) func(context.Context, time.Time, string, func(domain.CloudAssetState) (domain.CloudAssetChanges, error)) error {
+	return func(_ context.Context, _ time.Time, _ string, derive func(domain.CloudAssetState) (domain.CloudAssetChanges, error)) error {
+		if _, e := derive(state); e != nil {
+			return e
+		}
+		return storeErr
+	}
+}
+
+func validConfigurationItem() awsconfig.ConfigurationItem {
+	return awsconfig.ConfigurationItem{
+		CaptureTime:  "2019-04-09T08:29:35.000Z",
+		Status:       "OK",
+		StateID:      "1",
+		AccountID:    "aid",
+		Region:       "region",
+		ARN:          "arn:aws:ec2:region:aid:network-interface/eni-1",
+		ResourceType: awsconfig.TypeNetworkInterface,
+		ResourceID:   "eni-1",
+		Configuration: json.RawMessage(`{"vpcId": "vpc-1", "subnetId": "subnet-1",
+			"privateIpAddresses": [{"privateIpAddress": "10.0.0.1"}, {"privateIpAddress": "10.0.0.2"}]}`),
+	}
+}
+
+func TestInsertConfigurationItemInvalidInput(t *testing.T) {
+	tc := []struct {
+		name   string
+		modify func(*awsconfig.ConfigurationItem)
+		field  string
+	}{
+		{"invalid capture time", func(ci *awsconfig.ConfigurationItem) { ci.CaptureTime = "yesterday" }, "configurationItemCaptureTime"},
+		{"unsupported type", func(ci *awsconfig.ConfigurationItem) { ci.ResourceType = "AWS::S3::Bucket" }, "resourceType"},
+		{"invalid configuration", func(ci *awsconfig.ConfigurationItem) { ci.Configuration = json.RawMessage(`"foo"`) }, "configuration"},
+	}
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			input := validConfigurationItem()
+			tt.modify(&input)
+			_, e := newConfigurationItemInsertHandler(nil).Handle(context.Background(), input)
+			require.IsType(t, InvalidInput{}, e)
+			assert.Equal(t, tt.field, e.(InvalidInput).Field)
+		})
+	}
+}
+
+func TestInsertConfigurationItemStateError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	storer := NewMockCloudAssetStateStorer(ctrl)
+	storer.EXPECT().StoreFromState(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(errors.New(""))
+
+	_, e := newConfigurationItemInsertHandler(storer).Handle(context.Background(), validConfigurationItem())
+	assert.Error(t, e)
+}
+
+func TestInsertConfigurationItem(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	captureTime, _ := time.Parse(time.RFC3339, "2019-04-09T08:29:35Z")
+	storer := NewMockCloudAssetStateStorer(ctrl)
+	storer.EXPECT().StoreFromState(gomock.Any(), captureTime, "arn:aws:ec2:region:aid:network-interface/eni-1", gomock.Any()).
+		DoAndReturn(func(_ context.Context, _ time.Time, _ string, derive func(domain.CloudAssetState) (domain.CloudAssetChanges, error)) error {
+			changes, e := derive(domain.CloudAssetState{
+				PrivateIPAddresses: []domain.PrivateIPAssignment{{IPAddress: "10.0.0.1"}, {IPAddress: "10.0.0.3"}},
+			})
+			require.NoError(t, e)
+			assert.Equal(t, "eni-1/1", changes.EventID)
+			assert.Equal(t, []domain.NetworkChanges{
+				{PrivateIPAddresses: []string{"10.0.0.3"}, ChangeType: "DELETED"},
+				{PrivateIPAddresses: []string{"10.0.0.2"}, VPCID: "vpc-1", SubnetID: "subnet-1", ChangeType: "ADDED"},
+			}, changes.Changes)
+			return nil
+		})
+
+	res, e := newConfigurationItemInsertHandler(storer).Handle(context.Background(), validConfigurationItem())
+	require.NoError(t, e)
+	assert.False(t, res.Deduplicated)
+}
+
+func TestInsertConfigurationItemDuplicate(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	storer := NewMockCloudAssetStateStorer(ctrl)
+	storer.EXPECT().StoreFromState(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(deriveFrom(domain.CloudAssetState{}, domain.DuplicateEvent{EventID: "eni-1/1"}))
+
+	res, e := newConfigurationItemInsertHandler(storer).Handle(context.Background(), validConfigurationItem())
+	require.NoError(t, e)
+	assert.True(t, res.Deduplicated)
+}
+
+func TestInsertConfigurationItemStale(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	captureTime, _ := time.Parse(time.RFC3339, "2019-04-09T08:29:35Z")
+	storer := NewMockCloudAssetStateStorer(ctrl)
+	storer.EXPECT().StoreFromState(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(deriveFrom(domain.CloudAssetState{}, domain.StaleChange{ARN: "eni-1", ChangeTime: captureTime, Latest: captureTime.Add(time.Minute)}))
+
+	_, e := newConfigurationItemInsertHandler(storer).Handle(context.Background(), validConfigurationItem())
+	require.IsType(t, InvalidInput{}, e)
+	assert.Equal(t, "configurationItemCaptureTime", e.(InvalidInput).Field)
+}
+
+func TestInsertConfigurationItemStorageError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	storer := NewMockCloudAssetStateStorer(ctrl)
+	storer.EXPECT().StoreFromState(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(deriveFrom(domain.CloudAssetState{}, errors.New("")))
+
+	_, e := newConfigurationItemInsertHandler(storer).Handle(context.Background(), validConfigurationItem())
+	assert.Error(t, e)
+}
@@ -0,0 +1,37 @@
+package v1
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPageTokenConfigName(t *testing.T) {
+	assert.Equal(t, "PageToken", (&PageTokenConfig{}).Name())
+}
+
+func TestPageTokenComponentNew(t *testing.T) {
+	cmp := NewPageTokenComponent()
+	conf := cmp.Settings()
+	assert.Equal(t, 24*time.Hour, conf.TTL)
+
+	// the service starts without a secret, page tokens are disabled
+	signer, err := cmp.New(context.Background(), conf)
+	require.Nil(t, err)
+	_, err = signer.sign(pageCursor{Kind: bulkPageKind})
+	assert.Equal(t, errPageTokensDisabled, err)
+
+	conf.Secret = "secret"
+	conf.TTL = 0
+	_, err = cmp.New(context.Background(), conf)
+	assert.Error(t, err, "a positive TTL must be required")
+
+	conf.TTL = time.Minute
+	signer, err = cmp.New(context.Background(), conf)
+	require.Nil(t, err)
+	assert.Equal(t, []byte("secret"), signer.Secret)
+	assert.Equal(t, time.Minute, signer.TTL)
+}
@@ -1,3 +1,3 @@
 package v1
 
-//go:generate mockgen -destination mock_storage_test.go -package v1 github.com/asecurityteam/asset-inventory-api/pkg/domain CloudAssetStorer,CloudAssetByIPFetcher,CloudAssetByHostnameFetcher,CloudAssetByResourceIDFetcher,CloudAllAssetsByTimeFetcher,SchemaMigratorUp,SchemaMigratorDown,SchemaVersionGetter,SchemaVersionForcer,AccountOwnerStorer
+//go:generate mockgen -destination mock_storage_test.go -package v1 github.com/asecurityteam/asset-inventory-api/pkg/domain CloudAssetStorer,CloudAssetBatchStorer,CloudAssetByIPFetcher,CloudAssetByIPBatchFetcher,CloudAssetByHostnameFetcher,CloudAssetByHostnamePatternFetcher,CloudAssetByCIDRFetcher,CloudAssetBehindNATGatewayFetcher,CloudAssetByTagsFetcher,CloudAssetByResourceIDFetcher,CloudAssetHistoryFetcher,CloudAssetGraphFetcher,CloudAssetStateStorer,CloudAssetChangesReplayer,CloudAllAssetsByTimeFetcher,SchemaMigratorUp,SchemaMigratorDown,SchemaVersionGetter,SchemaVersionForcer,AccountOwnerStorer,PrivateIPClassifier
@@ -0,0 +1,122 @@
+package v1
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/asecurityteam/asset-inventory-api/pkg/domain"
+	"github.com/asecurityteam/asset-inventory-api/pkg/logs"
+)
+
+// KubernetesWorkloadChanges represents the incoming payload for changes to a kubernetes pod or service, identified by
+// its cluster, namespace and name. The IP addresses are assigned or released according to the change type, along with
+// the node instance a pod runs on. They are private or public according to the private ranges of the storage, as a
+// service of type LoadBalancer may have public ones.
+type KubernetesWorkloadChanges struct {
+	Kind           string            `json:"kind"`
+	Cluster        string            `json:"cluster"`
+	Namespace      string            `json:"namespace"`
+	Name           string            `json:"name"`
+	AccountID      string            `json:"accountId"`
+	Region         string            `json:"region"`
+	ChangeTime     string            `json:"changeTime"`
+	IPAddresses    []string          `json:"ipAddresses"`
+	NodeInstanceID string            `json:"nodeInstanceId"`
+	VPCID          string            `json:"vpcId"`
+	SubnetID       string            `json:"subnetId"`
+	ChangeType     string            `json:"changeType"`
+	Labels         map[string]string `json:"labels"`
+	Lifecycle      string            `json:"lifecycle"`
+	EventID        string            `json:"eventId"`
+}
+
+// KubernetesInsertHandler defines a lambda handler for storing changes to kubernetes workloads
+type KubernetesInsertHandler struct {
+	LogFn               domain.LogFn
+	StatFn              domain.StatFn
+	CloudAssetStorer    domain.CloudAssetStorer
+	PrivateIPClassifier domain.PrivateIPClassifier
+}
+
+// Handle handles the insert operation for kubernetes workloads
+func (h *KubernetesInsertHandler) Handle(ctx context.Context, input KubernetesWorkloadChanges) (CloudInsertResult, error) {
+	logger := h.LogFn(ctx)
+
+	assetChanges, e := kubernetesToDomainCloudAssetChanges(input, h.PrivateIPClassifier)
+	if e != nil {
+		logger.Info(logs.InvalidInput{Reason: e.Error()})
+		return CloudInsertResult{}, e
+	}
+	return StoreChanges(ctx, logger, h.CloudAssetStorer, assetChanges)
+}
+
+// kubernetesToDomainCloudAssetChanges validates the incoming payload and turns it into changes to the resource named
+// after the workload. The node a pod runs on is recorded as the resource the pod is attached to, so that lookups by
+// IP address report the node instance with the pod.
+func kubernetesToDomainCloudAssetChanges(input KubernetesWorkloadChanges, classifier domain.PrivateIPClassifier) (domain.CloudAssetChanges, error) {
+	kind := strings.ToLower(input.Kind)
+	var resourceType string
+	switch kind {
+	case domain.KubernetesKindPod:
+		resourceType = domain.ResourceTypeKubernetesPod
+	case domain.KubernetesKindService:
+		resourceType = domain.ResourceTypeKubernetesService
+	default:
+		return domain.CloudAssetChanges{}, InvalidInput{Field: "kind", Cause: fmt.Errorf("unknown kind %s", input.Kind)}
+	}
+	// the names make up the resource ID, in which they are separated by slashes
+	for _, name := range []struct{ field, value string }{
+		{"cluster", input.Cluster}, {"namespace", input.Namespace}, {"name", input.Name},
+	} {
+		if name.value == "" || strings.Contains(name.value, "/") {
+			return domain.CloudAssetChanges{}, InvalidInput{Field: name.field, Cause: fmt.Errorf("invalid %s %q", name.field, name.value)}
+		}
+	}
+	changeTime, lifecycle, e := ValidateChangeTimeAndLifecycle(input.ChangeTime, input.Lifecycle)
+	if e != nil {
+		return domain.CloudAssetChanges{}, e
+	}
+	if e = validateIPAddresses(input.IPAddresses); e != nil {
+		return domain.CloudAssetChanges{}, InvalidInput{Field: "ipAddresses", Cause: e}
+	}
+	if input.NodeInstanceID != "" && kind != domain.KubernetesKindPod {
+		e = fmt.Errorf("only pods run on a node")
+		return domain.CloudAssetChanges{}, InvalidInput{Field: "nodeInstanceId", Cause: e}
+	}
+	assetChanges := domain.CloudAssetChanges{
+		ChangeTime:   changeTime,
+		ResourceType: resourceType,
+		AccountID:    input.AccountID,
+		Region:       input.Region,
+		ARN:          domain.KubernetesARN(input.Region, input.AccountID, kind, input.Cluster, input.Namespace, input.Name),
+		Tags:         input.Labels,
+		Lifecycle:    lifecycle,
+		EventID:      input.EventID,
+		Changes:      make([]domain.NetworkChanges, 0, 1),
+	}
+	if len(input.IPAddresses) == 0 && input.NodeInstanceID == "" {
+		return assetChanges, nil
+	}
+	changeType, e := validateChangeType(input.ChangeType)
+	if e != nil {
+		return domain.CloudAssetChanges{}, InvalidInput{Field: "changeType", Cause: e}
+	}
+	networkChanges := domain.NetworkChanges{
+		VPCID:      input.VPCID,
+		SubnetID:   input.SubnetID,
+		ChangeType: changeType,
+	}
+	for _, ip := range input.IPAddresses {
+		if classifier.IsPrivateIP(ip) {
+			networkChanges.PrivateIPAddresses = append(networkChanges.PrivateIPAddresses, ip)
+		} else {
+			networkChanges.Addresses = append(networkChanges.Addresses, domain.AddressChange{IPAddress: ip, Public: true})
+		}
+	}
+	if input.NodeInstanceID != "" {
+		networkChanges.AttachedTo = []string{input.NodeInstanceID}
+	}
+	assetChanges.Changes = append(assetChanges.Changes, networkChanges)
+	return assetChanges, nil
+}
@@ -0,0 +1,191 @@
+package v1
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/asecurityteam/asset-inventory-api/pkg/domain"
+)
+
+func newKubernetesInsertHandler(storer domain.CloudAssetStorer, classifier domain.PrivateIPClassifier) *KubernetesInsertHandler {
+	return &KubernetesInsertHandler{
+		LogFn:               testLogFn,
+		StatFn:              testStatFn,
+		CloudAssetStorer:    storer,
+		PrivateIPClassifier: classifier,
+	}
+}
+
+// privateClassifier classifies the addresses as private when starting with 10.
+func privateClassifier(ctrl *gomock.Controller) domain.PrivateIPClassifier {
+	classifier := NewMockPrivateIPClassifier(ctrl)
+	classifier.EXPECT().IsPrivateIP(gomock.Any()).DoAndReturn(func(ip string) bool {
+		return strings.HasPrefix(ip, "10.")
+	}).AnyTimes()
+	return classifier
+}
+
+func validKubernetesInput() KubernetesWorkloadChanges {
+	return KubernetesWorkloadChanges{
+		Kind:           "pod",
+		Cluster:        "cluster",
+		Namespace:      "namespace",
+		Name:           "web-5d8f7c9b4-x2x7q",
+		AccountID:      "123456789012",
+		Region:         "us-west-2",
+		ChangeTime:     time.Now().Format(time.RFC3339Nano),
+		IPAddresses:    []string{"10.0.0.1"},
+		NodeInstanceID: "i-1",
+		VPCID:          "vpc-1",
+		ChangeType:     "ADDED",
+		Labels:         map[string]string{"app": "web"},
+	}
+}
+
+func TestKubernetesInsertInvalidInput(t *testing.T) {
+	tc := []struct {
+		name   string
+		modify func(*KubernetesWorkloadChanges)
+		field  string
+	}{
+		{"kind", func(i *KubernetesWorkloadChanges) { i.Kind = "deployment" }, "kind"},
+		{"cluster", func(i *KubernetesWorkloadChanges) { i.Cluster = "" }, "cluster"},
+		{"namespace", func(i *KubernetesWorkloadChanges) { i.Namespace = "name/space" }, "namespace"},
+		{"name", func(i *KubernetesWorkloadChanges) { i.Name = "" }, "name"},
+		{"changeTime", func(i *KubernetesWorkloadChanges) { i.ChangeTime = "not a timestamp" }, "changeTime"},
+		{"lifecycle", func(i *KubernetesWorkloadChanges) { i.Lifecycle = "PAUSED" }, "lifecycle"},
+		{"ipAddresses", func(i *KubernetesWorkloadChanges) { i.IPAddresses = []string{"not an IP"} }, "ipAddresses"},
+		{"node of a service", func(i *KubernetesWorkloadChanges) { i.Kind = "service" }, "nodeInstanceId"},
+		{"changeType", func(i *KubernetesWorkloadChanges) { i.ChangeType = "" }, "changeType"},
+	}
+	for _, tt := range tc {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			input := validKubernetesInput()
+			tt.modify(&input)
+			_, e := newKubernetesInsertHandler(nil, nil).Handle(context.Background(), input)
+			assert.NotNil(t, e)
+
+			invalid, ok := e.(InvalidInput)
+			assert.True(t, ok)
+			assert.Equal(t, tt.field, invalid.Field)
+		})
+	}
+}
+
+func TestKubernetesInsertStorageError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	storage := NewMockCloudAssetStorer(ctrl)
+	storage.EXPECT().Store(gomock.Any(), gomock.Any()).Return(errors.New(""))
+
+	_, e := newKubernetesInsertHandler(storage, privateClassifier(ctrl)).Handle(context.Background(), validKubernetesInput())
+	assert.NotNil(t, e)
+}
+
+func TestKubernetesInsertDuplicateEvent(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	input := validKubernetesInput()
+	input.EventID = "event-1"
+	storage := NewMockCloudAssetStorer(ctrl)
+	storage.EXPECT().Store(gomock.Any(), gomock.Any()).Return(domain.DuplicateEvent{EventID: "event-1"})
+
+	res, e := newKubernetesInsertHandler(storage, privateClassifier(ctrl)).Handle(context.Background(), input)
+	assert.Nil(t, e)
+	assert.True(t, res.Deduplicated)
+}
+
+func TestKubernetesInsertPod(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	input := validKubernetesInput()
+	changeTime, _ := time.Parse(time.RFC3339Nano, input.ChangeTime)
+	expected := domain.CloudAssetChanges{
+		ChangeTime:   changeTime,
+		ResourceType: domain.ResourceTypeKubernetesPod,
+		AccountID:    "123456789012",
+		Region:       "us-west-2",
+		ARN:          "arn:aws:eks:us-west-2:123456789012:pod/cluster/namespace/web-5d8f7c9b4-x2x7q",
+		Tags:         map[string]string{"app": "web"},
+		Changes: []domain.NetworkChanges{
+			{
+				PrivateIPAddresses: []string{"10.0.0.1"},
+				AttachedTo:         []string{"i-1"},
+				VPCID:              "vpc-1",
+				ChangeType:         "ADDED",
+			},
+		},
+	}
+	storage := NewMockCloudAssetStorer(ctrl)
+	storage.EXPECT().Store(gomock.Any(), expected).Return(nil)
+
+	res, e := newKubernetesInsertHandler(storage, privateClassifier(ctrl)).Handle(context.Background(), input)
+	assert.Nil(t, e)
+	assert.False(t, res.Deduplicated)
+}
+
+func TestKubernetesInsertServicePublicAddress(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	input := validKubernetesInput()
+	input.Kind = "service"
+	input.NodeInstanceID = ""
+	input.IPAddresses = []string{"10.0.0.1", "34.0.0.1"}
+	storage := NewMockCloudAssetStorer(ctrl)
+	storage.EXPECT().Store(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, changes domain.CloudAssetChanges) error {
+		assert.Equal(t, []domain.NetworkChanges{
+			{
+				PrivateIPAddresses: []string{"10.0.0.1"},
+				Addresses:          []domain.AddressChange{{IPAddress: "34.0.0.1", Public: true}},
+				VPCID:              "vpc-1",
+				ChangeType:         "ADDED",
+			},
+		}, changes.Changes)
+		return nil
+	})
+
+	_, e := newKubernetesInsertHandler(storage, privateClassifier(ctrl)).Handle(context.Background(), input)
+	assert.Nil(t, e)
+}
+
+func TestKubernetesInsertServiceTerminated(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	input := KubernetesWorkloadChanges{
+		Kind:       "Service",
+		Cluster:    "cluster",
+		Namespace:  "namespace",
+		Name:       "web",
+		AccountID:  "123456789012",
+		Region:     "us-west-2",
+		ChangeTime: time.Now().Format(time.RFC3339Nano),
+		Lifecycle:  "terminated",
+	}
+	changeTime, _ := time.Parse(time.RFC3339Nano, input.ChangeTime)
+	expected := domain.CloudAssetChanges{
+		ChangeTime:   changeTime,
+		ResourceType: domain.ResourceTypeKubernetesService,
+		AccountID:    "123456789012",
+		Region:       "us-west-2",
+		ARN:          "arn:aws:eks:us-west-2:123456789012:service/cluster/namespace/web",
+		Lifecycle:    domain.LifecycleTerminated,
+		Changes:      []domain.NetworkChanges{},
+	}
+	storage := NewMockCloudAssetStorer(ctrl)
+	storage.EXPECT().Store(gomock.Any(), expected).Return(nil)
+
+	_, e := newKubernetesInsertHandler(storage, privateClassifier(ctrl)).Handle(context.Background(), input)
+	assert.Nil(t, e)
+}
@@ -1,5 +1,5 @@
 // Code generated by MockGen. DO NOT EDIT.
-// Source: github.com/asecurityteam/asset-inventory-api/pkg/domain (interfaces: CloudAssetStorer,CloudAssetByIPFetcher,CloudAssetByHostnameFetcher,CloudAssetByResourceIDFetcher,CloudAllAssetsByTimeFetcher,SchemaMigratorUp,SchemaMigratorDown,SchemaVersionGetter,SchemaVersionForcer,AccountOwnerStorer)
+// Source: github.com/asecurityteam/asset-inventory-api/pkg/domain (interfaces: CloudAssetStorer,CloudAssetBatchStorer,CloudAssetByIPFetcher,CloudAssetByIPBatchFetcher,CloudAssetByHostnameFetcher,CloudAssetByHostnamePatternFetcher,CloudAssetByCIDRFetcher,CloudAssetBehindNATGatewayFetcher,CloudAssetByTagsFetcher,CloudAssetByResourceIDFetcher,CloudAssetHistoryFetcher,CloudAssetGraphFetcher,CloudAssetStateStorer,CloudAssetChangesReplayer,CloudAllAssetsByTimeFetcher,SchemaMigratorUp,SchemaMigratorDown,SchemaVersionGetter,SchemaVersionForcer,AccountOwnerStorer,PrivateIPClassifier)
 
 // Package v1 is a generated GoMock package.
 package v1
@@ -51,6 +51,44 @@ func (mr *MockCloudAssetStorerMockRecorder) Store(arg0, arg1 interface{}) *gomoc
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Store", reflect.TypeOf((*MockCloudAssetStorer)(nil).Store), arg0, arg1)
 }
 
+// MockCloudAssetBatchStorer is a mock of CloudAssetBatchStorer interface
+type MockCloudAssetBatchStorer struct {
+	ctrl     *gomock.Controller
+	recorder *MockCloudAssetBatchStorerMockRecorder
+}
+
+// MockCloudAssetBatchStorerMockRecorder is the mock recorder for MockCloudAssetBatchStorer
+type MockCloudAssetBatchStorerMockRecorder struct {
+	mock *MockCloudAssetBatchStorer
+}
+
+// NewMockCloudAssetBatchStorer creates a new mock instance
+func NewMockCloudAssetBatchStorer(ctrl *gomock.Controller) *MockCloudAssetBatchStorer {
+	mock := &MockCloudAssetBatchStorer{ctrl: ctrl}
+	mock.recorder = &MockCloudAssetBatchStorerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockCloudAssetBatchStorer) EXPECT() *MockCloudAssetBatchStorerMockRecorder {
+	return m.recorder
+}
+
+// StoreBatch mocks base method
+func (m *MockCloudAssetBatchStorer) StoreBatch(arg0 context.Context, arg1 []domain.CloudAssetChanges, arg2 bool) ([]error, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "StoreBatch", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]error)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// StoreBatch indicates an expected call of StoreBatch
+func (mr *MockCloudAssetBatchStorerMockRecorder) StoreBatch(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StoreBatch", reflect.TypeOf((*MockCloudAssetBatchStorer)(nil).StoreBatch), arg0, arg1, arg2)
+}
+
 // MockCloudAssetByIPFetcher is a mock of CloudAssetByIPFetcher interface
 type MockCloudAssetByIPFetcher struct {
 	ctrl     *gomock.Controller
@@ -75,18 +113,56 @@ func (m *MockCloudAssetByIPFetcher) EXPECT() *MockCloudAssetByIPFetcherMockRecor
 }
 
 // FetchByIP mocks base method
-func (m *MockCloudAssetByIPFetcher) FetchByIP(arg0 context.Context, arg1 time.Time, arg2 string) ([]domain.CloudAssetDetails, error) {
+func (m *MockCloudAssetByIPFetcher) FetchByIP(arg0 context.Context, arg1 time.Time, arg2 string, arg3 domain.IPScope) ([]domain.CloudAssetDetails, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "FetchByIP", arg0, arg1, arg2)
+	ret := m.ctrl.Call(m, "FetchByIP", arg0, arg1, arg2, arg3)
 	ret0, _ := ret[0].([]domain.CloudAssetDetails)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // FetchByIP indicates an expected call of FetchByIP
-func (mr *MockCloudAssetByIPFetcherMockRecorder) FetchByIP(arg0, arg1, arg2 interface{}) *gomock.Call {
+func (mr *MockCloudAssetByIPFetcherMockRecorder) FetchByIP(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FetchByIP", reflect.TypeOf((*MockCloudAssetByIPFetcher)(nil).FetchByIP), arg0, arg1, arg2)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FetchByIP", reflect.TypeOf((*MockCloudAssetByIPFetcher)(nil).FetchByIP), arg0, arg1, arg2, arg3)
+}
+
+// MockCloudAssetByIPBatchFetcher is a mock of CloudAssetByIPBatchFetcher interface
+type MockCloudAssetByIPBatchFetcher struct {
+	ctrl     *gomock.Controller
+	recorder *MockCloudAssetByIPBatchFetcherMockRecorder
+}
+
+// MockCloudAssetByIPBatchFetcherMockRecorder is the mock recorder for MockCloudAssetByIPBatchFetcher
+type MockCloudAssetByIPBatchFetcherMockRecorder struct {
+	mock *MockCloudAssetByIPBatchFetcher
+}
+
+// NewMockCloudAssetByIPBatchFetcher creates a new mock instance
+func NewMockCloudAssetByIPBatchFetcher(ctrl *gomock.Controller) *MockCloudAssetByIPBatchFetcher {
+	mock := &MockCloudAssetByIPBatchFetcher{ctrl: ctrl}
+	mock.recorder = &MockCloudAssetByIPBatchFetcherMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockCloudAssetByIPBatchFetcher) EXPECT() *MockCloudAssetByIPBatchFetcherMockRecorder {
+	return m.recorder
+}
+
+// FetchByIPs mocks base method
+func (m *MockCloudAssetByIPBatchFetcher) FetchByIPs(arg0 context.Context, arg1 []domain.IPLookup) ([][]domain.CloudAssetDetails, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FetchByIPs", arg0, arg1)
+	ret0, _ := ret[0].([][]domain.CloudAssetDetails)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FetchByIPs indicates an expected call of FetchByIPs
+func (mr *MockCloudAssetByIPBatchFetcherMockRecorder) FetchByIPs(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FetchByIPs", reflect.TypeOf((*MockCloudAssetByIPBatchFetcher)(nil).FetchByIPs), arg0, arg1)
 }
 
 // MockCloudAssetByHostnameFetcher is a mock of CloudAssetByHostnameFetcher interface
@@ -127,6 +203,162 @@ func (mr *MockCloudAssetByHostnameFetcherMockRecorder) FetchByHostname(arg0, arg
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FetchByHostname", reflect.TypeOf((*MockCloudAssetByHostnameFetcher)(nil).FetchByHostname), arg0, arg1, arg2)
 }
 
+// MockCloudAssetByHostnamePatternFetcher is a mock of CloudAssetByHostnamePatternFetcher interface
+type MockCloudAssetByHostnamePatternFetcher struct {
+	ctrl     *gomock.Controller
+	recorder *MockCloudAssetByHostnamePatternFetcherMockRecorder
+}
+
+// MockCloudAssetByHostnamePatternFetcherMockRecorder is the mock recorder for MockCloudAssetByHostnamePatternFetcher
+type MockCloudAssetByHostnamePatternFetcherMockRecorder struct {
+	mock *MockCloudAssetByHostnamePatternFetcher
+}
+
+// NewMockCloudAssetByHostnamePatternFetcher creates a new mock instance
+func NewMockCloudAssetByHostnamePatternFetcher(ctrl *gomock.Controller) *MockCloudAssetByHostnamePatternFetcher {
+	mock := &MockCloudAssetByHostnamePatternFetcher{ctrl: ctrl}
+	mock.recorder = &MockCloudAssetByHostnamePatternFetcherMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockCloudAssetByHostnamePatternFetcher) EXPECT() *MockCloudAssetByHostnamePatternFetcherMockRecorder {
+	return m.recorder
+}
+
+// FetchByHostnamePattern mocks base method
+func (m *MockCloudAssetByHostnamePatternFetcher) FetchByHostnamePattern(arg0 context.Context, arg1 time.Time, arg2, arg3 string, arg4 uint, arg5 int64) ([]domain.CloudAssetDetails, int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FetchByHostnamePattern", arg0, arg1, arg2, arg3, arg4, arg5)
+	ret0, _ := ret[0].([]domain.CloudAssetDetails)
+	ret1, _ := ret[1].(int64)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// FetchByHostnamePattern indicates an expected call of FetchByHostnamePattern
+func (mr *MockCloudAssetByHostnamePatternFetcherMockRecorder) FetchByHostnamePattern(arg0, arg1, arg2, arg3, arg4, arg5 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FetchByHostnamePattern", reflect.TypeOf((*MockCloudAssetByHostnamePatternFetcher)(nil).FetchByHostnamePattern), arg0, arg1, arg2, arg3, arg4, arg5)
+}
+
+// MockCloudAssetByCIDRFetcher is a mock of CloudAssetByCIDRFetcher interface
+type MockCloudAssetByCIDRFetcher struct {
+	ctrl     *gomock.Controller
+	recorder *MockCloudAssetByCIDRFetcherMockRecorder
+}
+
+// MockCloudAssetByCIDRFetcherMockRecorder is the mock recorder for MockCloudAssetByCIDRFetcher
+type MockCloudAssetByCIDRFetcherMockRecorder struct {
+	mock *MockCloudAssetByCIDRFetcher
+}
+
+// NewMockCloudAssetByCIDRFetcher creates a new mock instance
+func NewMockCloudAssetByCIDRFetcher(ctrl *gomock.Controller) *MockCloudAssetByCIDRFetcher {
+	mock := &MockCloudAssetByCIDRFetcher{ctrl: ctrl}
+	mock.recorder = &MockCloudAssetByCIDRFetcherMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockCloudAssetByCIDRFetcher) EXPECT() *MockCloudAssetByCIDRFetcherMockRecorder {
+	return m.recorder
+}
+
+// FetchByCIDR mocks base method
+func (m *MockCloudAssetByCIDRFetcher) FetchByCIDR(arg0 context.Context, arg1 time.Time, arg2 string, arg3 uint, arg4 int64) ([]domain.CloudAssetDetails, int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FetchByCIDR", arg0, arg1, arg2, arg3, arg4)
+	ret0, _ := ret[0].([]domain.CloudAssetDetails)
+	ret1, _ := ret[1].(int64)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// FetchByCIDR indicates an expected call of FetchByCIDR
+func (mr *MockCloudAssetByCIDRFetcherMockRecorder) FetchByCIDR(arg0, arg1, arg2, arg3, arg4 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FetchByCIDR", reflect.TypeOf((*MockCloudAssetByCIDRFetcher)(nil).FetchByCIDR), arg0, arg1, arg2, arg3, arg4)
+}
+
+// MockCloudAssetBehindNATGatewayFetcher is a mock of CloudAssetBehindNATGatewayFetcher interface
+type MockCloudAssetBehindNATGatewayFetcher struct {
+	ctrl     *gomock.Controller
+	recorder *MockCloudAssetBehindNATGatewayFetcherMockRecorder
+}
+
+// MockCloudAssetBehindNATGatewayFetcherMockRecorder is the mock recorder for MockCloudAssetBehindNATGatewayFetcher
+type MockCloudAssetBehindNATGatewayFetcherMockRecorder struct {
+	mock *MockCloudAssetBehindNATGatewayFetcher
+}
+
+// NewMockCloudAssetBehindNATGatewayFetcher creates a new mock instance
+func NewMockCloudAssetBehindNATGatewayFetcher(ctrl *gomock.Controller) *MockCloudAssetBehindNATGatewayFetcher {
+	mock := &MockCloudAssetBehindNATGatewayFetcher{ctrl: ctrl}
+	mock.recorder = &MockCloudAssetBehindNATGatewayFetcherMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockCloudAssetBehindNATGatewayFetcher) EXPECT() *MockCloudAssetBehindNATGatewayFetcherMockRecorder {
+	return m.recorder
+}
+
+// FetchBehindNATGateway mocks base method
+func (m *MockCloudAssetBehindNATGatewayFetcher) FetchBehindNATGateway(arg0 context.Context, arg1 time.Time, arg2 string, arg3 uint, arg4 int64) ([]domain.CloudAssetDetails, int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FetchBehindNATGateway", arg0, arg1, arg2, arg3, arg4)
+	ret0, _ := ret[0].([]domain.CloudAssetDetails)
+	ret1, _ := ret[1].(int64)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// FetchBehindNATGateway indicates an expected call of FetchBehindNATGateway
+func (mr *MockCloudAssetBehindNATGatewayFetcherMockRecorder) FetchBehindNATGateway(arg0, arg1, arg2, arg3, arg4 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FetchBehindNATGateway", reflect.TypeOf((*MockCloudAssetBehindNATGatewayFetcher)(nil).FetchBehindNATGateway), arg0, arg1, arg2, arg3, arg4)
+}
+
+// MockCloudAssetByTagsFetcher is a mock of CloudAssetByTagsFetcher interface
+type MockCloudAssetByTagsFetcher struct {
+	ctrl     *gomock.Controller
+	recorder *MockCloudAssetByTagsFetcherMockRecorder
+}
+
+// MockCloudAssetByTagsFetcherMockRecorder is the mock recorder for MockCloudAssetByTagsFetcher
+type MockCloudAssetByTagsFetcherMockRecorder struct {
+	mock *MockCloudAssetByTagsFetcher
+}
+
+// NewMockCloudAssetByTagsFetcher creates a new mock instance
+func NewMockCloudAssetByTagsFetcher(ctrl *gomock.Controller) *MockCloudAssetByTagsFetcher {
+	mock := &MockCloudAssetByTagsFetcher{ctrl: ctrl}
+	mock.recorder = &MockCloudAssetByTagsFetcherMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockCloudAssetByTagsFetcher) EXPECT() *MockCloudAssetByTagsFetcherMockRecorder {
+	return m.recorder
+}
+
+// FetchByTags mocks base method
+func (m *MockCloudAssetByTagsFetcher) FetchByTags(arg0 context.Context, arg1 time.Time, arg2 []domain.TagPredicate, arg3 uint, arg4 int64) ([]domain.CloudAssetDetails, int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FetchByTags", arg0, arg1, arg2, arg3, arg4)
+	ret0, _ := ret[0].([]domain.CloudAssetDetails)
+	ret1, _ := ret[1].(int64)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// FetchByTags indicates an expected call of FetchByTags
+func (mr *MockCloudAssetByTagsFetcherMockRecorder) FetchByTags(arg0, arg1, arg2, arg3, arg4 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FetchByTags", reflect.TypeOf((*MockCloudAssetByTagsFetcher)(nil).FetchByTags), arg0, arg1, arg2, arg3, arg4)
+}
+
 // MockCloudAssetByResourceIDFetcher is a mock of CloudAssetByResourceIDFetcher interface
 type MockCloudAssetByResourceIDFetcher struct {
 	ctrl     *gomock.Controller
@@ -165,6 +397,157 @@ func (mr *MockCloudAssetByResourceIDFetcherMockRecorder) FetchByResourceID(arg0,
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FetchByResourceID", reflect.TypeOf((*MockCloudAssetByResourceIDFetcher)(nil).FetchByResourceID), arg0, arg1, arg2)
 }
 
+// MockCloudAssetHistoryFetcher is a mock of CloudAssetHistoryFetcher interface
+type MockCloudAssetHistoryFetcher struct {
+	ctrl     *gomock.Controller
+	recorder *MockCloudAssetHistoryFetcherMockRecorder
+}
+
+// MockCloudAssetHistoryFetcherMockRecorder is the mock recorder for MockCloudAssetHistoryFetcher
+type MockCloudAssetHistoryFetcherMockRecorder struct {
+	mock *MockCloudAssetHistoryFetcher
+}
+
+// NewMockCloudAssetHistoryFetcher creates a new mock instance
+func NewMockCloudAssetHistoryFetcher(ctrl *gomock.Controller) *MockCloudAssetHistoryFetcher {
+	mock := &MockCloudAssetHistoryFetcher{ctrl: ctrl}
+	mock.recorder = &MockCloudAssetHistoryFetcherMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockCloudAssetHistoryFetcher) EXPECT() *MockCloudAssetHistoryFetcherMockRecorder {
+	return m.recorder
+}
+
+// FetchHistoryByResourceID mocks base method
+func (m *MockCloudAssetHistoryFetcher) FetchHistoryByResourceID(arg0 context.Context, arg1 string) ([]domain.CloudAssetHistoryEntry, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FetchHistoryByResourceID", arg0, arg1)
+	ret0, _ := ret[0].([]domain.CloudAssetHistoryEntry)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FetchHistoryByResourceID indicates an expected call of FetchHistoryByResourceID
+func (mr *MockCloudAssetHistoryFetcherMockRecorder) FetchHistoryByResourceID(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FetchHistoryByResourceID", reflect.TypeOf((*MockCloudAssetHistoryFetcher)(nil).FetchHistoryByResourceID), arg0, arg1)
+}
+
+// MockCloudAssetGraphFetcher is a mock of CloudAssetGraphFetcher interface
+type MockCloudAssetGraphFetcher struct {
+	ctrl     *gomock.Controller
+	recorder *MockCloudAssetGraphFetcherMockRecorder
+}
+
+// MockCloudAssetGraphFetcherMockRecorder is the mock recorder for MockCloudAssetGraphFetcher
+type MockCloudAssetGraphFetcherMockRecorder struct {
+	mock *MockCloudAssetGraphFetcher
+}
+
+// NewMockCloudAssetGraphFetcher creates a new mock instance
+func NewMockCloudAssetGraphFetcher(ctrl *gomock.Controller) *MockCloudAssetGraphFetcher {
+	mock := &MockCloudAssetGraphFetcher{ctrl: ctrl}
+	mock.recorder = &MockCloudAssetGraphFetcherMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockCloudAssetGraphFetcher) EXPECT() *MockCloudAssetGraphFetcherMockRecorder {
+	return m.recorder
+}
+
+// FetchGraph mocks base method
+func (m *MockCloudAssetGraphFetcher) FetchGraph(arg0 context.Context, arg1 time.Time, arg2, arg3 string, arg4 uint) (domain.ResourceGraph, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FetchGraph", arg0, arg1, arg2, arg3, arg4)
+	ret0, _ := ret[0].(domain.ResourceGraph)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FetchGraph indicates an expected call of FetchGraph
+func (mr *MockCloudAssetGraphFetcherMockRecorder) FetchGraph(arg0, arg1, arg2, arg3, arg4 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FetchGraph", reflect.TypeOf((*MockCloudAssetGraphFetcher)(nil).FetchGraph), arg0, arg1, arg2, arg3, arg4)
+}
+
+// MockCloudAssetStateStorer is a mock of CloudAssetStateStorer interface
+type MockCloudAssetStateStorer struct {
+	ctrl     *gomock.Controller
+	recorder *MockCloudAssetStateStorerMockRecorder
+}
+
+// MockCloudAssetStateStorerMockRecorder is the mock recorder for MockCloudAssetStateStorer
+type MockCloudAssetStateStorerMockRecorder struct {
+	mock *MockCloudAssetStateStorer
+}
+
+// NewMockCloudAssetStateStorer creates a new mock instance
+func NewMockCloudAssetStateStorer(ctrl *gomock.Controller) *MockCloudAssetStateStorer {
+	mock := &MockCloudAssetStateStorer{ctrl: ctrl}
+	mock.recorder = &MockCloudAssetStateStorerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockCloudAssetStateStorer) EXPECT() *MockCloudAssetStateStorerMockRecorder {
+	return m.recorder
+}
+
+// StoreFromState mocks base method
+func (m *MockCloudAssetStateStorer) StoreFromState(arg0 context.Context, arg1 time.Time, arg2 string, arg3 func(domain.CloudAssetState) (domain.CloudAssetChanges, error)) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "StoreFromState", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// StoreFromState indicates an expected call of StoreFromState
+func (mr *MockCloudAssetStateStorerMockRecorder) StoreFromState(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StoreFromState", reflect.TypeOf((*MockCloudAssetStateStorer)(nil).StoreFromState), arg0, arg1, arg2, arg3)
+}
+
+// MockCloudAssetChangesReplayer is a mock of CloudAssetChangesReplayer interface
+type MockCloudAssetChangesReplayer struct {
+	ctrl     *gomock.Controller
+	recorder *MockCloudAssetChangesReplayerMockRecorder
+}
+
+// MockCloudAssetChangesReplayerMockRecorder is the mock recorder for MockCloudAssetChangesReplayer
+type MockCloudAssetChangesReplayerMockRecorder struct {
+	mock *MockCloudAssetChangesReplayer
+}
+
+// NewMockCloudAssetChangesReplayer creates a new mock instance
+func NewMockCloudAssetChangesReplayer(ctrl *gomock.Controller) *MockCloudAssetChangesReplayer {
+	mock := &MockCloudAssetChangesReplayer{ctrl: ctrl}
+	mock.recorder = &MockCloudAssetChangesReplayerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockCloudAssetChangesReplayer) EXPECT() *MockCloudAssetChangesReplayerMockRecorder {
+	return m.recorder
+}
+
+// Replay mocks base method
+func (m *MockCloudAssetChangesReplayer) Replay(arg0 context.Context, arg1 domain.ReplayScope) (domain.ReplayResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Replay", arg0, arg1)
+	ret0, _ := ret[0].(domain.ReplayResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Replay indicates an expected call of Replay
+func (mr *MockCloudAssetChangesReplayerMockRecorder) Replay(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Replay", reflect.TypeOf((*MockCloudAssetChangesReplayer)(nil).Replay), arg0, arg1)
+}
+
 // MockCloudAllAssetsByTimeFetcher is a mock of CloudAllAssetsByTimeFetcher interface
 type MockCloudAllAssetsByTimeFetcher struct {
 	ctrl     *gomock.Controller
@@ -189,12 +572,13 @@ func (m *MockCloudAllAssetsByTimeFetcher) EXPECT() *MockCloudAllAssetsByTimeFetc
 }
 
 // FetchAll mocks base method
-func (m *MockCloudAllAssetsByTimeFetcher) FetchAll(arg0 context.Context, arg1 time.Time, arg2, arg3 uint, arg4 string) ([]domain.CloudAssetDetails, error) {
+func (m *MockCloudAllAssetsByTimeFetcher) FetchAll(arg0 context.Context, arg1 time.Time, arg2 uint, arg3 int64, arg4 string) ([]domain.CloudAssetDetails, int64, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "FetchAll", arg0, arg1, arg2, arg3, arg4)
 	ret0, _ := ret[0].([]domain.CloudAssetDetails)
-	ret1, _ := ret[1].(error)
-	return ret0, ret1
+	ret1, _ := ret[1].(int64)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
 }
 
 // FetchAll indicates an expected call of FetchAll
@@ -391,3 +775,40 @@ func (mr *MockAccountOwnerStorerMockRecorder) StoreAccountOwner(arg0, arg1 inter
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StoreAccountOwner", reflect.TypeOf((*MockAccountOwnerStorer)(nil).StoreAccountOwner), arg0, arg1)
 }
+
+// MockPrivateIPClassifier is a mock of PrivateIPClassifier interface
+type MockPrivateIPClassifier struct {
+	ctrl     *gomock.Controller
+	recorder *MockPrivateIPClassifierMockRecorder
+}
+
+// MockPrivateIPClassifierMockRecorder is the mock recorder for MockPrivateIPClassifier
+type MockPrivateIPClassifierMockRecorder struct {
+	mock *MockPrivateIPClassifier
+}
+
+// NewMockPrivateIPClassifier creates a new mock instance
+func NewMockPrivateIPClassifier(ctrl *gomock.Controller) *MockPrivateIPClassifier {
+	mock := &MockPrivateIPClassifier{ctrl: ctrl}
+	mock.recorder = &MockPrivateIPClassifierMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockPrivateIPClassifier) EXPECT() *MockPrivateIPClassifierMockRecorder {
+	return m.recorder
+}
+
+// IsPrivateIP mocks base method
+func (m *MockPrivateIPClassifier) IsPrivateIP(arg0 string) bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IsPrivateIP", arg0)
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// IsPrivateIP indicates an expected call of IsPrivateIP
+func (mr *MockPrivateIPClassifierMockRecorder) IsPrivateIP(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsPrivateIP", reflect.TypeOf((*MockPrivateIPClassifier)(nil).IsPrivateIP), arg0)
+}
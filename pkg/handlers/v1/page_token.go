@@ -0,0 +1,120 @@
+package v1
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/asecurityteam/asset-inventory-api/pkg/domain"
+	"github.com/asecurityteam/asset-inventory-api/pkg/logs"
+)
+
+var (
+	errMalformedPageToken = errors.New("malformed pageToken")
+	errPageTokenSignature = errors.New("pageToken signature mismatch")
+	errPageTokenExpired   = errors.New("pageToken expired")
+	errPageTokenKind      = errors.New("pageToken issued for a different listing")
+	errPageTokensDisabled = errors.New("paged listings are disabled, the page token secret is not set")
+)
+
+var pageTokenEncoding = base64.RawURLEncoding
+
+// pageCursor is the position within a paged listing carried inside a page token.
+// Field names are kept short as the token ends up in URLs.
+type pageCursor struct {
+	Kind      string `json:"k"`
+	Timestamp string `json:"t"`
+	Count     uint   `json:"c"`
+	After     int64  `json:"a"`
+	Filter    string `json:"f,omitempty"`
+	Expires   int64  `json:"e"`
+}
+
+// PageTokenSigner issues and verifies the opaque page tokens handed out by paged listings.
+// Tokens are signed with HMAC-SHA256 so callers can not alter the position, filter or expiry they carry.
+// Without a secret no token is issued or accepted, so only listings fitting in their first page succeed.
+type PageTokenSigner struct {
+	Secret []byte
+	TTL    time.Duration
+	now    func() time.Time // unit test seam
+}
+
+func (s *PageTokenSigner) currentTime() time.Time {
+	if s.now == nil {
+		return time.Now()
+	}
+	return s.now()
+}
+
+// sign stamps the cursor with an expiry and encodes it as payload.signature
+func (s *PageTokenSigner) sign(cursor pageCursor) (string, error) {
+	if len(s.Secret) == 0 {
+		return "", errPageTokensDisabled
+	}
+	cursor.Expires = s.currentTime().Add(s.TTL).Unix()
+	js, err := json.Marshal(cursor)
+	if err != nil {
+		return "", err
+	}
+	payload := pageTokenEncoding.EncodeToString(js)
+	return payload + "." + pageTokenEncoding.EncodeToString(s.mac(payload)), nil
+}
+
+// verify checks the signature and expiry of a token and returns the cursor it carries if it was issued for kind
+func (s *PageTokenSigner) verify(token string, kind string) (pageCursor, error) {
+	if len(s.Secret) == 0 {
+		return pageCursor{}, errPageTokensDisabled
+	}
+	parts := strings.Split(token, ".")
+	if len(parts) != 2 {
+		return pageCursor{}, errMalformedPageToken
+	}
+	signature, err := pageTokenEncoding.DecodeString(parts[1])
+	if err != nil {
+		return pageCursor{}, errMalformedPageToken
+	}
+	if !hmac.Equal(signature, s.mac(parts[0])) {
+		return pageCursor{}, errPageTokenSignature
+	}
+	js, err := pageTokenEncoding.DecodeString(parts[0])
+	if err != nil {
+		return pageCursor{}, errMalformedPageToken
+	}
+	cursor := pageCursor{}
+	if err = json.Unmarshal(js, &cursor); err != nil {
+		return pageCursor{}, errMalformedPageToken
+	}
+	if cursor.Kind != kind {
+		return pageCursor{}, errPageTokenKind
+	}
+	if s.currentTime().Unix() > cursor.Expires {
+		return pageCursor{}, errPageTokenExpired
+	}
+	return cursor, nil
+}
+
+func (s *PageTokenSigner) mac(payload string) []byte {
+	h := hmac.New(sha256.New, s.Secret)
+	_, _ = h.Write([]byte(payload)) // writes to a hash never fail
+	return h.Sum(nil)
+}
+
+// verifyPageToken checks the token of a following page. A token failing verification is reported as malformed without
+// revealing why, while the specific error is still logged.
+func verifyPageToken(logger domain.Logger, signer *PageTokenSigner, token string, kind string) (pageCursor, error) {
+	cursor, e := signer.verify(token, kind)
+	switch e {
+	case nil:
+		return cursor, nil
+	case errPageTokensDisabled:
+		logger.Error(logs.StorageError{Reason: e.Error()})
+		return pageCursor{}, e
+	default:
+		logger.Info(logs.InvalidInput{Reason: e.Error()})
+		return pageCursor{}, InvalidInput{Field: "pageToken", Cause: errMalformedPageToken}
+	}
+}
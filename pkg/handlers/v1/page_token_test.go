@@ -0,0 +1,69 @@
+package v1
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testPageTokenSigner() *PageTokenSigner {
+	return &PageTokenSigner{
+		Secret: []byte("secret"),
+		TTL:    time.Hour,
+	}
+}
+
+func TestPageTokenRoundTrip(t *testing.T) {
+	signer := testPageTokenSigner()
+	cursor := pageCursor{
+		Kind:      bulkPageKind,
+		Timestamp: "2019-04-09T08:55:35Z",
+		Count:     10,
+		After:     1234,
+		Filter:    awsEC2,
+	}
+	token, err := signer.sign(cursor)
+	require.Nil(t, err)
+	assert.NotContains(t, token, "/")
+
+	actual, err := signer.verify(token, bulkPageKind)
+	require.Nil(t, err)
+	cursor.Expires = actual.Expires
+	assert.Equal(t, cursor, actual)
+	assert.InDelta(t, time.Now().Add(time.Hour).Unix(), actual.Expires, 5)
+}
+
+func TestPageTokenVerifyErrors(t *testing.T) {
+	signer := testPageTokenSigner()
+	token, _ := signer.sign(pageCursor{Kind: bulkPageKind, After: 1})
+	parts := strings.Split(token, ".")
+	unsignedPayload := pageTokenEncoding.EncodeToString([]byte(`{"k":"bulk","a":2,"e":99999999999}`))
+	notJSON := pageTokenEncoding.EncodeToString([]byte("this is not json"))
+
+	expired := testPageTokenSigner()
+	expired.now = func() time.Time { return time.Now().Add(-2 * time.Hour) }
+	expiredToken, _ := expired.sign(pageCursor{Kind: bulkPageKind, After: 1})
+
+	tests := []struct {
+		name  string
+		token string
+		kind  string
+		want  error
+	}{
+		{"NoSeparator", parts[0], bulkPageKind, errMalformedPageToken},
+		{"BadSignatureEncoding", parts[0] + ".$$$", bulkPageKind, errMalformedPageToken},
+		{"ChangedPayload", unsignedPayload + "." + parts[1], bulkPageKind, errPageTokenSignature},
+		{"NotJSON", notJSON + "." + pageTokenEncoding.EncodeToString(signer.mac(notJSON)), bulkPageKind, errMalformedPageToken},
+		{"OtherKind", token, "other", errPageTokenKind},
+		{"Expired", expiredToken, bulkPageKind, errPageTokenExpired},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := signer.verify(tt.token, tt.kind)
+			assert.Equal(t, tt.want, err)
+		})
+	}
+}
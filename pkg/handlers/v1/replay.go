@@ -0,0 +1,46 @@
+package v1
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/asecurityteam/asset-inventory-api/pkg/domain"
+	"github.com/asecurityteam/asset-inventory-api/pkg/logs"
+)
+
+// ReplayParameters represents the scope of a replay of the change journal, either a resource ID or an account and
+// region. Larger replays are long running operations, left to the replay command.
+type ReplayParameters struct {
+	ResourceID string `json:"resourceId"`
+	AccountID  string `json:"accountId"`
+	Region     string `json:"region"`
+}
+
+// ReplayResult represents the amount of resources rebuilt and of changes applied by a replay
+type ReplayResult struct {
+	Resources int `json:"resources"`
+	Events    int `json:"events"`
+}
+
+// ReplayHandler defines a lambda handler for rebuilding resources from the change journal
+type ReplayHandler struct {
+	LogFn    domain.LogFn
+	Replayer domain.CloudAssetChangesReplayer
+}
+
+// Handle handles the call to rebuild resources from the change journal
+func (h *ReplayHandler) Handle(ctx context.Context, input ReplayParameters) (ReplayResult, error) {
+	logger := h.LogFn(ctx)
+	if input.ResourceID == "" && (input.AccountID == "" || input.Region == "") {
+		e := fmt.Errorf("a resource ID, or an account ID and a region, are required")
+		logger.Info(logs.InvalidInput{Reason: e.Error()})
+		return ReplayResult{}, InvalidInput{Field: "scope", Cause: e}
+	}
+	result, e := h.Replayer.Replay(ctx, domain.ReplayScope{ResourceID: input.ResourceID, AccountID: input.AccountID, Region: input.Region})
+	if e != nil {
+		logger.Error(logs.StorageError{Reason: e.Error()})
+		return ReplayResult{}, e
+	}
+	logger.Info(logs.ReplayComplete{Resources: result.Resources, Events: result.Events})
+	return ReplayResult{Resources: result.Resources, Events: result.Events}, nil
+}
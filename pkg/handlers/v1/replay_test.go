@@ -0,0 +1,59 @@
+package v1
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/asecurityteam/asset-inventory-api/pkg/domain"
+)
+
+func TestReplayHandlerNoScope(t *testing.T) {
+	handler := ReplayHandler{
+		LogFn: testLogFn,
+	}
+
+	// everything, or a whole account, is only replayed by the replay command
+	for _, input := range []ReplayParameters{{}, {AccountID: "aid"}, {Region: "region"}} {
+		_, err := handler.Handle(context.Background(), input)
+		require.Error(t, err)
+		assert.IsType(t, InvalidInput{}, err)
+	}
+}
+
+func TestReplayHandlerAccountRegion(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockReplayer := NewMockCloudAssetChangesReplayer(ctrl)
+	mockReplayer.EXPECT().Replay(gomock.Any(), domain.ReplayScope{AccountID: "aid", Region: "region"}).Return(domain.ReplayResult{Resources: 1, Events: 3}, nil)
+
+	handler := ReplayHandler{
+		LogFn:    testLogFn,
+		Replayer: mockReplayer,
+	}
+
+	result, err := handler.Handle(context.Background(), ReplayParameters{AccountID: "aid", Region: "region"})
+	require.NoError(t, err)
+	assert.Equal(t, ReplayResult{Resources: 1, Events: 3}, result)
+}
+
+func TestReplayHandlerErr(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockReplayer := NewMockCloudAssetChangesReplayer(ctrl)
+	mockReplayer.EXPECT().Replay(gomock.Any(), domain.ReplayScope{ResourceID: "i-1"}).Return(domain.ReplayResult{}, errors.New("error replaying"))
+
+	handler := ReplayHandler{
+		LogFn:    testLogFn,
+		Replayer: mockReplayer,
+	}
+
+	_, err := handler.Handle(context.Background(), ReplayParameters{ResourceID: "i-1"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,135 @@
+package v1
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/asecurityteam/asset-inventory-api/pkg/domain"
+	"github.com/asecurityteam/asset-inventory-api/pkg/logs"
+)
+
+// WorkloadChanges represents the incoming payload for changes to an ECS task or a Lambda function, whose network
+// interfaces are assigned or released according to the change type. The cluster, task definition and service of a
+// task are recorded by resource ID, whether given as ARNs or as such, from the first change giving them.
+type WorkloadChanges struct {
+	ResourceType      string                     `json:"resourceType"`
+	ARN               string                     `json:"arn"`
+	AccountID         string                     `json:"accountId"`
+	Region            string                     `json:"region"`
+	ChangeTime        string                     `json:"changeTime"`
+	Cluster           string                     `json:"cluster"`
+	TaskDefinition    string                     `json:"taskDefinition"`
+	Service           string                     `json:"service"`
+	NetworkInterfaces []WorkloadNetworkInterface `json:"networkInterfaces"`
+	ChangeType        string                     `json:"changeType"`
+	Tags              map[string]string          `json:"tags"`
+	Lifecycle         string                     `json:"lifecycle"`
+	EventID           string                     `json:"eventId"`
+}
+
+// WorkloadNetworkInterface is a network interface of a workload, with the private IP addresses it holds
+type WorkloadNetworkInterface struct {
+	NetworkInterfaceID string   `json:"networkInterfaceId"`
+	PrivateIPAddresses []string `json:"privateIpAddresses"`
+	VPCID              string   `json:"vpcId"`
+	SubnetID           string   `json:"subnetId"`
+}
+
+// WorkloadInsertHandler defines a lambda handler for storing changes to ECS tasks and Lambda functions
+type WorkloadInsertHandler struct {
+	LogFn            domain.LogFn
+	StatFn           domain.StatFn
+	CloudAssetStorer domain.CloudAssetStorer
+}
+
+// Handle handles the insert operation for ECS tasks and Lambda functions
+func (h *WorkloadInsertHandler) Handle(ctx context.Context, input WorkloadChanges) (CloudInsertResult, error) {
+	logger := h.LogFn(ctx)
+
+	assetChanges, e := workloadToDomainCloudAssetChanges(input)
+	if e != nil {
+		logger.Info(logs.InvalidInput{Reason: e.Error()})
+		return CloudInsertResult{}, e
+	}
+	return StoreChanges(ctx, logger, h.CloudAssetStorer, assetChanges)
+}
+
+// workloadToDomainCloudAssetChanges validates the incoming payload and turns it into changes to the workload. The
+// workload holds the addresses of its network interfaces, and is related to each of them, so that lookups by IP
+// address return the workload rather than only the network interface.
+func workloadToDomainCloudAssetChanges(input WorkloadChanges) (domain.CloudAssetChanges, error) {
+	switch input.ResourceType {
+	case awsECS:
+	case awsFn:
+		if input.Cluster != "" || input.TaskDefinition != "" || input.Service != "" {
+			e := fmt.Errorf("only ECS tasks have a cluster, task definition or service")
+			return domain.CloudAssetChanges{}, InvalidInput{Field: "cluster", Cause: e}
+		}
+	default:
+		e := fmt.Errorf("unknown workload type %s", input.ResourceType)
+		return domain.CloudAssetChanges{}, InvalidInput{Field: "resourceType", Cause: e}
+	}
+	if input.ARN == "" {
+		return domain.CloudAssetChanges{}, InvalidInput{Field: "arn", Cause: fmt.Errorf("missing ARN")}
+	}
+	changeTime, lifecycle, e := ValidateChangeTimeAndLifecycle(input.ChangeTime, input.Lifecycle)
+	if e != nil {
+		return domain.CloudAssetChanges{}, e
+	}
+	assetChanges := domain.CloudAssetChanges{
+		ChangeTime:   changeTime,
+		ResourceType: input.ResourceType,
+		AccountID:    input.AccountID,
+		Region:       input.Region,
+		ARN:          input.ARN,
+		Tags:         input.Tags,
+		Lifecycle:    lifecycle,
+		EventID:      input.EventID,
+		Changes:      make([]domain.NetworkChanges, 0, len(input.NetworkInterfaces)+1),
+	}
+	if lifecycle != domain.LifecycleTerminated {
+		// a task keeps its cluster, task definition and service for its whole life, those already held are kept as
+		// they are, so they are recorded even when the creation of the task was missed
+		related := make([]string, 0, 3)
+		for _, resource := range []string{input.Cluster, input.TaskDefinition, input.Service} {
+			if resource != "" {
+				related = append(related, domain.ResourceIDFromARN(resource))
+			}
+		}
+		if len(related) > 0 {
+			assetChanges.Changes = append(assetChanges.Changes, domain.NetworkChanges{
+				RelatedResources: related,
+				ChangeType:       changeTypeAdded,
+			})
+		}
+	}
+	if len(input.NetworkInterfaces) == 0 {
+		return assetChanges, nil
+	}
+	changeType, e := validateChangeType(input.ChangeType)
+	if e != nil {
+		return domain.CloudAssetChanges{}, InvalidInput{Field: "changeType", Cause: e}
+	}
+	for i, eni := range input.NetworkInterfaces {
+		if !strings.HasPrefix(eni.NetworkInterfaceID, "eni-") {
+			e = fmt.Errorf("invalid network interface ID %q", eni.NetworkInterfaceID)
+			return domain.CloudAssetChanges{}, InvalidInput{Field: fmt.Sprintf("networkInterfaces[%d].networkInterfaceId", i), Cause: e}
+		}
+		if e = validateIPAddresses(eni.PrivateIPAddresses); e != nil {
+			return domain.CloudAssetChanges{}, InvalidInput{Field: fmt.Sprintf("networkInterfaces[%d].privateIpAddresses", i), Cause: e}
+		}
+		addresses := make([]domain.AddressChange, 0, len(eni.PrivateIPAddresses))
+		for _, ip := range eni.PrivateIPAddresses {
+			addresses = append(addresses, domain.AddressChange{IPAddress: ip, NetworkInterfaceID: eni.NetworkInterfaceID})
+		}
+		assetChanges.Changes = append(assetChanges.Changes, domain.NetworkChanges{
+			Addresses:        addresses,
+			RelatedResources: []string{eni.NetworkInterfaceID},
+			VPCID:            eni.VPCID,
+			SubnetID:         eni.SubnetID,
+			ChangeType:       changeType,
+		})
+	}
+	return assetChanges, nil
+}
@@ -0,0 +1,205 @@
+package v1
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/asecurityteam/asset-inventory-api/pkg/domain"
+)
+
+func newWorkloadInsertHandler(storer domain.CloudAssetStorer) *WorkloadInsertHandler {
+	return &WorkloadInsertHandler{
+		LogFn:            testLogFn,
+		StatFn:           testStatFn,
+		CloudAssetStorer: storer,
+	}
+}
+
+func validWorkloadInput() WorkloadChanges {
+	return WorkloadChanges{
+		ResourceType:   awsECS,
+		ARN:            "arn:aws:ecs:us-west-2:123456789012:task/cluster/0b69d5c0d0c64d8f8a4d1e3c1c8b1a2e",
+		AccountID:      "123456789012",
+		Region:         "us-west-2",
+		ChangeTime:     time.Now().Format(time.RFC3339Nano),
+		Cluster:        "arn:aws:ecs:us-west-2:123456789012:cluster/cluster",
+		TaskDefinition: "arn:aws:ecs:us-west-2:123456789012:task-definition/web:42",
+		Service:        "web",
+		NetworkInterfaces: []WorkloadNetworkInterface{
+			{NetworkInterfaceID: "eni-1", PrivateIPAddresses: []string{"10.0.0.1"}, VPCID: "vpc-1", SubnetID: "subnet-1"},
+		},
+		ChangeType: "ADDED",
+		Lifecycle:  "CREATED",
+	}
+}
+
+func TestWorkloadInsertInvalidInput(t *testing.T) {
+	tc := []struct {
+		name   string
+		modify func(*WorkloadChanges)
+		field  string
+	}{
+		{"resourceType", func(i *WorkloadChanges) { i.ResourceType = awsEC2 }, "resourceType"},
+		{"lambda in a cluster", func(i *WorkloadChanges) { i.ResourceType = awsFn }, "cluster"},
+		{"arn", func(i *WorkloadChanges) { i.ARN = "" }, "arn"},
+		{"changeTime", func(i *WorkloadChanges) { i.ChangeTime = "not a timestamp" }, "changeTime"},
+		{"lifecycle", func(i *WorkloadChanges) { i.Lifecycle = "PAUSED" }, "lifecycle"},
+		{"changeType", func(i *WorkloadChanges) { i.ChangeType = "MOVED" }, "changeType"},
+		{"networkInterfaceId", func(i *WorkloadChanges) { i.NetworkInterfaces[0].NetworkInterfaceID = "i-1" },
+			"networkInterfaces[0].networkInterfaceId"},
+		{"privateIpAddresses", func(i *WorkloadChanges) { i.NetworkInterfaces[0].PrivateIPAddresses = []string{"not an IP"} },
+			"networkInterfaces[0].privateIpAddresses"},
+	}
+	for _, tt := range tc {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			input := validWorkloadInput()
+			tt.modify(&input)
+			_, e := newWorkloadInsertHandler(nil).Handle(context.Background(), input)
+			assert.NotNil(t, e)
+
+			invalid, ok := e.(InvalidInput)
+			assert.True(t, ok)
+			assert.Equal(t, tt.field, invalid.Field)
+		})
+	}
+}
+
+func TestWorkloadInsertStorageError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	storage := NewMockCloudAssetStorer(ctrl)
+	storage.EXPECT().Store(gomock.Any(), gomock.Any()).Return(errors.New(""))
+
+	_, e := newWorkloadInsertHandler(storage).Handle(context.Background(), validWorkloadInput())
+	assert.NotNil(t, e)
+}
+
+func TestWorkloadInsertDuplicateEvent(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	input := validWorkloadInput()
+	input.EventID = "event-1"
+	storage := NewMockCloudAssetStorer(ctrl)
+	storage.EXPECT().Store(gomock.Any(), gomock.Any()).Return(domain.DuplicateEvent{EventID: "event-1"})
+
+	res, e := newWorkloadInsertHandler(storage).Handle(context.Background(), input)
+	assert.Nil(t, e)
+	assert.True(t, res.Deduplicated)
+}
+
+func TestWorkloadInsertECSTaskCreated(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	input := validWorkloadInput()
+	changeTime, _ := time.Parse(time.RFC3339Nano, input.ChangeTime)
+	expected := domain.CloudAssetChanges{
+		ChangeTime:   changeTime,
+		ResourceType: awsECS,
+		AccountID:    "123456789012",
+		Region:       "us-west-2",
+		ARN:          input.ARN,
+		Lifecycle:    domain.LifecycleCreated,
+		Changes: []domain.NetworkChanges{
+			{
+				RelatedResources: []string{"cluster", "web:42", "web"},
+				ChangeType:       "ADDED",
+			},
+			{
+				Addresses:        []domain.AddressChange{{IPAddress: "10.0.0.1", NetworkInterfaceID: "eni-1"}},
+				RelatedResources: []string{"eni-1"},
+				VPCID:            "vpc-1",
+				SubnetID:         "subnet-1",
+				ChangeType:       "ADDED",
+			},
+		},
+	}
+	storage := NewMockCloudAssetStorer(ctrl)
+	storage.EXPECT().Store(gomock.Any(), expected).Return(nil)
+
+	res, e := newWorkloadInsertHandler(storage).Handle(context.Background(), input)
+	assert.Nil(t, e)
+	assert.False(t, res.Deduplicated)
+}
+
+func TestWorkloadInsertECSTaskWithoutCreation(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	input := validWorkloadInput()
+	input.Lifecycle = ""
+	input.Service = "arn:aws:ecs:us-west-2:123456789012:service/cluster/web"
+	storage := NewMockCloudAssetStorer(ctrl)
+	storage.EXPECT().Store(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, changes domain.CloudAssetChanges) error {
+		assert.Equal(t, domain.NetworkChanges{
+			RelatedResources: []string{"cluster", "web:42", "web"},
+			ChangeType:       "ADDED",
+		}, changes.Changes[0])
+		return nil
+	})
+
+	_, e := newWorkloadInsertHandler(storage).Handle(context.Background(), input)
+	assert.Nil(t, e)
+}
+
+func TestWorkloadInsertECSTaskTerminated(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	input := validWorkloadInput()
+	input.Lifecycle = "TERMINATED"
+	input.NetworkInterfaces = nil
+	storage := NewMockCloudAssetStorer(ctrl)
+	storage.EXPECT().Store(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, changes domain.CloudAssetChanges) error {
+		assert.Empty(t, changes.Changes)
+		return nil
+	})
+
+	_, e := newWorkloadInsertHandler(storage).Handle(context.Background(), input)
+	assert.Nil(t, e)
+}
+
+func TestWorkloadInsertLambdaReleased(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	input := WorkloadChanges{
+		ResourceType: awsFn,
+		ARN:          "arn:aws:lambda:us-west-2:123456789012:function:fn",
+		AccountID:    "123456789012",
+		Region:       "us-west-2",
+		ChangeTime:   time.Now().Format(time.RFC3339Nano),
+		NetworkInterfaces: []WorkloadNetworkInterface{
+			{NetworkInterfaceID: "eni-2", PrivateIPAddresses: []string{"10.0.0.2"}},
+		},
+		ChangeType: "deleted",
+	}
+	changeTime, _ := time.Parse(time.RFC3339Nano, input.ChangeTime)
+	expected := domain.CloudAssetChanges{
+		ChangeTime:   changeTime,
+		ResourceType: awsFn,
+		AccountID:    "123456789012",
+		Region:       "us-west-2",
+		ARN:          input.ARN,
+		Changes: []domain.NetworkChanges{
+			{
+				Addresses:        []domain.AddressChange{{IPAddress: "10.0.0.2", NetworkInterfaceID: "eni-2"}},
+				RelatedResources: []string{"eni-2"},
+				ChangeType:       "DELETED",
+			},
+		},
+	}
+	storage := NewMockCloudAssetStorer(ctrl)
+	storage.EXPECT().Store(gomock.Any(), expected).Return(nil)
+
+	_, e := newWorkloadInsertHandler(storage).Handle(context.Background(), input)
+	assert.Nil(t, e)
+}
@@ -0,0 +1,114 @@
+package v2
+
+import (
+	"context"
+	"fmt"
+	"net"
+
+	"github.com/asecurityteam/asset-inventory-api/pkg/domain"
+	v1 "github.com/asecurityteam/asset-inventory-api/pkg/handlers/v1"
+	"github.com/asecurityteam/asset-inventory-api/pkg/logs"
+)
+
+// CloudAssetChanges represents the incoming payload. It is the v1 payload, with the IP addresses and hostnames of
+// each change given as explicit address entries.
+type CloudAssetChanges struct {
+	Changes      []NetworkChanges  `json:"changes"`
+	ChangeTime   string            `json:"changeTime"`
+	ResourceType string            `json:"resourceType"`
+	AccountID    string            `json:"accountId"`
+	Region       string            `json:"region"`
+	ARN          string            `json:"arn"`
+	Tags         map[string]string `json:"tags"`
+	Lifecycle    string            `json:"lifecycle"`
+	EventID      string            `json:"eventId"`
+}
+
+// NetworkChanges detail the changes in addresses and relationships for an asset. AttachedTo names the resources an
+// elastic IP or network interface is attached to.
+type NetworkChanges struct {
+	Addresses        []Address `json:"addresses"`
+	RelatedResources []string  `json:"relatedResources"`
+	AttachedTo       []string  `json:"attachedTo"`
+	VPCID            string    `json:"vpcId"`
+	SubnetID         string    `json:"subnetId"`
+	ChangeType       string    `json:"changeType"`
+}
+
+// Address is an IP address of an asset, stored along with its own hostname and network interface only. A private
+// address known by several hostnames is given once per hostname.
+type Address struct {
+	IPAddress          string `json:"ipAddress"`
+	Hostname           string `json:"hostname"`
+	NetworkInterfaceID string `json:"networkInterfaceId"`
+	Public             bool   `json:"public"`
+}
+
+// CloudInsertHandler defines a lambda handler for inserting new cloud asset or changes to existing cloud assets
+type CloudInsertHandler struct {
+	LogFn            domain.LogFn
+	StatFn           domain.StatFn
+	CloudAssetStorer domain.CloudAssetStorer
+}
+
+// Handle handles the insert operation for cloud assets
+func (h *CloudInsertHandler) Handle(ctx context.Context, input CloudAssetChanges) (v1.CloudInsertResult, error) {
+	logger := h.LogFn(ctx)
+
+	assetChanges, e := toDomainCloudAssetChanges(input)
+	if e != nil {
+		logger.Info(logs.InvalidInput{Reason: e.Error()})
+		return v1.CloudInsertResult{}, e
+	}
+	return v1.StoreChanges(ctx, logger, h.CloudAssetStorer, assetChanges)
+}
+
+// toDomainCloudAssetChanges validates the incoming payload, returning an InvalidInput error naming the offending field
+func toDomainCloudAssetChanges(input CloudAssetChanges) (domain.CloudAssetChanges, error) {
+	changeTime, lifecycle, e := v1.ValidateChangeTimeAndLifecycle(input.ChangeTime, input.Lifecycle)
+	if e != nil {
+		return domain.CloudAssetChanges{}, e
+	}
+	assetChanges := domain.CloudAssetChanges{
+		ChangeTime:   changeTime,
+		ResourceType: input.ResourceType,
+		AccountID:    input.AccountID,
+		Region:       input.Region,
+		ARN:          input.ARN,
+		Tags:         input.Tags,
+		Lifecycle:    lifecycle,
+		EventID:      input.EventID,
+		Changes:      make([]domain.NetworkChanges, 0, len(input.Changes)),
+	}
+	for i, val := range input.Changes {
+		addresses := make([]domain.AddressChange, 0, len(val.Addresses))
+		for j, address := range val.Addresses {
+			if e = validateAddress(address); e != nil {
+				return domain.CloudAssetChanges{}, v1.InvalidInput{Field: fmt.Sprintf("changes[%d].addresses[%d]", i, j), Cause: e}
+			}
+			addresses = append(addresses, domain.AddressChange{
+				IPAddress:          address.IPAddress,
+				Hostname:           address.Hostname,
+				NetworkInterfaceID: address.NetworkInterfaceID,
+				Public:             address.Public,
+			})
+		}
+		assetChanges.Changes = append(assetChanges.Changes, domain.NetworkChanges{
+			Addresses:        addresses,
+			RelatedResources: val.RelatedResources,
+			AttachedTo:       val.AttachedTo,
+			VPCID:            val.VPCID,
+			SubnetID:         val.SubnetID,
+			ChangeType:       val.ChangeType,
+		})
+	}
+	return assetChanges, nil
+}
+
+// validateAddress checks that the entry holds an IPv4 or IPv6 address
+func validateAddress(address Address) error {
+	if net.ParseIP(address.IPAddress) == nil {
+		return fmt.Errorf("invalid IPv4 or IPv6 address %q", address.IPAddress)
+	}
+	return nil
+}
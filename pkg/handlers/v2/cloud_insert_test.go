@@ -0,0 +1,148 @@
+package v2
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/asecurityteam/asset-inventory-api/pkg/domain"
+	v1 "github.com/asecurityteam/asset-inventory-api/pkg/handlers/v1"
+)
+
+func newInsertHandler(storer domain.CloudAssetStorer) *CloudInsertHandler {
+	return &CloudInsertHandler{
+		LogFn:            testLogFn,
+		StatFn:           testStatFn,
+		CloudAssetStorer: storer,
+	}
+}
+
+func validInsertInput() CloudAssetChanges {
+	return CloudAssetChanges{
+		ChangeTime:   time.Now().Format(time.RFC3339Nano),
+		ARN:          "cloud-resource-arn",
+		ResourceType: "AWS::EC2::Instance",
+		Region:       "cloud-region",
+		AccountID:    "cloud-account-id",
+		Tags:         make(map[string]string),
+		Changes: []NetworkChanges{
+			{
+				Addresses: []Address{
+					{IPAddress: "10.0.0.1", Hostname: "ip-10-0-0-1.ec2.internal", NetworkInterfaceID: "eni-1"},
+					{IPAddress: "34.0.0.1", Hostname: "one.example.com", NetworkInterfaceID: "eni-1", Public: true},
+					{IPAddress: "34.0.0.2", NetworkInterfaceID: "eni-2", Public: true},
+				},
+				RelatedResources: []string{"app/marketp-ALB-eeeeeee5555555/ffffffff66666666"},
+				VPCID:            "vpc-1",
+				SubnetID:         "subnet-1",
+				ChangeType:       "ADDED",
+			},
+			{
+				AttachedTo: []string{"i-1"},
+				ChangeType: "ADDED",
+			},
+		},
+	}
+}
+
+func TestInsertInvalidInput(t *testing.T) {
+	input := CloudAssetChanges{
+		ChangeTime: "not a timestamp",
+	}
+	_, e := newInsertHandler(nil).Handle(context.Background(), input)
+	assert.NotNil(t, e)
+
+	_, ok := e.(v1.InvalidInput)
+	assert.True(t, ok)
+}
+
+func TestInsertInvalidAddress(t *testing.T) {
+	input := validInsertInput()
+	input.Changes[0].Addresses[1].IPAddress = "not an IP"
+	_, e := newInsertHandler(nil).Handle(context.Background(), input)
+	assert.NotNil(t, e)
+
+	invalid, ok := e.(v1.InvalidInput)
+	assert.True(t, ok)
+	assert.Equal(t, "changes[0].addresses[1]", invalid.Field)
+}
+
+func TestInsertInvalidLifecycle(t *testing.T) {
+	input := validInsertInput()
+	input.Lifecycle = "PAUSED"
+	_, e := newInsertHandler(nil).Handle(context.Background(), input)
+	assert.NotNil(t, e)
+
+	invalid, ok := e.(v1.InvalidInput)
+	assert.True(t, ok)
+	assert.Equal(t, "lifecycle", invalid.Field)
+}
+
+func TestInsertStorageError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	storage := NewMockCloudAssetStorer(ctrl)
+	storage.EXPECT().Store(gomock.Any(), gomock.Any()).Return(errors.New(""))
+
+	_, e := newInsertHandler(storage).Handle(context.Background(), validInsertInput())
+	assert.NotNil(t, e)
+}
+
+func TestInsertDuplicateEvent(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	input := validInsertInput()
+	input.EventID = "event-1"
+	storage := NewMockCloudAssetStorer(ctrl)
+	storage.EXPECT().Store(gomock.Any(), gomock.Any()).Return(domain.DuplicateEvent{EventID: "event-1"})
+
+	res, e := newInsertHandler(storage).Handle(context.Background(), input)
+	assert.Nil(t, e)
+	assert.True(t, res.Deduplicated)
+}
+
+func TestInsertSuccess(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	input := validInsertInput()
+	changeTime, _ := time.Parse(time.RFC3339Nano, input.ChangeTime)
+	expected := domain.CloudAssetChanges{
+		ChangeTime:   changeTime,
+		ResourceType: input.ResourceType,
+		AccountID:    input.AccountID,
+		Region:       input.Region,
+		ARN:          input.ARN,
+		Tags:         input.Tags,
+		Changes: []domain.NetworkChanges{
+			{
+				Addresses: []domain.AddressChange{
+					{IPAddress: "10.0.0.1", Hostname: "ip-10-0-0-1.ec2.internal", NetworkInterfaceID: "eni-1"},
+					{IPAddress: "34.0.0.1", Hostname: "one.example.com", NetworkInterfaceID: "eni-1", Public: true},
+					{IPAddress: "34.0.0.2", NetworkInterfaceID: "eni-2", Public: true},
+				},
+				RelatedResources: []string{"app/marketp-ALB-eeeeeee5555555/ffffffff66666666"},
+				VPCID:            "vpc-1",
+				SubnetID:         "subnet-1",
+				ChangeType:       "ADDED",
+			},
+			{
+				Addresses:  []domain.AddressChange{},
+				AttachedTo: []string{"i-1"},
+				ChangeType: "ADDED",
+			},
+		},
+	}
+	storage := NewMockCloudAssetStorer(ctrl)
+	storage.EXPECT().Store(gomock.Any(), expected).Return(nil)
+
+	res, e := newInsertHandler(storage).Handle(context.Background(), input)
+	assert.Nil(t, e)
+	assert.False(t, res.Deduplicated)
+}
@@ -0,0 +1,3 @@
+// Package v2 is a container for endpoints that are used
+// to power the v2 of the service.
+package v2
@@ -0,0 +1,3 @@
+package v2
+
+//go:generate mockgen -destination mock_storage_test.go -package v2 github.com/asecurityteam/asset-inventory-api/pkg/domain CloudAssetStorer
@@ -0,0 +1,51 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/asecurityteam/asset-inventory-api/pkg/domain (interfaces: CloudAssetStorer)
+
+// Package v2 is a generated GoMock package.
+package v2
+
+import (
+	context "context"
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+
+	domain "github.com/asecurityteam/asset-inventory-api/pkg/domain"
+)
+
+// MockCloudAssetStorer is a mock of CloudAssetStorer interface
+type MockCloudAssetStorer struct {
+	ctrl     *gomock.Controller
+	recorder *MockCloudAssetStorerMockRecorder
+}
+
+// MockCloudAssetStorerMockRecorder is the mock recorder for MockCloudAssetStorer
+type MockCloudAssetStorerMockRecorder struct {
+	mock *MockCloudAssetStorer
+}
+
+// NewMockCloudAssetStorer creates a new mock instance
+func NewMockCloudAssetStorer(ctrl *gomock.Controller) *MockCloudAssetStorer {
+	mock := &MockCloudAssetStorer{ctrl: ctrl}
+	mock.recorder = &MockCloudAssetStorerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockCloudAssetStorer) EXPECT() *MockCloudAssetStorerMockRecorder {
+	return m.recorder
+}
+
+// Store mocks base method
+func (m *MockCloudAssetStorer) Store(arg0 context.Context, arg1 domain.CloudAssetChanges) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Store", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Store indicates an expected call of Store
+func (mr *MockCloudAssetStorerMockRecorder) Store(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Store", reflect.TypeOf((*MockCloudAssetStorer)(nil).Store), arg0, arg1)
+}
@@ -0,0 +1,8 @@
+package logs
+
+// BackfillFailure is logged for records of a backfill that could not be stored
+type BackfillFailure struct {
+	Message string `logevent:"message,default=backfill-failure"`
+	Line    int    `logevent:"line"`
+	Reason  string `logevent:"reason"`
+}
@@ -0,0 +1,7 @@
+package logs
+
+// DuplicateEvent is logged when changes with an already processed event ID are ignored
+type DuplicateEvent struct {
+	Message string `logevent:"message,default=duplicate-event"`
+	EventID string `logevent:"event_id"`
+}
@@ -0,0 +1,8 @@
+package logs
+
+// ReplayComplete is logged when resources were rebuilt from the change journal
+type ReplayComplete struct {
+	Message   string `logevent:"message,default=replay-complete"`
+	Resources int    `logevent:"resources"`
+	Events    int    `logevent:"events"`
+}
@@ -0,0 +1,8 @@
+package logs
+
+// SnapshotImportFailure is logged for resources of a configuration snapshot that could not be recorded
+type SnapshotImportFailure struct {
+	Message    string `logevent:"message,default=snapshot-import-failure"`
+	ResourceID string `logevent:"resource_id"`
+	Reason     string `logevent:"reason"`
+}
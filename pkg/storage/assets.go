@@ -0,0 +1,263 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/lib/pq"
+
+	"github.com/asecurityteam/asset-inventory-api/pkg/domain"
+)
+
+// Query to list a page of resources of the given type that hold at least one IP assignment at the point in time
+const resourceIDsByTypeQuery = `
+select res.id
+from aws_resource res
+         join aws_resource_type rt on res.aws_resource_type_id = rt.id
+where rt.resource_type = $1
+  and (exists(select 1
+              from aws_private_ip_assignment pria
+              where pria.aws_resource_id = res.id
+                and pria.not_before < $2
+                and (pria.not_after is null or pria.not_after > $2))
+    or exists(select 1
+              from aws_public_ip_assignment puia
+              where puia.aws_resource_id = res.id
+                and puia.not_before < $2
+                and (puia.not_after is null or puia.not_after > $2)))
+  and res.id > $4
+order by res.id
+limit $3`
+
+// Query to list a page of resources holding at least one IP address within the network at the point in time
+const resourceIDsByCIDRQuery = `
+select ids.id
+from (select pria.aws_resource_id as id
+      from aws_private_ip_assignment pria
+      where pria.private_ip <<= $1::inet
+        and pria.not_before < $2
+        and (pria.not_after is null or pria.not_after > $2)
+        and pria.aws_resource_id > $4
+      union
+      select puia.aws_resource_id
+      from aws_public_ip_assignment puia
+      where puia.public_ip <<= $1::inet
+        and puia.not_before < $2
+        and (puia.not_after is null or puia.not_after > $2)
+        and puia.aws_resource_id > $4) ids
+order by ids.id
+limit $3`
+
+// Query to hydrate a set of resources with their assignments, account owner and champions at the point in time.
+// The result is one row per combination of private IP, public IP and champion, ordered by resource.
+const assetDetailsByResourceIDsQuery = `
+select res.id,
+       res.arn_id,
+       rt.resource_type,
+       aa.account,
+       reg.region,
+       get_tags_at(res.id, $2),
+       pria.private_ip,
+       puia.public_ip,
+       puia.aws_hostname,
+       prha.aws_hostname,
+       o.t_account,
+       o.t_login,
+       o.t_email,
+       o.t_name,
+       o.t_valid,
+       o.p_login,
+       o.p_email,
+       o.p_name,
+       o.p_valid
+from aws_resource res
+         left join aws_region reg on res.aws_region_id = reg.id
+         left join aws_account aa on res.aws_account_id = aa.id
+         left join aws_resource_type rt on res.aws_resource_type_id = rt.id
+         left join aws_private_ip_assignment pria
+                   on pria.aws_resource_id = res.id
+                       and pria.not_before < $2
+                       and (pria.not_after is null or pria.not_after > $2)
+         left join aws_private_hostname_assignment prha
+                   on prha.aws_resource_id = res.id
+                       and prha.private_ip = pria.private_ip
+                       and prha.not_before < $2
+                       and (prha.not_after is null or prha.not_after > $2)
+         left join aws_public_ip_assignment puia
+                   on puia.aws_resource_id = res.id
+                       and puia.not_before < $2
+                       and (puia.not_after is null or puia.not_after > $2)
+         left join lateral get_owner_and_champions_by_account_id(res.aws_account_id) o on true
+where res.id = any ($1)
+order by res.id`
+
+// FetchAll gets a page of the assets present at the specified time, starting after the given resource key
+func (db *DB) FetchAll(ctx context.Context, when time.Time, count uint, after int64, typeFilter string) ([]domain.CloudAssetDetails, int64, error) {
+	return db.fetchPage(ctx, when, after, resourceIDsByTypeQuery, typeFilter, when, count, after)
+}
+
+// FetchByCIDR gets a page of the assets holding an IP address within the network at the specified time, starting after
+// the given resource key
+func (db *DB) FetchByCIDR(ctx context.Context, when time.Time, cidr string, count uint, after int64) ([]domain.CloudAssetDetails, int64, error) {
+	return db.fetchPage(ctx, when, after, resourceIDsByCIDRQuery, cidr, when, count, after)
+}
+
+// fetchPage hydrates a page of resources listed by query, and returns the key of the last one or after if the page is empty
+func (db *DB) fetchPage(ctx context.Context, when time.Time, after int64, query string, args ...interface{}) ([]domain.CloudAssetDetails, int64, error) {
+	ids, err := db.queryResourceIDs(ctx, query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	assets, err := db.fetchAssetsByResourceIDs(ctx, when, ids)
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(ids) != 0 {
+		after = ids[len(ids)-1]
+	}
+	return assets, after, nil
+}
+
+// queryResourceIDs runs a query returning a single column of aws_resource IDs
+func (db *DB) queryResourceIDs(ctx context.Context, query string, args ...interface{}) ([]int64, error) {
+	rows, err := db.sqldb.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	ids := make([]int64, 0)
+	for rows.Next() {
+		var id int64
+		if err = rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// fetchAssetsByResourceIDs builds the point-in-time details for the given aws_resource IDs, preserving their order
+func (db *DB) fetchAssetsByResourceIDs(ctx context.Context, when time.Time, ids []int64) ([]domain.CloudAssetDetails, error) {
+	cloudAssetDetails := make([]domain.CloudAssetDetails, 0, len(ids))
+	if len(ids) == 0 { // nothing to hydrate, spare the round trip
+		return cloudAssetDetails, nil
+	}
+	rows, err := db.sqldb.QueryContext(ctx, assetDetailsByResourceIDsQuery, pq.Array(ids), when)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	assets := make(map[int64]*assetAggregate, len(ids))
+	for rows.Next() {
+		var id int64
+		var asset domain.CloudAssetDetails
+		var metaBytes []byte
+		var privateIPAddress sql.NullString
+		var publicIPAddress sql.NullString
+		var hostname sql.NullString
+		var privateHostname sql.NullString
+		var owner domain.Person
+		var ownerAccountID *string
+		var champion domain.Person
+		if err = rows.Scan(&id, &asset.ARN, &asset.ResourceType, &asset.AccountID, &asset.Region, &metaBytes,
+			&privateIPAddress, &publicIPAddress, &hostname, &privateHostname, &ownerAccountID, &owner.Login, &owner.Email,
+			&owner.Name, &owner.Valid, &champion.Login, &champion.Email, &champion.Name, &champion.Valid); err != nil {
+			return nil, err
+		}
+		agg, ok := assets[id]
+		if !ok {
+			if metaBytes != nil {
+				var i map[string]string
+				_ = json.Unmarshal(metaBytes, &i) // we already checked for nil, and the DB column is JSONB; no need for err check here
+				asset.Tags = i
+			}
+			asset.AccountOwner = domain.AccountOwner{
+				AccountID: ownerAccountID,
+				Owner:     owner,
+				Champions: make([]domain.Person, 0),
+			}
+			agg = newAssetAggregate(asset)
+			assets[id] = agg
+		}
+		if privateIPAddress.Valid {
+			agg.addPrivateIP(privateIPAddress.String)
+			if privateHostname.Valid {
+				agg.addHostname(privateHostname.String)
+			}
+		}
+		if publicIPAddress.Valid {
+			agg.addPublicIP(publicIPAddress.String)
+			if hostname.Valid {
+				agg.addHostname(hostname.String)
+			}
+		}
+		if champion.Login != nil {
+			agg.addChampion(champion)
+		}
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, id := range ids {
+		if agg, ok := assets[id]; ok {
+			cloudAssetDetails = append(cloudAssetDetails, agg.asset)
+		}
+	}
+	return cloudAssetDetails, nil
+}
+
+// assetAggregate collects the de-duplicated values of a single asset spread across multiple result rows
+type assetAggregate struct {
+	asset      domain.CloudAssetDetails
+	privateIPs map[string]struct{}
+	publicIPs  map[string]struct{}
+	hostnames  map[string]struct{}
+	champions  map[string]struct{}
+}
+
+func newAssetAggregate(asset domain.CloudAssetDetails) *assetAggregate {
+	return &assetAggregate{
+		asset:      asset,
+		privateIPs: make(map[string]struct{}),
+		publicIPs:  make(map[string]struct{}),
+		hostnames:  make(map[string]struct{}),
+		champions:  make(map[string]struct{}),
+	}
+}
+
+func (a *assetAggregate) addPrivateIP(ip string) {
+	if _, ok := a.privateIPs[ip]; !ok {
+		a.privateIPs[ip] = struct{}{}
+		a.asset.PrivateIPAddresses = append(a.asset.PrivateIPAddresses, ip)
+	}
+}
+
+func (a *assetAggregate) addPublicIP(ip string) {
+	if _, ok := a.publicIPs[ip]; !ok {
+		a.publicIPs[ip] = struct{}{}
+		a.asset.PublicIPAddresses = append(a.asset.PublicIPAddresses, ip)
+	}
+}
+
+func (a *assetAggregate) addHostname(hostname string) {
+	if _, ok := a.hostnames[hostname]; !ok {
+		a.hostnames[hostname] = struct{}{}
+		a.asset.Hostnames = append(a.asset.Hostnames, hostname)
+	}
+}
+
+func (a *assetAggregate) addChampion(champion domain.Person) {
+	if _, ok := a.champions[*champion.Login]; !ok {
+		a.champions[*champion.Login] = struct{}{}
+		a.asset.AccountOwner.Champions = append(a.asset.AccountOwner.Champions, champion)
+	}
+}
@@ -0,0 +1,262 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/asecurityteam/asset-inventory-api/pkg/domain"
+)
+
+var assetDetailsColumns = []string{"id",
+	"arn_id",
+	"resource_type",
+	"account",
+	"region",
+	"meta",
+	"private_ip",
+	"public_ip",
+	"aws_hostname",
+	"private_hostname",
+	"t_account",
+	"t_login",
+	"t_email",
+	"t_name",
+	"t_valid",
+	"p_login",
+	"p_email",
+	"p_name",
+	"p_valid",
+}
+
+func TestFetchAllIDQueryError(t *testing.T) {
+	mockdb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockdb.Close()
+
+	thedb := DB{
+		sqldb: mockdb,
+	}
+
+	at, _ := time.Parse(time.RFC3339, "2019-04-09T08:55:35+00:00")
+	mock.ExpectQuery("select res.id").WithArgs("type", at, 10, 20).WillReturnError(errors.New("no bueno"))
+
+	_, _, err = thedb.FetchAll(context.Background(), at, 10, 20, "type")
+	assert.Error(t, err)
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestFetchAllEmpty(t *testing.T) {
+	mockdb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockdb.Close()
+
+	thedb := DB{
+		sqldb: mockdb,
+	}
+
+	at, _ := time.Parse(time.RFC3339, "2019-04-09T08:55:35+00:00")
+	mock.ExpectQuery("select res.id").WithArgs("type", at, 10, 0).WillReturnRows(sqlmock.NewRows([]string{"id"})).RowsWillBeClosed()
+
+	results, last, err := thedb.FetchAll(context.Background(), at, 10, 0, "type")
+	assert.NoError(t, err)
+	assert.Empty(t, results)
+	assert.Equal(t, int64(0), last)
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestFetchAllHydrateError(t *testing.T) {
+	mockdb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockdb.Close()
+
+	thedb := DB{
+		sqldb: mockdb,
+	}
+
+	at, _ := time.Parse(time.RFC3339, "2019-04-09T08:55:35+00:00")
+	mock.ExpectQuery("select res.id").WithArgs("type", at, 10, 0).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1)).RowsWillBeClosed()
+	mock.ExpectQuery("select res.id,").WithArgs(pq.Array([]int64{1}), at).WillReturnError(errors.New("no bueno"))
+
+	_, _, err = thedb.FetchAll(context.Background(), at, 10, 0, "type")
+	assert.Error(t, err)
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestFetchAll(t *testing.T) {
+	mockdb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockdb.Close()
+
+	thedb := DB{
+		sqldb: mockdb,
+	}
+
+	at, _ := time.Parse(time.RFC3339, "2019-04-09T08:55:35+00:00")
+	mock.ExpectQuery("select res.id").WithArgs("type", at, 2, 2).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3).AddRow(7)).RowsWillBeClosed()
+	rows := sqlmock.NewRows(assetDetailsColumns).
+		AddRow(3, "rid3", "type", "aid", "region", nil, "10.0.0.3", nil, nil,
+			nil, nil, nil, nil, nil, nil, nil, nil, nil, nil).
+		AddRow(7, "rid7", "type", "aid", "region", []byte("{\"hi\":\"there\"}"), "10.0.0.7", "9.8.7.6", "yahoo.com",
+			nil, "aid", "login", "email@atlassian.com", "name", true, "login2", "email2@atlassian.com", "name2", true).
+		AddRow(7, "rid7", "type", "aid", "region", []byte("{\"hi\":\"there\"}"), "10.0.0.8", "9.8.7.6", "yahoo.com",
+			nil, "aid", "login", "email@atlassian.com", "name", true, "login2", "email2@atlassian.com", "name2", true).
+		AddRow(7, "rid7", "type", "aid", "region", []byte("{\"hi\":\"there\"}"), "10.0.0.8", "9.8.7.6", "yahoo.com",
+			nil, "aid", "login", "email@atlassian.com", "name", true, "login3", "email3@atlassian.com", "name3", false)
+	mock.ExpectQuery("select res.id,").WithArgs(pq.Array([]int64{3, 7}), at).WillReturnRows(rows).RowsWillBeClosed()
+
+	results, last, err := thedb.FetchAll(context.Background(), at, 2, 2, "type")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(7), last)
+	assert.Equal(t, []domain.CloudAssetDetails{
+		{
+			PrivateIPAddresses: []string{"10.0.0.3"},
+			ResourceType:       "type",
+			AccountID:          "aid",
+			Region:             "region",
+			ARN:                "rid3",
+			AccountOwner: domain.AccountOwner{
+				Champions: []domain.Person{},
+			},
+		},
+		{
+			PrivateIPAddresses: []string{"10.0.0.7", "10.0.0.8"},
+			PublicIPAddresses:  []string{"9.8.7.6"},
+			Hostnames:          []string{"yahoo.com"},
+			ResourceType:       "type",
+			AccountID:          "aid",
+			Region:             "region",
+			ARN:                "rid7",
+			Tags:               map[string]string{"hi": "there"},
+			AccountOwner: domain.AccountOwner{
+				AccountID: toStringPointer("aid"),
+				Owner: domain.Person{
+					Login: toStringPointer("login"),
+					Email: toStringPointer("email@atlassian.com"),
+					Name:  toStringPointer("name"),
+					Valid: toBoolPointer(true),
+				},
+				Champions: []domain.Person{
+					{
+						Login: toStringPointer("login2"),
+						Email: toStringPointer("email2@atlassian.com"),
+						Name:  toStringPointer("name2"),
+						Valid: toBoolPointer(true),
+					},
+					{
+						Login: toStringPointer("login3"),
+						Email: toStringPointer("email3@atlassian.com"),
+						Name:  toStringPointer("name3"),
+						Valid: toBoolPointer(false),
+					},
+				},
+			},
+		},
+	}, results)
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestFetchByCIDRIDQueryError(t *testing.T) {
+	mockdb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockdb.Close()
+
+	thedb := DB{
+		sqldb: mockdb,
+	}
+
+	at, _ := time.Parse(time.RFC3339, "2019-04-09T08:55:35+00:00")
+	mock.ExpectQuery("select ids.id").WithArgs("10.0.0.0/8", at, 10, 20).WillReturnError(errors.New("no bueno"))
+
+	_, _, err = thedb.FetchByCIDR(context.Background(), at, "10.0.0.0/8", 10, 20)
+	assert.Error(t, err)
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestFetchByCIDREmpty(t *testing.T) {
+	mockdb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockdb.Close()
+
+	thedb := DB{
+		sqldb: mockdb,
+	}
+
+	at, _ := time.Parse(time.RFC3339, "2019-04-09T08:55:35+00:00")
+	mock.ExpectQuery("select ids.id").WithArgs("10.0.0.0/8", at, 10, 20).WillReturnRows(sqlmock.NewRows([]string{"id"})).RowsWillBeClosed()
+
+	results, last, err := thedb.FetchByCIDR(context.Background(), at, "10.0.0.0/8", 10, 20)
+	assert.NoError(t, err)
+	assert.Empty(t, results)
+	assert.Equal(t, int64(20), last)
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestFetchByCIDR(t *testing.T) {
+	mockdb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockdb.Close()
+
+	thedb := DB{
+		sqldb: mockdb,
+	}
+
+	at, _ := time.Parse(time.RFC3339, "2019-04-09T08:55:35+00:00")
+	mock.ExpectQuery("select ids.id").WithArgs("10.0.0.0/8", at, 10, 0).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3)).RowsWillBeClosed()
+	rows := sqlmock.NewRows(assetDetailsColumns).
+		AddRow(3, "rid3", "type", "aid", "region", nil, "10.0.0.3", nil, nil,
+			nil, nil, nil, nil, nil, nil, nil, nil, nil, nil)
+	mock.ExpectQuery("select res.id,").WithArgs(pq.Array([]int64{3}), at).WillReturnRows(rows).RowsWillBeClosed()
+
+	results, last, err := thedb.FetchByCIDR(context.Background(), at, "10.0.0.0/8", 10, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), last)
+	assert.Equal(t, []domain.CloudAssetDetails{
+		{
+			PrivateIPAddresses: []string{"10.0.0.3"},
+			ResourceType:       "type",
+			AccountID:          "aid",
+			Region:             "region",
+			ARN:                "rid3",
+			AccountOwner: domain.AccountOwner{
+				Champions: []domain.Person{},
+			},
+		},
+	}, results)
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
@@ -0,0 +1,126 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+
+	"github.com/asecurityteam/asset-inventory-api/pkg/domain"
+)
+
+// maxAttachmentDepth bounds the attachments followed from a resource. An elastic IP attached to a network interface
+// attached to an instance is two hops, the bound guards against cycles recorded by mistake.
+const maxAttachmentDepth = 4
+
+// Query to follow the attachments of resources, each at its own point in time. The ordinality column is the 1-based
+// position of the resource in the arrays.
+const attachmentChainsQuery = `
+with recursive chain (ord, arn_id, ts, depth) as (
+    select q.ord, att.attached_to_arn_id, q.ts, 1
+    from unnest($1::varchar[], $2::timestamp[]) with ordinality as q(arn_id, ts, ord)
+             join aws_resource_attachment att
+                  on att.arn_id = q.arn_id
+                      and att.not_before < q.ts
+                      and (att.not_after is null or att.not_after > q.ts)
+    union all
+    select chain.ord, att.attached_to_arn_id, chain.ts, chain.depth + 1
+    from chain
+             join aws_resource_attachment att
+                  on att.arn_id = chain.arn_id
+                      and att.not_before < chain.ts
+                      and (att.not_after is null or att.not_after > chain.ts)
+    where chain.depth < $3
+)
+select ord, arn_id
+from chain
+order by ord, depth, arn_id`
+
+func (db *DB) assignAttachment(ctx context.Context, tx *sql.Tx, arnID string, attachedTo string, when time.Time) error {
+	const assignAttachmentQueryUpdate = `
+update aws_resource_attachment
+set not_before = $1
+where attached_to_arn_id = $2
+  and not_before = to_timestamp(0)
+  and not_after > $1
+  and arn_id = $3`
+
+	const assignAttachmentQueryInsert = `
+insert into aws_resource_attachment
+    (not_before, attached_to_arn_id, arn_id)
+values ($1, $2, $3) on conflict do nothing`
+
+	res, err := tx.ExecContext(ctx, assignAttachmentQueryUpdate, when, attachedTo, arnID)
+	if err != nil {
+		return err
+	}
+	changedRows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if changedRows != 0 {
+		return nil
+	}
+	_, err = tx.ExecContext(ctx, assignAttachmentQueryInsert, when, attachedTo, arnID)
+	return err
+}
+
+func (db *DB) releaseAttachment(ctx context.Context, tx *sql.Tx, arnID string, attachedTo string, when time.Time) error {
+	const releaseAttachmentQueryUpdate = `
+update aws_resource_attachment
+set not_after = $1
+where attached_to_arn_id = $2
+  and arn_id = $3
+  and not_after is null`
+
+	const releaseAttachmentQueryInsert = `
+insert into aws_resource_attachment
+    (not_before, not_after, attached_to_arn_id, arn_id)
+values (to_timestamp(0), $1, $2, $3) on conflict do nothing`
+
+	res, err := tx.ExecContext(ctx, releaseAttachmentQueryUpdate, when, attachedTo, arnID)
+	if err != nil {
+		return err
+	}
+	changedRows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if changedRows != 0 {
+		return nil
+	}
+	_, err = tx.ExecContext(ctx, releaseAttachmentQueryInsert, when, attachedTo, arnID)
+	return err
+}
+
+// addAttachmentChains sets the resources each asset is attached to at the matching time, so that an address held by
+// an elastic IP or a network interface is followed to the instance
+func (db *DB) addAttachmentChains(ctx context.Context, assets []*domain.CloudAssetDetails, times []time.Time) error {
+	if len(assets) == 0 { // nothing to follow, spare the round trip
+		return nil
+	}
+	arnIDs := make([]string, len(assets))
+	for i, asset := range assets {
+		arnIDs[i] = asset.ARN
+	}
+	rows, err := db.sqldb.QueryContext(ctx, attachmentChainsQuery, pq.Array(arnIDs), pq.Array(times), maxAttachmentDepth)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var ord int
+		var arnID string
+		if err = rows.Scan(&ord, &arnID); err != nil {
+			return err
+		}
+		if ord < 1 || ord > len(assets) {
+			return errors.New("attachment position out of range")
+		}
+		assets[ord-1].AttachedTo = append(assets[ord-1].AttachedTo, arnID)
+	}
+	rows.Close()
+	return rows.Err()
+}
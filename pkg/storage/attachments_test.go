@@ -0,0 +1,254 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/asecurityteam/asset-inventory-api/pkg/domain"
+)
+
+func fakeAttachmentChanges(changeType string) domain.CloudAssetChanges {
+	at, _ := time.Parse(time.RFC3339, "2019-04-09T08:29:35+00:00")
+	return domain.CloudAssetChanges{
+		ChangeTime:   at,
+		ResourceType: "AWS::EC2::NetworkInterface",
+		AccountID:    "aid",
+		Region:       "region",
+		ARN:          "arn:aws:ec2:region:aid:network-interface/eni-1",
+		Changes: []domain.NetworkChanges{
+			{AttachedTo: []string{"i-1"}, ChangeType: changeType},
+		},
+	}
+}
+
+func TestStoreAttachmentAssign(t *testing.T) {
+	mockdb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockdb.Close()
+
+	thedb := DB{
+		sqldb: mockdb,
+	}
+
+	changes := fakeAttachmentChanges("ADDED")
+	mock.ExpectBegin()
+	mock.ExpectExec("with sel as").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery("SELECT").WithArgs("eni-1", "aid", "region").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectExec(regexp.QuoteMeta(`update aws_resource_attachment`)).WithArgs(changes.ChangeTime, "i-1", "eni-1").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta(`insert into aws_resource_attachment`)).WithArgs(changes.ChangeTime, "i-1", "eni-1").WillReturnResult(sqlmock.NewResult(1, 1))
+	expectJournaled(mock)
+	mock.ExpectCommit()
+
+	assert.NoError(t, thedb.Store(context.Background(), changes))
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestStoreAttachmentRelease(t *testing.T) {
+	mockdb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockdb.Close()
+
+	thedb := DB{
+		sqldb: mockdb,
+	}
+
+	changes := fakeAttachmentChanges("DELETED")
+	mock.ExpectBegin()
+	mock.ExpectExec("with sel as").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery("SELECT").WithArgs("eni-1", "aid", "region").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectExec(regexp.QuoteMeta(`update aws_resource_attachment`)).WithArgs(changes.ChangeTime, "i-1", "eni-1").WillReturnResult(sqlmock.NewResult(0, 1))
+	expectJournaled(mock)
+	mock.ExpectCommit()
+
+	assert.NoError(t, thedb.Store(context.Background(), changes))
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestStoreAttachmentError(t *testing.T) {
+	mockdb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockdb.Close()
+
+	thedb := DB{
+		sqldb: mockdb,
+	}
+
+	changes := fakeAttachmentChanges("DELETED")
+	mock.ExpectBegin()
+	mock.ExpectExec("with sel as").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery("SELECT").WithArgs("eni-1", "aid", "region").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectExec(regexp.QuoteMeta(`update aws_resource_attachment`)).WithArgs(changes.ChangeTime, "i-1", "eni-1").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta(`insert into aws_resource_attachment`)).WillReturnError(errors.New("failed to store attachment"))
+	mock.ExpectRollback()
+
+	assert.Error(t, thedb.Store(context.Background(), changes))
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestFetchByIPAttachmentChain(t *testing.T) {
+	mockdb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockdb.Close()
+
+	thedb := DB{
+		sqldb: mockdb,
+	}
+
+	at, _ := time.Parse(time.RFC3339, "2019-04-09T08:55:35+00:00")
+	publicRows := sqlmock.NewRows([]string{"public_ip", "aws_hostname", "arn_id", "meta", "region", "resource_type",
+		"account", "id", "t_account", "t_login", "t_email", "t_name", "t_valid", "p_login", "p_email", "p_name", "p_valid"}).
+		AddRow("34.0.0.1", nil, "eipalloc-1", nil, "region", "AWS::EC2::EIP", "aid", 1, nil, nil, nil, nil, nil, nil, nil, nil, nil)
+	mock.ExpectQuery(regexp.QuoteMeta(resourceByPublicIPQuery)).WithArgs("34.0.0.1", at, "").WillReturnRows(publicRows)
+	mock.ExpectQuery(regexp.QuoteMeta(`with recursive chain`)).
+		WithArgs(pq.Array([]string{"eipalloc-1"}), pq.Array([]time.Time{at}), maxAttachmentDepth).
+		WillReturnRows(sqlmock.NewRows([]string{"ord", "arn_id"}).AddRow(1, "eni-1").AddRow(1, "i-1"))
+
+	results, err := thedb.FetchByIP(context.Background(), at, "34.0.0.1", domain.IPScope{})
+	assert.NoError(t, err)
+	assert.Len(t, results, 1)
+	assert.Equal(t, "eipalloc-1", results[0].ARN)
+	assert.Equal(t, []string{"eni-1", "i-1"}, results[0].AttachedTo)
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestFetchByIPAttachmentChainError(t *testing.T) {
+	mockdb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockdb.Close()
+
+	thedb := DB{
+		sqldb: mockdb,
+	}
+
+	at, _ := time.Parse(time.RFC3339, "2019-04-09T08:55:35+00:00")
+	publicRows := sqlmock.NewRows([]string{"public_ip", "aws_hostname", "arn_id", "meta", "region", "resource_type",
+		"account", "id", "t_account", "t_login", "t_email", "t_name", "t_valid", "p_login", "p_email", "p_name", "p_valid"}).
+		AddRow("34.0.0.1", nil, "eipalloc-1", nil, "region", "AWS::EC2::EIP", "aid", 1, nil, nil, nil, nil, nil, nil, nil, nil, nil)
+	mock.ExpectQuery(regexp.QuoteMeta(resourceByPublicIPQuery)).WithArgs("34.0.0.1", at, "").WillReturnRows(publicRows)
+	mock.ExpectQuery(regexp.QuoteMeta(`with recursive chain`)).WillReturnError(errors.New("no bueno"))
+
+	_, err = thedb.FetchByIP(context.Background(), at, "34.0.0.1", domain.IPScope{})
+	assert.Error(t, err)
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestFetchByIPsAttachmentChains(t *testing.T) {
+	mockdb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockdb.Close()
+
+	thedb := DB{
+		sqldb: mockdb,
+	}
+
+	at, _ := time.Parse(time.RFC3339, "2019-04-09T08:55:35+00:00")
+	later := at.Add(time.Hour)
+	privateRows := sqlmock.NewRows(ipBatchColumns).
+		AddRow(1, "10.0.0.1", nil, "eni-1", nil, "region", "AWS::EC2::NetworkInterface", "aid",
+			nil, nil, nil, nil, nil, nil, nil, nil, nil).
+		AddRow(2, "10.0.0.1", nil, "eni-1", nil, "region", "AWS::EC2::NetworkInterface", "aid",
+			nil, nil, nil, nil, nil, nil, nil, nil, nil)
+	mock.ExpectQuery("join aws_private_ip_assignment").WillReturnRows(privateRows)
+	// the network interface moved from an instance to another in between
+	mock.ExpectQuery(regexp.QuoteMeta(`with recursive chain`)).
+		WithArgs(pq.Array([]string{"eni-1", "eni-1"}), pq.Array([]time.Time{at, later}), maxAttachmentDepth).
+		WillReturnRows(sqlmock.NewRows([]string{"ord", "arn_id"}).AddRow(1, "i-1").AddRow(2, "i-2"))
+
+	results, err := thedb.FetchByIPs(context.Background(), []domain.IPLookup{
+		{IPAddress: "10.0.0.1", When: at},
+		{IPAddress: "10.0.0.1", When: later},
+	})
+	assert.NoError(t, err)
+	assert.Len(t, results, 2)
+	assert.Equal(t, []string{"i-1"}, results[0][0].AttachedTo)
+	assert.Equal(t, []string{"i-2"}, results[1][0].AttachedTo)
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestAttachmentChainOrdinalOutOfRange(t *testing.T) {
+	mockdb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockdb.Close()
+
+	thedb := DB{
+		sqldb: mockdb,
+	}
+
+	at, _ := time.Parse(time.RFC3339, "2019-04-09T08:55:35+00:00")
+	mock.ExpectQuery(regexp.QuoteMeta(`with recursive chain`)).
+		WillReturnRows(sqlmock.NewRows([]string{"ord", "arn_id"}).AddRow(2, "i-1"))
+
+	err = thedb.addAttachmentChains(context.Background(), []*domain.CloudAssetDetails{{ARN: "eni-1"}}, []time.Time{at})
+	assert.Error(t, err)
+}
+
+func TestStoreKubernetesPodOnNode(t *testing.T) {
+	mockdb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockdb.Close()
+
+	thedb := DB{
+		sqldb: mockdb,
+	}
+
+	at, _ := time.Parse(time.RFC3339, "2019-04-09T08:29:35+00:00")
+	changes := domain.CloudAssetChanges{
+		ChangeTime:   at,
+		ResourceType: domain.ResourceTypeKubernetesPod,
+		AccountID:    "aid",
+		Region:       "region",
+		ARN:          domain.KubernetesARN("region", "aid", domain.KubernetesKindPod, "cluster", "namespace", "web"),
+		Changes: []domain.NetworkChanges{
+			{PrivateIPAddresses: []string{"10.0.0.1"}, AttachedTo: []string{"i-1"}, VPCID: "vpc-1", ChangeType: "ADDED"},
+		},
+	}
+	// the pod is known by its cluster and namespace, so that pods of the same name elsewhere are not mixed up
+	const podID = "pod/cluster/namespace/web"
+	mock.ExpectBegin()
+	mock.ExpectExec("with sel as").WithArgs(podID, "region", "aid", domain.ResourceTypeKubernetesPod, []byte("null")).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery("SELECT").WithArgs(podID, "aid", "region").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectExec(regexp.QuoteMeta(`update aws_private_ip_assignment`)).WithArgs(at, "10.0.0.1", 1, "vpc-1", "").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`update aws_resource_attachment`)).WithArgs(at, "i-1", podID).WillReturnResult(sqlmock.NewResult(1, 1))
+	expectJournaled(mock)
+	mock.ExpectCommit()
+
+	assert.NoError(t, thedb.Store(context.Background(), changes))
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
@@ -0,0 +1,84 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+
+	"github.com/pkg/errors"
+
+	"github.com/asecurityteam/asset-inventory-api/pkg/domain"
+)
+
+// StoreBatch records several cloud asset changes in a single transaction, in the order of their change time, storing
+// each one as Store does. When atomic, the first failure rolls every change back and is reported against the failing
+// change. Otherwise each change is applied within a savepoint, so a failure only rolls that change back and the others
+// are kept.
+func (db *DB) StoreBatch(ctx context.Context, changes []domain.CloudAssetChanges, atomic bool) ([]error, error) {
+	itemErrors := make([]error, len(changes))
+	order := make([]int, len(changes))
+	for i := range order {
+		order[i] = i
+	}
+	// changes with the same change time keep the order they were sent in
+	sort.SliceStable(order, func(a, b int) bool {
+		return changes[order[a]].ChangeTime.Before(changes[order[b]].ChangeTime)
+	})
+
+	tx, err := db.sqldb.Begin()
+	if err != nil {
+		return itemErrors, err
+	}
+	for _, i := range order {
+		if atomic {
+			err = db.storeChanges(ctx, changes[i], tx)
+		} else {
+			err = db.storeChangesWithinSavepoint(ctx, changes[i], tx)
+		}
+		if err == nil {
+			continue
+		}
+		if _, ok := err.(domain.DuplicateEvent); ok {
+			itemErrors[i] = err // nothing was written, the transaction can go on
+			continue
+		}
+		if isTxAborted(err) || atomic {
+			itemErrors[i] = err
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				return itemErrors, errors.Wrap(rollbackErr, err.Error()) // so we don't lose the original error
+			}
+			return itemErrors, errors.Wrapf(err, "failed to store changes %d", i)
+		}
+		itemErrors[i] = err
+	}
+	if err = tx.Commit(); err != nil {
+		return itemErrors, err
+	}
+	return itemErrors, nil
+}
+
+// txAborted is returned when a change could not be rolled back to its savepoint, leaving the transaction unusable
+type txAborted struct {
+	error
+}
+
+func isTxAborted(err error) bool {
+	_, ok := err.(txAborted)
+	return ok
+}
+
+func (db *DB) storeChangesWithinSavepoint(ctx context.Context, cloudAssetChanges domain.CloudAssetChanges, tx *sql.Tx) error {
+	if _, err := tx.ExecContext(ctx, `savepoint batch_item`); err != nil {
+		return txAborted{err}
+	}
+	if err := db.storeChanges(ctx, cloudAssetChanges, tx); err != nil {
+		if _, rollbackErr := tx.ExecContext(ctx, `rollback to savepoint batch_item`); rollbackErr != nil {
+			return txAborted{errors.Wrap(rollbackErr, err.Error())}
+		}
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `release savepoint batch_item`); err != nil {
+		return txAborted{err}
+	}
+	return nil
+}
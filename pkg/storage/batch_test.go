@@ -0,0 +1,165 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/asecurityteam/asset-inventory-api/pkg/domain"
+)
+
+// fakeLifecycleChanges returns changes of a resource without any network change nor tags, so only the resource itself
+// and the journal are written
+func fakeLifecycleChanges(arn string, when time.Time) domain.CloudAssetChanges {
+	return domain.CloudAssetChanges{
+		ChangeTime:   when,
+		ResourceType: "rtype",
+		AccountID:    "aid",
+		Region:       "region",
+		ARN:          arn,
+	}
+}
+
+func expectStored(mock sqlmock.Sqlmock, arn string, resourceID int) {
+	mock.ExpectExec("with sel as").WithArgs(arn, "region", "aid", "rtype", sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery("SELECT").WithArgs(arn, "aid", "region").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(resourceID))
+	expectJournaled(mock)
+}
+
+func TestStoreBatchAtomicInTimeOrder(t *testing.T) {
+	mockdb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockdb.Close()
+
+	thedb := DB{
+		sqldb: mockdb,
+	}
+
+	at, _ := time.Parse(time.RFC3339, "2019-04-09T08:55:35+00:00")
+	changes := []domain.CloudAssetChanges{
+		fakeLifecycleChanges("later", at.Add(time.Minute)),
+		fakeLifecycleChanges("earlier", at),
+	}
+	mock.ExpectBegin()
+	expectStored(mock, "earlier", 1)
+	expectStored(mock, "later", 2)
+	mock.ExpectCommit()
+
+	itemErrors, err := thedb.StoreBatch(context.Background(), changes, true)
+	assert.NoError(t, err)
+	assert.Equal(t, []error{nil, nil}, itemErrors)
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestStoreBatchAtomicRollsBack(t *testing.T) {
+	mockdb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockdb.Close()
+
+	thedb := DB{
+		sqldb: mockdb,
+	}
+
+	at, _ := time.Parse(time.RFC3339, "2019-04-09T08:55:35+00:00")
+	changes := []domain.CloudAssetChanges{
+		fakeLifecycleChanges("first", at),
+		fakeLifecycleChanges("second", at),
+		fakeLifecycleChanges("third", at),
+	}
+	mock.ExpectBegin()
+	expectStored(mock, "first", 1)
+	mock.ExpectExec("with sel as").WithArgs("second", "region", "aid", "rtype", sqlmock.AnyArg()).WillReturnError(errors.New("no bueno"))
+	mock.ExpectRollback()
+
+	itemErrors, err := thedb.StoreBatch(context.Background(), changes, true)
+	assert.Error(t, err)
+	assert.Nil(t, itemErrors[0])
+	assert.Error(t, itemErrors[1])
+	assert.Nil(t, itemErrors[2])
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestStoreBatchBestEffort(t *testing.T) {
+	mockdb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockdb.Close()
+
+	at, _ := time.Parse(time.RFC3339, "2019-04-09T08:55:35+00:00")
+	thedb := DB{
+		sqldb:         mockdb,
+		now:           func() time.Time { return at },
+		eventIDWindow: time.Hour,
+	}
+
+	duplicate := fakeLifecycleChanges("duplicate", at)
+	duplicate.EventID = "event-1"
+	changes := []domain.CloudAssetChanges{
+		fakeLifecycleChanges("first", at),
+		fakeLifecycleChanges("failing", at),
+		duplicate,
+		fakeLifecycleChanges("last", at),
+	}
+	mock.ExpectBegin()
+	mock.ExpectExec("savepoint batch_item").WillReturnResult(sqlmock.NewResult(0, 0))
+	expectStored(mock, "first", 1)
+	mock.ExpectExec("release savepoint batch_item").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("savepoint batch_item").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("with sel as").WithArgs("failing", "region", "aid", "rtype", sqlmock.AnyArg()).WillReturnError(errors.New("no bueno"))
+	mock.ExpectExec("rollback to savepoint batch_item").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("savepoint batch_item").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta(`insert into aws_processed_event`)).WithArgs("event-1", at, at.Add(-time.Hour)).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("rollback to savepoint batch_item").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("savepoint batch_item").WillReturnResult(sqlmock.NewResult(0, 0))
+	expectStored(mock, "last", 2)
+	mock.ExpectExec("release savepoint batch_item").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	itemErrors, err := thedb.StoreBatch(context.Background(), changes, false)
+	assert.NoError(t, err)
+	assert.Nil(t, itemErrors[0])
+	assert.EqualError(t, itemErrors[1], "no bueno")
+	assert.Equal(t, domain.DuplicateEvent{EventID: "event-1"}, itemErrors[2])
+	assert.Nil(t, itemErrors[3])
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestStoreBatchBestEffortSavepointError(t *testing.T) {
+	mockdb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockdb.Close()
+
+	thedb := DB{
+		sqldb: mockdb,
+	}
+
+	at, _ := time.Parse(time.RFC3339, "2019-04-09T08:55:35+00:00")
+	changes := []domain.CloudAssetChanges{fakeLifecycleChanges("first", at)}
+	mock.ExpectBegin()
+	mock.ExpectExec("savepoint batch_item").WillReturnError(errors.New("no bueno"))
+	mock.ExpectRollback()
+
+	_, err = thedb.StoreBatch(context.Background(), changes, false)
+	assert.Error(t, err)
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
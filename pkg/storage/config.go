@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"os"
+	"time"
 
 	"github.com/golang-migrate/migrate/v4"
 	_ "github.com/golang-migrate/migrate/v4/database/postgres" // used internally by migrate
@@ -26,6 +27,9 @@ type PostgresConfig struct {
 	PartitionTTL     int
 	MinSchemaVersion uint
 	MigrationsPath   string
+	PrivateNetworks  []string      `description:"Space separated ranges in CIDR notation of addresses looked up as private."`
+	UnifiedIPLookup  bool          `description:"Look addresses outside of PrivateNetworks up as both public and private."`
+	EventIDWindow    time.Duration `description:"How long processed event IDs are remembered to ignore redelivered changes."`
 }
 
 // Name is used by the settings library to replace the default naming convention.
@@ -49,6 +53,8 @@ func (*PostgresConfigComponent) Settings() *PostgresConfig {
 		PartitionTTL:     360,
 		MinSchemaVersion: MinimumSchemaVersion,
 		MigrationsPath:   "/db-migrations",
+		PrivateNetworks:  DefaultPrivateNetworks,
+		EventIDWindow:    24 * time.Hour,
 	}
 }
 
@@ -71,8 +77,18 @@ func NewSchemaManager(sourcePath string, datasourceURL string) (*SchemaManager,
 
 // New constructs a DB from a config.
 func (*PostgresConfigComponent) New(ctx context.Context, c *PostgresConfig, t connectionType) (*DB, error) {
-	db := &DB{}
-	var err error
+	networks, err := parseNetworks(c.PrivateNetworks)
+	if err != nil {
+		return nil, err
+	}
+	if len(networks) == 0 {
+		networks = privateIPNetworks
+	}
+	db := &DB{
+		privateNetworks: networks,
+		unifiedIPLookup: c.UnifiedIPLookup,
+		eventIDWindow:   c.EventIDWindow,
+	}
 	url := c.URL
 	if t == Replica {
 		url = c.ReplicaURL
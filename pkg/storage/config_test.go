@@ -44,3 +44,17 @@ func TestShouldFailToConnectToInvalidDB(t *testing.T) {
 	_, err := postgresConfigComponent.New(context.Background(), &postgresConfig, Primary)
 	assert.NotNil(t, err)
 }
+
+func TestSettingsDefaultPrivateNetworks(t *testing.T) {
+	postgresConfig := (&PostgresConfigComponent{}).Settings()
+	assert.Equal(t, DefaultPrivateNetworks, postgresConfig.PrivateNetworks)
+	assert.False(t, postgresConfig.UnifiedIPLookup)
+}
+
+func TestShouldFailOnInvalidPrivateNetwork(t *testing.T) {
+	postgresConfig := PostgresConfig{URL: "not a valid db url", PrivateNetworks: []string{"10.0.0.0/8", "10.0.0.1"}}
+
+	_, err := (&PostgresConfigComponent{}).New(context.Background(), &postgresConfig, Primary)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "10.0.0.1")
+}
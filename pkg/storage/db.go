@@ -19,18 +19,24 @@ const (
 	deleted = "DELETED" // deleted network event
 )
 
-// Query to find resource by private IP using v2 schema
-const resourceByPrivateIPQuery = `select * from get_resource_by_private_ip($1, $2)`
+// Query to find resource by private IP using v2 schema, optionally narrowed to an account and VPC
+const resourceByPrivateIPQuery = `select * from get_resource_by_private_ip($1, $2, nullif($3::varchar, ''), nullif($4::varchar, ''))`
 
-// Query to find resource by public IP using v2 schema
-const resourceByPublicIPQuery = `select * from get_resource_by_public_ip($1, $2)`
+// Query to find resource by public IP using v2 schema, optionally narrowed to an account
+const resourceByPublicIPQuery = `select * from get_resource_by_public_ip($1, $2, nullif($3::varchar, ''))`
 
 // Query to find resource by hostname using v2 schema
 const resourceByHostnameQuery = `select * from get_resource_by_hostname($1, $2)`
 
+// Query to find resource by hostname of a private IP address
+const resourceByPrivateHostnameQuery = `select * from get_resource_by_private_hostname($1, $2)`
+
 // Query to find resource by ARN ID
 const resourceByARNIDQuery = `select * from get_resource_by_arn_id($1, $2)`
 
+// Query to find the hostnames of the private IP addresses returned by resourceByARNIDQuery
+const privateHostnamesByARNIDQuery = `select * from get_private_hostnames_by_arn_id($1, $2)`
+
 // Query to find owner and champions by account ID, which is auto-increment primary key
 const ownerByAccountIDQuery = `select * from get_owner_and_champions_by_account_id($1)`
 
@@ -56,23 +62,24 @@ type DB struct {
 	once                sync.Once
 	now                 func() time.Time // unit test seam
 	defaultPartitionTTL int
+	privateNetworks     []net.IPNet   // ranges of private addresses, privateIPNetworks when not set
+	unifiedIPLookup     bool          // look addresses outside of the private ranges up in both assignment tables
+	eventIDWindow       time.Duration // how long processed event IDs are remembered
 }
 
-var privateIPNetworks = []net.IPNet{
-	{
-		IP:   net.IPv4(192, 168, 1, 0),
-		Mask: net.IPv4Mask(255, 255, 0, 0),
-	},
-	{
-		IP:   net.IPv4(172, 16, 0, 0),
-		Mask: net.IPv4Mask(255, 240, 0, 0),
-	},
-	{
-		IP:   net.IPv4(10, 0, 0, 0),
-		Mask: net.IPv4Mask(255, 0, 0, 0),
-	},
+// DefaultPrivateNetworks are the ranges of addresses looked up as private unless configured otherwise:
+// RFC1918, shared address space (CGNAT), IPv6 unique local and link-local addresses
+var DefaultPrivateNetworks = []string{
+	"10.0.0.0/8",
+	"172.16.0.0/12",
+	"192.168.0.0/16",
+	"100.64.0.0/10",
+	"fc00::/7",
+	"fe80::/10",
 }
 
+var privateIPNetworks = mustParseNetworks(DefaultPrivateNetworks)
+
 // Init initializes a connection to a Postgres database according to the environment variables POSTGRES_HOST, POSTGRES_PORT, POSTGRES_USER, POSTGRES_PASSWORD, POSTGRES_DATABASE
 func (db *DB) Init(ctx context.Context, url string, partitionTTL int) error {
 	var initErr error
@@ -149,10 +156,7 @@ func (db *DB) Store(ctx context.Context, cloudAssetChanges domain.CloudAssetChan
 	if err != nil {
 		return err
 	}
-	if err = db.ensureResourceExists(ctx, cloudAssetChanges, tx); err == nil {
-		err = db.applyChanges(ctx, cloudAssetChanges, tx)
-	}
-	if err != nil {
+	if err = db.storeChanges(ctx, cloudAssetChanges, tx); err != nil {
 		if rollbackErr := tx.Rollback(); rollbackErr != nil {
 			return errors.Wrap(rollbackErr, err.Error()) // so we don't lose the original error
 		}
@@ -164,25 +168,45 @@ func (db *DB) Store(ctx context.Context, cloudAssetChanges domain.CloudAssetChan
 	return nil
 }
 
+// storeChanges records the changes as part of the transaction, unless they carry the ID of an event already processed
+func (db *DB) storeChanges(ctx context.Context, cloudAssetChanges domain.CloudAssetChanges, tx *sql.Tx) error {
+	if err := db.markEventProcessed(ctx, tx, cloudAssetChanges.EventID); err != nil {
+		return err
+	}
+	if err := db.ensureResourceExists(ctx, cloudAssetChanges, tx); err != nil {
+		return err
+	}
+	if err := db.applyChanges(ctx, cloudAssetChanges, tx); err != nil {
+		return err
+	}
+	return db.journalChanges(ctx, cloudAssetChanges, tx)
+}
+
 func (db *DB) applyChanges(ctx context.Context, cloudAssetChanges domain.CloudAssetChanges, tx *sql.Tx) error {
 	var err error
-	arnID := resIDFromARN(cloudAssetChanges.ARN)
+	arnID := domain.ResourceIDFromARN(cloudAssetChanges.ARN)
 	resourceID, err := db.getResourceID(ctx, tx, arnID, cloudAssetChanges.Region, cloudAssetChanges.AccountID)
 	if err != nil {
 		return err
 	}
 	for _, val := range cloudAssetChanges.Changes {
 		for _, ip := range val.PrivateIPAddresses {
+			if ip, err = canonicalIP(ip); err != nil {
+				return err
+			}
 			if strings.EqualFold(added, val.ChangeType) {
-				err = db.assignPrivateIP(ctx, tx, resourceID, ip, cloudAssetChanges.ChangeTime)
+				err = db.assignPrivateIP(ctx, tx, resourceID, ip, val.VPCID, val.SubnetID, cloudAssetChanges.ChangeTime)
 			} else {
-				err = db.releasePrivateIP(ctx, tx, resourceID, ip, cloudAssetChanges.ChangeTime)
+				err = db.releasePrivateIP(ctx, tx, resourceID, ip, val.VPCID, val.SubnetID, cloudAssetChanges.ChangeTime)
 			}
 			if err != nil {
 				return err
 			}
 		}
 		for _, ip := range val.PublicIPAddresses {
+			if ip, err = canonicalIP(ip); err != nil {
+				return err
+			}
 			for _, hostname := range val.Hostnames { //TODO look very closely into awsconfig-tranformerd logic for this
 				if strings.EqualFold(added, val.ChangeType) {
 					err = db.assignPublicIP(ctx, tx, resourceID, ip, hostname, cloudAssetChanges.ChangeTime)
@@ -194,16 +218,195 @@ func (db *DB) applyChanges(ctx context.Context, cloudAssetChanges domain.CloudAs
 				}
 			}
 		}
-		for _, res := range val.RelatedResources {
-			if strings.EqualFold(added, val.ChangeType) {
-				err = db.assignResourceRelationship(ctx, tx, arnID, res, cloudAssetChanges.ChangeTime)
-			} else {
-				err = db.releaseResourceRelationship(ctx, tx, arnID, res, cloudAssetChanges.ChangeTime)
-			}
-			if err != nil {
+		for _, address := range val.Addresses {
+			if err = db.applyAddressChange(ctx, tx, resourceID, address, val, cloudAssetChanges.ChangeTime); err != nil {
 				return err
 			}
 		}
+		if err = db.applyLinkChanges(ctx, tx, arnID, val, cloudAssetChanges.ChangeTime); err != nil {
+			return err
+		}
+	}
+	if cloudAssetChanges.Tags != nil {
+		if err = db.storeTags(ctx, tx, resourceID, cloudAssetChanges.Tags, cloudAssetChanges.ChangeTime); err != nil {
+			return err
+		}
+	}
+	switch strings.ToUpper(cloudAssetChanges.Lifecycle) {
+	case "":
+		return nil
+	case domain.LifecycleCreated:
+		return db.createResource(ctx, tx, resourceID, cloudAssetChanges.ChangeTime)
+	case domain.LifecycleTerminated:
+		return db.terminateResource(ctx, tx, resourceID, arnID, cloudAssetChanges.ChangeTime)
+	default:
+		return errors.Errorf("unknown lifecycle %s", cloudAssetChanges.Lifecycle)
+	}
+}
+
+// applyLinkChanges assigns or releases the relationships and attachments recorded by the resource. Those are kept by
+// resource ID, unlike addresses which belong to the resource of an account and region.
+func (db *DB) applyLinkChanges(ctx context.Context, tx *sql.Tx, arnID string, networkChanges domain.NetworkChanges, when time.Time) error {
+	var err error
+	assign := strings.EqualFold(added, networkChanges.ChangeType)
+	for _, res := range networkChanges.RelatedResources {
+		if assign {
+			err = db.assignResourceRelationship(ctx, tx, arnID, res, when)
+		} else {
+			err = db.releaseResourceRelationship(ctx, tx, arnID, res, when)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	for _, res := range networkChanges.AttachedTo {
+		if assign {
+			err = db.assignAttachment(ctx, tx, arnID, res, when)
+		} else {
+			err = db.releaseAttachment(ctx, tx, arnID, res, when)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// applyAddressChange assigns or releases an address with its own hostname only, a public address without a hostname
+// is recorded as such. The hostname of a private address is assigned separately, as a private address may have
+// several. The network interface is recorded against the interval the change opened or closed.
+func (db *DB) applyAddressChange(ctx context.Context, tx *sql.Tx, resourceID int, address domain.AddressChange,
+	networkChanges domain.NetworkChanges, when time.Time) error {
+	ip, err := canonicalIP(address.IPAddress)
+	if err != nil {
+		return err
+	}
+	assign := strings.EqualFold(added, networkChanges.ChangeType)
+	switch {
+	case address.Public && assign:
+		err = db.assignPublicIP(ctx, tx, resourceID, ip, address.Hostname, when)
+	case address.Public:
+		err = db.releasePublicIP(ctx, tx, resourceID, ip, address.Hostname, when)
+	case assign:
+		err = db.assignPrivateIP(ctx, tx, resourceID, ip, networkChanges.VPCID, networkChanges.SubnetID, when)
+	default:
+		err = db.releasePrivateIP(ctx, tx, resourceID, ip, networkChanges.VPCID, networkChanges.SubnetID, when)
+	}
+	if err == nil && !address.Public && address.Hostname != "" {
+		if assign {
+			err = db.assignPrivateHostname(ctx, tx, resourceID, ip, address.Hostname, when)
+		} else {
+			err = db.releasePrivateHostname(ctx, tx, resourceID, ip, address.Hostname, when)
+		}
+	}
+	if err != nil || address.NetworkInterfaceID == "" {
+		return err
+	}
+	return db.recordNetworkInterface(ctx, tx, resourceID, ip, address.Public, address.NetworkInterfaceID, when)
+}
+
+func (db *DB) recordNetworkInterface(ctx context.Context, tx *sql.Tx, resourceID int, ip string, public bool,
+	networkInterfaceID string, when time.Time) error {
+	const recordPrivateNetworkInterfaceQuery = `
+update aws_private_ip_assignment
+set network_interface_id = $1
+where private_ip = $2
+  and aws_resource_id = $3
+  and (not_before = $4 or not_after = $4)`
+
+	const recordPublicNetworkInterfaceQuery = `
+update aws_public_ip_assignment
+set network_interface_id = $1
+where public_ip = $2
+  and aws_resource_id = $3
+  and (not_before = $4 or not_after = $4)`
+
+	query := recordPrivateNetworkInterfaceQuery
+	if public {
+		query = recordPublicNetworkInterfaceQuery
+	}
+	_, err := tx.ExecContext(ctx, query, networkInterfaceID, ip, resourceID, when)
+	return err
+}
+
+// createResource records the start of the existence interval of the resource, keeping the earliest one seen
+func (db *DB) createResource(ctx context.Context, tx *sql.Tx, resourceID int, when time.Time) error {
+	const createResourceQuery = `
+update aws_resource
+set not_before = $1
+where id = $2
+  and (not_before is null or not_before > $1)`
+
+	_, err := tx.ExecContext(ctx, createResourceQuery, when, resourceID)
+	return err
+}
+
+// terminateResource records the end of the existence interval of the resource, and closes every assignment,
+// relationship and the tags still open at that time so the producer does not have to list them all
+func (db *DB) terminateResource(ctx context.Context, tx *sql.Tx, resourceID int, arnID string, when time.Time) error {
+	const terminateResourceQuery = `
+update aws_resource
+set not_after = $1
+where id = $2
+  and (not_after is null or not_after > $1)`
+
+	const releaseAllPrivateIPsQuery = `
+update aws_private_ip_assignment
+set not_after = $1
+where aws_resource_id = $2
+  and not_after is null
+  and not_before <= $1`
+
+	const releaseAllPublicIPsQuery = `
+update aws_public_ip_assignment
+set not_after = $1
+where aws_resource_id = $2
+  and not_after is null
+  and not_before <= $1`
+
+	const releaseAllPrivateHostnamesQuery = `
+update aws_private_hostname_assignment
+set not_after = $1
+where aws_resource_id = $2
+  and not_after is null
+  and not_before <= $1`
+
+	const closeTagsQuery = `
+update aws_resource_tags
+set not_after = $1
+where aws_resource_id = $2
+  and not_after is null
+  and not_before <= $1`
+
+	for _, query := range []string{terminateResourceQuery, releaseAllPrivateIPsQuery, releaseAllPublicIPsQuery, releaseAllPrivateHostnamesQuery, closeTagsQuery} {
+		if _, err := tx.ExecContext(ctx, query, when, resourceID); err != nil {
+			return err
+		}
+	}
+	return db.releaseAllLinks(ctx, tx, arnID, when)
+}
+
+// releaseAllLinks closes the relationships and attachments recorded by the resource, and the ones other resources
+// recorded towards it, still open at the time
+func (db *DB) releaseAllLinks(ctx context.Context, tx *sql.Tx, arnID string, when time.Time) error {
+	const releaseAllResourceRelationshipsQuery = `
+update aws_resource_relationship
+set not_after = $1
+where (arn_id = $2 or related_arn_id = $2)
+  and not_after is null
+  and (not_before is null or not_before <= $1)`
+
+	const releaseAllAttachmentsQuery = `
+update aws_resource_attachment
+set not_after = $1
+where (arn_id = $2 or attached_to_arn_id = $2)
+  and not_after is null
+  and not_before <= $1`
+
+	for _, query := range []string{releaseAllResourceRelationshipsQuery, releaseAllAttachmentsQuery} {
+		if _, err := tx.ExecContext(ctx, query, when, arnID); err != nil {
+			return err
+		}
 	}
 	return nil
 }
@@ -257,7 +460,7 @@ on conflict do nothing
 	tagsBytes, _ := json.Marshal(cloudAssetChanges.Tags) // an error here is not possible considering json.Marshal is taking a simple map or nil
 	if _, err := tx.ExecContext(ctx,
 		createResourceQuery,
-		resIDFromARN(cloudAssetChanges.ARN),
+		domain.ResourceIDFromARN(cloudAssetChanges.ARN),
 		cloudAssetChanges.Region,
 		cloudAssetChanges.AccountID,
 		cloudAssetChanges.ResourceType,
@@ -267,43 +470,131 @@ on conflict do nothing
 	return nil
 }
 
-// extract unique resource-type/resource-id from full ARN format
-// it is always the part after account-id:
-// https://docs.aws.amazon.com/general/latest/gr/aws-arns-and-namespaces.html
-func resIDFromARN(ARN string) string {
-	parts := strings.SplitN(ARN, ":", 6)
-	resourceID := parts[len(parts)-1]
-	if strings.HasPrefix(resourceID, "loadbalancer/app") {
-		return resourceID[13:]
+// FetchByHostname gets the assets who have hostname at the specified time, for either a public or a private IP address.
+// An asset holding the hostname for both is returned once, with the addresses of both.
+func (db *DB) FetchByHostname(ctx context.Context, when time.Time, hostname string) ([]domain.CloudAssetDetails, error) {
+	public, err := db.runLookupQuery(ctx, false, resourceByHostnameQuery, hostname, when)
+	if err != nil {
+		return nil, err
+	}
+	private, err := db.runLookupQuery(ctx, true, resourceByPrivateHostnameQuery, hostname, when)
+	if err != nil {
+		return nil, err
 	}
-	parts = strings.SplitAfterN(resourceID, "/", -1)
-	return parts[len(parts)-1]
+	byARN := make(map[string]int, len(public))
+	for i, asset := range public {
+		byARN[asset.ARN] = i
+	}
+	for _, asset := range private {
+		i, ok := byARN[asset.ARN]
+		if !ok {
+			asset.Hostnames = append(asset.Hostnames, hostname)
+			byARN[asset.ARN] = len(public)
+			public = append(public, asset)
+			continue
+		}
+		public[i].PrivateIPAddresses = append(public[i].PrivateIPAddresses, asset.PrivateIPAddresses...)
+		found := false
+		for _, val := range public[i].Hostnames {
+			if strings.EqualFold(val, hostname) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			public[i].Hostnames = append(public[i].Hostnames, hostname)
+		}
+	}
+	return public, nil
 }
 
-// FetchAll gets all the assets present at the specified time
-func (db *DB) FetchAll(ctx context.Context, when time.Time, count uint, offset uint, typeFilter string) ([]domain.CloudAssetDetails, error) {
-	return nil, errors.New("bulk export API is not available")
+// FetchByIP gets the assets who have IP address at the specified time, within the account and VPC of the scope if set,
+// along with the resources they are attached to
+func (db *DB) FetchByIP(ctx context.Context, when time.Time, ipAddress string, scope domain.IPScope) ([]domain.CloudAssetDetails, error) {
+	assets, err := db.fetchByIP(ctx, when, ipAddress, scope)
+	if err != nil {
+		return nil, err
+	}
+	attached := make([]*domain.CloudAssetDetails, len(assets))
+	times := make([]time.Time, len(assets))
+	for i := range assets {
+		attached[i] = &assets[i]
+		times[i] = when
+	}
+	if err = db.addAttachmentChains(ctx, attached, times); err != nil {
+		return nil, err
+	}
+	return assets, nil
 }
 
-// FetchByHostname gets the assets who have hostname at the specified time
-func (db *DB) FetchByHostname(ctx context.Context, when time.Time, hostname string) ([]domain.CloudAssetDetails, error) {
-	return db.runLookupQuery(ctx, false, resourceByHostnameQuery, hostname, when)
+func (db *DB) fetchByIP(ctx context.Context, when time.Time, ipAddress string, scope domain.IPScope) ([]domain.CloudAssetDetails, error) {
+	ipaddr := net.ParseIP(ipAddress)
+	if ipaddr == nil {
+		return nil, errors.New("invalid IP address")
+	}
+	if db.isPrivateIP(ipaddr) {
+		return db.runLookupQuery(ctx, true, resourceByPrivateIPQuery, ipaddr.String(), when, scope.AccountID, scope.VPCID)
+	}
+	public, err := db.runLookupQuery(ctx, false, resourceByPublicIPQuery, ipaddr.String(), when, scope.AccountID)
+	if err != nil || !db.unifiedIPLookup {
+		return public, err
+	}
+	// the address may still be used privately, e.g. from a non RFC1918 VPC range that is not configured
+	private, err := db.runLookupQuery(ctx, true, resourceByPrivateIPQuery, ipaddr.String(), when, scope.AccountID, scope.VPCID)
+	if err != nil {
+		return nil, err
+	}
+	return append(public, private...), nil
+}
+
+// parseNetworks parses a list of ranges in CIDR notation
+func parseNetworks(cidrs []string) ([]net.IPNet, error) {
+	networks := make([]net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid private network %s", cidr)
+		}
+		networks = append(networks, *network)
+	}
+	return networks, nil
 }
 
-// FetchByIP gets the assets who have IP address at the specified time
-func (db *DB) FetchByIP(ctx context.Context, when time.Time, ipAddress string) ([]domain.CloudAssetDetails, error) {
+func mustParseNetworks(cidrs []string) []net.IPNet {
+	networks, err := parseNetworks(cidrs)
+	if err != nil {
+		panic(err)
+	}
+	return networks
+}
+
+// canonicalIP returns the canonical form of an IPv4 or IPv6 address, so the same address is always stored and
+// reported the same way. IPv4-mapped IPv6 addresses are reduced to IPv4.
+func canonicalIP(ipAddress string) (string, error) {
 	ipaddr := net.ParseIP(ipAddress)
 	if ipaddr == nil {
-		return nil, errors.New("invalid IP address")
+		return "", errors.Errorf("invalid IP address %s", ipAddress)
 	}
-	if isPrivateIP(ipaddr) {
-		return db.runLookupQuery(ctx, true, resourceByPrivateIPQuery, ipAddress, when)
+	return ipaddr.String(), nil
+}
+
+// IsPrivateIP tells whether the address falls within the private ranges of the DB, invalid addresses are not private
+func (db *DB) IsPrivateIP(ipAddress string) bool {
+	ipaddr := net.ParseIP(ipAddress)
+	return ipaddr != nil && db.isPrivateIP(ipaddr)
+}
+
+// isPrivateIP tells whether the address falls within the private ranges of the DB
+func (db *DB) isPrivateIP(ip net.IP) bool {
+	networks := db.privateNetworks
+	if networks == nil {
+		networks = privateIPNetworks
 	}
-	return db.runLookupQuery(ctx, false, resourceByPublicIPQuery, ipAddress, when)
+	return isPrivateIP(ip, networks)
 }
 
-func isPrivateIP(ip net.IP) bool {
-	for _, net := range privateIPNetworks {
+func isPrivateIP(ip net.IP, networks []net.IPNet) bool {
+	for _, net := range networks {
 		if net.Contains(ip) {
 			return true
 		}
@@ -510,6 +801,13 @@ func (db *DB) FetchByResourceID(ctx context.Context, when time.Time, resID strin
 	for ip := range tempPublicIPMap {
 		asset.PublicIPAddresses = append(asset.PublicIPAddresses, ip)
 	}
+	privateHostnames, err := db.fetchPrivateHostnames(ctx, when, resID)
+	if err != nil {
+		return nil, err
+	}
+	for _, hostname := range privateHostnames {
+		tempHostnameMap[hostname] = struct{}{}
+	}
 	for hostname := range tempHostnameMap {
 		asset.Hostnames = append(asset.Hostnames, hostname)
 	}
@@ -532,10 +830,12 @@ func (db *DB) FetchByResourceID(ctx context.Context, when time.Time, resID strin
 	return cloudAssetDetails, err
 }
 
-func (db *DB) assignPrivateIP(ctx context.Context, tx *sql.Tx, resourceID int, ip string, when time.Time) error {
+func (db *DB) assignPrivateIP(ctx context.Context, tx *sql.Tx, resourceID int, ip string, vpcID string, subnetID string, when time.Time) error {
 	const assignPrivateIPQueryUpdate = `
 update aws_private_ip_assignment
-set not_before = $1
+set not_before = $1,
+    vpc_id     = coalesce(nullif($4::varchar, ''), vpc_id),
+    subnet_id  = coalesce(nullif($5::varchar, ''), subnet_id)
 where private_ip = $2
   and not_before = to_timestamp(0)
   and not_after > $1
@@ -543,10 +843,10 @@ where private_ip = $2
 
 	const assignPrivateIPQueryInsert = `
 insert into aws_private_ip_assignment
-    (not_before, private_ip, aws_resource_id)
-values ($1, $2, $3) on conflict do nothing ;`
+    (not_before, private_ip, aws_resource_id, vpc_id, subnet_id)
+values ($1, $2, $3, nullif($4::varchar, ''), nullif($5::varchar, '')) on conflict do nothing ;`
 
-	res, err := tx.ExecContext(ctx, assignPrivateIPQueryUpdate, when, ip, resourceID)
+	res, err := tx.ExecContext(ctx, assignPrivateIPQueryUpdate, when, ip, resourceID, vpcID, subnetID)
 	if err != nil {
 		return err
 	}
@@ -557,28 +857,30 @@ values ($1, $2, $3) on conflict do nothing ;`
 	if changedRows != 0 {
 		return nil
 	}
-	_, err = tx.ExecContext(ctx, assignPrivateIPQueryInsert, when, ip, resourceID)
+	_, err = tx.ExecContext(ctx, assignPrivateIPQueryInsert, when, ip, resourceID, vpcID, subnetID)
 	return err
 }
 
-func (db *DB) releasePrivateIP(ctx context.Context, tx *sql.Tx, resourceID int, ip string, when time.Time) error {
+func (db *DB) releasePrivateIP(ctx context.Context, tx *sql.Tx, resourceID int, ip string, vpcID string, subnetID string, when time.Time) error {
 	//we use to_timestamp(0) for release events w/o known assignment as the start of epoch - 1970-01-01 is known
 	//to not have AWS resources by definition
 	//this way we can find unbalanced events while avoiding nullable not_before
 	//which provides minimal support for out-of-order events
 	const releasePrivateIPQueryUpdate = `
 update aws_private_ip_assignment
-set not_after=$1
+set not_after=$1,
+    vpc_id = coalesce(nullif($4::varchar, ''), vpc_id),
+    subnet_id = coalesce(nullif($5::varchar, ''), subnet_id)
 where private_ip = $2
   and aws_resource_id = $3
   and not_after is null ;`
 
 	const releasePrivateIPQueryInsert = `
 insert into aws_private_ip_assignment
-    (not_before, not_after, private_ip, aws_resource_id)
-values (to_timestamp(0), $1, $2, $3) on conflict do nothing ;`
+    (not_before, not_after, private_ip, aws_resource_id, vpc_id, subnet_id)
+values (to_timestamp(0), $1, $2, $3, nullif($4::varchar, ''), nullif($5::varchar, '')) on conflict do nothing ;`
 
-	res, err := tx.ExecContext(ctx, releasePrivateIPQueryUpdate, when, ip, resourceID)
+	res, err := tx.ExecContext(ctx, releasePrivateIPQueryUpdate, when, ip, resourceID, vpcID, subnetID)
 	if err != nil {
 		return err
 	}
@@ -589,10 +891,32 @@ values (to_timestamp(0), $1, $2, $3) on conflict do nothing ;`
 	if changedRows != 0 {
 		return nil
 	}
-	_, err = tx.ExecContext(ctx, releasePrivateIPQueryInsert, when, ip, resourceID)
+	_, err = tx.ExecContext(ctx, releasePrivateIPQueryInsert, when, ip, resourceID, vpcID, subnetID)
 	return err
 }
 
+// fetchPrivateHostnames gets the hostnames of the private IP addresses found by FetchByResourceID
+func (db *DB) fetchPrivateHostnames(ctx context.Context, when time.Time, resID string) ([]string, error) {
+	rows, err := db.sqldb.QueryContext(ctx, privateHostnamesByARNIDQuery, resID, when)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	hostnames := make([]string, 0)
+	for rows.Next() {
+		var hostname string
+		if err = rows.Scan(&hostname); err != nil {
+			return nil, err
+		}
+		hostnames = append(hostnames, hostname)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return hostnames, nil
+}
+
 func (db *DB) getResourceID(ctx context.Context, tx *sql.Tx, arnID string, region string, accountID string) (int, error) {
 	row := tx.QueryRowContext(ctx, resourceIDQuery, arnID, accountID, region)
 	var resourceID int
@@ -602,6 +926,50 @@ func (db *DB) getResourceID(ctx context.Context, tx *sql.Tx, arnID string, regio
 	return resourceID, nil
 }
 
+// storeTags records the tags of the resource as in effect from the time of the change until the next recorded change,
+// which is only known for events arriving out of order. Nothing is recorded when the tags in effect are the same.
+func (db *DB) storeTags(ctx context.Context, tx *sql.Tx, resourceID int, tags map[string]string, when time.Time) error {
+	const currentTagsQuery = `
+select id, meta = $3::jsonb
+from aws_resource_tags
+where aws_resource_id = $1
+  and not_before <= $2
+  and (not_after is null or not_after > $2)
+order by not_before desc
+limit 1`
+
+	const closeTagsQuery = `
+update aws_resource_tags
+set not_after = $1
+where id = $2`
+
+	const insertTagsQuery = `
+insert into aws_resource_tags
+    (not_before, not_after, meta, aws_resource_id)
+values ($1,
+        (select min(not_before) from aws_resource_tags where aws_resource_id = $3 and not_before > $1),
+        $2::jsonb,
+        $3)`
+
+	tagsBytes, _ := json.Marshal(tags) // an error here is not possible considering json.Marshal is taking a simple map
+	var currentID int64
+	var same bool
+	err := tx.QueryRowContext(ctx, currentTagsQuery, resourceID, when, tagsBytes).Scan(&currentID, &same)
+	switch {
+	case err == sql.ErrNoRows:
+	case err != nil:
+		return err
+	case same:
+		return nil
+	default:
+		if _, err = tx.ExecContext(ctx, closeTagsQuery, when, currentID); err != nil {
+			return err
+		}
+	}
+	_, err = tx.ExecContext(ctx, insertTagsQuery, when, tagsBytes, resourceID)
+	return err
+}
+
 func (db *DB) assignPublicIP(ctx context.Context, tx *sql.Tx, resourceID int, ip string, hostname string, when time.Time) error {
 	const assignPublicIPQueryUpdate = `
 update aws_public_ip_assignment
@@ -610,12 +978,12 @@ where public_ip = $2
   and not_before = to_timestamp(0)
   and not_after > $1
   and aws_resource_id = $3
-  and aws_hostname = $4`
+  and aws_hostname is not distinct from nullif($4::varchar, '')`
 
 	const assignPublicIPQueryInsert = `
 insert into aws_public_ip_assignment
     (not_before, public_ip, aws_resource_id, aws_hostname)
-values ($1, $2, $3, $4) on conflict do nothing`
+values ($1, $2, $3, nullif($4::varchar, '')) on conflict do nothing`
 
 	res, err := tx.ExecContext(ctx, assignPublicIPQueryUpdate, when, ip, resourceID, hostname)
 	if err != nil {
@@ -642,13 +1010,13 @@ func (db *DB) releasePublicIP(ctx context.Context, tx *sql.Tx, resourceID int, i
         set not_after=$1
         where public_ip = $2
           and aws_resource_id = $3
-          and aws_hostname = $4
+          and aws_hostname is not distinct from nullif($4::varchar, '')
           and not_after is null`
 
 	const releasePublicIPQueryInsert = `
             insert into aws_public_ip_assignment
                 (not_before, not_after, public_ip, aws_resource_id, aws_hostname)
-            values (to_timestamp(0), $1, $2, $3, $4) on conflict do nothing `
+            values (to_timestamp(0), $1, $2, $3, nullif($4::varchar, '')) on conflict do nothing `
 
 	res, err := tx.ExecContext(ctx, releasePublicIPQueryUpdate, when, ip, resourceID, hostname)
 	if err != nil {
@@ -665,6 +1033,65 @@ func (db *DB) releasePublicIP(ctx context.Context, tx *sql.Tx, resourceID int, i
 	return err
 }
 
+func (db *DB) assignPrivateHostname(ctx context.Context, tx *sql.Tx, resourceID int, ip string, hostname string, when time.Time) error {
+	const assignPrivateHostnameQueryUpdate = `
+update aws_private_hostname_assignment
+set not_before = $1
+where private_ip = $2
+  and not_before = to_timestamp(0)
+  and not_after > $1
+  and aws_resource_id = $3
+  and aws_hostname = $4`
+
+	const assignPrivateHostnameQueryInsert = `
+insert into aws_private_hostname_assignment
+    (not_before, private_ip, aws_resource_id, aws_hostname)
+values ($1, $2, $3, $4) on conflict do nothing`
+
+	res, err := tx.ExecContext(ctx, assignPrivateHostnameQueryUpdate, when, ip, resourceID, hostname)
+	if err != nil {
+		return err
+	}
+	changedRows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if changedRows != 0 {
+		return nil
+	}
+	_, err = tx.ExecContext(ctx, assignPrivateHostnameQueryInsert, when, ip, resourceID, hostname)
+	return err
+}
+
+func (db *DB) releasePrivateHostname(ctx context.Context, tx *sql.Tx, resourceID int, ip string, hostname string, when time.Time) error {
+	const releasePrivateHostnameQueryUpdate = `
+update aws_private_hostname_assignment
+set not_after = $1
+where private_ip = $2
+  and aws_resource_id = $3
+  and aws_hostname = $4
+  and not_after is null`
+
+	const releasePrivateHostnameQueryInsert = `
+insert into aws_private_hostname_assignment
+    (not_before, not_after, private_ip, aws_resource_id, aws_hostname)
+values (to_timestamp(0), $1, $2, $3, $4) on conflict do nothing`
+
+	res, err := tx.ExecContext(ctx, releasePrivateHostnameQueryUpdate, when, ip, resourceID, hostname)
+	if err != nil {
+		return err
+	}
+	changedRows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if changedRows != 0 {
+		return nil
+	}
+	_, err = tx.ExecContext(ctx, releasePrivateHostnameQueryInsert, when, ip, resourceID, hostname)
+	return err
+}
+
 func (db *DB) assignResourceRelationship(ctx context.Context, tx *sql.Tx, arnID string, resource string, when time.Time) error {
 	const assignResourceRelationshipQueryUpdate = `
 update aws_resource_relationship
@@ -674,10 +1101,17 @@ where related_arn_id = $2
   and not_after > $1
   and arn_id = $3;`
 
+	// a relationship already held is kept as it is, as workloads give theirs along with every change
 	const assignResourceRelationshipQueryInsert = `
 insert into aws_resource_relationship
     (not_before, related_arn_id, arn_id)
-values ($1, $2, $3) on conflict do nothing ;`
+select $1, $2, $3
+where not exists(select 1
+                 from aws_resource_relationship held
+                 where held.related_arn_id = $2
+                   and held.arn_id = $3
+                   and held.not_before <= $1
+                   and held.not_after is null) on conflict do nothing ;`
 
 	res, err := tx.ExecContext(ctx, assignResourceRelationshipQueryUpdate, when, resource, arnID)
 	if err != nil {
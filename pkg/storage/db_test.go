@@ -2,9 +2,11 @@ package storage
 
 import (
 	"context"
+	"database/sql/driver"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"net"
 	"reflect"
 	"regexp"
 	"sort"
@@ -129,9 +131,10 @@ func TestGetPrivateIPsAtTimeM1Schema(t *testing.T) {
 		"name2",
 		true)
 
-	mock.ExpectQuery("select").WithArgs(ipAddress, at).WillReturnRows(rows).RowsWillBeClosed()
+	mock.ExpectQuery("select").WithArgs(ipAddress, at, "", "").WillReturnRows(rows).RowsWillBeClosed()
+	expectNoAttachments(mock)
 
-	results, err := thedb.FetchByIP(context.Background(), at, ipAddress)
+	results, err := thedb.FetchByIP(context.Background(), at, ipAddress, domain.IPScope{})
 	if err != nil {
 		t.Errorf("error was not expected while saving resource: %s", err)
 	}
@@ -215,9 +218,10 @@ func TestGetPublicIPsAtTimeM1Schema(t *testing.T) {
 		"name2",
 		true)
 
-	mock.ExpectQuery("select").WithArgs(ipAddress, at).WillReturnRows(rows).RowsWillBeClosed()
+	mock.ExpectQuery("select").WithArgs(ipAddress, at, "").WillReturnRows(rows).RowsWillBeClosed()
+	expectNoAttachments(mock)
 
-	results, err := thedb.FetchByIP(context.Background(), at, ipAddress)
+	results, err := thedb.FetchByIP(context.Background(), at, ipAddress, domain.IPScope{})
 	if err != nil {
 		t.Errorf("error was not expected while saving resource: %s", err)
 	}
@@ -315,9 +319,10 @@ func TestGetPrivateIPsAtTimeMultiRowsM1Schema(t *testing.T) {
 		"name2",
 		true)
 
-	mock.ExpectQuery("select").WithArgs(ipAddress, at).WillReturnRows(rows).RowsWillBeClosed()
+	mock.ExpectQuery("select").WithArgs(ipAddress, at, "", "").WillReturnRows(rows).RowsWillBeClosed()
+	expectNoAttachments(mock)
 
-	results, err := thedb.FetchByIP(context.Background(), at, ipAddress)
+	results, err := thedb.FetchByIP(context.Background(), at, ipAddress, domain.IPScope{})
 	if err != nil {
 		t.Errorf("error was not expected while saving resource: %s", err)
 	}
@@ -445,9 +450,10 @@ func TestGetPublicIPsAtTimeMultiRowsM1Schema(t *testing.T) {
 		"name2",
 		true)
 
-	mock.ExpectQuery("select").WithArgs(ipAddress, at).WillReturnRows(rows).RowsWillBeClosed()
+	mock.ExpectQuery("select").WithArgs(ipAddress, at, "").WillReturnRows(rows).RowsWillBeClosed()
+	expectNoAttachments(mock)
 
-	results, err := thedb.FetchByIP(context.Background(), at, ipAddress)
+	results, err := thedb.FetchByIP(context.Background(), at, ipAddress, domain.IPScope{})
 	if err != nil {
 		t.Errorf("error was not expected while saving resource: %s", err)
 	}
@@ -563,6 +569,7 @@ func TestGetHostnamesAtTimeSchema2(t *testing.T) {
 		true)
 
 	mock.ExpectQuery("select").WithArgs(hostname, at).WillReturnRows(rows).RowsWillBeClosed()
+	expectNoPrivateHostnameMatch(mock, hostname, at)
 
 	results, err := thedb.FetchByHostname(context.Background(), at, hostname)
 	if err != nil {
@@ -683,6 +690,7 @@ func TestGetHostnamesAtTimeMultiRowsSchema2(t *testing.T) {
 		true)
 
 	mock.ExpectQuery("select").WithArgs(hostname, at).WillReturnRows(rows).RowsWillBeClosed()
+	expectNoPrivateHostnameMatch(mock, hostname, at)
 
 	results, err := thedb.FetchByHostname(context.Background(), at, hostname)
 	if err != nil {
@@ -723,6 +731,153 @@ func TestGetHostnamesAtTimeMultiRowsSchema2(t *testing.T) {
 	}
 }
 
+func TestGetHostnamesAtTimePrivate(t *testing.T) {
+	mockdb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockdb.Close()
+
+	thedb := DB{
+		sqldb: mockdb,
+	}
+
+	at, _ := time.Parse(time.RFC3339, "2019-04-09T08:55:35+00:00")
+	hostname := "ip-10-1-2-3.ec2.internal"
+
+	publicRows := sqlmock.NewRows([]string{"public_ip", "aws_hostname", "arn_id", "meta", "region", "resource_type",
+		"account", "id", "t_account", "t_login", "t_email", "t_name", "t_valid", "p_login", "p_email", "p_name", "p_valid"})
+	privateRows := sqlmock.NewRows(privateLookupColumns).AddRow("10.1.2.3",
+		"rid",
+		[]byte("{\"hi\":\"there3\"}"),
+		"region",
+		"type",
+		"aid",
+		"1",
+		"aid",
+		"login",
+		"email@atlassian.com",
+		"name",
+		true,
+		"login2",
+		"email2@atlassian.com",
+		"name2",
+		true)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`get_resource_by_hostname`)).WithArgs(hostname, at).WillReturnRows(publicRows)
+	mock.ExpectQuery(regexp.QuoteMeta(`get_resource_by_private_hostname`)).WithArgs(hostname, at).WillReturnRows(privateRows)
+
+	results, err := thedb.FetchByHostname(context.Background(), at, hostname)
+	if err != nil {
+		t.Errorf("error was not expected while fetching resource: %s", err)
+	}
+
+	assert.Equal(t, 1, len(results))
+	assert.Equal(t, domain.CloudAssetDetails{
+		PrivateIPAddresses: []string{"10.1.2.3"},
+		Hostnames:          []string{"ip-10-1-2-3.ec2.internal"},
+		ResourceType:       "type",
+		AccountID:          "aid",
+		Region:             "region",
+		ARN:                "rid",
+		Tags:               map[string]string{"hi": "there3"},
+		AccountOwner: domain.AccountOwner{
+			AccountID: toStringPointer("aid"),
+			Owner: domain.Person{
+				Login: toStringPointer("login"),
+				Email: toStringPointer("email@atlassian.com"),
+				Name:  toStringPointer("name"),
+				Valid: toBoolPointer(true),
+			},
+			Champions: []domain.Person{
+				{
+					Login: toStringPointer("login2"),
+					Email: toStringPointer("email2@atlassian.com"),
+					Name:  toStringPointer("name2"),
+					Valid: toBoolPointer(true),
+				},
+			},
+		},
+	}, results[0])
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestGetHostnamesAtTimePublicAndPrivate(t *testing.T) {
+	mockdb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockdb.Close()
+
+	thedb := DB{
+		sqldb: mockdb,
+	}
+
+	at, _ := time.Parse(time.RFC3339, "2019-04-09T08:55:35+00:00")
+	hostname := "db.example.com"
+
+	// the same resource holds the hostname on a public and a private address, another one on a private address only
+	publicRows := sqlmock.NewRows([]string{"public_ip", "aws_hostname", "arn_id", "meta", "region", "resource_type",
+		"account", "id", "t_account", "t_login", "t_email", "t_name", "t_valid", "p_login", "p_email", "p_name", "p_valid"}).
+		AddRow("34.0.0.1", "public.example.com", "rid", nil, "region", "type", "aid", "1", "aid", "login",
+			"email@atlassian.com", "name", true, nil, nil, nil, nil).
+		AddRow("34.0.0.1", hostname, "rid", nil, "region", "type", "aid", "1", "aid", "login",
+			"email@atlassian.com", "name", true, nil, nil, nil, nil)
+	privateRows := sqlmock.NewRows(privateLookupColumns).
+		AddRow("10.0.0.1", "rid", nil, "region", "type", "aid", "1", "aid", "login",
+			"email@atlassian.com", "name", true, nil, nil, nil, nil).
+		AddRow("10.0.0.2", "other", nil, "region", "type", "aid", "2", "aid", "login",
+			"email@atlassian.com", "name", true, nil, nil, nil, nil)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`get_resource_by_hostname`)).WithArgs(hostname, at).WillReturnRows(publicRows)
+	mock.ExpectQuery(regexp.QuoteMeta(`get_resource_by_private_hostname`)).WithArgs(hostname, at).WillReturnRows(privateRows)
+
+	results, err := thedb.FetchByHostname(context.Background(), at, hostname)
+	assert.NoError(t, err)
+	assert.Len(t, results, 2)
+	assert.Equal(t, "rid", results[0].ARN)
+	assert.Equal(t, []string{"34.0.0.1"}, results[0].PublicIPAddresses)
+	assert.Equal(t, []string{"10.0.0.1"}, results[0].PrivateIPAddresses)
+	assert.Equal(t, []string{"public.example.com", hostname}, results[0].Hostnames)
+	assert.Equal(t, "other", results[1].ARN)
+	assert.Equal(t, []string{"10.0.0.2"}, results[1].PrivateIPAddresses)
+	assert.Equal(t, []string{hostname}, results[1].Hostnames)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestGetHostnamesAtTimePrivateError(t *testing.T) {
+	mockdb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockdb.Close()
+
+	thedb := DB{
+		sqldb: mockdb,
+	}
+
+	at, _ := time.Parse(time.RFC3339, "2019-04-09T08:55:35+00:00")
+	hostname := "ip-10-1-2-3.ec2.internal"
+
+	publicRows := sqlmock.NewRows([]string{"public_ip", "aws_hostname", "arn_id", "meta", "region", "resource_type",
+		"account", "id", "t_account", "t_login", "t_email", "t_name", "t_valid", "p_login", "p_email", "p_name", "p_valid"})
+	mock.ExpectQuery(regexp.QuoteMeta(`get_resource_by_hostname`)).WithArgs(hostname, at).WillReturnRows(publicRows)
+	mock.ExpectQuery(regexp.QuoteMeta(`get_resource_by_private_hostname`)).WithArgs(hostname, at).WillReturnError(errors.New("failed"))
+
+	_, err = thedb.FetchByHostname(context.Background(), at, hostname)
+	assert.Error(t, err)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
 func TestGetByResourceIDEmpty(t *testing.T) {
 	mockdb, mock, err := sqlmock.New()
 	if err != nil {
@@ -804,6 +959,7 @@ func TestGetResourceIDAtTime(t *testing.T) {
 		true)
 
 	mock.ExpectQuery("select").WithArgs(resID, at).WillReturnRows(rows).RowsWillBeClosed()
+	expectPrivateHostnamesByARNID(mock, resID, at)
 
 	results, err := thedb.FetchByResourceID(context.Background(), at, resID)
 	if err != nil {
@@ -844,6 +1000,73 @@ func TestGetResourceIDAtTime(t *testing.T) {
 	}
 }
 
+func TestGetResourceIDAtTimePrivateHostnames(t *testing.T) {
+	mockdb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockdb.Close()
+
+	thedb := DB{
+		sqldb: mockdb,
+	}
+
+	at, _ := time.Parse(time.RFC3339, "2019-04-09T08:55:35+00:00")
+	const resID = "resid"
+
+	rows := sqlmock.NewRows([]string{"aws_private_ip_assignment_private_ip",
+		"aws_public_ip_assignment_public_ip",
+		"aws_public_ip_assignment_aws_hostname",
+		"aws_resource_type_resource_type",
+		"aws_account_account",
+		"aws_region_region",
+		"aws_resource_meta",
+		"aws_resource_aws_account_id",
+		"aws_account_account",
+		"owner_login",
+		"owner_email",
+		"owner_name",
+		"owner_valid",
+		"champion_login",
+		"champion_email",
+		"champion_name",
+		"champion_valid",
+	}).AddRow("10.1.2.3",
+		"44.33.22.11",
+		"yahoo.com",
+		"type",
+		"aid",
+		"region",
+		[]byte("{\"hi\":\"there3\"}"),
+		1,
+		"aid",
+		"login",
+		"email@atlassian.com",
+		"name",
+		true,
+		"login2",
+		"email2@atlassian.com",
+		"name2",
+		true)
+
+	mock.ExpectQuery("select").WithArgs(resID, at).WillReturnRows(rows).RowsWillBeClosed()
+	expectPrivateHostnamesByARNID(mock, resID, at, "ip-10-1-2-3.ec2.internal", "db.internal.example.com")
+
+	results, err := thedb.FetchByResourceID(context.Background(), at, resID)
+	if err != nil {
+		t.Errorf("error was not expected while fetching resource: %s", err)
+	}
+
+	assert.Equal(t, 1, len(results))
+	assert.Equal(t, []string{"10.1.2.3"}, results[0].PrivateIPAddresses)
+	assert.Equal(t, []string{"44.33.22.11"}, results[0].PublicIPAddresses)
+	assert.ElementsMatch(t, []string{"yahoo.com", "ip-10-1-2-3.ec2.internal", "db.internal.example.com"}, results[0].Hostnames)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
 func TestGetResourceIDAtTimeMoreThanOnePublicIPs(t *testing.T) {
 	mockdb, mock, err := sqlmock.New()
 	if err != nil {
@@ -909,6 +1132,7 @@ func TestGetResourceIDAtTimeMoreThanOnePublicIPs(t *testing.T) {
 	at, _ := time.Parse(time.RFC3339, "2019-04-09T08:55:35+00:00")
 	resID := "resid"
 	mock.ExpectQuery("select").WithArgs(resID, at).WillReturnRows(rows).RowsWillBeClosed()
+	expectPrivateHostnamesByARNID(mock, resID, at)
 
 	results, err := thedb.FetchByResourceID(context.Background(), at, resID)
 	if err != nil {
@@ -1015,6 +1239,7 @@ func TestGetResourceIDAtTimeMoreThanOneChampions(t *testing.T) {
 	at, _ := time.Parse(time.RFC3339, "2019-04-09T08:55:35+00:00")
 	resID := "resid"
 	mock.ExpectQuery("select").WithArgs(resID, at).WillReturnRows(rows).RowsWillBeClosed()
+	expectPrivateHostnamesByARNID(mock, resID, at)
 
 	results, err := thedb.FetchByResourceID(context.Background(), at, resID)
 	if err != nil {
@@ -1097,6 +1322,34 @@ func fakeCloudChange(changeType string) domain.CloudAssetChanges {
 	return cloudAssetChanges
 }
 
+// expectTagsUnchanged expects the tags of fakeCloudAssetChanges to be found already in effect
+func expectTagsUnchanged(mock sqlmock.Sqlmock, resourceID int, timestamp time.Time) {
+	mock.ExpectQuery(regexp.QuoteMeta(`from aws_resource_tags`)).WithArgs(resourceID, timestamp, []byte("{\"tag1\":\"val1\"}")).WillReturnRows(sqlmock.NewRows([]string{"id", "same"}).AddRow(7, true))
+}
+
+func expectJournaled(mock sqlmock.Sqlmock) {
+	mock.ExpectExec(regexp.QuoteMeta(`insert into aws_change_journal`)).WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(1, 1))
+}
+
+var privateLookupColumns = []string{"private_ip", "arn_id", "meta", "region", "resource_type", "account", "id",
+	"t_account", "t_login", "t_email", "t_name", "t_valid", "p_login", "p_email", "p_name", "p_valid"}
+
+func expectNoAttachments(mock sqlmock.Sqlmock) {
+	mock.ExpectQuery(regexp.QuoteMeta(`with recursive chain`)).WillReturnRows(sqlmock.NewRows([]string{"ord", "arn_id"}))
+}
+
+func expectNoPrivateHostnameMatch(mock sqlmock.Sqlmock, hostname string, at time.Time) {
+	mock.ExpectQuery(regexp.QuoteMeta(`get_resource_by_private_hostname`)).WithArgs(hostname, at).WillReturnRows(sqlmock.NewRows(privateLookupColumns))
+}
+
+func expectPrivateHostnamesByARNID(mock sqlmock.Sqlmock, resID string, at time.Time, hostnames ...string) {
+	rows := sqlmock.NewRows([]string{"aws_hostname"})
+	for _, hostname := range hostnames {
+		rows.AddRow(hostname)
+	}
+	mock.ExpectQuery(regexp.QuoteMeta(`get_private_hostnames_by_arn_id`)).WithArgs(resID, at).WillReturnRows(rows)
+}
+
 func assertArrayEqualIgnoreOrder(t *testing.T, expected, actual []domain.CloudAssetDetails) {
 	// brute force
 	assert.Equal(t, len(expected), len(actual))
@@ -1198,9 +1451,11 @@ func TestStoreV2Assign(t *testing.T) {
 	mock.ExpectQuery("SELECT").WithArgs("arn", "aid", "region").WillReturnRows(row)
 	// NB we need to escape '$' and other special chars as the value passed as expected query is a regexp
 	timestamp, _ := time.Parse(time.RFC3339, "2019-04-09T08:29:35+00:00")
-	mock.ExpectExec(regexp.QuoteMeta(`update aws_private_ip_assignment`)).WithArgs(timestamp, "4.3.2.1", 1).WillReturnResult(sqlmock.NewResult(1, 1))                                             // nolint
+	mock.ExpectExec(regexp.QuoteMeta(`update aws_private_ip_assignment`)).WithArgs(timestamp, "4.3.2.1", 1, "", "").WillReturnResult(sqlmock.NewResult(1, 1))                                     // nolint
 	mock.ExpectExec(regexp.QuoteMeta(`update aws_public_ip_assignment`)).WithArgs(timestamp, "8.7.6.5", 1, "google.com").WillReturnResult(sqlmock.NewResult(1, 1))                                // nolint
 	mock.ExpectExec(regexp.QuoteMeta(`update aws_resource_relationship`)).WithArgs(timestamp, "app/marketp-ALB-eeeeeee5555555/ffffffff66666666", "arn").WillReturnResult(sqlmock.NewResult(1, 1)) // nolint
+	expectTagsUnchanged(mock, 1, timestamp)
+	expectJournaled(mock)
 	mock.ExpectCommit()
 
 	ctx := context.Background()
@@ -1236,9 +1491,11 @@ func TestStoreV2Remove(t *testing.T) {
 	mock.ExpectQuery("SELECT").WithArgs("arn", "aid", "region").WillReturnRows(row)
 	timestamp, _ := time.Parse(time.RFC3339, "2019-04-09T08:29:35+00:00")
 	// NB we need to escape '$' and other special chars as the value passed as expected query is a regexp
-	mock.ExpectExec(regexp.QuoteMeta(`update aws_private_ip_assignment`)).WithArgs(timestamp, "4.3.2.1", 1).WillReturnResult(sqlmock.NewResult(1, 1))                                             // nolint
+	mock.ExpectExec(regexp.QuoteMeta(`update aws_private_ip_assignment`)).WithArgs(timestamp, "4.3.2.1", 1, "", "").WillReturnResult(sqlmock.NewResult(1, 1))                                     // nolint
 	mock.ExpectExec(regexp.QuoteMeta(`update aws_public_ip_assignment`)).WithArgs(timestamp, "8.7.6.5", 1, "google.com").WillReturnResult(sqlmock.NewResult(1, 1))                                // nolint
 	mock.ExpectExec(regexp.QuoteMeta(`update aws_resource_relationship`)).WithArgs(timestamp, "app/marketp-ALB-eeeeeee5555555/ffffffff66666666", "arn").WillReturnResult(sqlmock.NewResult(1, 1)) // nolint
+	expectTagsUnchanged(mock, 1, timestamp)
+	expectJournaled(mock)
 	mock.ExpectCommit()
 
 	ctx := context.Background()
@@ -1305,7 +1562,7 @@ func TestStoreV2FailPrivate(t *testing.T) {
 	mock.ExpectQuery("SELECT").WithArgs("arn", "aid", "region").WillReturnRows(row)
 	timestamp, _ := time.Parse(time.RFC3339, "2019-04-09T08:29:35+00:00")
 	// NB we need to escape '$' and other special chars as the value passed as expected query is a regexp
-	mock.ExpectExec(regexp.QuoteMeta(`update aws_private_ip_assignment`)).WithArgs(timestamp, "4.3.2.1", 1).WillReturnError(errors.New("failed to store assignment"))
+	mock.ExpectExec(regexp.QuoteMeta(`update aws_private_ip_assignment`)).WithArgs(timestamp, "4.3.2.1", 1, "", "").WillReturnError(errors.New("failed to store assignment"))
 	mock.ExpectRollback()
 
 	ctx := context.Background()
@@ -1341,7 +1598,7 @@ func TestStoreV2FailPublic(t *testing.T) {
 	mock.ExpectQuery("SELECT").WithArgs("arn", "aid", "region").WillReturnRows(row)
 	timestamp, _ := time.Parse(time.RFC3339, "2019-04-09T08:29:35+00:00")
 	// NB we need to escape '$' and other special chars as the value passed as expected query is a regexp
-	mock.ExpectExec(regexp.QuoteMeta(`update aws_private_ip_assignment`)).WithArgs(timestamp, "4.3.2.1", 1).WillReturnResult(sqlmock.NewResult(1, 1))                              // nolint
+	mock.ExpectExec(regexp.QuoteMeta(`update aws_private_ip_assignment`)).WithArgs(timestamp, "4.3.2.1", 1, "", "").WillReturnResult(sqlmock.NewResult(1, 1))                      // nolint
 	mock.ExpectExec(regexp.QuoteMeta(`update aws_public_ip_assignment`)).WithArgs(timestamp, "8.7.6.5", 1, "google.com").WillReturnError(errors.New("failed to store assignment")) // nolint
 	mock.ExpectRollback()
 
@@ -1379,7 +1636,7 @@ func TestStoreV2FailResourceRelationship(t *testing.T) {
 	timestamp, _ := time.Parse(time.RFC3339, "2019-04-09T08:29:35+00:00")
 	// NB we need to escape '$' and other special chars as the value passed as expected query is a regexp
 	// Note: All related changes must be successful otherwise the whole transaction is canceled
-	mock.ExpectExec(regexp.QuoteMeta(`update aws_private_ip_assignment`)).WithArgs(timestamp, "4.3.2.1", 1).WillReturnResult(sqlmock.NewResult(1, 1))              // nolint
+	mock.ExpectExec(regexp.QuoteMeta(`update aws_private_ip_assignment`)).WithArgs(timestamp, "4.3.2.1", 1, "", "").WillReturnResult(sqlmock.NewResult(1, 1))      // nolint
 	mock.ExpectExec(regexp.QuoteMeta(`update aws_public_ip_assignment`)).WithArgs(timestamp, "8.7.6.5", 1, "google.com").WillReturnResult(sqlmock.NewResult(1, 1)) // nolint
 	mock.ExpectExec(regexp.QuoteMeta(`update aws_resource_relationship`)).WithArgs(timestamp, "app/marketp-ALB-eeeeeee5555555/ffffffff66666666", "arn").WillReturnError(errors.New("failed to store relationship"))
 	mock.ExpectRollback()
@@ -1420,83 +1677,90 @@ func TestStoreV2FailTxOpen(t *testing.T) {
 	}
 }
 
-func fakeAccountOwnerInput() domain.AccountOwner {
-	return domain.AccountOwner{
-		AccountID: toStringPointer("awsaccountid123"),
-		Owner: domain.Person{
-			Name:  toStringPointer("john dane"),
-			Login: toStringPointer("jdane"),
-			Email: toStringPointer("jdane@atlassian.com"),
-			Valid: toBoolPointer(true),
-		},
-		Champions: []domain.Person{
-			{
-				Name:  toStringPointer("john dane"),
-				Login: toStringPointer("jdane"),
-				Email: toStringPointer("jdane@atlassian.com"),
-				Valid: toBoolPointer(true),
+func fakeExplicitAddressChanges(changeType string) domain.CloudAssetChanges {
+	at, _ := time.Parse(time.RFC3339, "2019-04-09T08:29:35+00:00")
+	return domain.CloudAssetChanges{
+		ChangeTime:   at,
+		ResourceType: "rtype",
+		AccountID:    "aid",
+		Region:       "region",
+		ARN:          "arn",
+		Changes: []domain.NetworkChanges{{
+			Addresses: []domain.AddressChange{
+				{IPAddress: "10.0.0.1", NetworkInterfaceID: "eni-1"},
+				{IPAddress: "34.0.0.1", Hostname: "one.example.com", NetworkInterfaceID: "eni-1", Public: true},
+				{IPAddress: "34.0.0.2", Hostname: "two.example.com", Public: true},
+				{IPAddress: "34.0.0.3", Public: true},
 			},
-		},
-	}
-}
-
-func fakeAccountOwnerInputNoChampion() domain.AccountOwner {
-	return domain.AccountOwner{
-		AccountID: toStringPointer("awsaccountid123"),
-		Owner: domain.Person{
-			Name:  toStringPointer("john dane"),
-			Login: toStringPointer("jdane"),
-			Email: toStringPointer("jdane@atlassian.com"),
-			Valid: toBoolPointer(true),
-		},
+			VPCID:      "vpc-1",
+			SubnetID:   "subnet-1",
+			ChangeType: changeType,
+		}},
 	}
 }
 
-func TestAccountOwnerTxBeginFailure(t *testing.T) {
-	// no panics, in other words
+func TestStoreExplicitAddressesAssign(t *testing.T) {
 	mockdb, mock, err := sqlmock.New()
 	if err != nil {
 		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
 	}
 	defer mockdb.Close()
 
-	thedb := DB{
+	theDB := DB{
 		sqldb: mockdb,
 	}
 
-	mock.ExpectBegin().WillReturnError(fmt.Errorf("could not start transaction"))
-
-	ctx := context.Background()
+	timestamp, _ := time.Parse(time.RFC3339, "2019-04-09T08:29:35+00:00")
+	mock.ExpectBegin()
+	mock.ExpectExec("with sel as").WithArgs("arn", "region", "aid", "rtype", sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery("SELECT").WithArgs("arn", "aid", "region").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectExec(regexp.QuoteMeta(`update aws_private_ip_assignment`)).WithArgs(timestamp, "10.0.0.1", 1, "vpc-1", "subnet-1").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`set network_interface_id`)).WithArgs("eni-1", "10.0.0.1", 1, timestamp).WillReturnResult(sqlmock.NewResult(1, 1))
+	// each public address is assigned with its own hostname only
+	mock.ExpectExec(regexp.QuoteMeta(`update aws_public_ip_assignment`)).WithArgs(timestamp, "34.0.0.1", 1, "one.example.com").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`set network_interface_id`)).WithArgs("eni-1", "34.0.0.1", 1, timestamp).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`update aws_public_ip_assignment`)).WithArgs(timestamp, "34.0.0.2", 1, "two.example.com").WillReturnResult(sqlmock.NewResult(1, 1))
+	// a public address without a hostname is kept rather than dropped
+	mock.ExpectExec(regexp.QuoteMeta(`update aws_public_ip_assignment`)).WithArgs(timestamp, "34.0.0.3", 1, "").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta(`insert into aws_public_ip_assignment`)).WithArgs(timestamp, "34.0.0.3", 1, "").WillReturnResult(sqlmock.NewResult(1, 1))
+	expectJournaled(mock)
+	mock.ExpectCommit()
 
-	if err = thedb.StoreAccountOwner(ctx, fakeAccountOwnerInput()); err == nil {
-		t.Errorf("was expecting an error, but there was none")
+	if err = theDB.Store(context.Background(), fakeExplicitAddressChanges("ADDED")); err != nil {
+		t.Errorf("error was not expected while saving resource: %s", err)
 	}
-	assert.Equal(t, "could not start transaction", err.Error())
 
 	if err := mock.ExpectationsWereMet(); err != nil {
 		t.Errorf("there were unfulfilled expectations: %s", err)
 	}
 }
 
-func TestShouldRollbackOnFailureToINSERTAccountOwner(t *testing.T) {
+func TestStoreExplicitAddressesRelease(t *testing.T) {
 	mockdb, mock, err := sqlmock.New()
 	if err != nil {
 		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
 	}
 	defer mockdb.Close()
 
-	thedb := DB{
+	theDB := DB{
 		sqldb: mockdb,
 	}
 
+	timestamp, _ := time.Parse(time.RFC3339, "2019-04-09T08:29:35+00:00")
 	mock.ExpectBegin()
-	mock.ExpectExec("INSERT INTO").WithArgs("awsaccountid123").WillReturnError(fmt.Errorf("some error"))
-	mock.ExpectRollback()
-
-	ctx := context.Background()
+	mock.ExpectExec("with sel as").WithArgs("arn", "region", "aid", "rtype", sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery("SELECT").WithArgs("arn", "aid", "region").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectExec(regexp.QuoteMeta(`update aws_private_ip_assignment`)).WithArgs(timestamp, "10.0.0.1", 1, "vpc-1", "subnet-1").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`set network_interface_id`)).WithArgs("eni-1", "10.0.0.1", 1, timestamp).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`update aws_public_ip_assignment`)).WithArgs(timestamp, "34.0.0.1", 1, "one.example.com").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`set network_interface_id`)).WithArgs("eni-1", "34.0.0.1", 1, timestamp).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`update aws_public_ip_assignment`)).WithArgs(timestamp, "34.0.0.2", 1, "two.example.com").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`update aws_public_ip_assignment`)).WithArgs(timestamp, "34.0.0.3", 1, "").WillReturnResult(sqlmock.NewResult(1, 1))
+	expectJournaled(mock)
+	mock.ExpectCommit()
 
-	if err = thedb.StoreAccountOwner(ctx, fakeAccountOwnerInput()); err == nil {
-		t.Errorf("was expecting an error, but there was none")
+	if err = theDB.Store(context.Background(), fakeExplicitAddressChanges("DELETED")); err != nil {
+		t.Errorf("error was not expected while saving resource: %s", err)
 	}
 
 	if err := mock.ExpectationsWereMet(); err != nil {
@@ -1504,26 +1768,54 @@ func TestShouldRollbackOnFailureToINSERTAccountOwner(t *testing.T) {
 	}
 }
 
-func TestShouldRollbackOnFailureToINSERTAccountOwner2(t *testing.T) {
+func TestStoreExplicitPrivateHostnames(t *testing.T) {
 	mockdb, mock, err := sqlmock.New()
 	if err != nil {
 		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
 	}
 	defer mockdb.Close()
 
-	thedb := DB{
+	theDB := DB{
 		sqldb: mockdb,
 	}
 
+	timestamp, _ := time.Parse(time.RFC3339, "2019-04-09T08:29:35+00:00")
+	added := fakeExplicitAddressChanges("ADDED")
+	added.Changes[0].Addresses = []domain.AddressChange{
+		{IPAddress: "10.1.2.3", Hostname: "ip-10-1-2-3.ec2.internal"},
+		{IPAddress: "10.1.2.3", Hostname: "db.internal.example.com"},
+	}
+	deleted := fakeExplicitAddressChanges("DELETED")
+	deleted.Changes[0].Addresses = added.Changes[0].Addresses[:1]
+
 	mock.ExpectBegin()
-	mock.ExpectExec("INSERT INTO").WithArgs("awsaccountid123").WillReturnResult(sqlmock.NewResult(1, 1))
-	mock.ExpectExec("INSERT INTO").WithArgs("jdane", "jdane@atlassian.com", "john dane", true).WillReturnError(fmt.Errorf("some error"))
-	mock.ExpectRollback()
+	mock.ExpectExec("with sel as").WithArgs("arn", "region", "aid", "rtype", sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery("SELECT").WithArgs("arn", "aid", "region").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectExec(regexp.QuoteMeta(`update aws_private_ip_assignment`)).WithArgs(timestamp, "10.1.2.3", 1, "vpc-1", "subnet-1").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta(`insert into aws_private_ip_assignment`)).WithArgs(timestamp, "10.1.2.3", 1, "vpc-1", "subnet-1").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`update aws_private_hostname_assignment`)).WithArgs(timestamp, "10.1.2.3", 1, "ip-10-1-2-3.ec2.internal").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta(`insert into aws_private_hostname_assignment`)).WithArgs(timestamp, "10.1.2.3", 1, "ip-10-1-2-3.ec2.internal").WillReturnResult(sqlmock.NewResult(1, 1))
+	// the address is already held, only its other hostname is assigned
+	mock.ExpectExec(regexp.QuoteMeta(`update aws_private_ip_assignment`)).WithArgs(timestamp, "10.1.2.3", 1, "vpc-1", "subnet-1").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta(`insert into aws_private_ip_assignment`)).WithArgs(timestamp, "10.1.2.3", 1, "vpc-1", "subnet-1").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta(`update aws_private_hostname_assignment`)).WithArgs(timestamp, "10.1.2.3", 1, "db.internal.example.com").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta(`insert into aws_private_hostname_assignment`)).WithArgs(timestamp, "10.1.2.3", 1, "db.internal.example.com").WillReturnResult(sqlmock.NewResult(1, 1))
+	expectJournaled(mock)
+	mock.ExpectCommit()
 
-	ctx := context.Background()
+	mock.ExpectBegin()
+	mock.ExpectExec("with sel as").WithArgs("arn", "region", "aid", "rtype", sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery("SELECT").WithArgs("arn", "aid", "region").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectExec(regexp.QuoteMeta(`update aws_private_ip_assignment`)).WithArgs(timestamp, "10.1.2.3", 1, "vpc-1", "subnet-1").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`update aws_private_hostname_assignment`)).WithArgs(timestamp, "10.1.2.3", 1, "ip-10-1-2-3.ec2.internal").WillReturnResult(sqlmock.NewResult(1, 1))
+	expectJournaled(mock)
+	mock.ExpectCommit()
 
-	if err = thedb.StoreAccountOwner(ctx, fakeAccountOwnerInput()); err == nil {
-		t.Errorf("was expecting an error, but there was none")
+	if err = theDB.Store(context.Background(), added); err != nil {
+		t.Errorf("error was not expected while saving resource: %s", err)
+	}
+	if err = theDB.Store(context.Background(), deleted); err != nil {
+		t.Errorf("error was not expected while saving resource: %s", err)
 	}
 
 	if err := mock.ExpectationsWereMet(); err != nil {
@@ -1531,21 +1823,159 @@ func TestShouldRollbackOnFailureToINSERTAccountOwner2(t *testing.T) {
 	}
 }
 
-func TestShouldINSERTAccountOwnerWithChampion(t *testing.T) {
+func TestStoreExplicitAddressesInvalidIP(t *testing.T) {
 	mockdb, mock, err := sqlmock.New()
 	if err != nil {
 		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
 	}
 	defer mockdb.Close()
 
-	thedb := DB{
+	theDB := DB{
 		sqldb: mockdb,
 	}
 
+	changes := fakeExplicitAddressChanges("ADDED")
+	changes.Changes[0].Addresses = []domain.AddressChange{{IPAddress: "not an IP", Public: true}}
 	mock.ExpectBegin()
-	mock.ExpectExec("INSERT INTO").WithArgs("awsaccountid123").WillReturnResult(sqlmock.NewResult(1, 1))
-	mock.ExpectExec("INSERT INTO").WithArgs("jdane", "jdane@atlassian.com", "john dane", true).WillReturnResult(sqlmock.NewResult(1, 1))
-	row := sqlmock.NewRows([]string{
+	mock.ExpectExec("with sel as").WithArgs("arn", "region", "aid", "rtype", sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery("SELECT").WithArgs("arn", "aid", "region").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectRollback()
+
+	if err = theDB.Store(context.Background(), changes); err == nil {
+		t.Error("error was expected while saving resource")
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func fakeAccountOwnerInput() domain.AccountOwner {
+	return domain.AccountOwner{
+		AccountID: toStringPointer("awsaccountid123"),
+		Owner: domain.Person{
+			Name:  toStringPointer("john dane"),
+			Login: toStringPointer("jdane"),
+			Email: toStringPointer("jdane@atlassian.com"),
+			Valid: toBoolPointer(true),
+		},
+		Champions: []domain.Person{
+			{
+				Name:  toStringPointer("john dane"),
+				Login: toStringPointer("jdane"),
+				Email: toStringPointer("jdane@atlassian.com"),
+				Valid: toBoolPointer(true),
+			},
+		},
+	}
+}
+
+func fakeAccountOwnerInputNoChampion() domain.AccountOwner {
+	return domain.AccountOwner{
+		AccountID: toStringPointer("awsaccountid123"),
+		Owner: domain.Person{
+			Name:  toStringPointer("john dane"),
+			Login: toStringPointer("jdane"),
+			Email: toStringPointer("jdane@atlassian.com"),
+			Valid: toBoolPointer(true),
+		},
+	}
+}
+
+func TestAccountOwnerTxBeginFailure(t *testing.T) {
+	// no panics, in other words
+	mockdb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockdb.Close()
+
+	thedb := DB{
+		sqldb: mockdb,
+	}
+
+	mock.ExpectBegin().WillReturnError(fmt.Errorf("could not start transaction"))
+
+	ctx := context.Background()
+
+	if err = thedb.StoreAccountOwner(ctx, fakeAccountOwnerInput()); err == nil {
+		t.Errorf("was expecting an error, but there was none")
+	}
+	assert.Equal(t, "could not start transaction", err.Error())
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestShouldRollbackOnFailureToINSERTAccountOwner(t *testing.T) {
+	mockdb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockdb.Close()
+
+	thedb := DB{
+		sqldb: mockdb,
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO").WithArgs("awsaccountid123").WillReturnError(fmt.Errorf("some error"))
+	mock.ExpectRollback()
+
+	ctx := context.Background()
+
+	if err = thedb.StoreAccountOwner(ctx, fakeAccountOwnerInput()); err == nil {
+		t.Errorf("was expecting an error, but there was none")
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestShouldRollbackOnFailureToINSERTAccountOwner2(t *testing.T) {
+	mockdb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockdb.Close()
+
+	thedb := DB{
+		sqldb: mockdb,
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO").WithArgs("awsaccountid123").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO").WithArgs("jdane", "jdane@atlassian.com", "john dane", true).WillReturnError(fmt.Errorf("some error"))
+	mock.ExpectRollback()
+
+	ctx := context.Background()
+
+	if err = thedb.StoreAccountOwner(ctx, fakeAccountOwnerInput()); err == nil {
+		t.Errorf("was expecting an error, but there was none")
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestShouldINSERTAccountOwnerWithChampion(t *testing.T) {
+	mockdb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockdb.Close()
+
+	thedb := DB{
+		sqldb: mockdb,
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO").WithArgs("awsaccountid123").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO").WithArgs("jdane", "jdane@atlassian.com", "john dane", true).WillReturnResult(sqlmock.NewResult(1, 1))
+	row := sqlmock.NewRows([]string{
 		"id",
 	}).AddRow('1')
 	mock.ExpectQuery("SELECT").WithArgs("jdane").WillReturnRows(row)
@@ -1673,10 +2103,28 @@ func TestResIDFromARN(t *testing.T) {
 		{"TestResIDFromARNForCLB",
 			"arn:aws:ec2:us-west-2:909420000000:loadbalancer/my-classic-lb",
 			"my-classic-lb"},
+		{"TestResIDFromARNForPod",
+			"arn:aws:eks:us-west-2:909420000000:pod/my-cluster/my-namespace/my-pod-5d8f7c9b4-x2x7q",
+			"pod/my-cluster/my-namespace/my-pod-5d8f7c9b4-x2x7q"},
+		{"TestResIDFromARNForService",
+			"arn:aws:eks:us-west-2:909420000000:service/my-cluster/my-namespace/my-service",
+			"service/my-cluster/my-namespace/my-service"},
+		{"TestResIDFromARNForECSTask",
+			"arn:aws:ecs:us-west-2:909420000000:task/my-cluster/0b69d5c0d0c64d8f8a4d1e3c1c8b1a2e",
+			"0b69d5c0d0c64d8f8a4d1e3c1c8b1a2e"},
+		{"TestResIDFromARNForLambda",
+			"arn:aws:lambda:us-west-2:909420000000:function:my-function",
+			"my-function"},
+		{"TestResIDFromARNForLambdaVersion",
+			"arn:aws:lambda:us-west-2:909420000000:function:my-function:42",
+			"my-function"},
+		{"TestResIDFromARNForCluster",
+			"arn:aws:eks:us-west-2:909420000000:cluster/my-cluster",
+			"my-cluster"},
 	}
 	for _, tc := range testCases {
 		t.Run(tc.Name, func(t *testing.T) {
-			actual := resIDFromARN(tc.Arn)
+			actual := domain.ResourceIDFromARN(tc.Arn)
 			assert.Equal(t, tc.Expected, actual, "Resource ID doesn't match expected output")
 		})
 	}
@@ -1691,3 +2139,532 @@ func toStringPointer(s string) *string {
 func toBoolPointer(b bool) *bool {
 	return &b
 }
+
+func TestIsPrivateIP(t *testing.T) {
+	tc := []struct {
+		ip      string
+		private bool
+	}{
+		{"10.1.2.3", true},
+		{"172.16.0.1", true},
+		{"172.32.0.1", false},
+		{"192.168.0.1", true},
+		{"192.168.200.1", true},
+		{"192.169.0.1", false},
+		{"100.64.0.1", true},
+		{"100.128.0.1", false},
+		{"8.8.8.8", false},
+		{"::ffff:10.1.2.3", true},
+		{"fd12:3456:789a::1", true},
+		{"fc00::1", true},
+		{"fe80::1ff:fe23:4567:890a", true},
+		{"2600:1f18:abcd::1", false},
+		{"2001:db8::1", false},
+	}
+	for _, tt := range tc {
+		t.Run(tt.ip, func(t *testing.T) {
+			assert.Equal(t, tt.private, (&DB{}).isPrivateIP(net.ParseIP(tt.ip)))
+		})
+	}
+}
+
+func TestCanonicalIP(t *testing.T) {
+	tc := []struct {
+		ip       string
+		expected string
+	}{
+		{"10.1.2.3", "10.1.2.3"},
+		{"::ffff:10.1.2.3", "10.1.2.3"},
+		{"2600:1F18:ABCD:0000:0000:0000:0000:0001", "2600:1f18:abcd::1"},
+		{"fe80:0:0::1", "fe80::1"},
+	}
+	for _, tt := range tc {
+		t.Run(tt.ip, func(t *testing.T) {
+			actual, err := canonicalIP(tt.ip)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, actual)
+		})
+	}
+	_, err := canonicalIP("10.1.2")
+	assert.Error(t, err)
+}
+
+func TestStoreMixedFamilies(t *testing.T) {
+	mockdb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockdb.Close()
+
+	theDB := DB{
+		sqldb: mockdb,
+	}
+
+	changes := fakeCloudAssetChanges()
+	changes.Changes[0].PrivateIPAddresses = []string{"4.3.2.1", "FD00:0:0::0A"}
+	changes.Changes[0].PublicIPAddresses = []string{"2600:1F18::1"}
+	changes.Changes[0].RelatedResources = nil
+
+	mock.ExpectBegin()
+	mock.ExpectExec("with sel as").WithArgs("arn", "region", "aid", "rtype", []byte("{\"tag1\":\"val1\"}")).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery("SELECT").WithArgs("arn", "aid", "region").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	timestamp, _ := time.Parse(time.RFC3339, "2019-04-09T08:29:35+00:00")
+	mock.ExpectExec(regexp.QuoteMeta(`update aws_private_ip_assignment`)).WithArgs(timestamp, "4.3.2.1", 1, "", "").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`update aws_private_ip_assignment`)).WithArgs(timestamp, "fd00::a", 1, "", "").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`update aws_public_ip_assignment`)).WithArgs(timestamp, "2600:1f18::1", 1, "google.com").WillReturnResult(sqlmock.NewResult(1, 1))
+	expectTagsUnchanged(mock, 1, timestamp)
+	expectJournaled(mock)
+	mock.ExpectCommit()
+
+	if err = theDB.Store(context.Background(), changes); err != nil {
+		t.Errorf("error was not expected while saving resource: %s", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestStoreInvalidIP(t *testing.T) {
+	mockdb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockdb.Close()
+
+	theDB := DB{
+		sqldb: mockdb,
+	}
+
+	changes := fakeCloudAssetChanges()
+	changes.Changes[0].PrivateIPAddresses = []string{"not an ip"}
+
+	mock.ExpectBegin()
+	mock.ExpectExec("with sel as").WithArgs("arn", "region", "aid", "rtype", []byte("{\"tag1\":\"val1\"}")).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery("SELECT").WithArgs("arn", "aid", "region").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectRollback()
+
+	assert.Error(t, theDB.Store(context.Background(), changes))
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestFetchByIPv6(t *testing.T) {
+	at, _ := time.Parse(time.RFC3339, "2019-04-09T08:55:35+00:00")
+	tc := []struct {
+		name      string
+		ipAddress string
+		query     string
+		args      []driver.Value
+	}{
+		{"unique local", "FD00::0:A", resourceByPrivateIPQuery, []driver.Value{"fd00::a", at, "", ""}},
+		{"link local", "fe80:0::1", resourceByPrivateIPQuery, []driver.Value{"fe80::1", at, "", ""}},
+		{"global unicast", "2600:1F18::1", resourceByPublicIPQuery, []driver.Value{"2600:1f18::1", at, ""}},
+	}
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			mockdb, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+			}
+			defer mockdb.Close()
+
+			thedb := &DB{
+				sqldb: mockdb,
+			}
+
+			mock.ExpectQuery(regexp.QuoteMeta(tt.query)).WithArgs(tt.args...).WillReturnError(errors.New("stop here"))
+
+			_, err = thedb.FetchByIP(context.Background(), at, tt.ipAddress, domain.IPScope{})
+			assert.Error(t, err)
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("there were unfulfilled expectations: %s", err)
+			}
+		})
+	}
+}
+
+func TestIsPrivateIPConfiguredNetworks(t *testing.T) {
+	networks, err := parseNetworks([]string{"203.0.113.0/24", "2600:1f18:aaaa::/48"})
+	assert.NoError(t, err)
+	thedb := &DB{privateNetworks: networks}
+
+	assert.True(t, thedb.isPrivateIP(net.ParseIP("203.0.113.7")))
+	assert.True(t, thedb.isPrivateIP(net.ParseIP("2600:1f18:aaaa:1::1")))
+	assert.False(t, thedb.isPrivateIP(net.ParseIP("10.0.0.1")))
+	assert.False(t, thedb.isPrivateIP(net.ParseIP("2600:1f18:bbbb::1")))
+	assert.True(t, thedb.IsPrivateIP("203.0.113.7"))
+	assert.False(t, thedb.IsPrivateIP("10.0.0.1"))
+	assert.False(t, thedb.IsPrivateIP("not an IP"))
+}
+
+func TestFetchByIPUnifiedLookup(t *testing.T) {
+	mockdb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockdb.Close()
+
+	thedb := &DB{
+		sqldb:           mockdb,
+		unifiedIPLookup: true,
+	}
+
+	at, _ := time.Parse(time.RFC3339, "2019-04-09T08:55:35+00:00")
+	ipAddress := "198.19.0.1"
+	publicRows := sqlmock.NewRows([]string{"public_ip", "aws_hostname", "arn_id", "meta", "region", "resource_type",
+		"account", "id", "t_account", "t_login", "t_email", "t_name", "t_valid", "p_login", "p_email", "p_name", "p_valid"}).
+		AddRow(ipAddress, "yahoo.com", "rid1", nil, "region", "type", "aid", 1, nil, nil, nil, nil, nil, nil, nil, nil, nil)
+	privateRows := sqlmock.NewRows([]string{"private_ip", "arn_id", "meta", "region", "resource_type",
+		"account", "id", "t_account", "t_login", "t_email", "t_name", "t_valid", "p_login", "p_email", "p_name", "p_valid"}).
+		AddRow(ipAddress, "rid2", nil, "region", "type", "aid", 1, nil, nil, nil, nil, nil, nil, nil, nil, nil)
+	mock.ExpectQuery(regexp.QuoteMeta(resourceByPublicIPQuery)).WithArgs(ipAddress, at, "").WillReturnRows(publicRows).RowsWillBeClosed()
+	mock.ExpectQuery(regexp.QuoteMeta(resourceByPrivateIPQuery)).WithArgs(ipAddress, at, "", "").WillReturnRows(privateRows).RowsWillBeClosed()
+	expectNoAttachments(mock)
+
+	results, err := thedb.FetchByIP(context.Background(), at, ipAddress, domain.IPScope{})
+	assert.NoError(t, err)
+	assert.Len(t, results, 2)
+	assert.Equal(t, "rid1", results[0].ARN)
+	assert.Equal(t, []string{ipAddress}, results[0].PublicIPAddresses)
+	assert.Equal(t, "rid2", results[1].ARN)
+	assert.Equal(t, []string{ipAddress}, results[1].PrivateIPAddresses)
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestFetchByIPUnifiedLookupSkipsPrivateAddresses(t *testing.T) {
+	mockdb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockdb.Close()
+
+	thedb := &DB{
+		sqldb:           mockdb,
+		unifiedIPLookup: true,
+	}
+
+	at, _ := time.Parse(time.RFC3339, "2019-04-09T08:55:35+00:00")
+	mock.ExpectQuery(regexp.QuoteMeta(resourceByPrivateIPQuery)).WithArgs("10.0.0.1", at, "", "").WillReturnError(errors.New("stop here"))
+
+	_, err = thedb.FetchByIP(context.Background(), at, "10.0.0.1", domain.IPScope{})
+	assert.Error(t, err)
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestFetchByIPScoped(t *testing.T) {
+	at, _ := time.Parse(time.RFC3339, "2019-04-09T08:55:35+00:00")
+	scope := domain.IPScope{AccountID: "123456789012", VPCID: "vpc-1234"}
+	tc := []struct {
+		name      string
+		ipAddress string
+		query     string
+		args      []driver.Value
+	}{
+		{"private", "10.0.0.1", resourceByPrivateIPQuery, []driver.Value{"10.0.0.1", at, scope.AccountID, scope.VPCID}},
+		{"public", "8.8.8.8", resourceByPublicIPQuery, []driver.Value{"8.8.8.8", at, scope.AccountID}},
+	}
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			mockdb, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+			}
+			defer mockdb.Close()
+
+			thedb := &DB{
+				sqldb: mockdb,
+			}
+
+			mock.ExpectQuery(regexp.QuoteMeta(tt.query)).WithArgs(tt.args...).WillReturnError(errors.New("stop here"))
+
+			_, err = thedb.FetchByIP(context.Background(), at, tt.ipAddress, scope)
+			assert.Error(t, err)
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("there were unfulfilled expectations: %s", err)
+			}
+		})
+	}
+}
+
+func TestStoreWithVPC(t *testing.T) {
+	mockdb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockdb.Close()
+
+	theDB := DB{
+		sqldb: mockdb,
+	}
+
+	changes := fakeCloudAssetChanges()
+	changes.Changes[0].VPCID = "vpc-1234"
+	changes.Changes[0].SubnetID = "subnet-5678"
+	changes.Changes[0].PublicIPAddresses = nil
+	changes.Changes[0].RelatedResources = nil
+
+	mock.ExpectBegin()
+	mock.ExpectExec("with sel as").WithArgs("arn", "region", "aid", "rtype", []byte("{\"tag1\":\"val1\"}")).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery("SELECT").WithArgs("arn", "aid", "region").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	timestamp, _ := time.Parse(time.RFC3339, "2019-04-09T08:29:35+00:00")
+	mock.ExpectExec(regexp.QuoteMeta(`update aws_private_ip_assignment`)).WithArgs(timestamp, "4.3.2.1", 1, "vpc-1234", "subnet-5678").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta(`insert into aws_private_ip_assignment`)).WithArgs(timestamp, "4.3.2.1", 1, "vpc-1234", "subnet-5678").WillReturnResult(sqlmock.NewResult(1, 1))
+	expectTagsUnchanged(mock, 1, timestamp)
+	expectJournaled(mock)
+	mock.ExpectCommit()
+
+	if err = theDB.Store(context.Background(), changes); err != nil {
+		t.Errorf("error was not expected while saving resource: %s", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestStoreTagsChanged(t *testing.T) {
+	mockdb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockdb.Close()
+
+	theDB := DB{
+		sqldb: mockdb,
+	}
+
+	changes := fakeCloudAssetChanges()
+	changes.Changes = nil
+	tags := []byte("{\"tag1\":\"val1\"}")
+
+	mock.ExpectBegin()
+	mock.ExpectExec("with sel as").WithArgs("arn", "region", "aid", "rtype", tags).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery("SELECT").WithArgs("arn", "aid", "region").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectQuery(regexp.QuoteMeta(`from aws_resource_tags`)).WithArgs(1, changes.ChangeTime, tags).WillReturnRows(sqlmock.NewRows([]string{"id", "same"}).AddRow(7, false))
+	mock.ExpectExec(regexp.QuoteMeta(`update aws_resource_tags`)).WithArgs(changes.ChangeTime, 7).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`insert into aws_resource_tags`)).WithArgs(changes.ChangeTime, tags, 1).WillReturnResult(sqlmock.NewResult(8, 1))
+	expectJournaled(mock)
+	mock.ExpectCommit()
+
+	if err = theDB.Store(context.Background(), changes); err != nil {
+		t.Errorf("error was not expected while saving resource: %s", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestStoreTagsFirstSeen(t *testing.T) {
+	mockdb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockdb.Close()
+
+	theDB := DB{
+		sqldb: mockdb,
+	}
+
+	changes := fakeCloudAssetChanges()
+	changes.Changes = nil
+	tags := []byte("{\"tag1\":\"val1\"}")
+
+	mock.ExpectBegin()
+	mock.ExpectExec("with sel as").WithArgs("arn", "region", "aid", "rtype", tags).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery("SELECT").WithArgs("arn", "aid", "region").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectQuery(regexp.QuoteMeta(`from aws_resource_tags`)).WithArgs(1, changes.ChangeTime, tags).WillReturnRows(sqlmock.NewRows([]string{"id", "same"}))
+	mock.ExpectExec(regexp.QuoteMeta(`insert into aws_resource_tags`)).WithArgs(changes.ChangeTime, tags, 1).WillReturnResult(sqlmock.NewResult(1, 1))
+	expectJournaled(mock)
+	mock.ExpectCommit()
+
+	if err = theDB.Store(context.Background(), changes); err != nil {
+		t.Errorf("error was not expected while saving resource: %s", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestStoreWithoutTags(t *testing.T) {
+	mockdb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockdb.Close()
+
+	theDB := DB{
+		sqldb: mockdb,
+	}
+
+	changes := fakeCloudAssetChanges()
+	changes.Changes = nil
+	changes.Tags = nil
+
+	mock.ExpectBegin()
+	mock.ExpectExec("with sel as").WithArgs("arn", "region", "aid", "rtype", []byte("null")).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery("SELECT").WithArgs("arn", "aid", "region").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	expectJournaled(mock)
+	mock.ExpectCommit()
+
+	if err = theDB.Store(context.Background(), changes); err != nil {
+		t.Errorf("error was not expected while saving resource: %s", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestStoreTagsError(t *testing.T) {
+	mockdb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockdb.Close()
+
+	theDB := DB{
+		sqldb: mockdb,
+	}
+
+	changes := fakeCloudAssetChanges()
+	changes.Changes = nil
+	tags := []byte("{\"tag1\":\"val1\"}")
+
+	mock.ExpectBegin()
+	mock.ExpectExec("with sel as").WithArgs("arn", "region", "aid", "rtype", tags).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery("SELECT").WithArgs("arn", "aid", "region").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectQuery(regexp.QuoteMeta(`from aws_resource_tags`)).WithArgs(1, changes.ChangeTime, tags).WillReturnError(errors.New("failed to read tags"))
+	mock.ExpectRollback()
+
+	assert.Error(t, theDB.Store(context.Background(), changes))
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestStoreCreated(t *testing.T) {
+	mockdb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockdb.Close()
+
+	theDB := DB{
+		sqldb: mockdb,
+	}
+
+	changes := fakeCloudAssetChanges()
+	changes.Changes = nil
+	changes.Tags = nil
+	changes.Lifecycle = "created"
+
+	mock.ExpectBegin()
+	mock.ExpectExec("with sel as").WithArgs("arn", "region", "aid", "rtype", []byte("null")).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery("SELECT").WithArgs("arn", "aid", "region").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectExec(regexp.QuoteMeta(`update aws_resource`)).WithArgs(changes.ChangeTime, 1).WillReturnResult(sqlmock.NewResult(1, 1))
+	expectJournaled(mock)
+	mock.ExpectCommit()
+
+	if err = theDB.Store(context.Background(), changes); err != nil {
+		t.Errorf("error was not expected while saving resource: %s", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestStoreTerminated(t *testing.T) {
+	mockdb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockdb.Close()
+
+	theDB := DB{
+		sqldb: mockdb,
+	}
+
+	changes := fakeCloudAssetChanges()
+	changes.Changes = nil
+	changes.Lifecycle = domain.LifecycleTerminated
+
+	mock.ExpectBegin()
+	mock.ExpectExec("with sel as").WithArgs("arn", "region", "aid", "rtype", []byte("{\"tag1\":\"val1\"}")).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery("SELECT").WithArgs("arn", "aid", "region").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	expectTagsUnchanged(mock, 1, changes.ChangeTime)
+	mock.ExpectExec(regexp.QuoteMeta(`update aws_resource`)).WithArgs(changes.ChangeTime, 1).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`update aws_private_ip_assignment`)).WithArgs(changes.ChangeTime, 1).WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec(regexp.QuoteMeta(`update aws_public_ip_assignment`)).WithArgs(changes.ChangeTime, 1).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`update aws_private_hostname_assignment`)).WithArgs(changes.ChangeTime, 1).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`update aws_resource_tags`)).WithArgs(changes.ChangeTime, 1).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`update aws_resource_relationship`)).WithArgs(changes.ChangeTime, "arn").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`update aws_resource_attachment`)).WithArgs(changes.ChangeTime, "arn").WillReturnResult(sqlmock.NewResult(0, 1))
+	expectJournaled(mock)
+	mock.ExpectCommit()
+
+	if err = theDB.Store(context.Background(), changes); err != nil {
+		t.Errorf("error was not expected while saving resource: %s", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestStoreTerminatedError(t *testing.T) {
+	mockdb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockdb.Close()
+
+	theDB := DB{
+		sqldb: mockdb,
+	}
+
+	changes := fakeCloudAssetChanges()
+	changes.Changes = nil
+	changes.Tags = nil
+	changes.Lifecycle = domain.LifecycleTerminated
+
+	mock.ExpectBegin()
+	mock.ExpectExec("with sel as").WithArgs("arn", "region", "aid", "rtype", []byte("null")).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery("SELECT").WithArgs("arn", "aid", "region").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectExec(regexp.QuoteMeta(`update aws_resource`)).WithArgs(changes.ChangeTime, 1).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`update aws_private_ip_assignment`)).WithArgs(changes.ChangeTime, 1).WillReturnError(errors.New("failed to release"))
+	mock.ExpectRollback()
+
+	assert.Error(t, theDB.Store(context.Background(), changes))
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestStoreUnknownLifecycle(t *testing.T) {
+	mockdb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockdb.Close()
+
+	theDB := DB{
+		sqldb: mockdb,
+	}
+
+	changes := fakeCloudAssetChanges()
+	changes.Changes = nil
+	changes.Tags = nil
+	changes.Lifecycle = "PAUSED"
+
+	mock.ExpectBegin()
+	mock.ExpectExec("with sel as").WithArgs("arn", "region", "aid", "rtype", []byte("null")).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery("SELECT").WithArgs("arn", "aid", "region").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectRollback()
+
+	assert.Error(t, theDB.Store(context.Background(), changes))
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
@@ -0,0 +1,49 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/asecurityteam/asset-inventory-api/pkg/domain"
+)
+
+// Query to record an event ID as processed. An ID seen within the window conflicts and is left untouched, so no row
+// is affected, while an ID seen before the window is recorded again.
+const markEventProcessedQuery = `
+insert into aws_processed_event (event_id, processed_at)
+values ($1, $2)
+on conflict (event_id) do update set processed_at = excluded.processed_at
+where aws_processed_event.processed_at < $3`
+
+// Query to forget a bounded amount of event IDs processed before the window, rows already being forgotten by a
+// concurrent transaction are skipped rather than waited for
+const pruneProcessedEventsQuery = `
+delete
+from aws_processed_event
+where event_id in (select event_id
+                   from aws_processed_event
+                   where processed_at < $1
+                   limit 100 for update skip locked)`
+
+// markEventProcessed records the event ID as processed as part of the transaction storing the changes, so that a
+// failure to store them lets the event be delivered again. Changes without an event ID are never duplicates.
+func (db *DB) markEventProcessed(ctx context.Context, tx *sql.Tx, eventID string) error {
+	if eventID == "" {
+		return nil
+	}
+	now := db.now()
+	windowStart := now.Add(-db.eventIDWindow)
+	res, err := tx.ExecContext(ctx, markEventProcessedQuery, eventID, now, windowStart)
+	if err != nil {
+		return err
+	}
+	changedRows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if changedRows == 0 {
+		return domain.DuplicateEvent{EventID: eventID}
+	}
+	_, err = tx.ExecContext(ctx, pruneProcessedEventsQuery, windowStart)
+	return err
+}
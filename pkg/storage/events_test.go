@@ -0,0 +1,101 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/asecurityteam/asset-inventory-api/pkg/domain"
+)
+
+func TestStoreDuplicateEvent(t *testing.T) {
+	mockdb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockdb.Close()
+
+	now, _ := time.Parse(time.RFC3339, "2019-04-09T08:55:35+00:00")
+	thedb := DB{
+		sqldb:         mockdb,
+		now:           func() time.Time { return now },
+		eventIDWindow: time.Hour,
+	}
+
+	changes := fakeCloudAssetChanges()
+	changes.EventID = "event-1"
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`insert into aws_processed_event`)).WithArgs("event-1", now, now.Add(-time.Hour)).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+
+	err = thedb.Store(context.Background(), changes)
+	assert.Equal(t, domain.DuplicateEvent{EventID: "event-1"}, err)
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestStoreNewEvent(t *testing.T) {
+	mockdb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockdb.Close()
+
+	now, _ := time.Parse(time.RFC3339, "2019-04-09T08:55:35+00:00")
+	thedb := DB{
+		sqldb:         mockdb,
+		now:           func() time.Time { return now },
+		eventIDWindow: time.Hour,
+	}
+
+	changes := fakeCloudAssetChanges()
+	changes.EventID = "event-1"
+	changes.Changes = nil
+	changes.Tags = nil
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`insert into aws_processed_event`)).WithArgs("event-1", now, now.Add(-time.Hour)).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`delete`)).WithArgs(now.Add(-time.Hour)).WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectExec("with sel as").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery("SELECT").WithArgs("arn", "aid", "region").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	expectJournaled(mock)
+	mock.ExpectCommit()
+
+	err = thedb.Store(context.Background(), changes)
+	assert.NoError(t, err)
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestStoreEventError(t *testing.T) {
+	mockdb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockdb.Close()
+
+	now, _ := time.Parse(time.RFC3339, "2019-04-09T08:55:35+00:00")
+	thedb := DB{
+		sqldb:         mockdb,
+		now:           func() time.Time { return now },
+		eventIDWindow: time.Hour,
+	}
+
+	changes := fakeCloudAssetChanges()
+	changes.EventID = "event-1"
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`insert into aws_processed_event`)).WillReturnError(errors.New("no bueno"))
+	mock.ExpectRollback()
+
+	err = thedb.Store(context.Background(), changes)
+	assert.Error(t, err)
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
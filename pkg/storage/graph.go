@@ -0,0 +1,111 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/asecurityteam/asset-inventory-api/pkg/domain"
+)
+
+// Query to walk the relationships in place at the point in time from a resource, up to the depth. Each row past the
+// starting one is a hop along a relationship, resources already on the path are not visited again to avoid cycles.
+// A not_before of to_timestamp(0) is written when a release is seen before the matching assignment, so it is
+// reported as unknown.
+const graphByARNIDQuery = `
+with recursive walk(node, depth, path, from_id, to_id, not_before, not_after) as (
+    select $1::varchar, 0, array [$1::varchar], null::varchar, null::varchar, null::timestamp, null::timestamp
+    union all
+    select e.next, w.depth + 1, w.path || e.next, e.from_id, e.to_id, e.not_before, e.not_after
+    from walk w
+             join lateral (select rel.related_arn_id as next,
+                                  rel.arn_id         as from_id,
+                                  rel.related_arn_id as to_id,
+                                  rel.not_before,
+                                  rel.not_after
+                           from aws_resource_relationship rel
+                           where $3::varchar <> 'incoming'
+                             and rel.arn_id = w.node
+                           union all
+                           select rel.arn_id, rel.arn_id, rel.related_arn_id, rel.not_before, rel.not_after
+                           from aws_resource_relationship rel
+                           where $3::varchar <> 'outgoing'
+                             and rel.related_arn_id = w.node) e
+                  on (e.not_before is null or e.not_before < $2)
+                      and (e.not_after is null or e.not_after > $2)
+    where w.depth < $4
+      and not e.next = any (w.path)
+)
+select w.depth,
+       w.node,
+       rt.resource_type,
+       w.from_id,
+       w.to_id,
+       nullif(w.not_before, to_timestamp(0)::timestamp),
+       w.not_after
+from walk w
+         left join aws_resource res on res.arn_id = w.node
+         left join aws_resource_type rt on res.aws_resource_type_id = rt.id
+order by w.depth, w.node`
+
+// graphEdgeKey identifies a relationship interval, the same pair of resources may have been related several times
+type graphEdgeKey struct {
+	from      string
+	to        string
+	notBefore time.Time
+}
+
+// FetchGraph gets the resources and relationships reachable from the resource at the specified time, following
+// relationships in the direction up to depth hops away
+func (db *DB) FetchGraph(ctx context.Context, when time.Time, resID string, direction string, depth uint) (domain.ResourceGraph, error) {
+	switch direction {
+	case domain.GraphDirectionOutgoing, domain.GraphDirectionIncoming, domain.GraphDirectionBoth:
+	default:
+		return domain.ResourceGraph{}, fmt.Errorf("unknown graph direction %s", direction)
+	}
+	rows, err := db.sqldb.QueryContext(ctx, graphByARNIDQuery, resID, when, direction, depth)
+	if err != nil {
+		return domain.ResourceGraph{}, err
+	}
+	defer rows.Close()
+
+	graph := domain.ResourceGraph{
+		Nodes: make([]domain.ResourceGraphNode, 0),
+		Edges: make([]domain.ResourceGraphEdge, 0),
+	}
+	// the same resource or relationship can be reached along several paths, rows come ordered by depth so the
+	// first one seen is the closest
+	seenNodes := make(map[string]struct{})
+	seenEdges := make(map[graphEdgeKey]struct{})
+	for rows.Next() {
+		var node domain.ResourceGraphNode
+		var resourceType, from, to sql.NullString
+		var edge domain.ResourceGraphEdge
+		if err = rows.Scan(&node.Depth, &node.ResourceID, &resourceType, &from, &to, &edge.NotBefore, &edge.NotAfter); err != nil {
+			return domain.ResourceGraph{}, err
+		}
+		if _, ok := seenNodes[node.ResourceID]; !ok {
+			seenNodes[node.ResourceID] = struct{}{}
+			node.ResourceType = resourceType.String
+			graph.Nodes = append(graph.Nodes, node)
+		}
+		if !from.Valid {
+			continue // the starting resource
+		}
+		edge.From, edge.To = from.String, to.String
+		key := graphEdgeKey{from: edge.From, to: edge.To}
+		if edge.NotBefore != nil {
+			key.notBefore = *edge.NotBefore
+		}
+		if _, ok := seenEdges[key]; !ok {
+			seenEdges[key] = struct{}{}
+			graph.Edges = append(graph.Edges, edge)
+		}
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return domain.ResourceGraph{}, err
+	}
+	return graph, nil
+}
@@ -0,0 +1,118 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/asecurityteam/asset-inventory-api/pkg/domain"
+)
+
+var graphColumns = []string{"depth", "node", "resource_type", "from_id", "to_id", "not_before", "not_after"}
+
+func TestFetchGraph(t *testing.T) {
+	mockdb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockdb.Close()
+
+	thedb := DB{
+		sqldb: mockdb,
+	}
+
+	at, _ := time.Parse(time.RFC3339, "2019-04-09T08:55:35+00:00")
+	before := at.Add(-time.Hour)
+	after := at.Add(time.Hour)
+	// the instance is reached both directly and through the second network interface
+	rows := sqlmock.NewRows(graphColumns).
+		AddRow(0, "app/lb/1", "AWS::ElasticLoadBalancingV2::LoadBalancer", nil, nil, nil, nil).
+		AddRow(1, "eni-1", "AWS::EC2::NetworkInterface", "eni-1", "app/lb/1", before, nil).
+		AddRow(1, "eni-2", "AWS::EC2::NetworkInterface", "eni-2", "app/lb/1", nil, after).
+		AddRow(2, "i-1", nil, "eni-1", "i-1", before, nil).
+		AddRow(2, "i-1", nil, "eni-2", "i-1", before, nil)
+	mock.ExpectQuery("with recursive walk").WithArgs("app/lb/1", at, domain.GraphDirectionBoth, 2).WillReturnRows(rows)
+
+	graph, err := thedb.FetchGraph(context.Background(), at, "app/lb/1", domain.GraphDirectionBoth, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, domain.ResourceGraph{
+		Nodes: []domain.ResourceGraphNode{
+			{ResourceID: "app/lb/1", ResourceType: "AWS::ElasticLoadBalancingV2::LoadBalancer", Depth: 0},
+			{ResourceID: "eni-1", ResourceType: "AWS::EC2::NetworkInterface", Depth: 1},
+			{ResourceID: "eni-2", ResourceType: "AWS::EC2::NetworkInterface", Depth: 1},
+			{ResourceID: "i-1", Depth: 2},
+		},
+		Edges: []domain.ResourceGraphEdge{
+			{From: "eni-1", To: "app/lb/1", NotBefore: &before},
+			{From: "eni-2", To: "app/lb/1", NotAfter: &after},
+			{From: "eni-1", To: "i-1", NotBefore: &before},
+			{From: "eni-2", To: "i-1", NotBefore: &before},
+		},
+	}, graph)
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestFetchGraphDeduplicatesEdges(t *testing.T) {
+	mockdb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockdb.Close()
+
+	thedb := DB{
+		sqldb: mockdb,
+	}
+
+	at, _ := time.Parse(time.RFC3339, "2019-04-09T08:55:35+00:00")
+	before := at.Add(-time.Hour)
+	// a triangle a-b-c walked both ways reaches the b-c relationship twice
+	rows := sqlmock.NewRows(graphColumns).
+		AddRow(0, "a", "type", nil, nil, nil, nil).
+		AddRow(1, "b", "type", "a", "b", before, nil).
+		AddRow(1, "c", "type", "a", "c", before, nil).
+		AddRow(2, "b", "type", "b", "c", before, nil).
+		AddRow(2, "c", "type", "b", "c", before, nil)
+	mock.ExpectQuery("with recursive walk").WithArgs("a", at, domain.GraphDirectionOutgoing, 2).WillReturnRows(rows)
+
+	graph, err := thedb.FetchGraph(context.Background(), at, "a", domain.GraphDirectionOutgoing, 2)
+	assert.NoError(t, err)
+	assert.Len(t, graph.Nodes, 3)
+	assert.Len(t, graph.Edges, 3)
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestFetchGraphQueryError(t *testing.T) {
+	mockdb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockdb.Close()
+
+	thedb := DB{
+		sqldb: mockdb,
+	}
+
+	at, _ := time.Parse(time.RFC3339, "2019-04-09T08:55:35+00:00")
+	mock.ExpectQuery("with recursive walk").WillReturnError(errors.New("no bueno"))
+
+	_, err = thedb.FetchGraph(context.Background(), at, "a", domain.GraphDirectionIncoming, 1)
+	assert.Error(t, err)
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestFetchGraphUnknownDirection(t *testing.T) {
+	thedb := DB{}
+	at, _ := time.Parse(time.RFC3339, "2019-04-09T08:55:35+00:00")
+	_, err := thedb.FetchGraph(context.Background(), at, "a", "sideways", 1)
+	assert.Error(t, err)
+}
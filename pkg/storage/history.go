@@ -0,0 +1,76 @@
+package storage
+
+import (
+	"context"
+
+	"github.com/asecurityteam/asset-inventory-api/pkg/domain"
+)
+
+// Query to list every assignment and relationship interval of a resource.
+// A not_before of to_timestamp(0) is written when a release is seen before the matching assignment, so it is
+// reported as unknown. Public IP addresses without a hostname have no hostname entry. Relationships are recorded
+// against the resource ID only, and may point either way. Attachments are those of the resource to others.
+const historyByARNIDQuery = `
+select 'privateIp' as kind, host(pria.private_ip) as value,
+       nullif(pria.not_before, to_timestamp(0)::timestamp) as not_before, pria.not_after
+from aws_private_ip_assignment pria
+         join aws_resource res on pria.aws_resource_id = res.id
+where res.arn_id = $1
+union
+select 'publicIp', host(puia.public_ip),
+       nullif(puia.not_before, to_timestamp(0)::timestamp), puia.not_after
+from aws_public_ip_assignment puia
+         join aws_resource res on puia.aws_resource_id = res.id
+where res.arn_id = $1
+union
+select 'hostname', puia.aws_hostname,
+       nullif(puia.not_before, to_timestamp(0)::timestamp), puia.not_after
+from aws_public_ip_assignment puia
+         join aws_resource res on puia.aws_resource_id = res.id
+where res.arn_id = $1
+  and puia.aws_hostname is not null
+union
+select 'hostname', ha.aws_hostname,
+       nullif(ha.not_before, to_timestamp(0)::timestamp), ha.not_after
+from aws_private_hostname_assignment ha
+         join aws_resource res on ha.aws_resource_id = res.id
+where res.arn_id = $1
+union
+select 'relatedResource', rel.related_arn_id,
+       nullif(rel.not_before, to_timestamp(0)::timestamp), rel.not_after
+from aws_resource_relationship rel
+where rel.arn_id = $1
+union
+select 'relatedResource', rel.arn_id,
+       nullif(rel.not_before, to_timestamp(0)::timestamp), rel.not_after
+from aws_resource_relationship rel
+where rel.related_arn_id = $1
+union
+select 'attachedTo', att.attached_to_arn_id,
+       nullif(att.not_before, to_timestamp(0)::timestamp), att.not_after
+from aws_resource_attachment att
+where att.arn_id = $1
+order by not_before nulls first, not_after nulls last, kind, value`
+
+// FetchHistoryByResourceID gets every interval of IP addresses, hostnames and related resources held by the resource
+func (db *DB) FetchHistoryByResourceID(ctx context.Context, resID string) ([]domain.CloudAssetHistoryEntry, error) {
+	rows, err := db.sqldb.QueryContext(ctx, historyByARNIDQuery, resID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	history := make([]domain.CloudAssetHistoryEntry, 0)
+	for rows.Next() {
+		var entry domain.CloudAssetHistoryEntry
+		if err = rows.Scan(&entry.Kind, &entry.Value, &entry.NotBefore, &entry.NotAfter); err != nil {
+			return nil, err
+		}
+		history = append(history, entry)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return history, nil
+}
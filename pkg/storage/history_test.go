@@ -0,0 +1,111 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/asecurityteam/asset-inventory-api/pkg/domain"
+)
+
+var historyColumns = []string{"kind", "value", "not_before", "not_after"}
+
+func TestFetchHistoryByResourceIDQueryError(t *testing.T) {
+	mockdb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockdb.Close()
+
+	thedb := DB{
+		sqldb: mockdb,
+	}
+
+	mock.ExpectQuery("select 'privateIp'").WithArgs("rid").WillReturnError(errors.New("no bueno"))
+
+	_, err = thedb.FetchHistoryByResourceID(context.Background(), "rid")
+	assert.Error(t, err)
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestFetchHistoryByResourceIDScanError(t *testing.T) {
+	mockdb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockdb.Close()
+
+	thedb := DB{
+		sqldb: mockdb,
+	}
+
+	rows := sqlmock.NewRows(historyColumns).AddRow(domain.HistoryPrivateIP, "10.0.0.1", "not a time", nil)
+	mock.ExpectQuery("select 'privateIp'").WithArgs("rid").WillReturnRows(rows).RowsWillBeClosed()
+
+	_, err = thedb.FetchHistoryByResourceID(context.Background(), "rid")
+	assert.Error(t, err)
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestFetchHistoryByResourceIDEmpty(t *testing.T) {
+	mockdb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockdb.Close()
+
+	thedb := DB{
+		sqldb: mockdb,
+	}
+
+	mock.ExpectQuery("select 'privateIp'").WithArgs("rid").WillReturnRows(sqlmock.NewRows(historyColumns)).RowsWillBeClosed()
+
+	history, err := thedb.FetchHistoryByResourceID(context.Background(), "rid")
+	assert.NoError(t, err)
+	assert.NotNil(t, history)
+	assert.Empty(t, history)
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestFetchHistoryByResourceID(t *testing.T) {
+	mockdb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockdb.Close()
+
+	thedb := DB{
+		sqldb: mockdb,
+	}
+
+	first, _ := time.Parse(time.RFC3339, "2019-04-09T08:55:35+00:00")
+	second, _ := time.Parse(time.RFC3339, "2019-04-10T08:55:35+00:00")
+	rows := sqlmock.NewRows(historyColumns).
+		AddRow(domain.HistoryPrivateIP, "10.0.0.1", nil, first).
+		AddRow(domain.HistoryHostname, "yahoo.com", first, nil).
+		AddRow(domain.HistoryPublicIP, "9.8.7.6", first, nil).
+		AddRow(domain.HistoryRelatedResource, "eni-1234", first, second)
+	mock.ExpectQuery("select 'privateIp'").WithArgs("rid").WillReturnRows(rows).RowsWillBeClosed()
+
+	history, err := thedb.FetchHistoryByResourceID(context.Background(), "rid")
+	assert.NoError(t, err)
+	assert.Equal(t, []domain.CloudAssetHistoryEntry{
+		{Kind: domain.HistoryPrivateIP, Value: "10.0.0.1", NotAfter: &first},
+		{Kind: domain.HistoryHostname, Value: "yahoo.com", NotBefore: &first},
+		{Kind: domain.HistoryPublicIP, Value: "9.8.7.6", NotBefore: &first},
+		{Kind: domain.HistoryRelatedResource, Value: "eni-1234", NotBefore: &first, NotAfter: &second},
+	}, history)
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
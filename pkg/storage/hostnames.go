@@ -0,0 +1,50 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/asecurityteam/asset-inventory-api/pkg/domain"
+)
+
+// Query to list a page of resources holding a hostname that matches the pattern at the point in time, for either a
+// public or a private IP address. The trigram indexes on aws_hostname serve case insensitive patterns with leading
+// wildcards.
+const resourceIDsByHostnamePatternQuery = `
+select ids.id
+from (select puia.aws_resource_id as id
+      from aws_public_ip_assignment puia
+      where puia.aws_hostname ilike $1
+        and puia.not_before < $2
+        and (puia.not_after is null or puia.not_after > $2)
+        and puia.aws_resource_id > $4
+      union
+      select prha.aws_resource_id
+      from aws_private_hostname_assignment prha
+      where prha.aws_hostname ilike $1
+        and prha.not_before < $2
+        and (prha.not_after is null or prha.not_after > $2)
+        and prha.aws_resource_id > $4) ids
+order by ids.id
+limit $3`
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// FetchByHostnamePattern gets a page of the assets with a hostname that starts with, ends with or contains the pattern at
+// the specified time, starting after the given resource key
+func (db *DB) FetchByHostnamePattern(ctx context.Context, when time.Time, pattern string, match string, count uint, after int64) ([]domain.CloudAssetDetails, int64, error) {
+	like := likeEscaper.Replace(pattern)
+	switch strings.ToLower(match) {
+	case domain.HostnameMatchPrefix:
+		like = like + "%"
+	case domain.HostnameMatchSuffix:
+		like = "%" + like
+	case domain.HostnameMatchContains:
+		like = "%" + like + "%"
+	default:
+		return nil, 0, fmt.Errorf("unknown hostname match %s", match)
+	}
+	return db.fetchPage(ctx, when, after, resourceIDsByHostnamePatternQuery, like, when, count, after)
+}
@@ -0,0 +1,134 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/asecurityteam/asset-inventory-api/pkg/domain"
+)
+
+func TestFetchByHostnamePatternMatches(t *testing.T) {
+	tc := []struct {
+		match   string
+		pattern string
+		like    string
+	}{
+		{domain.HostnameMatchPrefix, "api.", "api.%"},
+		{domain.HostnameMatchSuffix, ".example.com", "%.example.com"},
+		{"CONTAINS", "web_1", `%web\_1%`},
+		{domain.HostnameMatchContains, `100%\`, `%100\%\\%`},
+	}
+	for _, tt := range tc {
+		t.Run(tt.like, func(t *testing.T) {
+			mockdb, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+			}
+			defer mockdb.Close()
+
+			thedb := DB{
+				sqldb: mockdb,
+			}
+
+			at, _ := time.Parse(time.RFC3339, "2019-04-09T08:55:35+00:00")
+			mock.ExpectQuery("from aws_private_hostname_assignment prha").WithArgs(tt.like, at, 10, 0).
+				WillReturnRows(sqlmock.NewRows([]string{"id"})).RowsWillBeClosed()
+
+			results, last, err := thedb.FetchByHostnamePattern(context.Background(), at, tt.pattern, tt.match, 10, 0)
+			assert.NoError(t, err)
+			assert.Empty(t, results)
+			assert.Equal(t, int64(0), last)
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("there were unfulfilled expectations: %s", err)
+			}
+		})
+	}
+}
+
+func TestFetchByHostnamePattern(t *testing.T) {
+	mockdb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockdb.Close()
+
+	thedb := DB{
+		sqldb: mockdb,
+	}
+
+	at, _ := time.Parse(time.RFC3339, "2019-04-09T08:55:35+00:00")
+	mock.ExpectQuery("from aws_private_hostname_assignment prha").WithArgs("%.example.com", at, 10, 2).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3).AddRow(5)).RowsWillBeClosed()
+	// the hostname of a private IP address is found along with those of public ones
+	rows := sqlmock.NewRows(assetDetailsColumns).
+		AddRow(3, "rid3", "type", "aid", "region", nil, nil, "8.8.8.8", "api.example.com",
+			nil, nil, nil, nil, nil, nil, nil, nil, nil, nil).
+		AddRow(5, "rid5", "type", "aid", "region", nil, "10.0.0.5", nil, nil,
+			"db.example.com", nil, nil, nil, nil, nil, nil, nil, nil, nil)
+	mock.ExpectQuery("select res.id,").WithArgs(pq.Array([]int64{3, 5}), at).WillReturnRows(rows).RowsWillBeClosed()
+
+	results, last, err := thedb.FetchByHostnamePattern(context.Background(), at, ".example.com", domain.HostnameMatchSuffix, 10, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(5), last)
+	assert.Equal(t, []domain.CloudAssetDetails{
+		{
+			PublicIPAddresses: []string{"8.8.8.8"},
+			Hostnames:         []string{"api.example.com"},
+			ResourceType:      "type",
+			AccountID:         "aid",
+			Region:            "region",
+			ARN:               "rid3",
+			AccountOwner: domain.AccountOwner{
+				Champions: []domain.Person{},
+			},
+		},
+		{
+			PrivateIPAddresses: []string{"10.0.0.5"},
+			Hostnames:          []string{"db.example.com"},
+			ResourceType:       "type",
+			AccountID:          "aid",
+			Region:             "region",
+			ARN:                "rid5",
+			AccountOwner: domain.AccountOwner{
+				Champions: []domain.Person{},
+			},
+		},
+	}, results)
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestFetchByHostnamePatternQueryError(t *testing.T) {
+	mockdb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockdb.Close()
+
+	thedb := DB{
+		sqldb: mockdb,
+	}
+
+	at, _ := time.Parse(time.RFC3339, "2019-04-09T08:55:35+00:00")
+	mock.ExpectQuery("from aws_private_hostname_assignment prha").WillReturnError(errors.New("no bueno"))
+
+	_, _, err = thedb.FetchByHostnamePattern(context.Background(), at, "api", domain.HostnameMatchPrefix, 10, 0)
+	assert.Error(t, err)
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestFetchByHostnamePatternUnknownMatch(t *testing.T) {
+	thedb := DB{}
+	at, _ := time.Parse(time.RFC3339, "2019-04-09T08:55:35+00:00")
+	_, _, err := thedb.FetchByHostnamePattern(context.Background(), at, "api", "regex", 10, 0)
+	assert.Error(t, err)
+}
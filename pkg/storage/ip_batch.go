@@ -0,0 +1,217 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net"
+	"time"
+
+	"github.com/lib/pq"
+
+	"github.com/asecurityteam/asset-inventory-api/pkg/domain"
+)
+
+// Query to resolve a batch of private IP addresses, each at its own point in time and optionally narrowed to an
+// account and VPC. The ordinality column is the 1-based position of the lookup in the arrays.
+const resourcesByPrivateIPBatchQuery = `
+select q.ord,
+       host(pria.private_ip),
+       null::varchar,
+       res.arn_id,
+       get_tags_at(res.id, q.ts),
+       reg.region,
+       rt.resource_type,
+       aa.account,
+       o.t_account,
+       o.t_login,
+       o.t_email,
+       o.t_name,
+       o.t_valid,
+       o.p_login,
+       o.p_email,
+       o.p_name,
+       o.p_valid
+from unnest($1::inet[], $2::timestamp[], $3::varchar[], $4::varchar[]) with ordinality as q(ip, ts, account, vpc, ord)
+         join aws_private_ip_assignment pria
+              on pria.private_ip = q.ip
+                  and pria.not_before < q.ts
+                  and (pria.not_after is null or pria.not_after > q.ts)
+                  and (q.vpc = '' or pria.vpc_id = q.vpc)
+         join aws_resource res on pria.aws_resource_id = res.id
+         left join aws_region reg on res.aws_region_id = reg.id
+         left join aws_account aa on res.aws_account_id = aa.id
+         left join aws_resource_type rt on res.aws_resource_type_id = rt.id
+         left join lateral get_owner_and_champions_by_account_id(res.aws_account_id) o on true
+where q.account = '' or aa.account = q.account
+order by q.ord, res.id`
+
+// Query to resolve a batch of public IP addresses, each at its own point in time and optionally narrowed to an account
+const resourcesByPublicIPBatchQuery = `
+select q.ord,
+       host(puia.public_ip),
+       puia.aws_hostname,
+       res.arn_id,
+       get_tags_at(res.id, q.ts),
+       reg.region,
+       rt.resource_type,
+       aa.account,
+       o.t_account,
+       o.t_login,
+       o.t_email,
+       o.t_name,
+       o.t_valid,
+       o.p_login,
+       o.p_email,
+       o.p_name,
+       o.p_valid
+from unnest($1::inet[], $2::timestamp[], $3::varchar[], $4::varchar[]) with ordinality as q(ip, ts, account, vpc, ord)
+         join aws_public_ip_assignment puia
+              on puia.public_ip = q.ip
+                  and puia.not_before < q.ts
+                  and (puia.not_after is null or puia.not_after > q.ts)
+         join aws_resource res on puia.aws_resource_id = res.id
+         left join aws_region reg on res.aws_region_id = reg.id
+         left join aws_account aa on res.aws_account_id = aa.id
+         left join aws_resource_type rt on res.aws_resource_type_id = rt.id
+         left join lateral get_owner_and_champions_by_account_id(res.aws_account_id) o on true
+where q.account = '' or aa.account = q.account
+order by q.ord, res.id`
+
+// ipBatch is the share of a batch of lookups resolved by a single query
+type ipBatch struct {
+	ips      []string
+	times    []time.Time
+	accounts []string
+	vpcs     []string
+	indexes  []int // position of each lookup in the original batch
+}
+
+func (b *ipBatch) add(index int, lookup domain.IPLookup) {
+	b.ips = append(b.ips, lookup.IPAddress)
+	b.times = append(b.times, lookup.When)
+	b.accounts = append(b.accounts, lookup.Scope.AccountID)
+	b.vpcs = append(b.vpcs, lookup.Scope.VPCID)
+	b.indexes = append(b.indexes, index)
+}
+
+// FetchByIPs gets the assets who have each of the IP addresses at the matching time, along with the resources they
+// are attached to.
+// The lookups are split between private and public addresses, and each share is resolved with a single query.
+// With unified lookups, addresses outside of the private ranges are part of both shares.
+func (db *DB) FetchByIPs(ctx context.Context, lookups []domain.IPLookup) ([][]domain.CloudAssetDetails, error) {
+	var private, public ipBatch
+	for i, lookup := range lookups {
+		ipaddr := net.ParseIP(lookup.IPAddress)
+		if ipaddr == nil {
+			return nil, errors.New("invalid IP address")
+		}
+		lookup.IPAddress = ipaddr.String()
+		if db.isPrivateIP(ipaddr) {
+			private.add(i, lookup)
+			continue
+		}
+		public.add(i, lookup)
+		if db.unifiedIPLookup {
+			private.add(i, lookup)
+		}
+	}
+
+	aggregates := make([][]*assetAggregate, len(lookups))
+	if err := db.runBatchLookupQuery(ctx, true, resourcesByPrivateIPBatchQuery, private, aggregates); err != nil {
+		return nil, err
+	}
+	if err := db.runBatchLookupQuery(ctx, false, resourcesByPublicIPBatchQuery, public, aggregates); err != nil {
+		return nil, err
+	}
+
+	attached := make([]*domain.CloudAssetDetails, 0)
+	times := make([]time.Time, 0)
+	for i, aggs := range aggregates {
+		for _, agg := range aggs {
+			attached = append(attached, &agg.asset)
+			times = append(times, lookups[i].When)
+		}
+	}
+	if err := db.addAttachmentChains(ctx, attached, times); err != nil {
+		return nil, err
+	}
+
+	results := make([][]domain.CloudAssetDetails, len(lookups))
+	for i, aggs := range aggregates {
+		results[i] = make([]domain.CloudAssetDetails, 0, len(aggs))
+		for _, agg := range aggs {
+			results[i] = append(results[i], agg.asset)
+		}
+	}
+	return results, nil
+}
+
+// runBatchLookupQuery resolves a share of a batch, adding the assets found for each lookup to aggregates
+func (db *DB) runBatchLookupQuery(ctx context.Context, isPrivateIP bool, query string, batch ipBatch, aggregates [][]*assetAggregate) error {
+	if len(batch.ips) == 0 { // nothing to resolve, spare the round trip
+		return nil
+	}
+	rows, err := db.sqldb.QueryContext(ctx, query, pq.Array(batch.ips), pq.Array(batch.times),
+		pq.Array(batch.accounts), pq.Array(batch.vpcs))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var ord int
+		var asset domain.CloudAssetDetails
+		var ipAddress string
+		var hostname sql.NullString
+		var metaBytes []byte
+		var owner domain.Person
+		var ownerAccountID *string
+		var champion domain.Person
+		if err = rows.Scan(&ord, &ipAddress, &hostname, &asset.ARN, &metaBytes, &asset.Region, &asset.ResourceType,
+			&asset.AccountID, &ownerAccountID, &owner.Login, &owner.Email, &owner.Name, &owner.Valid,
+			&champion.Login, &champion.Email, &champion.Name, &champion.Valid); err != nil {
+			return err
+		}
+		if ord < 1 || ord > len(batch.indexes) {
+			return errors.New("lookup position out of range")
+		}
+		index := batch.indexes[ord-1]
+
+		var agg *assetAggregate
+		for _, candidate := range aggregates[index] {
+			if candidate.asset.ARN == asset.ARN {
+				agg = candidate
+				break
+			}
+		}
+		if agg == nil {
+			if metaBytes != nil {
+				var i map[string]string
+				_ = json.Unmarshal(metaBytes, &i) // we already checked for nil, and the DB column is JSONB; no need for err check here
+				asset.Tags = i
+			}
+			asset.AccountOwner = domain.AccountOwner{
+				AccountID: ownerAccountID,
+				Owner:     owner,
+				Champions: make([]domain.Person, 0),
+			}
+			agg = newAssetAggregate(asset)
+			aggregates[index] = append(aggregates[index], agg)
+		}
+		if isPrivateIP {
+			agg.addPrivateIP(ipAddress)
+		} else {
+			agg.addPublicIP(ipAddress)
+			if hostname.Valid {
+				agg.addHostname(hostname.String)
+			}
+		}
+		if champion.Login != nil {
+			agg.addChampion(champion)
+		}
+	}
+	rows.Close()
+	return rows.Err()
+}
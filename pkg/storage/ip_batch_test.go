@@ -0,0 +1,255 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/asecurityteam/asset-inventory-api/pkg/domain"
+)
+
+var ipBatchColumns = []string{"ord",
+	"ip",
+	"aws_hostname",
+	"arn_id",
+	"meta",
+	"region",
+	"resource_type",
+	"account",
+	"t_account",
+	"t_login",
+	"t_email",
+	"t_name",
+	"t_valid",
+	"p_login",
+	"p_email",
+	"p_name",
+	"p_valid",
+}
+
+func TestFetchByIPsInvalidIP(t *testing.T) {
+	thedb := DB{}
+	_, err := thedb.FetchByIPs(context.Background(), []domain.IPLookup{{IPAddress: "nope", When: time.Now()}})
+	assert.Error(t, err)
+}
+
+func TestFetchByIPsQueryError(t *testing.T) {
+	mockdb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockdb.Close()
+
+	thedb := DB{
+		sqldb: mockdb,
+	}
+
+	at, _ := time.Parse(time.RFC3339, "2019-04-09T08:55:35+00:00")
+	mock.ExpectQuery("join aws_private_ip_assignment").
+		WithArgs(pq.Array([]string{"10.0.0.1"}), pq.Array([]time.Time{at}), pq.Array([]string{""}), pq.Array([]string{""})).
+		WillReturnError(errors.New("no bueno"))
+
+	_, err = thedb.FetchByIPs(context.Background(), []domain.IPLookup{{IPAddress: "10.0.0.1", When: at}})
+	assert.Error(t, err)
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestFetchByIPsOrdinalOutOfRange(t *testing.T) {
+	mockdb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockdb.Close()
+
+	thedb := DB{
+		sqldb: mockdb,
+	}
+
+	at, _ := time.Parse(time.RFC3339, "2019-04-09T08:55:35+00:00")
+	rows := sqlmock.NewRows(ipBatchColumns).
+		AddRow(2, "9.8.7.6", "yahoo.com", "rid", nil, "region", "type", "aid", nil, nil, nil, nil, nil, nil, nil, nil, nil)
+	mock.ExpectQuery("join aws_public_ip_assignment").
+		WithArgs(pq.Array([]string{"9.8.7.6"}), pq.Array([]time.Time{at}), pq.Array([]string{""}), pq.Array([]string{""})).
+		WillReturnRows(rows).RowsWillBeClosed()
+
+	_, err = thedb.FetchByIPs(context.Background(), []domain.IPLookup{{IPAddress: "9.8.7.6", When: at}})
+	assert.Error(t, err)
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestFetchByIPs(t *testing.T) {
+	mockdb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockdb.Close()
+
+	thedb := DB{
+		sqldb: mockdb,
+	}
+
+	at, _ := time.Parse(time.RFC3339, "2019-04-09T08:55:35+00:00")
+	later := at.Add(time.Hour)
+	lookups := []domain.IPLookup{
+		{IPAddress: "9.8.7.6", When: at},
+		{IPAddress: "10.0.0.1", When: at},
+		{IPAddress: "10.0.0.2", When: later},
+		{IPAddress: "10.0.0.1", When: later},
+	}
+	privateRows := sqlmock.NewRows(ipBatchColumns).
+		AddRow(1, "10.0.0.1", nil, "rid1", []byte("{\"hi\":\"there\"}"), "region", "type", "aid",
+			"aid", "login", "email@atlassian.com", "name", true, "login2", "email2@atlassian.com", "name2", true).
+		AddRow(1, "10.0.0.1", nil, "rid1", []byte("{\"hi\":\"there\"}"), "region", "type", "aid",
+			"aid", "login", "email@atlassian.com", "name", true, "login3", "email3@atlassian.com", "name3", true).
+		AddRow(3, "10.0.0.1", nil, "rid2", nil, "region", "type", "aid",
+			nil, nil, nil, nil, nil, nil, nil, nil, nil)
+	mock.ExpectQuery("join aws_private_ip_assignment").
+		WithArgs(pq.Array([]string{"10.0.0.1", "10.0.0.2", "10.0.0.1"}), pq.Array([]time.Time{at, later, later}), pq.Array([]string{"", "", ""}), pq.Array([]string{"", "", ""})).
+		WillReturnRows(privateRows).RowsWillBeClosed()
+	publicRows := sqlmock.NewRows(ipBatchColumns).
+		AddRow(1, "9.8.7.6", "yahoo.com", "rid3", nil, "region", "type", "aid",
+			nil, nil, nil, nil, nil, nil, nil, nil, nil).
+		AddRow(1, "9.8.7.6", "google.com", "rid3", nil, "region", "type", "aid",
+			nil, nil, nil, nil, nil, nil, nil, nil, nil)
+	mock.ExpectQuery("join aws_public_ip_assignment").
+		WithArgs(pq.Array([]string{"9.8.7.6"}), pq.Array([]time.Time{at}), pq.Array([]string{""}), pq.Array([]string{""})).
+		WillReturnRows(publicRows).RowsWillBeClosed()
+	expectNoAttachments(mock)
+
+	results, err := thedb.FetchByIPs(context.Background(), lookups)
+	assert.NoError(t, err)
+	assert.Equal(t, [][]domain.CloudAssetDetails{
+		{
+			{
+				PublicIPAddresses: []string{"9.8.7.6"},
+				Hostnames:         []string{"yahoo.com", "google.com"},
+				ResourceType:      "type",
+				AccountID:         "aid",
+				Region:            "region",
+				ARN:               "rid3",
+				AccountOwner:      domain.AccountOwner{Champions: []domain.Person{}},
+			},
+		},
+		{
+			{
+				PrivateIPAddresses: []string{"10.0.0.1"},
+				ResourceType:       "type",
+				AccountID:          "aid",
+				Region:             "region",
+				ARN:                "rid1",
+				Tags:               map[string]string{"hi": "there"},
+				AccountOwner: domain.AccountOwner{
+					AccountID: toStringPointer("aid"),
+					Owner: domain.Person{
+						Login: toStringPointer("login"),
+						Email: toStringPointer("email@atlassian.com"),
+						Name:  toStringPointer("name"),
+						Valid: toBoolPointer(true),
+					},
+					Champions: []domain.Person{
+						{
+							Login: toStringPointer("login2"),
+							Email: toStringPointer("email2@atlassian.com"),
+							Name:  toStringPointer("name2"),
+							Valid: toBoolPointer(true),
+						},
+						{
+							Login: toStringPointer("login3"),
+							Email: toStringPointer("email3@atlassian.com"),
+							Name:  toStringPointer("name3"),
+							Valid: toBoolPointer(true),
+						},
+					},
+				},
+			},
+		},
+		{},
+		{
+			{
+				PrivateIPAddresses: []string{"10.0.0.1"},
+				ResourceType:       "type",
+				AccountID:          "aid",
+				Region:             "region",
+				ARN:                "rid2",
+				AccountOwner:       domain.AccountOwner{Champions: []domain.Person{}},
+			},
+		},
+	}, results)
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestFetchByIPsUnifiedLookup(t *testing.T) {
+	mockdb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockdb.Close()
+
+	thedb := DB{
+		sqldb:           mockdb,
+		unifiedIPLookup: true,
+	}
+
+	at, _ := time.Parse(time.RFC3339, "2019-04-09T08:55:35+00:00")
+	privateRows := sqlmock.NewRows(ipBatchColumns).
+		AddRow(2, "198.19.0.1", nil, "rid1", nil, "region", "type", "aid", nil, nil, nil, nil, nil, nil, nil, nil, nil)
+	mock.ExpectQuery("join aws_private_ip_assignment").
+		WithArgs(pq.Array([]string{"10.0.0.1", "198.19.0.1"}), pq.Array([]time.Time{at, at}), pq.Array([]string{"", ""}), pq.Array([]string{"", ""})).
+		WillReturnRows(privateRows).RowsWillBeClosed()
+	mock.ExpectQuery("join aws_public_ip_assignment").
+		WithArgs(pq.Array([]string{"198.19.0.1"}), pq.Array([]time.Time{at}), pq.Array([]string{""}), pq.Array([]string{""})).
+		WillReturnRows(sqlmock.NewRows(ipBatchColumns)).RowsWillBeClosed()
+	expectNoAttachments(mock)
+
+	results, err := thedb.FetchByIPs(context.Background(), []domain.IPLookup{
+		{IPAddress: "10.0.0.1", When: at},
+		{IPAddress: "198.19.0.1", When: at},
+	})
+	assert.NoError(t, err)
+	assert.Len(t, results, 2)
+	assert.Empty(t, results[0])
+	assert.Len(t, results[1], 1)
+	assert.Equal(t, []string{"198.19.0.1"}, results[1][0].PrivateIPAddresses)
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestFetchByIPsScoped(t *testing.T) {
+	mockdb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockdb.Close()
+
+	thedb := DB{
+		sqldb: mockdb,
+	}
+
+	at, _ := time.Parse(time.RFC3339, "2019-04-09T08:55:35+00:00")
+	mock.ExpectQuery("join aws_private_ip_assignment").
+		WithArgs(pq.Array([]string{"10.0.0.1", "10.0.0.1"}), pq.Array([]time.Time{at, at}),
+			pq.Array([]string{"123456789012", ""}), pq.Array([]string{"vpc-1234", ""})).
+		WillReturnRows(sqlmock.NewRows(ipBatchColumns)).RowsWillBeClosed()
+
+	results, err := thedb.FetchByIPs(context.Background(), []domain.IPLookup{
+		{IPAddress: "10.0.0.1", When: at, Scope: domain.IPScope{AccountID: "123456789012", VPCID: "vpc-1234"}},
+		{IPAddress: "10.0.0.1", When: at},
+	})
+	assert.NoError(t, err)
+	assert.Len(t, results, 2)
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
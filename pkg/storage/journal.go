@@ -0,0 +1,355 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+
+	"github.com/asecurityteam/asset-inventory-api/pkg/domain"
+)
+
+// Query to list the resource IDs with changes in the journal, optionally narrowed to a resource, an account and a region
+const journaledResourcesQuery = `
+select distinct arn_id
+from aws_change_journal
+where ($1::varchar = '' or arn_id = $1)
+  and ($2::varchar = '' or account = $2)
+  and ($3::varchar = '' or region = $3)
+order by arn_id`
+
+// Query to list the changes of the resources with a resource ID in the journal, in every account and region, in the
+// order they happened. Ties are broken by arrival.
+const journalByARNIDQuery = `
+select id, account, region, changes
+from aws_change_journal
+where arn_id = $1
+order by change_time, id`
+
+// Query to list the terminations of resources in the journal from a point in time on, in the order they happened
+const journalTerminationsQuery = `
+select id, arn_id, changes
+from aws_change_journal
+where arn_id = any ($1::varchar[])
+  and upper(changes ->> 'Lifecycle') = 'TERMINATED'
+  and change_time >= $2
+order by change_time, id`
+
+// journalEntry is a change in the journal, along with the resource it was recorded for
+type journalEntry struct {
+	id        int64
+	arnID     string
+	accountID string
+	region    string
+	changes   domain.CloudAssetChanges
+}
+
+// journalChanges appends the changes to the journal, as part of the transaction storing them
+func (db *DB) journalChanges(ctx context.Context, cloudAssetChanges domain.CloudAssetChanges, tx *sql.Tx) error {
+	const journalInsertQuery = `
+insert into aws_change_journal
+    (change_time, arn_id, account, region, changes)
+values ($1, $2, $3, $4, $5::jsonb)`
+
+	changesBytes, err := json.Marshal(cloudAssetChanges)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, journalInsertQuery,
+		cloudAssetChanges.ChangeTime,
+		domain.ResourceIDFromARN(cloudAssetChanges.ARN),
+		cloudAssetChanges.AccountID,
+		cloudAssetChanges.Region,
+		changesBytes)
+	return err
+}
+
+// Replay rebuilds the resources in scope from the change journal, one transaction per resource ID.
+// Only resources with changes in the journal are rebuilt, and only from their first journaled change on, so the
+// history stored before the journal existed is kept.
+func (db *DB) Replay(ctx context.Context, scope domain.ReplayScope) (domain.ReplayResult, error) {
+	result := domain.ReplayResult{}
+	arnIDs, err := db.journaledResources(ctx, scope)
+	if err != nil {
+		return result, err
+	}
+	for _, arnID := range arnIDs {
+		resources, events, err := db.replayResource(ctx, arnID, scope)
+		if err != nil {
+			return result, errors.Wrapf(err, "failed to replay resource %s", arnID)
+		}
+		result.Resources += resources
+		result.Events += events
+	}
+	return result, nil
+}
+
+func (db *DB) journaledResources(ctx context.Context, scope domain.ReplayScope) ([]string, error) {
+	rows, err := db.sqldb.QueryContext(ctx, journaledResourcesQuery, scope.ResourceID, scope.AccountID, scope.Region)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	arnIDs := make([]string, 0)
+	for rows.Next() {
+		var arnID string
+		if err = rows.Scan(&arnID); err != nil {
+			return nil, err
+		}
+		arnIDs = append(arnIDs, arnID)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return arnIDs, nil
+}
+
+// replayResource rebuilds the resources with the resource ID in the account and region of the scope, or in every
+// account and region when empty, returning the number of resources rebuilt and of changes applied to them
+func (db *DB) replayResource(ctx context.Context, arnID string, scope domain.ReplayScope) (int, int, error) {
+	tx, err := db.sqldb.Begin()
+	if err != nil {
+		return 0, 0, err
+	}
+	resources, events, err := db.rebuildResource(ctx, tx, arnID, scope)
+	if err != nil {
+		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+			return 0, 0, errors.Wrap(rollbackErr, err.Error()) // so we don't lose the original error
+		}
+		return 0, 0, err
+	}
+	if err = tx.Commit(); err != nil {
+		return 0, 0, err
+	}
+	return resources, events, nil
+}
+
+// rebuildResource clears the intervals recorded by the resources with the resource ID in scope since their first
+// journaled change and applies their journaled changes again. Intervals started earlier were stored before the journal
+// existed and are kept, reopened when a journaled change closed them so that replaying it closes them again.
+// Relationships and attachments are kept by resource ID alone, so they are rebuilt from the changes of every resource
+// sharing it, along with the terminations of the resources they point to, which closed them. Those recorded by other
+// resources towards this one are left alone, they are rebuilt with those resources.
+func (db *DB) rebuildResource(ctx context.Context, tx *sql.Tx, arnID string, scope domain.ReplayScope) (int, int, error) {
+	const resetResourceQuery = `
+update aws_resource
+set not_before = case when not_before >= $2 then null else not_before end,
+    not_after  = case when not_after >= $2 then null else not_after end
+where id = $1`
+
+	entries, err := db.journaledChanges(ctx, tx, arnID)
+	if err != nil {
+		return 0, 0, err
+	}
+	inScope := func(entry journalEntry) bool {
+		return entry.arnID == arnID && (scope.AccountID == "" || entry.accountID == scope.AccountID) &&
+			(scope.Region == "" || entry.region == scope.Region)
+	}
+	if len(entries) == 0 {
+		return 0, 0, nil
+	}
+	// entries are in the order they happened, links are rebuilt from the first change of any account on
+	linksSince := entries[0].changes.ChangeTime
+	terminations, err := db.journaledTerminations(ctx, tx, linkedResources(arnID, entries), linksSince)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	rebuilt := make(map[[2]string]struct{})
+	for _, entry := range entries {
+		key := [2]string{entry.accountID, entry.region}
+		if _, ok := rebuilt[key]; ok || !inScope(entry) {
+			continue
+		}
+		resourceID, err := db.getResourceID(ctx, tx, arnID, entry.region, entry.accountID)
+		if err != nil {
+			return 0, 0, err
+		}
+		since := entry.changes.ChangeTime
+		for _, table := range []string{"aws_private_ip_assignment", "aws_public_ip_assignment", "aws_private_hostname_assignment", "aws_resource_tags"} {
+			if err = db.clearSince(ctx, tx, table, "aws_resource_id", resourceID, since); err != nil {
+				return 0, 0, err
+			}
+		}
+		if _, err = tx.ExecContext(ctx, resetResourceQuery, resourceID, since); err != nil {
+			return 0, 0, err
+		}
+		rebuilt[key] = struct{}{}
+	}
+	for _, table := range []string{"aws_resource_relationship", "aws_resource_attachment"} {
+		if err = db.clearSince(ctx, tx, table, "arn_id", arnID, linksSince); err != nil {
+			return 0, 0, err
+		}
+	}
+
+	entries = append(entries, terminations...)
+	sort.SliceStable(entries, func(i, j int) bool {
+		if !entries[i].changes.ChangeTime.Equal(entries[j].changes.ChangeTime) {
+			return entries[i].changes.ChangeTime.Before(entries[j].changes.ChangeTime)
+		}
+		return entries[i].id < entries[j].id
+	})
+	events := 0
+	for _, entry := range entries {
+		switch {
+		case entry.arnID != arnID:
+			err = db.releaseLinksTo(ctx, tx, arnID, entry.arnID, entry.changes.ChangeTime)
+		case inScope(entry):
+			err = db.applyChanges(ctx, entry.changes, tx)
+			events++
+		default:
+			err = db.applyLinks(ctx, tx, arnID, entry.changes)
+		}
+		if err != nil {
+			return 0, 0, err
+		}
+	}
+	return len(rebuilt), events, nil
+}
+
+// clearSince deletes the intervals of the table started since the time, and reopens the earlier ones closed since
+// then. Placeholders of releases without an assignment start at the epoch, the ones recorded since then go as well.
+func (db *DB) clearSince(ctx context.Context, tx *sql.Tx, table string, column string, id interface{}, since time.Time) error {
+	const clearSinceQuery = `
+delete from %[1]s
+where %[2]s = $1
+  and (not_before >= $2 or (not_before = to_timestamp(0) and not_after >= $2))`
+
+	const reopenSinceQuery = `
+update %[1]s
+set not_after = null
+where %[2]s = $1
+  and not_after >= $2`
+
+	for _, query := range []string{clearSinceQuery, reopenSinceQuery} {
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf(query, table, column), id, since); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// applyLinks applies the relationship and attachment changes only, for resources sharing the resource ID that are
+// out of the scope of the replay
+func (db *DB) applyLinks(ctx context.Context, tx *sql.Tx, arnID string, cloudAssetChanges domain.CloudAssetChanges) error {
+	for _, val := range cloudAssetChanges.Changes {
+		if err := db.applyLinkChanges(ctx, tx, arnID, val, cloudAssetChanges.ChangeTime); err != nil {
+			return err
+		}
+	}
+	if strings.EqualFold(domain.LifecycleTerminated, cloudAssetChanges.Lifecycle) {
+		return db.releaseAllLinks(ctx, tx, arnID, cloudAssetChanges.ChangeTime)
+	}
+	return nil
+}
+
+// releaseLinksTo closes the relationships and attachments the resource recorded towards a resource terminated at
+// the time, as the termination did when it was first stored
+func (db *DB) releaseLinksTo(ctx context.Context, tx *sql.Tx, arnID string, terminated string, when time.Time) error {
+	const releaseRelationshipsToQuery = `
+update aws_resource_relationship
+set not_after = $1
+where arn_id = $2
+  and related_arn_id = $3
+  and not_after is null
+  and (not_before is null or not_before <= $1)`
+
+	const releaseAttachmentsToQuery = `
+update aws_resource_attachment
+set not_after = $1
+where arn_id = $2
+  and attached_to_arn_id = $3
+  and not_after is null
+  and not_before <= $1`
+
+	for _, query := range []string{releaseRelationshipsToQuery, releaseAttachmentsToQuery} {
+		if _, err := tx.ExecContext(ctx, query, when, arnID, terminated); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// linkedResources lists the resources the changes relate or attach the resource to, other than itself
+func linkedResources(arnID string, entries []journalEntry) []string {
+	linked := make(map[string]struct{})
+	for _, entry := range entries {
+		for _, val := range entry.changes.Changes {
+			for _, res := range append(append([]string{}, val.RelatedResources...), val.AttachedTo...) {
+				if res != arnID {
+					linked[res] = struct{}{}
+				}
+			}
+		}
+	}
+	resources := make([]string, 0, len(linked))
+	for res := range linked {
+		resources = append(resources, res)
+	}
+	sort.Strings(resources)
+	return resources
+}
+
+// journaledChanges reads all of the changes of the resource ID upfront, as the transaction can not run other
+// statements while rows are still being read
+func (db *DB) journaledChanges(ctx context.Context, tx *sql.Tx, arnID string) ([]journalEntry, error) {
+	rows, err := tx.QueryContext(ctx, journalByARNIDQuery, arnID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	entries := make([]journalEntry, 0)
+	for rows.Next() {
+		entry := journalEntry{arnID: arnID}
+		var changesBytes []byte
+		if err = rows.Scan(&entry.id, &entry.accountID, &entry.region, &changesBytes); err != nil {
+			return nil, err
+		}
+		if err = json.Unmarshal(changesBytes, &entry.changes); err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// journaledTerminations reads the terminations of the resources since the time upfront, for the same reason as
+// journaledChanges
+func (db *DB) journaledTerminations(ctx context.Context, tx *sql.Tx, arnIDs []string, since time.Time) ([]journalEntry, error) {
+	entries := make([]journalEntry, 0)
+	if len(arnIDs) == 0 { // nothing to look for, spare the round trip
+		return entries, nil
+	}
+	rows, err := tx.QueryContext(ctx, journalTerminationsQuery, pq.Array(arnIDs), since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var entry journalEntry
+		var changesBytes []byte
+		if err = rows.Scan(&entry.id, &entry.arnID, &changesBytes); err != nil {
+			return nil, err
+		}
+		if err = json.Unmarshal(changesBytes, &entry.changes); err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
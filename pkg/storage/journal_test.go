@@ -0,0 +1,274 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/asecurityteam/asset-inventory-api/pkg/domain"
+)
+
+var journalColumns = []string{"id", "account", "region", "changes"}
+
+func expectResourceCleared(mock sqlmock.Sqlmock, resourceID int, since time.Time) {
+	for _, table := range []string{"aws_private_ip_assignment", "aws_public_ip_assignment", "aws_private_hostname_assignment", "aws_resource_tags"} {
+		mock.ExpectExec("delete from "+table).WithArgs(resourceID, since).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("update "+table).WithArgs(resourceID, since).WillReturnResult(sqlmock.NewResult(0, 1))
+	}
+	mock.ExpectExec(`update aws_resource\s+set not_before = case`).WithArgs(resourceID, since).WillReturnResult(sqlmock.NewResult(0, 1))
+}
+
+func expectLinksCleared(mock sqlmock.Sqlmock, arnID string, since time.Time) {
+	for _, table := range []string{"aws_resource_relationship", "aws_resource_attachment"} {
+		mock.ExpectExec("delete from "+table).WithArgs(arnID, since).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("update "+table).WithArgs(arnID, since).WillReturnResult(sqlmock.NewResult(0, 1))
+	}
+}
+
+func TestReplayResource(t *testing.T) {
+	mockdb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockdb.Close()
+
+	thedb := DB{
+		sqldb: mockdb,
+	}
+
+	// journaled times come back in UTC
+	timestamp, _ := time.Parse(time.RFC3339, "2019-04-09T08:29:35Z")
+	changes := domain.CloudAssetChanges{
+		ChangeTime:   timestamp,
+		ResourceType: "rtype",
+		AccountID:    "aid",
+		Region:       "region",
+		ARN:          "arn",
+		Changes: []domain.NetworkChanges{
+			{
+				PrivateIPAddresses: []string{"4.3.2.1"},
+				ChangeType:         "ADDED",
+			},
+		},
+	}
+	changesBytes, _ := json.Marshal(changes)
+
+	mock.ExpectQuery("from aws_change_journal").WithArgs("arn", "", "").WillReturnRows(sqlmock.NewRows([]string{"arn_id"}).AddRow("arn"))
+	mock.ExpectBegin()
+	mock.ExpectQuery("from aws_change_journal").WithArgs("arn").
+		WillReturnRows(sqlmock.NewRows(journalColumns).AddRow(1, "aid", "region", changesBytes))
+	mock.ExpectQuery("SELECT").WithArgs("arn", "aid", "region").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	expectResourceCleared(mock, 1, timestamp)
+	expectLinksCleared(mock, "arn", timestamp)
+	mock.ExpectQuery("SELECT").WithArgs("arn", "aid", "region").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectExec(regexp.QuoteMeta(`update aws_private_ip_assignment`)).WithArgs(timestamp, "4.3.2.1", 1, "", "").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta(`insert into aws_private_ip_assignment`)).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	result, err := thedb.Replay(context.Background(), domain.ReplayScope{ResourceID: "arn"})
+	assert.NoError(t, err)
+	assert.Equal(t, domain.ReplayResult{Resources: 1, Events: 1}, result)
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestReplayNothingJournaled(t *testing.T) {
+	mockdb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockdb.Close()
+
+	thedb := DB{
+		sqldb: mockdb,
+	}
+
+	mock.ExpectQuery("from aws_change_journal").WithArgs("", "aid", "").WillReturnRows(sqlmock.NewRows([]string{"arn_id"}))
+
+	result, err := thedb.Replay(context.Background(), domain.ReplayScope{AccountID: "aid"})
+	assert.NoError(t, err)
+	assert.Equal(t, domain.ReplayResult{}, result)
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestReplayRollsBackOnError(t *testing.T) {
+	mockdb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockdb.Close()
+
+	thedb := DB{
+		sqldb: mockdb,
+	}
+
+	mock.ExpectQuery("from aws_change_journal").WithArgs("", "", "").WillReturnRows(sqlmock.NewRows([]string{"arn_id"}).AddRow("arn1").AddRow("arn2"))
+	mock.ExpectBegin()
+	mock.ExpectQuery("from aws_change_journal").WithArgs("arn1").
+		WillReturnRows(sqlmock.NewRows(journalColumns).AddRow(1, "aid", "region", []byte("{}")))
+	mock.ExpectQuery("SELECT").WithArgs("arn1", "aid", "region").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectExec("delete from aws_private_ip_assignment").WithArgs(1, time.Time{}).WillReturnError(errors.New("no bueno"))
+	mock.ExpectRollback()
+
+	result, err := thedb.Replay(context.Background(), domain.ReplayScope{})
+	assert.Error(t, err)
+	assert.Equal(t, domain.ReplayResult{}, result)
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestStoreJournalError(t *testing.T) {
+	mockdb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockdb.Close()
+
+	thedb := DB{
+		sqldb: mockdb,
+	}
+
+	changes := fakeCloudAssetChanges()
+	changes.Changes = nil
+	changes.Tags = nil
+	mock.ExpectBegin()
+	mock.ExpectExec("with sel as").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery("SELECT").WithArgs("arn", "aid", "region").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectExec(regexp.QuoteMeta(`insert into aws_change_journal`)).WithArgs(changes.ChangeTime, "arn", "aid", "region", sqlmock.AnyArg()).WillReturnError(errors.New("no bueno"))
+	mock.ExpectRollback()
+
+	err = thedb.Store(context.Background(), changes)
+	assert.Error(t, err)
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestReplayAccountSharingResourceID(t *testing.T) {
+	mockdb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockdb.Close()
+
+	thedb := DB{
+		sqldb: mockdb,
+	}
+
+	timestamp, _ := time.Parse(time.RFC3339, "2019-04-09T08:29:35Z")
+	// the same function name in two accounts, only the first one is in scope
+	inScope := domain.CloudAssetChanges{
+		ChangeTime: timestamp,
+		AccountID:  "aid",
+		Region:     "region",
+		ARN:        "arn:aws:lambda:region:aid:function:fn",
+		Changes: []domain.NetworkChanges{
+			{PrivateIPAddresses: []string{"10.0.0.1"}, ChangeType: "ADDED"},
+		},
+	}
+	other := domain.CloudAssetChanges{
+		ChangeTime: timestamp.Add(time.Minute),
+		AccountID:  "other",
+		Region:     "region",
+		ARN:        "arn:aws:lambda:region:other:function:fn",
+		Changes: []domain.NetworkChanges{
+			{PrivateIPAddresses: []string{"10.0.0.2"}, RelatedResources: []string{"eni-2"}, ChangeType: "ADDED"},
+		},
+	}
+	inScopeBytes, _ := json.Marshal(inScope)
+	otherBytes, _ := json.Marshal(other)
+
+	mock.ExpectQuery("from aws_change_journal").WithArgs("", "aid", "").WillReturnRows(sqlmock.NewRows([]string{"arn_id"}).AddRow("fn"))
+	mock.ExpectBegin()
+	mock.ExpectQuery("from aws_change_journal").WithArgs("fn").
+		WillReturnRows(sqlmock.NewRows(journalColumns).AddRow(1, "aid", "region", inScopeBytes).AddRow(2, "other", "region", otherBytes))
+	mock.ExpectQuery("from aws_change_journal").WithArgs(pq.Array([]string{"eni-2"}), timestamp).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "arn_id", "changes"}))
+	// only the resource of the account in scope is cleared
+	mock.ExpectQuery("SELECT").WithArgs("fn", "aid", "region").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	expectResourceCleared(mock, 1, timestamp)
+	expectLinksCleared(mock, "fn", timestamp)
+	mock.ExpectQuery("SELECT").WithArgs("fn", "aid", "region").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectExec(regexp.QuoteMeta(`update aws_private_ip_assignment`)).WithArgs(timestamp, "10.0.0.1", 1, "", "").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta(`insert into aws_private_ip_assignment`)).WillReturnResult(sqlmock.NewResult(1, 1))
+	// the relationships shared by resource ID are rebuilt from the other account, its addresses are left alone
+	mock.ExpectExec(regexp.QuoteMeta(`update aws_resource_relationship`)).WithArgs(other.ChangeTime, "eni-2", "fn").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`(?s)insert into aws_resource_relationship.+held.not_after is null`).WithArgs(other.ChangeTime, "eni-2", "fn").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	result, err := thedb.Replay(context.Background(), domain.ReplayScope{AccountID: "aid"})
+	assert.NoError(t, err)
+	assert.Equal(t, domain.ReplayResult{Resources: 1, Events: 1}, result)
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestReplayTerminatedCounterpart(t *testing.T) {
+	mockdb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockdb.Close()
+
+	thedb := DB{
+		sqldb: mockdb,
+	}
+
+	timestamp, _ := time.Parse(time.RFC3339, "2019-04-09T08:29:35Z")
+	related := domain.CloudAssetChanges{
+		ChangeTime: timestamp,
+		AccountID:  "aid",
+		Region:     "region",
+		ARN:        "arn:aws:ec2:region:aid:network-interface/eni-1",
+		Changes: []domain.NetworkChanges{
+			{RelatedResources: []string{"i-1"}, AttachedTo: []string{"i-1"}, ChangeType: "ADDED"},
+		},
+	}
+	terminated := domain.CloudAssetChanges{
+		ChangeTime: timestamp.Add(time.Hour),
+		AccountID:  "aid",
+		Region:     "region",
+		ARN:        "arn:aws:ec2:region:aid:instance/i-1",
+		Lifecycle:  domain.LifecycleTerminated,
+	}
+	relatedBytes, _ := json.Marshal(related)
+	terminatedBytes, _ := json.Marshal(terminated)
+
+	mock.ExpectQuery("from aws_change_journal").WithArgs("eni-1", "", "").WillReturnRows(sqlmock.NewRows([]string{"arn_id"}).AddRow("eni-1"))
+	mock.ExpectBegin()
+	mock.ExpectQuery("from aws_change_journal").WithArgs("eni-1").
+		WillReturnRows(sqlmock.NewRows(journalColumns).AddRow(1, "aid", "region", relatedBytes))
+	mock.ExpectQuery("from aws_change_journal").WithArgs(pq.Array([]string{"i-1"}), timestamp).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "arn_id", "changes"}).AddRow(5, "i-1", terminatedBytes))
+	mock.ExpectQuery("SELECT").WithArgs("eni-1", "aid", "region").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	expectResourceCleared(mock, 1, timestamp)
+	expectLinksCleared(mock, "eni-1", timestamp)
+	mock.ExpectQuery("SELECT").WithArgs("eni-1", "aid", "region").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectExec(regexp.QuoteMeta(`update aws_resource_relationship`)).WithArgs(timestamp, "i-1", "eni-1").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta(`insert into aws_resource_relationship`)).WithArgs(timestamp, "i-1", "eni-1").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`update aws_resource_attachment`)).WithArgs(timestamp, "i-1", "eni-1").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta(`insert into aws_resource_attachment`)).WithArgs(timestamp, "i-1", "eni-1").WillReturnResult(sqlmock.NewResult(1, 1))
+	// the termination of the instance closes what the network interface recorded towards it, as it did when stored
+	mock.ExpectExec(regexp.QuoteMeta(`update aws_resource_relationship`)).WithArgs(terminated.ChangeTime, "eni-1", "i-1").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`update aws_resource_attachment`)).WithArgs(terminated.ChangeTime, "eni-1", "i-1").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	result, err := thedb.Replay(context.Background(), domain.ReplayScope{ResourceID: "eni-1"})
+	assert.NoError(t, err)
+	assert.Equal(t, domain.ReplayResult{Resources: 1, Events: 1}, result)
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
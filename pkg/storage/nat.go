@@ -0,0 +1,94 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"github.com/asecurityteam/asset-inventory-api/pkg/domain"
+)
+
+// Query to list a page of resources holding a private IP address, at the point in time, in a subnet served by the NAT
+// gateways holding the public IP address. A NAT gateway serves the subnets associated with the route tables routing
+// through it, and subnets related to it directly. A main route table is related to its VPC, and serves the subnets of
+// the VPC without a route table association of their own. Addresses recorded without a subnet fall back to their VPC,
+// when it is the VPC of the NAT gateway or of a main route table routing through it. Only the relationships between
+// NAT gateways, route tables, subnets and VPCs are considered, those recorded without a start are held from the start.
+const resourceIDsBehindNATGatewayQuery = `
+with nat as (
+    select res.id, res.arn_id
+    from aws_public_ip_assignment puia
+             join aws_resource res on res.id = puia.aws_resource_id
+             join aws_resource_type rt on rt.id = res.aws_resource_type_id
+    where puia.public_ip = $1::inet
+      and puia.not_before < $2
+      and (puia.not_after is null or puia.not_after > $2)
+      and rt.resource_type = 'AWS::EC2::NatGateway'),
+     rel as (
+         select r.arn_id, r.related_arn_id
+         from aws_resource_relationship r
+         where (r.arn_id like 'nat-%' or r.arn_id like 'rtb-%')
+           and (r.related_arn_id like 'nat-%' or r.related_arn_id like 'rtb-%'
+             or r.related_arn_id like 'subnet-%' or r.related_arn_id like 'vpc-%')
+           and (r.not_before is null or r.not_before < $2)
+           and (r.not_after is null or r.not_after > $2)),
+     rtb as (
+         select rel.arn_id as rtb_id
+         from rel
+                  join nat on rel.related_arn_id = nat.arn_id
+         where rel.arn_id like 'rtb-%'
+         union
+         select rel.related_arn_id
+         from rel
+                  join nat on rel.arn_id = nat.arn_id
+         where rel.related_arn_id like 'rtb-%'),
+     associated as (
+         select rel.arn_id as rtb_id, rel.related_arn_id as subnet_id
+         from rel
+         where rel.arn_id like 'rtb-%'
+           and rel.related_arn_id like 'subnet-%'),
+     served as (
+         select associated.subnet_id
+         from associated
+                  join rtb using (rtb_id)
+         union
+         select rel.related_arn_id
+         from rel
+                  join nat on rel.arn_id = nat.arn_id
+         where rel.related_arn_id like 'subnet-%'),
+     main as (
+         select rel.related_arn_id as vpc_id
+         from rel
+                  join rtb on rel.arn_id = rtb.rtb_id
+         where rel.related_arn_id like 'vpc-%'),
+     fallback as (
+         select main.vpc_id
+         from main
+         union
+         select pria.vpc_id
+         from aws_private_ip_assignment pria
+                  join nat on nat.id = pria.aws_resource_id
+         where pria.not_before < $2
+           and (pria.not_after is null or pria.not_after > $2)
+           and pria.vpc_id is not null)
+select distinct pria.aws_resource_id
+from aws_private_ip_assignment pria
+where pria.not_before < $2
+  and (pria.not_after is null or pria.not_after > $2)
+  and (pria.subnet_id in (select subnet_id from served)
+    or (pria.vpc_id in (select vpc_id from main)
+        and pria.subnet_id not in (select subnet_id from associated))
+    or (pria.subnet_id is null
+        and pria.vpc_id in (select vpc_id from fallback)))
+  and pria.aws_resource_id > $4
+order by pria.aws_resource_id
+limit $3`
+
+// FetchBehindNATGateway gets a page of the assets holding a private IP address in a subnet served by the NAT gateway
+// with the public IP address at the specified time, starting after the given resource key
+func (db *DB) FetchBehindNATGateway(ctx context.Context, when time.Time, ipAddress string, count uint, after int64) ([]domain.CloudAssetDetails, int64, error) {
+	ip, err := canonicalIP(ipAddress)
+	if err != nil {
+		return nil, 0, err
+	}
+	return db.fetchPage(ctx, when, after, resourceIDsBehindNATGatewayQuery, ip, when, count, after)
+}
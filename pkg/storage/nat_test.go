@@ -0,0 +1,122 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/asecurityteam/asset-inventory-api/pkg/domain"
+)
+
+func TestFetchBehindNATGatewayInvalidIP(t *testing.T) {
+	mockdb, _, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockdb.Close()
+
+	thedb := DB{
+		sqldb: mockdb,
+	}
+
+	_, _, err = thedb.FetchBehindNATGateway(context.Background(), time.Now(), "not an IP", 10, 0)
+	assert.Error(t, err)
+}
+
+func TestFetchBehindNATGatewayIDQueryError(t *testing.T) {
+	mockdb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockdb.Close()
+
+	thedb := DB{
+		sqldb: mockdb,
+	}
+
+	at, _ := time.Parse(time.RFC3339, "2019-04-09T08:55:35+00:00")
+	// only the relationships between NAT gateways, route tables, subnets and VPCs are followed, and addresses without
+	// a subnet fall back to their VPC
+	mock.ExpectQuery(`(?s)with nat as.+r.related_arn_id like 'vpc-%'.+r.not_before is null.+pria.subnet_id is null`).WithArgs("34.0.0.1", at, 10, 0).WillReturnError(errors.New("no bueno"))
+
+	_, _, err = thedb.FetchBehindNATGateway(context.Background(), at, "34.0.0.1", 10, 0)
+	assert.Error(t, err)
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestFetchBehindNATGatewayEmpty(t *testing.T) {
+	mockdb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockdb.Close()
+
+	thedb := DB{
+		sqldb: mockdb,
+	}
+
+	at, _ := time.Parse(time.RFC3339, "2019-04-09T08:55:35+00:00")
+	mock.ExpectQuery(regexp.QuoteMeta("with nat as")).WithArgs("34.0.0.1", at, 10, 7).
+		WillReturnRows(sqlmock.NewRows([]string{"id"})).RowsWillBeClosed()
+
+	results, last, err := thedb.FetchBehindNATGateway(context.Background(), at, "34.0.0.1", 10, 7)
+	assert.NoError(t, err)
+	assert.Empty(t, results)
+	assert.Equal(t, int64(7), last)
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestFetchBehindNATGateway(t *testing.T) {
+	mockdb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockdb.Close()
+
+	thedb := DB{
+		sqldb: mockdb,
+	}
+
+	at, _ := time.Parse(time.RFC3339, "2019-04-09T08:55:35+00:00")
+	// the address is looked up in its canonical form
+	mock.ExpectQuery(regexp.QuoteMeta("with nat as")).WithArgs("34.0.0.1", at, 10, 0).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3).AddRow(5)).RowsWillBeClosed()
+	rows := sqlmock.NewRows(assetDetailsColumns).
+		AddRow(3, "i-3", "AWS::EC2::Instance", "aid", "region", nil, "10.0.1.3", nil, nil,
+			nil, "aid", "login", "email", "name", true, nil, nil, nil, nil).
+		AddRow(5, "i-5", "AWS::EC2::Instance", "aid", "region", nil, "10.0.2.5", nil, nil,
+			nil, "aid", "login", "email", "name", true, nil, nil, nil, nil)
+	mock.ExpectQuery("select res.id,").WithArgs(pq.Array([]int64{3, 5}), at).WillReturnRows(rows).RowsWillBeClosed()
+
+	results, last, err := thedb.FetchBehindNATGateway(context.Background(), at, "::ffff:34.0.0.1", 10, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(5), last)
+	assert.Len(t, results, 2)
+	assert.Equal(t, "i-3", results[0].ARN)
+	assert.Equal(t, []string{"10.0.1.3"}, results[0].PrivateIPAddresses)
+	assert.Equal(t, "login", *results[0].AccountOwner.Owner.Login)
+	assert.Equal(t, "i-5", results[1].ARN)
+	assert.Equal(t, domain.AccountOwner{
+		AccountID: toStringPointer("aid"),
+		Owner: domain.Person{
+			Login: toStringPointer("login"),
+			Email: toStringPointer("email"),
+			Name:  toStringPointer("name"),
+			Valid: toBoolPointer(true),
+		},
+		Champions: []domain.Person{},
+	}, results[1].AccountOwner)
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
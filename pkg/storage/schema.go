@@ -28,8 +28,40 @@ const (
 	ReadsFromNewSchemaVersion uint = 4
 	// NewSchemaOnlyVersion Lowest version that stops dual-writes in preparation to drop old schema
 	NewSchemaOnlyVersion uint = 6
+	// VPCScopeSchemaVersion Lowest version of database schema that stores the VPC of private IP assignments
+	VPCScopeSchemaVersion uint = 17
+	// TagHistorySchemaVersion Lowest version of database schema that tracks the tags of resources over time
+	TagHistorySchemaVersion uint = 18
+	// TagSearchSchemaVersion Lowest version of database schema that indexes tags for the search by tag
+	TagSearchSchemaVersion uint = 19
+	// HostnameSearchSchemaVersion Lowest version of database schema that indexes hostnames for the search by pattern
+	HostnameSearchSchemaVersion uint = 20
+	// ResourceGraphSchemaVersion Lowest version of database schema that indexes relationships for the resource graph
+	ResourceGraphSchemaVersion uint = 21
+	// ResourceLifecycleSchemaVersion Lowest version of database schema that records the existence interval of resources
+	ResourceLifecycleSchemaVersion uint = 22
+	// ChangeJournalSchemaVersion Lowest version of database schema that journals the stored changes for replay
+	ChangeJournalSchemaVersion uint = 23
+	// ProcessedEventSchemaVersion Lowest version of database schema that remembers processed event IDs
+	ProcessedEventSchemaVersion uint = 24
+	// ExplicitAddressSchemaVersion Lowest version of database schema that records public IP addresses without a
+	// hostname and the network interface holding an address
+	ExplicitAddressSchemaVersion uint = 25
+	// PrivateHostnameSchemaVersion Lowest version of database schema that records hostnames of private IP addresses
+	PrivateHostnameSchemaVersion uint = 26
+	// ResourceAttachmentSchemaVersion Lowest version of database schema that records attachments of network
+	// interfaces and elastic IPs
+	ResourceAttachmentSchemaVersion uint = 27
+	// TerminatedTagsSchemaVersion Lowest version of database schema that does not report the tags of a resource past
+	// its termination
+	TerminatedTagsSchemaVersion uint = 28
+	// JournalRegionSchemaVersion Lowest version of database schema that journals the region of the changes
+	JournalRegionSchemaVersion uint = 29
+	// PrivateHostnameSearchSchemaVersion Lowest version of database schema that indexes the hostnames of private IP
+	// addresses for the hostname search
+	PrivateHostnameSearchSchemaVersion uint = 30
 	// MinimumSchemaVersion Lowest version of database schema current code is able to handle
-	MinimumSchemaVersion uint = 13
+	MinimumSchemaVersion = PrivateHostnameSearchSchemaVersion
 )
 
 // SchemaManager is an abstraction layer for manipulating database schema backed by golang/migrate
@@ -0,0 +1,237 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+
+	"github.com/asecurityteam/asset-inventory-api/pkg/domain"
+)
+
+// Queries to find what a resource holds at a point in time. Assignments starting at that very time are included, so
+// that the state right after changes at that time is found. Relationships recorded before their start was tracked
+// have none, and are held from the start. Attachments always have a start.
+const (
+	privateIPStateQuery = `
+select host(pria.private_ip), coalesce(pria.vpc_id, ''), coalesce(pria.subnet_id, ''), coalesce(prha.aws_hostname, '')
+from aws_private_ip_assignment pria
+         join aws_resource res on res.id = pria.aws_resource_id
+         left join aws_private_hostname_assignment prha
+                   on prha.aws_resource_id = pria.aws_resource_id
+                       and prha.private_ip = pria.private_ip
+                       and prha.not_before <= $2
+                       and (prha.not_after is null or prha.not_after > $2)
+where res.arn_id = $1
+  and pria.not_before <= $2
+  and (pria.not_after is null or pria.not_after > $2)
+order by pria.private_ip, prha.aws_hostname`
+
+	publicIPStateQuery = `
+select host(puia.public_ip), coalesce(puia.aws_hostname, '')
+from aws_public_ip_assignment puia
+         join aws_resource res on res.id = puia.aws_resource_id
+where res.arn_id = $1
+  and puia.not_before <= $2
+  and (puia.not_after is null or puia.not_after > $2)
+order by puia.public_ip, puia.aws_hostname`
+
+	relationshipStateQuery = `
+select rel.related_arn_id
+from aws_resource_relationship rel
+where rel.arn_id = $1
+  and (rel.not_before is null or rel.not_before <= $2)
+  and (rel.not_after is null or rel.not_after > $2)
+order by rel.related_arn_id`
+
+	attachmentStateQuery = `
+select att.attached_to_arn_id
+from aws_resource_attachment att
+where att.arn_id = $1
+  and att.not_before <= $2
+  and (att.not_after is null or att.not_after > $2)
+order by att.attached_to_arn_id`
+)
+
+// Query to lock the resources with a resource ID until the end of the transaction
+const lockResourceQuery = `
+select id
+from aws_resource
+where arn_id = $1
+order by id
+    for update`
+
+// Query to find the change time of the latest changes journaled for a resource ID
+const latestJournaledChangeQuery = `
+select max(change_time)
+from aws_change_journal
+where arn_id = $1`
+
+// queryer runs queries on the database, or within a transaction
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// FetchState gets the IP addresses, hostnames, relationships and attachments held by the resource with the ARN at the
+// specified time
+func (db *DB) FetchState(ctx context.Context, when time.Time, arn string) (domain.CloudAssetState, error) {
+	return db.fetchState(ctx, db.sqldb, when, arn)
+}
+
+// StoreFromState stores the changes derived from the state of the resource with the ARN at the specified time, in a
+// single transaction locking the resource. A resource stored for the first time has nothing to lock yet, its changes
+// are derived from an empty state either way. Changes which were already processed are reported as such rather than
+// stale.
+func (db *DB) StoreFromState(ctx context.Context, when time.Time, arn string,
+	derive func(domain.CloudAssetState) (domain.CloudAssetChanges, error)) error {
+	tx, err := db.sqldb.Begin()
+	if err != nil {
+		return err
+	}
+	if err = db.storeFromState(ctx, tx, when, arn, derive); err != nil {
+		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+			return errors.Wrap(rollbackErr, err.Error()) // so we don't lose the original error
+		}
+		return err
+	}
+	return tx.Commit()
+}
+
+func (db *DB) storeFromState(ctx context.Context, tx *sql.Tx, when time.Time, arn string,
+	derive func(domain.CloudAssetState) (domain.CloudAssetChanges, error)) error {
+	resID := domain.ResourceIDFromARN(arn)
+	if _, err := tx.ExecContext(ctx, lockResourceQuery, resID); err != nil {
+		return err
+	}
+	var latest pq.NullTime
+	if err := tx.QueryRowContext(ctx, latestJournaledChangeQuery, resID).Scan(&latest); err != nil {
+		return err
+	}
+	state, err := db.fetchState(ctx, tx, when, arn)
+	if err != nil {
+		return err
+	}
+	cloudAssetChanges, err := derive(state)
+	if err != nil {
+		return err
+	}
+	if latest.Valid && when.Before(latest.Time) {
+		if err = db.markEventProcessed(ctx, tx, cloudAssetChanges.EventID); err != nil {
+			return err
+		}
+		return domain.StaleChange{ARN: arn, ChangeTime: when, Latest: latest.Time}
+	}
+	return db.storeChanges(ctx, cloudAssetChanges, tx)
+}
+
+func (db *DB) fetchState(ctx context.Context, q queryer, when time.Time, arn string) (domain.CloudAssetState, error) {
+	resID := domain.ResourceIDFromARN(arn)
+	state := domain.CloudAssetState{
+		PrivateIPAddresses: make([]domain.PrivateIPAssignment, 0),
+		PublicIPAddresses:  make([]domain.PublicIPAssignment, 0),
+		RelatedResources:   make([]string, 0),
+		AttachedTo:         make([]string, 0),
+	}
+	rows, err := q.QueryContext(ctx, privateIPStateQuery, resID, when)
+	if err != nil {
+		return domain.CloudAssetState{}, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var assignment domain.PrivateIPAssignment
+		if err = rows.Scan(&assignment.IPAddress, &assignment.VPCID, &assignment.SubnetID, &assignment.Hostname); err != nil {
+			return domain.CloudAssetState{}, err
+		}
+		state.PrivateIPAddresses = append(state.PrivateIPAddresses, assignment)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return domain.CloudAssetState{}, err
+	}
+
+	rows, err = q.QueryContext(ctx, publicIPStateQuery, resID, when)
+	if err != nil {
+		return domain.CloudAssetState{}, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var assignment domain.PublicIPAssignment
+		if err = rows.Scan(&assignment.IPAddress, &assignment.Hostname); err != nil {
+			return domain.CloudAssetState{}, err
+		}
+		state.PublicIPAddresses = append(state.PublicIPAddresses, assignment)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return domain.CloudAssetState{}, err
+	}
+
+	if state.RelatedResources, err = db.fetchStateResourceIDs(ctx, q, relationshipStateQuery, resID, when); err != nil {
+		return domain.CloudAssetState{}, err
+	}
+	if state.AttachedTo, err = db.fetchStateResourceIDs(ctx, q, attachmentStateQuery, resID, when); err != nil {
+		return domain.CloudAssetState{}, err
+	}
+	return state, nil
+}
+
+// fetchStateResourceIDs gets the resource IDs the resource is related or attached to at the specified time
+func (db *DB) fetchStateResourceIDs(ctx context.Context, q queryer, query string, resID string, when time.Time) ([]string, error) {
+	rows, err := q.QueryContext(ctx, query, resID, when)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	resIDs := make([]string, 0)
+	for rows.Next() {
+		var related string
+		if err = rows.Scan(&related); err != nil {
+			return nil, err
+		}
+		resIDs = append(resIDs, related)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return resIDs, nil
+}
+
+// Query to list the resources of a type existing in an account and region at a point in time, resources stored before
+// their existence interval was recorded are considered to exist
+const resourceIDsByAccountRegionTypeQuery = `
+select res.arn_id
+from aws_resource res
+         join aws_account aa on aa.id = res.aws_account_id
+         join aws_region reg on reg.id = res.aws_region_id
+         join aws_resource_type rt on rt.id = res.aws_resource_type_id
+where aa.account = $1
+  and reg.region = $2
+  and rt.resource_type = $3
+  and (res.not_before is null or res.not_before <= $4)
+  and (res.not_after is null or res.not_after > $4)
+order by res.arn_id`
+
+// FetchResourceIDs gets the IDs of the resources of the type existing in the account and region at the specified time
+func (db *DB) FetchResourceIDs(ctx context.Context, when time.Time, accountID string, region string, resourceType string) ([]string, error) {
+	rows, err := db.sqldb.QueryContext(ctx, resourceIDsByAccountRegionTypeQuery, accountID, region, resourceType, when)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	resIDs := make([]string, 0)
+	for rows.Next() {
+		var resID string
+		if err = rows.Scan(&resID); err != nil {
+			return nil, err
+		}
+		resIDs = append(resIDs, resID)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return resIDs, nil
+}
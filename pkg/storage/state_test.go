@@ -0,0 +1,206 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/asecurityteam/asset-inventory-api/pkg/domain"
+)
+
+func TestFetchState(t *testing.T) {
+	mockdb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockdb.Close()
+
+	thedb := DB{
+		sqldb: mockdb,
+	}
+
+	at, _ := time.Parse(time.RFC3339, "2019-04-09T08:55:35+00:00")
+	mock.ExpectQuery("from aws_private_ip_assignment").WithArgs("eni-1", at).WillReturnRows(
+		sqlmock.NewRows([]string{"private_ip", "vpc_id", "subnet_id", "aws_hostname"}).
+			AddRow("10.0.0.1", "vpc-1", "subnet-1", "ip-10-0-0-1.ec2.internal").AddRow("10.0.0.2", "", "", ""))
+	mock.ExpectQuery("from aws_public_ip_assignment").WithArgs("eni-1", at).WillReturnRows(
+		sqlmock.NewRows([]string{"public_ip", "aws_hostname"}).AddRow("34.0.0.1", "example.com"))
+	// relationships recorded without a start are held from the start
+	mock.ExpectQuery(regexp.QuoteMeta("(rel.not_before is null or rel.not_before <= $2)")).WithArgs("eni-1", at).WillReturnRows(
+		sqlmock.NewRows([]string{"related_arn_id"}).AddRow("app/my-alb/1"))
+	mock.ExpectQuery("from aws_resource_attachment att").WithArgs("eni-1", at).WillReturnRows(
+		sqlmock.NewRows([]string{"attached_to_arn_id"}).AddRow("i-1"))
+
+	state, err := thedb.FetchState(context.Background(), at, "arn:aws:ec2:region:aid:network-interface/eni-1")
+	assert.NoError(t, err)
+	assert.Equal(t, domain.CloudAssetState{
+		PrivateIPAddresses: []domain.PrivateIPAssignment{
+			{IPAddress: "10.0.0.1", VPCID: "vpc-1", SubnetID: "subnet-1", Hostname: "ip-10-0-0-1.ec2.internal"},
+			{IPAddress: "10.0.0.2"},
+		},
+		PublicIPAddresses: []domain.PublicIPAssignment{{IPAddress: "34.0.0.1", Hostname: "example.com"}},
+		RelatedResources:  []string{"app/my-alb/1"},
+		AttachedTo:        []string{"i-1"},
+	}, state)
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestFetchStateQueryError(t *testing.T) {
+	mockdb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockdb.Close()
+
+	thedb := DB{
+		sqldb: mockdb,
+	}
+
+	at, _ := time.Parse(time.RFC3339, "2019-04-09T08:55:35+00:00")
+	mock.ExpectQuery("from aws_private_ip_assignment").WithArgs("eni-1", at).WillReturnRows(
+		sqlmock.NewRows([]string{"private_ip", "vpc_id", "subnet_id", "aws_hostname"}))
+	mock.ExpectQuery("from aws_public_ip_assignment").WillReturnError(errors.New("no bueno"))
+
+	_, err = thedb.FetchState(context.Background(), at, "eni-1")
+	assert.Error(t, err)
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestFetchResourceIDs(t *testing.T) {
+	mockdb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockdb.Close()
+
+	thedb := DB{
+		sqldb: mockdb,
+	}
+
+	at, _ := time.Parse(time.RFC3339, "2019-04-09T08:55:35+00:00")
+	mock.ExpectQuery("from aws_resource res").WithArgs("aid", "region", "AWS::EC2::Instance", at).WillReturnRows(
+		sqlmock.NewRows([]string{"arn_id"}).AddRow("i-1").AddRow("i-2"))
+
+	resIDs, err := thedb.FetchResourceIDs(context.Background(), at, "aid", "region", "AWS::EC2::Instance")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"i-1", "i-2"}, resIDs)
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func expectEmptyState(mock sqlmock.Sqlmock, resID string, at time.Time) {
+	mock.ExpectQuery("from aws_private_ip_assignment").WithArgs(resID, at).WillReturnRows(
+		sqlmock.NewRows([]string{"private_ip", "vpc_id", "subnet_id", "aws_hostname"}))
+	mock.ExpectQuery("from aws_public_ip_assignment").WithArgs(resID, at).WillReturnRows(
+		sqlmock.NewRows([]string{"public_ip", "aws_hostname"}))
+	mock.ExpectQuery("from aws_resource_relationship").WithArgs(resID, at).WillReturnRows(
+		sqlmock.NewRows([]string{"related_arn_id"}))
+	mock.ExpectQuery("from aws_resource_attachment").WithArgs(resID, at).WillReturnRows(
+		sqlmock.NewRows([]string{"attached_to_arn_id"}))
+}
+
+func TestStoreFromState(t *testing.T) {
+	mockdb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockdb.Close()
+
+	thedb := DB{
+		sqldb: mockdb,
+	}
+
+	at, _ := time.Parse(time.RFC3339, "2019-04-09T08:55:35+00:00")
+	changes := fakeLifecycleChanges("arn", at)
+	mock.ExpectBegin()
+	// the resource is locked before its state is read, and the state read within the transaction storing the changes
+	mock.ExpectExec(`(?s)from aws_resource.+for update`).WithArgs("arn").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(regexp.QuoteMeta("select max(change_time)")).WithArgs("arn").
+		WillReturnRows(sqlmock.NewRows([]string{"max"}).AddRow(at))
+	expectEmptyState(mock, "arn", at)
+	expectStored(mock, "arn", 1)
+	mock.ExpectCommit()
+
+	err = thedb.StoreFromState(context.Background(), at, "arn", func(state domain.CloudAssetState) (domain.CloudAssetChanges, error) {
+		assert.Empty(t, state.PrivateIPAddresses)
+		return changes, nil
+	})
+	assert.NoError(t, err)
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestStoreFromStateStale(t *testing.T) {
+	mockdb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockdb.Close()
+
+	at, _ := time.Parse(time.RFC3339, "2019-04-09T08:55:35+00:00")
+	thedb := DB{
+		sqldb:         mockdb,
+		now:           func() time.Time { return at },
+		eventIDWindow: time.Hour,
+	}
+
+	stale := fakeLifecycleChanges("arn", at)
+	stale.EventID = "event-1"
+	mock.ExpectBegin()
+	mock.ExpectExec(`(?s)from aws_resource.+for update`).WithArgs("arn").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(regexp.QuoteMeta("select max(change_time)")).WithArgs("arn").
+		WillReturnRows(sqlmock.NewRows([]string{"max"}).AddRow(at.Add(time.Minute)))
+	expectEmptyState(mock, "arn", at)
+	mock.ExpectExec(regexp.QuoteMeta(`insert into aws_processed_event`)).WithArgs("event-1", at, at.Add(-time.Hour)).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`delete`)).WithArgs(at.Add(-time.Hour)).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+
+	err = thedb.StoreFromState(context.Background(), at, "arn", func(domain.CloudAssetState) (domain.CloudAssetChanges, error) {
+		return stale, nil
+	})
+	assert.Equal(t, domain.StaleChange{ARN: "arn", ChangeTime: at, Latest: at.Add(time.Minute)}, err)
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestStoreFromStateDeriveError(t *testing.T) {
+	mockdb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockdb.Close()
+
+	thedb := DB{
+		sqldb: mockdb,
+	}
+
+	at, _ := time.Parse(time.RFC3339, "2019-04-09T08:55:35+00:00")
+	mock.ExpectBegin()
+	mock.ExpectExec(`(?s)from aws_resource.+for update`).WithArgs("arn").WillReturnResult(sqlmock.NewResult(0, 0))
+	// nothing journaled yet
+	mock.ExpectQuery(regexp.QuoteMeta("select max(change_time)")).WithArgs("arn").
+		WillReturnRows(sqlmock.NewRows([]string{"max"}).AddRow(nil))
+	expectEmptyState(mock, "arn", at)
+	mock.ExpectRollback()
+
+	deriveErr := errors.New("no bueno")
+	err = thedb.StoreFromState(context.Background(), at, "arn", func(domain.CloudAssetState) (domain.CloudAssetChanges, error) {
+		return domain.CloudAssetChanges{}, deriveErr
+	})
+	assert.Equal(t, deriveErr, err)
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
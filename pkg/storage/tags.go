@@ -0,0 +1,57 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+
+	"github.com/asecurityteam/asset-inventory-api/pkg/domain"
+)
+
+// Query to list a page of resources whose tags at the point in time contain the exact values, have all of the keys and
+// start with the prefixes given as parallel key and prefix arrays. Containment and key existence use the GIN index.
+const resourceIDsByTagsQuery = `
+select distinct t.aws_resource_id
+from aws_resource_tags t
+where t.not_before < $1
+  and (t.not_after is null or t.not_after > $1)
+  and t.meta @> $2::jsonb
+  and t.meta ?& $3::text[]
+  and not exists(select 1
+                 from unnest($4::text[], $5::text[]) as p(key, prefix)
+                 where left(t.meta ->> p.key, length(p.prefix)) is distinct from p.prefix)
+  and t.aws_resource_id > $7
+order by t.aws_resource_id
+limit $6`
+
+// FetchByTags gets a page of the assets whose tags match all of the predicates at the specified time, starting after
+// the given resource key
+func (db *DB) FetchByTags(ctx context.Context, when time.Time, predicates []domain.TagPredicate, count uint, after int64) ([]domain.CloudAssetDetails, int64, error) {
+	exact := make(map[string]string)
+	keys := make([]string, 0, len(predicates))
+	prefixKeys := make([]string, 0)
+	prefixes := make([]string, 0)
+	for _, predicate := range predicates {
+		switch strings.ToLower(predicate.Match) {
+		case domain.TagMatchExact:
+			if value, ok := exact[predicate.Key]; ok && value != predicate.Value {
+				return []domain.CloudAssetDetails{}, after, nil // a tag can not hold two values at once
+			}
+			exact[predicate.Key] = predicate.Value
+		case domain.TagMatchPrefix:
+			prefixKeys = append(prefixKeys, predicate.Key)
+			prefixes = append(prefixes, predicate.Value)
+		case domain.TagMatchExists:
+		default:
+			return nil, 0, fmt.Errorf("unknown tag match %s", predicate.Match)
+		}
+		keys = append(keys, predicate.Key)
+	}
+	exactBytes, _ := json.Marshal(exact) // an error here is not possible considering json.Marshal is taking a simple map
+	return db.fetchPage(ctx, when, after, resourceIDsByTagsQuery,
+		when, exactBytes, pq.Array(keys), pq.Array(prefixKeys), pq.Array(prefixes), count, after)
+}
@@ -0,0 +1,117 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/asecurityteam/asset-inventory-api/pkg/domain"
+)
+
+func TestFetchByTags(t *testing.T) {
+	mockdb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockdb.Close()
+
+	thedb := DB{
+		sqldb: mockdb,
+	}
+
+	at, _ := time.Parse(time.RFC3339, "2019-04-09T08:55:35+00:00")
+	predicates := []domain.TagPredicate{
+		{Key: "env", Value: "prod", Match: domain.TagMatchExact},
+		{Key: "service_name", Value: "foo", Match: domain.TagMatchPrefix},
+		{Key: "owner", Match: domain.TagMatchExists},
+	}
+	mock.ExpectQuery("from aws_resource_tags").
+		WithArgs(at, []byte(`{"env":"prod"}`), pq.Array([]string{"env", "service_name", "owner"}),
+			pq.Array([]string{"service_name"}), pq.Array([]string{"foo"}), 10, 0).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3)).RowsWillBeClosed()
+	rows := sqlmock.NewRows(assetDetailsColumns).
+		AddRow(3, "rid3", "type", "aid", "region", []byte(`{"env":"prod","service_name":"foo-api","owner":"me"}`),
+			"10.0.0.3", nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil)
+	mock.ExpectQuery("select res.id,").WithArgs(pq.Array([]int64{3}), at).WillReturnRows(rows).RowsWillBeClosed()
+
+	results, last, err := thedb.FetchByTags(context.Background(), at, predicates, 10, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), last)
+	assert.Equal(t, []domain.CloudAssetDetails{
+		{
+			PrivateIPAddresses: []string{"10.0.0.3"},
+			ResourceType:       "type",
+			AccountID:          "aid",
+			Region:             "region",
+			ARN:                "rid3",
+			Tags:               map[string]string{"env": "prod", "service_name": "foo-api", "owner": "me"},
+			AccountOwner: domain.AccountOwner{
+				Champions: []domain.Person{},
+			},
+		},
+	}, results)
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestFetchByTagsQueryError(t *testing.T) {
+	mockdb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockdb.Close()
+
+	thedb := DB{
+		sqldb: mockdb,
+	}
+
+	at, _ := time.Parse(time.RFC3339, "2019-04-09T08:55:35+00:00")
+	mock.ExpectQuery("from aws_resource_tags").WillReturnError(errors.New("no bueno"))
+
+	_, _, err = thedb.FetchByTags(context.Background(), at,
+		[]domain.TagPredicate{{Key: "env", Value: "prod", Match: domain.TagMatchExact}}, 10, 20)
+	assert.Error(t, err)
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestFetchByTagsConflictingValues(t *testing.T) {
+	mockdb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockdb.Close()
+
+	thedb := DB{
+		sqldb: mockdb,
+	}
+
+	at, _ := time.Parse(time.RFC3339, "2019-04-09T08:55:35+00:00")
+	predicates := []domain.TagPredicate{
+		{Key: "env", Value: "prod", Match: domain.TagMatchExact},
+		{Key: "env", Value: "dev", Match: domain.TagMatchExact},
+	}
+
+	results, last, err := thedb.FetchByTags(context.Background(), at, predicates, 10, 20)
+	assert.NoError(t, err)
+	assert.Empty(t, results)
+	assert.Equal(t, int64(20), last)
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestFetchByTagsUnknownMatch(t *testing.T) {
+	thedb := DB{}
+	at, _ := time.Parse(time.RFC3339, "2019-04-09T08:55:35+00:00")
+	_, _, err := thedb.FetchByTags(context.Background(), at,
+		[]domain.TagPredicate{{Key: "env", Value: "prod", Match: "regex"}}, 10, 0)
+	assert.Error(t, err)
+}